@@ -0,0 +1,10 @@
+// Package receiptprocessor embeds the OpenAPI specification in the binaries, so that the API
+// is validated against it whatever directory they are started from.
+package receiptprocessor
+
+import _ "embed"
+
+// OpenAPISpec is the content of api.yml.
+//
+//go:embed api.yml
+var OpenAPISpec []byte
@@ -1,28 +1,41 @@
 package main
 
 import (
-	receiptHttp "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/delivery/http"
-	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/repository"
-	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/service"
+	"context"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/config"
 	"github.com/CarlosMtz98/receipt-processor-challenge/internal/server"
 	"log"
-	"os"
+	"net"
 )
 
 func main() {
 	log.Println("Starting Server")
-	receiptRepo := repository.InitReceiptRepository()
-	receiptService := service.NewReceiptService(receiptRepo)
-	receiptHandler := receiptHttp.NewReceiptHandler(receiptService)
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Error loading configuration: %v", err)
+	}
+
+	app, err := server.NewApp(cfg)
+	if err != nil {
+		log.Fatalf("Error building the server: %v", err)
+	}
+	app.Start(context.Background())
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "7070"
+	if cfg.GRPCPort != "" {
+		listener, err := net.Listen("tcp", ":"+cfg.GRPCPort)
+		if err != nil {
+			log.Fatalf("Error listening on gRPC port: %v", err)
+		}
+		go func() {
+			if err := app.GRPCServer.Serve(listener); err != nil {
+				log.Fatalf("Error serving gRPC: %v", err)
+			}
+		}()
+		log.Printf("gRPC server listening on port: %s (reflection: %t)", cfg.GRPCPort, cfg.GRPCReflection)
 	}
 
-	r := server.SetupRoutes(receiptHandler)
-	log.Printf("Server listening on port: %s", port)
-	err := r.Run(":" + port)
+	log.Printf("Server listening on port: %s", cfg.Port)
+	err = app.Router.Run(":" + cfg.Port)
 	if err != nil {
 		log.Fatalf("Error starting server: %v", err)
 	}
@@ -0,0 +1,14 @@
+package main
+
+import (
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/receiptctl"
+	"os"
+)
+
+func main() {
+	os.Exit(receiptctl.Run(os.Args[1:], receiptctl.IO{
+		Stdin:  os.Stdin,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	}))
+}
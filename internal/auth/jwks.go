@@ -0,0 +1,269 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrUnknownKey is returned when no key in the set matches the token key id.
+	ErrUnknownKey = errors.New("no matching key found in the key set")
+	// ErrEmptyKeySet is returned when the key set contains no usable keys.
+	ErrEmptyKeySet = errors.New("the key set does not contain any usable key")
+)
+
+// minRefreshInterval bounds how often an unknown key id can force a key set reload.
+const minRefreshInterval = 30 * time.Second
+
+// KeySource resolves the public key used to verify a token.
+type KeySource interface {
+	Key(ctx context.Context, kid string) (*PublicKey, error)
+}
+
+// PublicKey is a verification key loaded from a JWKS document.
+type PublicKey struct {
+	ID        string
+	Algorithm string
+	Key       crypto.PublicKey
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+// JWKSCache loads a JSON Web Key Set and keeps it cached for a TTL. A token
+// signed with an unknown key id triggers an early reload so that rotated keys
+// are picked up without waiting for the cache to expire.
+type JWKSCache struct {
+	fetch func(ctx context.Context) ([]byte, error)
+	ttl   time.Duration
+	now   func() time.Time
+
+	mu          sync.RWMutex
+	keys        map[string]*PublicKey
+	fetchedAt   time.Time
+	lastAttempt time.Time
+}
+
+// NewFileJWKS builds a key set cache backed by a local JWKS file.
+func NewFileJWKS(path string, ttl time.Duration) *JWKSCache {
+	return newJWKSCache(func(ctx context.Context) ([]byte, error) {
+		return os.ReadFile(path)
+	}, ttl)
+}
+
+// NewRemoteJWKS builds a key set cache backed by a JWKS endpoint.
+func NewRemoteJWKS(url string, client *http.Client, ttl time.Duration) *JWKSCache {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return newJWKSCache(func(ctx context.Context) ([]byte, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("unexpected status fetching key set: %d", resp.StatusCode)
+		}
+		return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	}, ttl)
+}
+
+func newJWKSCache(fetch func(ctx context.Context) ([]byte, error), ttl time.Duration) *JWKSCache {
+	return &JWKSCache{
+		fetch: fetch,
+		ttl:   ttl,
+		now:   time.Now,
+	}
+}
+
+// Key returns the key with the given id. An empty kid matches the only key of a single-key set.
+func (c *JWKSCache) Key(ctx context.Context, kid string) (*PublicKey, error) {
+	c.mu.RLock()
+	key, found := c.lookup(kid)
+	stale := c.fetchedAt.IsZero() || c.now().Sub(c.fetchedAt) > c.ttl
+	c.mu.RUnlock()
+
+	if found && !stale {
+		return key, nil
+	}
+
+	if err := c.refresh(ctx, !found); err != nil {
+		if found {
+			log.Printf("Serving stale key set after refresh failure: %v", err)
+			return key, nil
+		}
+		return nil, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if key, found = c.lookup(kid); found {
+		return key, nil
+	}
+	return nil, ErrUnknownKey
+}
+
+func (c *JWKSCache) lookup(kid string) (*PublicKey, bool) {
+	if kid == "" {
+		if len(c.keys) != 1 {
+			return nil, false
+		}
+		for _, key := range c.keys {
+			return key, true
+		}
+	}
+	key, ok := c.keys[kid]
+	return key, ok
+}
+
+func (c *JWKSCache) refresh(ctx context.Context, missingKey bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if c.keys != nil {
+		fresh := now.Sub(c.fetchedAt) <= c.ttl
+		throttled := now.Sub(c.lastAttempt) < minRefreshInterval
+		// Another caller refreshed the set, or a reload was attempted too recently.
+		if (fresh && !missingKey) || throttled {
+			return nil
+		}
+	}
+	c.lastAttempt = now
+
+	raw, err := c.fetch(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load key set: %v", err)
+	}
+
+	keys, err := parseJWKS(raw)
+	if err != nil {
+		return err
+	}
+
+	c.keys = keys
+	c.fetchedAt = now
+	return nil
+}
+
+func parseJWKS(raw []byte) (map[string]*PublicKey, error) {
+	var set jsonWebKeySet
+	if err := json.Unmarshal(raw, &set); err != nil {
+		return nil, fmt.Errorf("failed to parse key set: %v", err)
+	}
+
+	keys := make(map[string]*PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		key, err := jwk.publicKey()
+		if err != nil {
+			log.Printf("Skipping key %q from key set: %v", jwk.Kid, err)
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+
+	if len(keys) == 0 {
+		return nil, ErrEmptyKeySet
+	}
+	return keys, nil
+}
+
+func (jwk jsonWebKey) publicKey() (*PublicKey, error) {
+	switch jwk.Kty {
+	case "RSA":
+		if jwk.Alg != "" && jwk.Alg != AlgRS256 {
+			return nil, fmt.Errorf("unsupported algorithm %q for RSA key", jwk.Alg)
+		}
+		n, err := decodeBigInt(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("rsa exponent is too large")
+		}
+		return &PublicKey{
+			ID:        jwk.Kid,
+			Algorithm: AlgRS256,
+			Key:       &rsa.PublicKey{N: n, E: int(e.Int64())},
+		}, nil
+	case "EC":
+		if jwk.Alg != "" && jwk.Alg != AlgES256 {
+			return nil, fmt.Errorf("unsupported algorithm %q for EC key", jwk.Alg)
+		}
+		if jwk.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %q", jwk.Crv)
+		}
+		x, err := decodeBigInt(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !elliptic.P256().IsOnCurve(x, y) {
+			return nil, errors.New("ec point is not on the P-256 curve")
+		}
+		return &PublicKey{
+			ID:        jwk.Kid,
+			Algorithm: AlgES256,
+			Key:       &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y},
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", jwk.Kty)
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	if s == "" {
+		return nil, errors.New("missing key parameter")
+	}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key parameter encoding: %v", err)
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"context"
+)
+
+// principalContextKey is the key under which the authenticated principal is stored. A
+// gin.Context resolves it through the context of its request.
+type principalContextKey struct{}
+
+// Principal is the authenticated caller derived from the token claims.
+type Principal struct {
+	Subject  string
+	Issuer   string
+	ClientID string
+	Scopes   []string
+	Claims   map[string]interface{}
+}
+
+// HasScopes reports whether the principal was granted every one of the given scopes.
+func (p *Principal) HasScopes(scopes ...string) bool {
+	if p == nil {
+		return len(scopes) == 0
+	}
+
+	granted := make(map[string]struct{}, len(p.Scopes))
+	for _, scope := range p.Scopes {
+		granted[scope] = struct{}{}
+	}
+
+	for _, scope := range scopes {
+		if _, ok := granted[scope]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// WithPrincipal returns a copy of ctx carrying the principal.
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalContextKey{}, p)
+}
+
+// PrincipalFromContext returns the principal stored in ctx, if any.
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	p, ok := ctx.Value(principalContextKey{}).(*Principal)
+	return p, ok && p != nil
+}
@@ -0,0 +1,232 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+const (
+	// AlgRS256 is RSASSA-PKCS1-v1_5 using SHA-256.
+	AlgRS256 = "RS256"
+	// AlgES256 is ECDSA using P-256 and SHA-256.
+	AlgES256 = "ES256"
+)
+
+var (
+	// ErrMalformedToken is returned when the token is not a valid compact JWS.
+	ErrMalformedToken = errors.New("the token is malformed")
+	// ErrUnsupportedAlgorithm is returned when the token is not signed with RS256 or ES256.
+	ErrUnsupportedAlgorithm = errors.New("the token signing algorithm is not supported")
+	// ErrInvalidSignature is returned when the token signature does not verify.
+	ErrInvalidSignature = errors.New("the token signature is invalid")
+	// ErrTokenExpired is returned when the token is past its expiry time.
+	ErrTokenExpired = errors.New("the token has expired")
+	// ErrTokenNotYetValid is returned when the token is used before its not-before time.
+	ErrTokenNotYetValid = errors.New("the token is not valid yet")
+	// ErrInvalidIssuer is returned when the token issuer does not match the configured one.
+	ErrInvalidIssuer = errors.New("the token issuer is not accepted")
+	// ErrInvalidAudience is returned when the token is not intended for this service.
+	ErrInvalidAudience = errors.New("the token audience is not accepted")
+)
+
+// VerifierOptions configures which claims a Verifier enforces.
+type VerifierOptions struct {
+	Issuer    string
+	Audience  string
+	ClockSkew time.Duration
+}
+
+// Verifier validates bearer tokens and maps their claims to a Principal.
+type Verifier struct {
+	keys    KeySource
+	options VerifierOptions
+	now     func() time.Time
+}
+
+func NewVerifier(keys KeySource, options VerifierOptions) *Verifier {
+	return &Verifier{
+		keys:    keys,
+		options: options,
+		now:     time.Now,
+	}
+}
+
+type tokenHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	Typ string `json:"typ"`
+}
+
+type tokenClaims struct {
+	Issuer    string   `json:"iss"`
+	Subject   string   `json:"sub"`
+	Audience  audience `json:"aud"`
+	ExpiresAt *int64   `json:"exp"`
+	NotBefore *int64   `json:"nbf"`
+	Scope     string   `json:"scope"`
+	Scp       []string `json:"scp"`
+	ClientID  string   `json:"client_id"`
+	Azp       string   `json:"azp"`
+}
+
+// audience accepts both the string and the array forms of the "aud" claim.
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
+
+// Verify checks the token signature and registered claims and returns the caller principal.
+func (v *Verifier) Verify(ctx context.Context, token string) (*Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformedToken
+	}
+
+	var header tokenHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, ErrMalformedToken
+	}
+
+	if header.Alg != AlgRS256 && header.Alg != AlgES256 {
+		return nil, ErrUnsupportedAlgorithm
+	}
+
+	key, err := v.keys.Key(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+
+	if key.Algorithm != header.Alg {
+		return nil, ErrUnsupportedAlgorithm
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrMalformedToken
+	}
+
+	if err := verifySignature(key, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	var claims tokenClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, ErrMalformedToken
+	}
+
+	if err := v.validateClaims(&claims); err != nil {
+		return nil, err
+	}
+
+	var rawClaims map[string]interface{}
+	if err := decodeSegment(parts[1], &rawClaims); err != nil {
+		return nil, ErrMalformedToken
+	}
+
+	return newPrincipal(&claims, rawClaims), nil
+}
+
+func (v *Verifier) validateClaims(claims *tokenClaims) error {
+	now := v.now()
+	skew := v.options.ClockSkew
+
+	if claims.ExpiresAt == nil {
+		return ErrTokenExpired
+	}
+	if now.After(time.Unix(*claims.ExpiresAt, 0).Add(skew)) {
+		return ErrTokenExpired
+	}
+	if claims.NotBefore != nil && now.Add(skew).Before(time.Unix(*claims.NotBefore, 0)) {
+		return ErrTokenNotYetValid
+	}
+
+	if v.options.Issuer != "" && claims.Issuer != v.options.Issuer {
+		return ErrInvalidIssuer
+	}
+
+	if v.options.Audience != "" {
+		for _, aud := range claims.Audience {
+			if aud == v.options.Audience {
+				return nil
+			}
+		}
+		return ErrInvalidAudience
+	}
+
+	return nil
+}
+
+func newPrincipal(claims *tokenClaims, raw map[string]interface{}) *Principal {
+	scopes := strings.Fields(claims.Scope)
+	if len(scopes) == 0 {
+		scopes = claims.Scp
+	}
+
+	clientID := claims.ClientID
+	if clientID == "" {
+		clientID = claims.Azp
+	}
+
+	return &Principal{
+		Subject:  claims.Subject,
+		Issuer:   claims.Issuer,
+		ClientID: clientID,
+		Scopes:   scopes,
+		Claims:   raw,
+	}
+}
+
+func verifySignature(key *PublicKey, signingInput string, signature []byte) error {
+	digest := sha256.Sum256([]byte(signingInput))
+
+	switch pub := key.Key.(type) {
+	case *rsa.PublicKey:
+		if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], signature); err != nil {
+			return ErrInvalidSignature
+		}
+		return nil
+	case *ecdsa.PublicKey:
+		// JWS encodes ECDSA signatures as the fixed-size concatenation of r and s.
+		if len(signature) != 64 {
+			return ErrInvalidSignature
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(pub, digest[:], r, s) {
+			return ErrInvalidSignature
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: %T", ErrUnsupportedAlgorithm, key.Key)
+	}
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	raw, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
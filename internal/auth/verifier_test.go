@@ -0,0 +1,226 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestVerifier_Verify(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	jwksPath := filepath.Join(t.TempDir(), "jwks.json")
+	writeJWKS(t, jwksPath, rsaJWK("rsa-1", &rsaKey.PublicKey), ecJWK("ec-1", &ecKey.PublicKey))
+
+	verifier := NewVerifier(NewFileJWKS(jwksPath, time.Minute), VerifierOptions{
+		Issuer:    "https://idp.example.com",
+		Audience:  "receipt-processor",
+		ClockSkew: 30 * time.Second,
+	})
+
+	validClaims := func() map[string]interface{} {
+		return map[string]interface{}{
+			"iss":   "https://idp.example.com",
+			"sub":   "partner-app",
+			"aud":   []string{"receipt-processor", "other"},
+			"exp":   time.Now().Add(time.Hour).Unix(),
+			"scope": "receipts:read receipts:write",
+			"azp":   "partner-client",
+		}
+	}
+
+	t.Run("RS256 token", func(t *testing.T) {
+		token := signRS256(t, rsaKey, "rsa-1", validClaims())
+		principal, err := verifier.Verify(context.Background(), token)
+		assert.NoError(t, err)
+		assert.Equal(t, "partner-app", principal.Subject)
+		assert.Equal(t, "partner-client", principal.ClientID)
+		assert.True(t, principal.HasScopes("receipts:read", "receipts:write"))
+		assert.False(t, principal.HasScopes("admin"))
+	})
+
+	t.Run("ES256 token", func(t *testing.T) {
+		claims := validClaims()
+		claims["aud"] = "receipt-processor"
+		token := signES256(t, ecKey, "ec-1", claims)
+		principal, err := verifier.Verify(context.Background(), token)
+		assert.NoError(t, err)
+		assert.Equal(t, "partner-app", principal.Subject)
+	})
+
+	t.Run("Expired within clock skew", func(t *testing.T) {
+		claims := validClaims()
+		claims["exp"] = time.Now().Add(-10 * time.Second).Unix()
+		_, err := verifier.Verify(context.Background(), signRS256(t, rsaKey, "rsa-1", claims))
+		assert.NoError(t, err)
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		claims := validClaims()
+		claims["exp"] = time.Now().Add(-time.Minute).Unix()
+		_, err := verifier.Verify(context.Background(), signRS256(t, rsaKey, "rsa-1", claims))
+		assert.Equal(t, ErrTokenExpired, err)
+	})
+
+	t.Run("Not yet valid", func(t *testing.T) {
+		claims := validClaims()
+		claims["nbf"] = time.Now().Add(time.Hour).Unix()
+		_, err := verifier.Verify(context.Background(), signRS256(t, rsaKey, "rsa-1", claims))
+		assert.Equal(t, ErrTokenNotYetValid, err)
+	})
+
+	t.Run("Wrong issuer", func(t *testing.T) {
+		claims := validClaims()
+		claims["iss"] = "https://evil.example.com"
+		_, err := verifier.Verify(context.Background(), signRS256(t, rsaKey, "rsa-1", claims))
+		assert.Equal(t, ErrInvalidIssuer, err)
+	})
+
+	t.Run("Wrong audience", func(t *testing.T) {
+		claims := validClaims()
+		claims["aud"] = "another-service"
+		_, err := verifier.Verify(context.Background(), signRS256(t, rsaKey, "rsa-1", claims))
+		assert.Equal(t, ErrInvalidAudience, err)
+	})
+
+	t.Run("Tampered signature", func(t *testing.T) {
+		otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		assert.NoError(t, err)
+		_, err = verifier.Verify(context.Background(), signRS256(t, otherKey, "rsa-1", validClaims()))
+		assert.Equal(t, ErrInvalidSignature, err)
+	})
+
+	t.Run("Algorithm does not match key", func(t *testing.T) {
+		_, err := verifier.Verify(context.Background(), signES256(t, ecKey, "rsa-1", validClaims()))
+		assert.Equal(t, ErrUnsupportedAlgorithm, err)
+	})
+
+	t.Run("Unsigned token", func(t *testing.T) {
+		header := encodeSegment(t, map[string]string{"alg": "none"})
+		payload := encodeSegment(t, validClaims())
+		_, err := verifier.Verify(context.Background(), header+"."+payload+".")
+		assert.Equal(t, ErrUnsupportedAlgorithm, err)
+	})
+
+	t.Run("Malformed token", func(t *testing.T) {
+		_, err := verifier.Verify(context.Background(), "not-a-token")
+		assert.Equal(t, ErrMalformedToken, err)
+	})
+}
+
+func TestJWKSCache_KeyRotation(t *testing.T) {
+	oldKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	newKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	var current atomic.Value
+	current.Store(rsaJWK("old", &oldKey.PublicKey))
+	var fetches atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []interface{}{current.Load()},
+		})
+	}))
+	defer server.Close()
+
+	cache := NewRemoteJWKS(server.URL, server.Client(), time.Hour)
+	clock := time.Now()
+	cache.now = func() time.Time { return clock }
+
+	key, err := cache.Key(context.Background(), "old")
+	assert.NoError(t, err)
+	assert.Equal(t, "old", key.ID)
+
+	// Cached keys are served without going back to the endpoint.
+	_, err = cache.Key(context.Background(), "old")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), fetches.Load())
+
+	// An unknown kid right after a fetch is throttled.
+	current.Store(rsaJWK("new", &newKey.PublicKey))
+	_, err = cache.Key(context.Background(), "new")
+	assert.Equal(t, ErrUnknownKey, err)
+	assert.Equal(t, int32(1), fetches.Load())
+
+	// Once the throttle window passes the rotated key is loaded.
+	clock = clock.Add(minRefreshInterval + time.Second)
+	key, err = cache.Key(context.Background(), "new")
+	assert.NoError(t, err)
+	assert.Equal(t, "new", key.ID)
+	assert.Equal(t, int32(2), fetches.Load())
+}
+
+func writeJWKS(t *testing.T, path string, keys ...map[string]string) {
+	t.Helper()
+	raw, err := json.Marshal(map[string]interface{}{"keys": keys})
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(path, raw, 0o600))
+}
+
+func rsaJWK(kid string, key *rsa.PublicKey) map[string]string {
+	return map[string]string{
+		"kty": "RSA",
+		"kid": kid,
+		"use": "sig",
+		"alg": AlgRS256,
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func ecJWK(kid string, key *ecdsa.PublicKey) map[string]string {
+	return map[string]string{
+		"kty": "EC",
+		"kid": kid,
+		"crv": "P-256",
+		"x":   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+		"y":   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+	}
+}
+
+func signRS256(t *testing.T, key *rsa.PrivateKey, kid string, claims interface{}) string {
+	t.Helper()
+	input := encodeSegment(t, map[string]string{"alg": AlgRS256, "kid": kid, "typ": "JWT"}) + "." + encodeSegment(t, claims)
+	digest := sha256.Sum256([]byte(input))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	assert.NoError(t, err)
+	return input + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func signES256(t *testing.T, key *ecdsa.PrivateKey, kid string, claims interface{}) string {
+	t.Helper()
+	input := encodeSegment(t, map[string]string{"alg": AlgES256, "kid": kid, "typ": "JWT"}) + "." + encodeSegment(t, claims)
+	digest := sha256.Sum256([]byte(input))
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	assert.NoError(t, err)
+	signature := append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	return input + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func encodeSegment(t *testing.T, v interface{}) string {
+	t.Helper()
+	raw, err := json.Marshal(v)
+	assert.NoError(t, err)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
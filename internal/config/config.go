@@ -0,0 +1,564 @@
+package config
+
+import (
+	"fmt"
+	"github.com/CarlosMtz98/receipt-processor-challenge/pkg/timezone"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config holds the runtime settings of the service, loaded from environment variables.
+type Config struct {
+	Port string
+	// GRPCPort serves the gRPC API. Setting GRPC_PORT to an empty value disables it.
+	GRPCPort string
+	// GRPCReflection registers the reflection service, which lists the API to anyone. It
+	// defaults to true unless GIN_MODE is release.
+	GRPCReflection bool
+	Auth           AuthConfig
+	RateLimit      RateLimitConfig
+	// TrustedProxies are the addresses or CIDR ranges of the proxies whose X-Forwarded-For
+	// header is trusted for the client IP. None are trusted by default.
+	TrustedProxies []string
+	// TerminalRegistryFile lists the trusted POS terminal keys and retailer signature policies.
+	TerminalRegistryFile string
+	Fraud                FraudConfig
+	Webhook              WebhookConfig
+	// OutboxPollInterval is how often committed events are relayed to the event bus.
+	OutboxPollInterval time.Duration
+	// StreamReplayBuffer is how many events the receipts stream keeps for reconnecting clients.
+	StreamReplayBuffer int
+	GraphQL            GraphQLConfig
+	OpenAPI            OpenAPIConfig
+	Mail               MailConfig
+	Rules              RulesConfig
+	// TotalTolerance is the largest difference accepted between the total of a receipt and
+	// what its items, discounts, taxes and tip add up to, in the currency of the receipt.
+	TotalTolerance float64
+	Currency       CurrencyConfig
+	// RetailerTimeZones are the time zones of the stores of each retailer, keyed by lowercase
+	// name, for the receipts that don't state one.
+	RetailerTimeZones map[string]string
+}
+
+// AuthConfig configures bearer token authentication. Authentication is disabled
+// unless a JWKS file or URL is provided.
+type AuthConfig struct {
+	JWKSFile     string
+	JWKSURL      string
+	JWKSCacheTTL time.Duration
+	Issuer       string
+	Audience     string
+	ClockSkew    time.Duration
+	// APIKeys maps the keys issued to integrations, sent in the X-API-Key header, to their
+	// names. Only these keys identify a client; the others are treated as absent.
+	APIKeys map[string]string
+}
+
+// RateLimitConfig configures per-client request limits. Limits are disabled when no rate is set.
+type RateLimitConfig struct {
+	RequestsPerSecond    float64
+	Burst                int
+	Routes               map[string]RouteLimit
+	DailySubmissionQuota int
+}
+
+// FraudConfig configures the fraud checks run when receipts are submitted.
+type FraudConfig struct {
+	Enabled       bool
+	HoldThreshold int
+	// FutureTolerance applies to the receipts without a time zone, read in UTC, and
+	// ZonedFutureTolerance to the others.
+	FutureTolerance      time.Duration
+	ZonedFutureTolerance time.Duration
+	MaxReceiptAge        time.Duration
+	MaxTotal             float64
+	VelocityLimit        int
+	VelocityWindow       time.Duration
+	RepeatedItemsLimit   int
+}
+
+// WebhookConfig tunes the background delivery of webhook notifications.
+type WebhookConfig struct {
+	MaxAttempts int
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	Timeout     time.Duration
+	// AllowPrivateNetworks accepts webhooks in loopback, link-local and private networks.
+	AllowPrivateNetworks bool
+}
+
+// GraphQLConfig bounds the cost of the queries accepted by the GraphQL endpoint.
+type GraphQLConfig struct {
+	MaxDepth      int
+	MaxComplexity int
+}
+
+// OpenAPIConfig configures the validation of the API traffic against the OpenAPI spec.
+// Validation is disabled when OPENAPI_SPEC_FILE is set to an empty value.
+type OpenAPIConfig struct {
+	Enabled bool
+	// SpecFile replaces the spec embedded in the binary when set.
+	SpecFile string
+	// ValidateResponses buffers and checks every documented response. It defaults to true
+	// unless GIN_MODE is release.
+	ValidateResponses bool
+}
+
+// MailConfig configures the receipts forwarded by email. The maildir watcher is disabled
+// unless EMAIL_MAILDIR is set.
+type MailConfig struct {
+	Maildir      string
+	PollInterval time.Duration
+	// MinConfidence is the confidence a receipt read from an email body needs to be submitted.
+	MinConfidence float64
+}
+
+// CurrencyConfig configures the base currency receipts are scored in. Receipts in other
+// currencies are only accepted when ExchangeRatesFile has a rate for their purchase date.
+type CurrencyConfig struct {
+	Base              string
+	ExchangeRatesFile string
+}
+
+// RulesConfig selects the variants of the scoring rules. The defaults are the original rules.
+type RulesConfig struct {
+	// QuantityAware counts every unit of an item with a quantity in the item rules.
+	QuantityAware bool
+	// PreTax scores the total rules on the amount before taxes and tip. RULES_AMOUNT_BASIS
+	// is pre-tax or post-tax, the default.
+	PreTax bool
+	// CanonicalRetailer scores the retailer name rule on the registered name of the retailer.
+	CanonicalRetailer bool
+}
+
+// RouteLimit overrides the default limit for a single "METHOD /path" route.
+type RouteLimit struct {
+	RequestsPerSecond float64
+	Burst             int
+}
+
+// Enabled reports whether a key set source has been configured.
+func (a AuthConfig) Enabled() bool {
+	return a.JWKSFile != "" || a.JWKSURL != ""
+}
+
+// Enabled reports whether any limit or quota has been configured.
+func (r RateLimitConfig) Enabled() bool {
+	return r.RequestsPerSecond > 0 || len(r.Routes) > 0 || r.DailySubmissionQuota > 0
+}
+
+// Load reads the configuration from the environment, applying defaults for unset values.
+func Load() (*Config, error) {
+	cacheTTL, err := getDuration("AUTH_JWKS_CACHE_TTL", 10*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	clockSkew, err := getDuration("AUTH_CLOCK_SKEW", time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	apiKeys, err := loadAPIKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	rateLimit, err := loadRateLimit()
+	if err != nil {
+		return nil, err
+	}
+
+	trustedProxies, err := loadTrustedProxies()
+	if err != nil {
+		return nil, err
+	}
+
+	fraud, err := loadFraud()
+	if err != nil {
+		return nil, err
+	}
+
+	webhook, err := loadWebhook()
+	if err != nil {
+		return nil, err
+	}
+
+	outboxPollInterval, err := getDuration("OUTBOX_POLL_INTERVAL", 250*time.Millisecond)
+	if err != nil {
+		return nil, err
+	}
+
+	streamReplayBuffer, err := getInt("STREAM_REPLAY_BUFFER", 1000)
+	if err != nil {
+		return nil, err
+	}
+	if streamReplayBuffer < 0 {
+		return nil, fmt.Errorf("STREAM_REPLAY_BUFFER cannot be negative")
+	}
+
+	graphQL, err := loadGraphQL()
+	if err != nil {
+		return nil, err
+	}
+
+	mail, err := loadMail()
+	if err != nil {
+		return nil, err
+	}
+
+	rules, err := loadRules()
+	if err != nil {
+		return nil, err
+	}
+
+	currency, err := loadCurrency()
+	if err != nil {
+		return nil, err
+	}
+
+	retailerTimeZones, err := timezone.ParseRetailerZones(os.Getenv("RETAILER_TIME_ZONES"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid RETAILER_TIME_ZONES: %v", err)
+	}
+
+	totalTolerance, err := getFloat("RECEIPT_TOTAL_TOLERANCE", 0)
+	if err != nil {
+		return nil, err
+	}
+	if totalTolerance < 0 {
+		return nil, fmt.Errorf("RECEIPT_TOTAL_TOLERANCE cannot be negative")
+	}
+
+	cfg := &Config{
+		Port:           getEnv("PORT", "7070"),
+		GRPCPort:       "7071",
+		GRPCReflection: os.Getenv("GIN_MODE") != "release",
+		Auth: AuthConfig{
+			JWKSFile:     os.Getenv("AUTH_JWKS_FILE"),
+			JWKSURL:      os.Getenv("AUTH_JWKS_URL"),
+			JWKSCacheTTL: cacheTTL,
+			Issuer:       os.Getenv("AUTH_ISSUER"),
+			Audience:     os.Getenv("AUTH_AUDIENCE"),
+			ClockSkew:    clockSkew,
+			APIKeys:      apiKeys,
+		},
+		RateLimit:            rateLimit,
+		TrustedProxies:       trustedProxies,
+		TerminalRegistryFile: os.Getenv("TERMINAL_REGISTRY_FILE"),
+		Fraud:                fraud,
+		Webhook:              webhook,
+		OutboxPollInterval:   outboxPollInterval,
+		StreamReplayBuffer:   streamReplayBuffer,
+		GraphQL:              graphQL,
+		OpenAPI: OpenAPIConfig{
+			Enabled:           true,
+			ValidateResponses: os.Getenv("GIN_MODE") != "release",
+		},
+		Mail:              mail,
+		Rules:             rules,
+		TotalTolerance:    totalTolerance,
+		Currency:          currency,
+		RetailerTimeZones: retailerTimeZones,
+	}
+
+	if grpcPort, ok := os.LookupEnv("GRPC_PORT"); ok {
+		cfg.GRPCPort = grpcPort
+	}
+
+	if reflection := os.Getenv("GRPC_REFLECTION"); reflection != "" {
+		cfg.GRPCReflection = reflection == "true"
+	}
+
+	if specFile, ok := os.LookupEnv("OPENAPI_SPEC_FILE"); ok {
+		cfg.OpenAPI.Enabled = specFile != ""
+		cfg.OpenAPI.SpecFile = specFile
+	}
+
+	if validateResponses := os.Getenv("OPENAPI_VALIDATE_RESPONSES"); validateResponses != "" {
+		cfg.OpenAPI.ValidateResponses = validateResponses == "true"
+	}
+
+	if cfg.Auth.JWKSFile != "" && cfg.Auth.JWKSURL != "" {
+		return nil, fmt.Errorf("only one of AUTH_JWKS_FILE or AUTH_JWKS_URL can be set")
+	}
+
+	return cfg, nil
+}
+
+// loadAPIKeys reads API_KEYS as a comma separated list of "name=key" entries, e.g.
+// "pos-integration=3f9c...,partner-a=81b2...", keyed by the key.
+func loadAPIKeys() (map[string]string, error) {
+	keys := make(map[string]string)
+	for i, entry := range strings.Split(os.Getenv("API_KEYS"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		name, key, found := strings.Cut(entry, "=")
+		name, key = strings.TrimSpace(name), strings.TrimSpace(key)
+		if !found || name == "" || key == "" {
+			// The entry is not quoted, it could hold a key.
+			return nil, fmt.Errorf("invalid API_KEYS entry %d", i+1)
+		}
+		if _, ok := keys[key]; ok {
+			return nil, fmt.Errorf("the API key of %s is also issued to %s", name, keys[key])
+		}
+		keys[key] = name
+	}
+	return keys, nil
+}
+
+// loadRateLimit reads RATE_LIMIT_ROUTES as a comma separated list of
+// "METHOD /path=rps:burst" entries, e.g. "POST /receipts/process=5:10".
+func loadRateLimit() (RateLimitConfig, error) {
+	rps, err := getFloat("RATE_LIMIT_RPS", 0)
+	if err != nil {
+		return RateLimitConfig{}, err
+	}
+
+	burst, err := getInt("RATE_LIMIT_BURST", defaultBurst(rps))
+	if err != nil {
+		return RateLimitConfig{}, err
+	}
+	if burst < 1 {
+		return RateLimitConfig{}, fmt.Errorf("RATE_LIMIT_BURST must be at least 1")
+	}
+
+	quota, err := getInt("RATE_LIMIT_DAILY_SUBMISSIONS", 0)
+	if err != nil {
+		return RateLimitConfig{}, err
+	}
+
+	routes := make(map[string]RouteLimit)
+	for _, entry := range strings.Split(os.Getenv("RATE_LIMIT_ROUTES"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		route, limit, found := strings.Cut(entry, "=")
+		if !found {
+			return RateLimitConfig{}, fmt.Errorf("invalid RATE_LIMIT_ROUTES entry %q", entry)
+		}
+
+		rateStr, burstStr, hasBurst := strings.Cut(limit, ":")
+		routeRPS, err := strconv.ParseFloat(strings.TrimSpace(rateStr), 64)
+		if err != nil || routeRPS < 0 {
+			return RateLimitConfig{}, fmt.Errorf("invalid rate in RATE_LIMIT_ROUTES entry %q", entry)
+		}
+
+		routeBurst := defaultBurst(routeRPS)
+		if hasBurst {
+			routeBurst, err = strconv.Atoi(strings.TrimSpace(burstStr))
+			if err != nil || routeBurst < 1 {
+				return RateLimitConfig{}, fmt.Errorf("invalid burst in RATE_LIMIT_ROUTES entry %q", entry)
+			}
+		}
+
+		routes[strings.Join(strings.Fields(route), " ")] = RouteLimit{RequestsPerSecond: routeRPS, Burst: routeBurst}
+	}
+
+	return RateLimitConfig{
+		RequestsPerSecond:    rps,
+		Burst:                burst,
+		Routes:               routes,
+		DailySubmissionQuota: quota,
+	}, nil
+}
+
+// loadTrustedProxies reads TRUSTED_PROXIES as a comma separated list of IP addresses and CIDR
+// ranges, e.g. "10.0.0.0/8,192.168.1.10".
+func loadTrustedProxies() ([]string, error) {
+	var proxies []string
+	for _, entry := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if _, _, err := net.ParseCIDR(entry); err != nil && net.ParseIP(entry) == nil {
+			return nil, fmt.Errorf("invalid TRUSTED_PROXIES entry %q", entry)
+		}
+		proxies = append(proxies, entry)
+	}
+	return proxies, nil
+}
+
+func loadFraud() (FraudConfig, error) {
+	cfg := FraudConfig{
+		Enabled: os.Getenv("FRAUD_CHECKS_ENABLED") == "true",
+	}
+
+	var err error
+	if cfg.HoldThreshold, err = getInt("FRAUD_HOLD_THRESHOLD", 70); err != nil {
+		return cfg, err
+	}
+	if cfg.FutureTolerance, err = getDuration("FRAUD_FUTURE_TOLERANCE", 24*time.Hour); err != nil {
+		return cfg, err
+	}
+	if cfg.ZonedFutureTolerance, err = getDuration("FRAUD_ZONED_FUTURE_TOLERANCE", time.Hour); err != nil {
+		return cfg, err
+	}
+	if cfg.MaxReceiptAge, err = getDuration("FRAUD_MAX_RECEIPT_AGE", 365*24*time.Hour); err != nil {
+		return cfg, err
+	}
+	if cfg.MaxTotal, err = getFloat("FRAUD_MAX_TOTAL", 10000); err != nil {
+		return cfg, err
+	}
+	if cfg.VelocityLimit, err = getInt("FRAUD_VELOCITY_LIMIT", 20); err != nil {
+		return cfg, err
+	}
+	if cfg.VelocityWindow, err = getDuration("FRAUD_VELOCITY_WINDOW", time.Minute); err != nil {
+		return cfg, err
+	}
+	if cfg.RepeatedItemsLimit, err = getInt("FRAUD_REPEATED_ITEMS_LIMIT", 3); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+func loadWebhook() (WebhookConfig, error) {
+	cfg := WebhookConfig{
+		AllowPrivateNetworks: os.Getenv("WEBHOOK_ALLOW_PRIVATE_NETWORKS") == "true",
+	}
+	var err error
+	if cfg.MaxAttempts, err = getInt("WEBHOOK_MAX_ATTEMPTS", 8); err != nil {
+		return cfg, err
+	}
+	if cfg.BaseBackoff, err = getDuration("WEBHOOK_BASE_BACKOFF", time.Second); err != nil {
+		return cfg, err
+	}
+	if cfg.MaxBackoff, err = getDuration("WEBHOOK_MAX_BACKOFF", 10*time.Minute); err != nil {
+		return cfg, err
+	}
+	if cfg.Timeout, err = getDuration("WEBHOOK_TIMEOUT", 10*time.Second); err != nil {
+		return cfg, err
+	}
+	if cfg.MaxAttempts < 1 {
+		return cfg, fmt.Errorf("WEBHOOK_MAX_ATTEMPTS must be at least 1")
+	}
+	return cfg, nil
+}
+
+func loadGraphQL() (GraphQLConfig, error) {
+	var cfg GraphQLConfig
+	var err error
+	if cfg.MaxDepth, err = getInt("GRAPHQL_MAX_DEPTH", 8); err != nil {
+		return cfg, err
+	}
+	if cfg.MaxComplexity, err = getInt("GRAPHQL_MAX_COMPLEXITY", 500); err != nil {
+		return cfg, err
+	}
+	if cfg.MaxDepth < 1 || cfg.MaxComplexity < 1 {
+		return cfg, fmt.Errorf("GRAPHQL_MAX_DEPTH and GRAPHQL_MAX_COMPLEXITY must be at least 1")
+	}
+	return cfg, nil
+}
+
+func loadMail() (MailConfig, error) {
+	cfg := MailConfig{
+		Maildir: os.Getenv("EMAIL_MAILDIR"),
+	}
+
+	var err error
+	if cfg.PollInterval, err = getDuration("EMAIL_POLL_INTERVAL", 10*time.Second); err != nil {
+		return cfg, err
+	}
+	if cfg.MinConfidence, err = getFloat("EMAIL_MIN_CONFIDENCE", 0.8); err != nil {
+		return cfg, err
+	}
+	if cfg.PollInterval <= 0 {
+		return cfg, fmt.Errorf("EMAIL_POLL_INTERVAL must be positive")
+	}
+	if cfg.MinConfidence < 0 || cfg.MinConfidence > 1 {
+		return cfg, fmt.Errorf("EMAIL_MIN_CONFIDENCE must be between 0 and 1")
+	}
+	return cfg, nil
+}
+
+func loadRules() (RulesConfig, error) {
+	cfg := RulesConfig{
+		QuantityAware:     os.Getenv("RULES_QUANTITY_AWARE") == "true",
+		CanonicalRetailer: os.Getenv("RULES_CANONICAL_RETAILER") == "true",
+	}
+
+	switch basis := getEnv("RULES_AMOUNT_BASIS", "post-tax"); basis {
+	case "post-tax":
+	case "pre-tax":
+		cfg.PreTax = true
+	default:
+		return cfg, fmt.Errorf("RULES_AMOUNT_BASIS must be pre-tax or post-tax, not %q", basis)
+	}
+	return cfg, nil
+}
+
+func loadCurrency() (CurrencyConfig, error) {
+	cfg := CurrencyConfig{
+		Base:              strings.ToUpper(getEnv("BASE_CURRENCY", "USD")),
+		ExchangeRatesFile: os.Getenv("EXCHANGE_RATES_FILE"),
+	}
+
+	if len(cfg.Base) != 3 || strings.Trim(cfg.Base, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+		return cfg, fmt.Errorf("BASE_CURRENCY must be an ISO 4217 code, not %q", cfg.Base)
+	}
+	return cfg, nil
+}
+
+func defaultBurst(rps float64) int {
+	if rps < 1 {
+		return 1
+	}
+	return int(rps)
+}
+
+func getEnv(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok && value != "" {
+		return value
+	}
+	return fallback
+}
+
+func getDuration(key string, fallback time.Duration) (time.Duration, error) {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return fallback, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration for %s: %v", key, err)
+	}
+	return d, nil
+}
+
+func getInt(key string, fallback int) (int, error) {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return fallback, nil
+	}
+
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid integer for %s: %v", key, err)
+	}
+	return i, nil
+}
+
+func getFloat(key string, fallback float64) (float64, error) {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return fallback, nil
+	}
+
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number for %s: %v", key, err)
+	}
+	return f, nil
+}
@@ -0,0 +1,218 @@
+package config
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestLoad(t *testing.T) {
+	t.Run("Defaults", func(t *testing.T) {
+		cfg, err := Load()
+		assert.NoError(t, err)
+		assert.Equal(t, "7070", cfg.Port)
+		assert.False(t, cfg.Auth.Enabled())
+		assert.Equal(t, time.Minute, cfg.Auth.ClockSkew)
+		assert.False(t, cfg.RateLimit.Enabled())
+		assert.Equal(t, 8, cfg.Webhook.MaxAttempts)
+		assert.False(t, cfg.Webhook.AllowPrivateNetworks)
+		assert.Equal(t, "7071", cfg.GRPCPort)
+		assert.True(t, cfg.OpenAPI.Enabled)
+		assert.Empty(t, cfg.OpenAPI.SpecFile)
+		assert.Empty(t, cfg.Mail.Maildir)
+		assert.Equal(t, 0.8, cfg.Mail.MinConfidence)
+		assert.False(t, cfg.Rules.QuantityAware)
+		assert.False(t, cfg.Rules.CanonicalRetailer)
+		assert.Equal(t, 24*time.Hour, cfg.Fraud.FutureTolerance)
+		assert.Equal(t, time.Hour, cfg.Fraud.ZonedFutureTolerance)
+	})
+
+	t.Run("Response validation follows the gin mode", func(t *testing.T) {
+		t.Setenv("GIN_MODE", "release")
+		cfg, err := Load()
+		assert.NoError(t, err)
+		assert.False(t, cfg.OpenAPI.ValidateResponses)
+
+		t.Setenv("OPENAPI_VALIDATE_RESPONSES", "true")
+		cfg, err = Load()
+		assert.NoError(t, err)
+		assert.True(t, cfg.OpenAPI.ValidateResponses)
+	})
+
+	t.Run("gRPC reflection follows the gin mode", func(t *testing.T) {
+		cfg, err := Load()
+		assert.NoError(t, err)
+		assert.True(t, cfg.GRPCReflection)
+
+		t.Setenv("GIN_MODE", "release")
+		cfg, err = Load()
+		assert.NoError(t, err)
+		assert.False(t, cfg.GRPCReflection)
+
+		t.Setenv("GRPC_REFLECTION", "true")
+		cfg, err = Load()
+		assert.NoError(t, err)
+		assert.True(t, cfg.GRPCReflection)
+	})
+
+	t.Run("Trusted proxies", func(t *testing.T) {
+		cfg, err := Load()
+		assert.NoError(t, err)
+		assert.Empty(t, cfg.TrustedProxies)
+
+		t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8, 192.168.1.10")
+		cfg, err = Load()
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"10.0.0.0/8", "192.168.1.10"}, cfg.TrustedProxies)
+
+		t.Setenv("TRUSTED_PROXIES", "proxy.internal")
+		_, err = Load()
+		assert.Error(t, err)
+	})
+
+	t.Run("Disabled gRPC", func(t *testing.T) {
+		t.Setenv("GRPC_PORT", "")
+		cfg, err := Load()
+		assert.NoError(t, err)
+		assert.Empty(t, cfg.GRPCPort)
+	})
+
+	t.Run("Rate limit routes", func(t *testing.T) {
+		t.Setenv("RATE_LIMIT_RPS", "10")
+		t.Setenv("RATE_LIMIT_ROUTES", "POST  /receipts/process=0.5:3, GET /receipts/:id/points=20")
+		t.Setenv("RATE_LIMIT_DAILY_SUBMISSIONS", "100")
+
+		cfg, err := Load()
+		assert.NoError(t, err)
+		assert.True(t, cfg.RateLimit.Enabled())
+		assert.Equal(t, 10, cfg.RateLimit.Burst)
+		assert.Equal(t, 100, cfg.RateLimit.DailySubmissionQuota)
+		assert.Equal(t, RouteLimit{RequestsPerSecond: 0.5, Burst: 3}, cfg.RateLimit.Routes["POST /receipts/process"])
+		assert.Equal(t, RouteLimit{RequestsPerSecond: 20, Burst: 20}, cfg.RateLimit.Routes["GET /receipts/:id/points"])
+	})
+
+	t.Run("Invalid values", func(t *testing.T) {
+		t.Setenv("RATE_LIMIT_ROUTES", "POST /receipts/process")
+		_, err := Load()
+		assert.Error(t, err)
+	})
+
+	t.Run("Invalid stream replay buffer", func(t *testing.T) {
+		t.Setenv("STREAM_REPLAY_BUFFER", "-1")
+		_, err := Load()
+		assert.EqualError(t, err, "STREAM_REPLAY_BUFFER cannot be negative")
+
+		t.Setenv("STREAM_REPLAY_BUFFER", "0")
+		cfg, err := Load()
+		assert.NoError(t, err)
+		assert.Zero(t, cfg.StreamReplayBuffer)
+	})
+
+	t.Run("Invalid rate limit burst", func(t *testing.T) {
+		t.Setenv("RATE_LIMIT_BURST", "0")
+		_, err := Load()
+		assert.EqualError(t, err, "RATE_LIMIT_BURST must be at least 1")
+	})
+
+	t.Run("Invalid webhook attempts", func(t *testing.T) {
+		t.Setenv("WEBHOOK_MAX_ATTEMPTS", "0")
+		_, err := Load()
+		assert.Error(t, err)
+	})
+
+	t.Run("Quantity-aware rules", func(t *testing.T) {
+		t.Setenv("RULES_QUANTITY_AWARE", "true")
+		cfg, err := Load()
+		assert.NoError(t, err)
+		assert.True(t, cfg.Rules.QuantityAware)
+	})
+
+	t.Run("Canonical retailer rule", func(t *testing.T) {
+		t.Setenv("RULES_CANONICAL_RETAILER", "true")
+		cfg, err := Load()
+		assert.NoError(t, err)
+		assert.True(t, cfg.Rules.CanonicalRetailer)
+	})
+
+	t.Run("Pre-tax rules and total tolerance", func(t *testing.T) {
+		t.Setenv("RULES_AMOUNT_BASIS", "pre-tax")
+		t.Setenv("RECEIPT_TOTAL_TOLERANCE", "0.02")
+		cfg, err := Load()
+		assert.NoError(t, err)
+		assert.True(t, cfg.Rules.PreTax)
+		assert.Equal(t, 0.02, cfg.TotalTolerance)
+
+		t.Setenv("RECEIPT_TOTAL_TOLERANCE", "-0.01")
+		_, err = Load()
+		assert.Error(t, err)
+
+		t.Setenv("RECEIPT_TOTAL_TOLERANCE", "")
+		t.Setenv("RULES_AMOUNT_BASIS", "gross")
+		_, err = Load()
+		assert.Error(t, err)
+	})
+
+	t.Run("Base currency and exchange rates", func(t *testing.T) {
+		cfg, err := Load()
+		assert.NoError(t, err)
+		assert.Equal(t, CurrencyConfig{Base: "USD"}, cfg.Currency)
+
+		t.Setenv("BASE_CURRENCY", "eur")
+		t.Setenv("EXCHANGE_RATES_FILE", "rates.json")
+		cfg, err = Load()
+		assert.NoError(t, err)
+		assert.Equal(t, CurrencyConfig{Base: "EUR", ExchangeRatesFile: "rates.json"}, cfg.Currency)
+
+		t.Setenv("BASE_CURRENCY", "euro")
+		_, err = Load()
+		assert.Error(t, err)
+	})
+
+	t.Run("API keys", func(t *testing.T) {
+		t.Setenv("API_KEYS", "pos-integration=k1, partner-a = k2")
+		cfg, err := Load()
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"k1": "pos-integration", "k2": "partner-a"}, cfg.Auth.APIKeys)
+
+		for _, value := range []string{"k1", "=k1", "pos-integration=", "a=k1,b=k1"} {
+			t.Setenv("API_KEYS", value)
+			_, err = Load()
+			assert.Error(t, err, value)
+		}
+	})
+
+	t.Run("Retailer time zones", func(t *testing.T) {
+		cfg, err := Load()
+		assert.NoError(t, err)
+		assert.Empty(t, cfg.RetailerTimeZones)
+
+		t.Setenv("RETAILER_TIME_ZONES", "Target=America/Chicago,Lawson=+09:00")
+		cfg, err = Load()
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"target": "America/Chicago", "lawson": "+09:00"}, cfg.RetailerTimeZones)
+
+		t.Setenv("RETAILER_TIME_ZONES", "Target=Central")
+		_, err = Load()
+		assert.Error(t, err)
+	})
+
+	t.Run("Email receipts", func(t *testing.T) {
+		t.Setenv("EMAIL_MAILDIR", "/var/mail/receipts")
+		t.Setenv("EMAIL_POLL_INTERVAL", "1m")
+		t.Setenv("EMAIL_MIN_CONFIDENCE", "0.6")
+		cfg, err := Load()
+		assert.NoError(t, err)
+		assert.Equal(t, MailConfig{Maildir: "/var/mail/receipts", PollInterval: time.Minute, MinConfidence: 0.6}, cfg.Mail)
+
+		t.Setenv("EMAIL_MIN_CONFIDENCE", "80")
+		_, err = Load()
+		assert.Error(t, err)
+	})
+
+	t.Run("Both key set sources", func(t *testing.T) {
+		t.Setenv("AUTH_JWKS_FILE", "jwks.json")
+		t.Setenv("AUTH_JWKS_URL", "https://idp.example.com/jwks.json")
+		_, err := Load()
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,51 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/domain/fraud/service/fraud_service.go
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	reflect "reflect"
+
+	models "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockFraudService is a mock of FraudService interface.
+type MockFraudService struct {
+	ctrl     *gomock.Controller
+	recorder *MockFraudServiceMockRecorder
+}
+
+// MockFraudServiceMockRecorder is the mock recorder for MockFraudService.
+type MockFraudServiceMockRecorder struct {
+	mock *MockFraudService
+}
+
+// NewMockFraudService creates a new mock instance.
+func NewMockFraudService(ctrl *gomock.Controller) *MockFraudService {
+	mock := &MockFraudService{ctrl: ctrl}
+	mock.recorder = &MockFraudServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockFraudService) EXPECT() *MockFraudServiceMockRecorder {
+	return m.recorder
+}
+
+// Assess mocks base method.
+func (m *MockFraudService) Assess(ctx context.Context, receipt *models.Receipt) (*models.RiskAssessment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Assess", ctx, receipt)
+	ret0, _ := ret[0].(*models.RiskAssessment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Assess indicates an expected call of Assess.
+func (mr *MockFraudServiceMockRecorder) Assess(ctx, receipt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Assess", reflect.TypeOf((*MockFraudService)(nil).Assess), ctx, receipt)
+}
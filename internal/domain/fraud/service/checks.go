@@ -0,0 +1,318 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	FutureDatetimeScore = 70
+	StaleDatetimeScore  = 40
+	AbsurdTotalScore    = 70
+	OutlierTotalScore   = 40
+	VelocityScore       = 50
+	RepeatedItemsScore  = 40
+)
+
+// Check is a single fraud heuristic. It returns nil when the receipt looks legitimate.
+type Check interface {
+	Name() string
+	Evaluate(ctx context.Context, receipt *models.Receipt) (*models.RiskFlag, error)
+}
+
+// CheckOptions configures the built-in checks.
+type CheckOptions struct {
+	// FutureTolerance applies to the receipts without a time zone or a purchase timestamp. Their
+	// purchase times are read in UTC, so it also absorbs the offsets of the stores ahead of UTC.
+	FutureTolerance time.Duration
+	// ZonedFutureTolerance applies to the receipts whose moment of purchase is known, and only
+	// absorbs the clock skew of the registers.
+	ZonedFutureTolerance time.Duration
+	MaxReceiptAge        time.Duration
+	MaxTotal             float64
+	OutlierZScore        float64
+	OutlierMinSamples    int
+	VelocityLimit        int
+	VelocityWindow       time.Duration
+	RepeatedItemsLimit   int
+	RepeatedItemsTTL     time.Duration
+}
+
+// DefaultCheckOptions returns conservative thresholds for the built-in checks.
+func DefaultCheckOptions() CheckOptions {
+	return CheckOptions{
+		FutureTolerance:      24 * time.Hour,
+		ZonedFutureTolerance: time.Hour,
+		MaxReceiptAge:        365 * 24 * time.Hour,
+		MaxTotal:             10000,
+		OutlierZScore:        4,
+		OutlierMinSamples:    20,
+		VelocityLimit:        20,
+		VelocityWindow:       time.Minute,
+		RepeatedItemsLimit:   3,
+		RepeatedItemsTTL:     24 * time.Hour,
+	}
+}
+
+// DefaultChecks builds the built-in checks sharing the same clock.
+func DefaultChecks(options CheckOptions, now func() time.Time) []Check {
+	return []Check{
+		&PurchaseDatetimeCheck{
+			futureTolerance:      options.FutureTolerance,
+			zonedFutureTolerance: options.ZonedFutureTolerance,
+			maxAge:               options.MaxReceiptAge,
+			now:                  now,
+		},
+		NewTotalOutlierCheck(options.MaxTotal, options.OutlierZScore, options.OutlierMinSamples),
+		NewVelocityCheck(options.VelocityLimit, options.VelocityWindow, now),
+		NewRepeatedItemsCheck(options.RepeatedItemsLimit, options.RepeatedItemsTTL, now),
+	}
+}
+
+// PurchaseDatetimeCheck flags receipts dated in the future or too far in the past.
+type PurchaseDatetimeCheck struct {
+	futureTolerance      time.Duration
+	zonedFutureTolerance time.Duration
+	maxAge               time.Duration
+	now                  func() time.Time
+}
+
+func (c *PurchaseDatetimeCheck) Name() string {
+	return "purchase_datetime"
+}
+
+func (c *PurchaseDatetimeCheck) Evaluate(ctx context.Context, receipt *models.Receipt) (*models.RiskFlag, error) {
+	purchasedAt, err := receipt.GetReceiptDatetime()
+	if err != nil {
+		return nil, err
+	}
+
+	tolerance := c.futureTolerance
+	if receipt.TimeZone != "" || receipt.PurchasedAt != "" {
+		tolerance = c.zonedFutureTolerance
+	}
+
+	now := c.now()
+	if purchasedAt.After(now.Add(tolerance)) {
+		return &models.RiskFlag{
+			Check:  c.Name(),
+			Score:  FutureDatetimeScore,
+			Reason: "the purchase datetime is in the future",
+		}, nil
+	}
+
+	if c.maxAge > 0 && now.Sub(purchasedAt) > c.maxAge {
+		return &models.RiskFlag{
+			Check:  c.Name(),
+			Score:  StaleDatetimeScore,
+			Reason: fmt.Sprintf("the purchase is older than %d days", int(c.maxAge.Hours()/24)),
+		}, nil
+	}
+
+	return nil, nil
+}
+
+// TotalOutlierCheck flags totals above an absolute cap or far from the retailer's usual totals.
+// Totals are compared in the base currency.
+type TotalOutlierCheck struct {
+	maxTotal   float64
+	zScore     float64
+	minSamples int
+
+	mu    sync.Mutex
+	stats map[string]*runningStats
+}
+
+// runningStats keeps the mean and variance of the totals seen for a retailer (Welford's algorithm).
+type runningStats struct {
+	count int
+	mean  float64
+	m2    float64
+}
+
+func NewTotalOutlierCheck(maxTotal, zScore float64, minSamples int) *TotalOutlierCheck {
+	return &TotalOutlierCheck{
+		maxTotal:   maxTotal,
+		zScore:     zScore,
+		minSamples: minSamples,
+		stats:      make(map[string]*runningStats),
+	}
+}
+
+func (c *TotalOutlierCheck) Name() string {
+	return "total_outlier"
+}
+
+func (c *TotalOutlierCheck) Evaluate(ctx context.Context, receipt *models.Receipt) (*models.RiskFlag, error) {
+	total, err := receipt.GetBaseTotal()
+	if err != nil {
+		return nil, err
+	}
+
+	if c.maxTotal > 0 && total > c.maxTotal {
+		return &models.RiskFlag{
+			Check:  c.Name(),
+			Score:  AbsurdTotalScore,
+			Reason: fmt.Sprintf("the total %.2f exceeds the maximum of %.2f", total, c.maxTotal),
+		}, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	retailer := strings.ToLower(receipt.GetRetailerName())
+	stats, ok := c.stats[retailer]
+	if !ok {
+		stats = &runningStats{}
+		c.stats[retailer] = stats
+	}
+
+	var flag *models.RiskFlag
+	if stats.count >= c.minSamples {
+		stddev := math.Sqrt(stats.m2 / float64(stats.count-1))
+		if stddev > 0 && math.Abs(total-stats.mean)/stddev > c.zScore {
+			flag = &models.RiskFlag{
+				Check:  c.Name(),
+				Score:  OutlierTotalScore,
+				Reason: fmt.Sprintf("the total %.2f is unusual for %s (average %.2f)", total, receipt.GetRetailerName(), stats.mean),
+			}
+		}
+	}
+
+	stats.add(total)
+	return flag, nil
+}
+
+func (s *runningStats) add(value float64) {
+	s.count++
+	delta := value - s.mean
+	s.mean += delta / float64(s.count)
+	s.m2 += delta * (value - s.mean)
+}
+
+// VelocityCheck flags clients submitting more receipts than allowed within a window.
+type VelocityCheck struct {
+	limit  int
+	window time.Duration
+	now    func() time.Time
+
+	mu          sync.Mutex
+	submissions map[string][]time.Time
+	lastSweep   time.Time
+}
+
+func NewVelocityCheck(limit int, window time.Duration, now func() time.Time) *VelocityCheck {
+	return &VelocityCheck{
+		limit:       limit,
+		window:      window,
+		now:         now,
+		submissions: make(map[string][]time.Time),
+	}
+}
+
+func (c *VelocityCheck) Name() string {
+	return "submission_velocity"
+}
+
+func (c *VelocityCheck) Evaluate(ctx context.Context, receipt *models.Receipt) (*models.RiskFlag, error) {
+	if receipt.SubmittedBy == "" || c.limit <= 0 {
+		return nil, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	c.lastSweep = sweepBefore(c.submissions, c.lastSweep, now, c.window)
+	recent := pruneBefore(c.submissions[receipt.SubmittedBy], now.Add(-c.window))
+	recent = append(recent, now)
+	c.submissions[receipt.SubmittedBy] = recent
+
+	if len(recent) > c.limit {
+		return &models.RiskFlag{
+			Check:  c.Name(),
+			Score:  VelocityScore,
+			Reason: fmt.Sprintf("%d receipts submitted by the same client within %s", len(recent), c.window),
+		}, nil
+	}
+	return nil, nil
+}
+
+// RepeatedItemsCheck flags item lists that keep being submitted, even across retailers.
+type RepeatedItemsCheck struct {
+	limit int
+	ttl   time.Duration
+	now   func() time.Time
+
+	mu        sync.Mutex
+	seen      map[string][]time.Time
+	lastSweep time.Time
+}
+
+func NewRepeatedItemsCheck(limit int, ttl time.Duration, now func() time.Time) *RepeatedItemsCheck {
+	return &RepeatedItemsCheck{
+		limit: limit,
+		ttl:   ttl,
+		now:   now,
+		seen:  make(map[string][]time.Time),
+	}
+}
+
+func (c *RepeatedItemsCheck) Name() string {
+	return "repeated_items"
+}
+
+func (c *RepeatedItemsCheck) Evaluate(ctx context.Context, receipt *models.Receipt) (*models.RiskFlag, error) {
+	if c.limit <= 0 || len(receipt.Items) == 0 {
+		return nil, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	c.lastSweep = sweepBefore(c.seen, c.lastSweep, now, c.ttl)
+	fingerprint := receipt.ItemsFingerprint()
+	recent := pruneBefore(c.seen[fingerprint], now.Add(-c.ttl))
+	recent = append(recent, now)
+	c.seen[fingerprint] = recent
+
+	if len(recent) > c.limit {
+		return &models.RiskFlag{
+			Check:  c.Name(),
+			Score:  RepeatedItemsScore,
+			Reason: fmt.Sprintf("the same item list was submitted %d times", len(recent)),
+		}, nil
+	}
+	return nil, nil
+}
+
+// sweepBefore evicts the keys not seen within the window, at most once per window, so that
+// clients and item lists that are never submitted again don't stay in memory. It returns the
+// time of the last sweep.
+func sweepBefore(times map[string][]time.Time, lastSweep, now time.Time, window time.Duration) time.Time {
+	if now.Sub(lastSweep) < window {
+		return lastSweep
+	}
+
+	cutoff := now.Add(-window)
+	for key, recent := range times {
+		if len(pruneBefore(recent, cutoff)) == 0 {
+			delete(times, key)
+		}
+	}
+	return now
+}
+
+func pruneBefore(times []time.Time, cutoff time.Time) []time.Time {
+	i := 0
+	for i < len(times) && times[i].Before(cutoff) {
+		i++
+	}
+	return times[i:]
+}
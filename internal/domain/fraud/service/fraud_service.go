@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
+	"log"
+)
+
+// MaxRiskScore caps the aggregated score of a receipt.
+const MaxRiskScore = 100
+
+type FraudService interface {
+	Assess(ctx context.Context, receipt *models.Receipt) (*models.RiskAssessment, error)
+}
+
+type FraudServiceImpl struct {
+	checks        []Check
+	holdThreshold int
+}
+
+func NewFraudService(holdThreshold int, checks ...Check) FraudService {
+	return &FraudServiceImpl{
+		checks:        checks,
+		holdThreshold: holdThreshold,
+	}
+}
+
+// Assess runs every check and adds up their scores. A failing check is logged and
+// skipped so that one broken heuristic cannot block submissions.
+func (s *FraudServiceImpl) Assess(ctx context.Context, receipt *models.Receipt) (*models.RiskAssessment, error) {
+	assessment := &models.RiskAssessment{
+		Flags: []models.RiskFlag{},
+	}
+
+	for _, check := range s.checks {
+		flag, err := check.Evaluate(ctx, receipt)
+		if err != nil {
+			log.Printf("Fraud check %s failed: %v", check.Name(), err)
+			continue
+		}
+		if flag == nil {
+			continue
+		}
+
+		assessment.Flags = append(assessment.Flags, *flag)
+		assessment.Score += flag.Score
+	}
+
+	if assessment.Score > MaxRiskScore {
+		assessment.Score = MaxRiskScore
+	}
+	assessment.Held = assessment.Score >= s.holdThreshold
+	return assessment, nil
+}
@@ -0,0 +1,165 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type stubCheck struct {
+	flag *models.RiskFlag
+	err  error
+}
+
+func (c stubCheck) Name() string {
+	return "stub"
+}
+
+func (c stubCheck) Evaluate(ctx context.Context, receipt *models.Receipt) (*models.RiskFlag, error) {
+	return c.flag, c.err
+}
+
+func TestFraudServiceImpl_Assess(t *testing.T) {
+	t.Run("Clean receipt", func(t *testing.T) {
+		fraudSvc := NewFraudService(70, stubCheck{})
+		assessment, err := fraudSvc.Assess(context.Background(), &models.Receipt{})
+		assert.NoError(t, err)
+		assert.Equal(t, 0, assessment.Score)
+		assert.Empty(t, assessment.Flags)
+		assert.False(t, assessment.Held)
+	})
+
+	t.Run("Scores add up and are capped", func(t *testing.T) {
+		fraudSvc := NewFraudService(70,
+			stubCheck{flag: &models.RiskFlag{Check: "a", Score: 60, Reason: "a"}},
+			stubCheck{err: fmt.Errorf("broken check")},
+			stubCheck{flag: &models.RiskFlag{Check: "b", Score: 50, Reason: "b"}},
+		)
+		assessment, err := fraudSvc.Assess(context.Background(), &models.Receipt{})
+		assert.NoError(t, err)
+		assert.Equal(t, MaxRiskScore, assessment.Score)
+		assert.Equal(t, []string{"a", "b"}, assessment.Reasons())
+		assert.True(t, assessment.Held)
+	})
+}
+
+func TestPurchaseDatetimeCheck(t *testing.T) {
+	now := time.Date(2023, time.October, 8, 12, 0, 0, 0, time.UTC)
+	check := &PurchaseDatetimeCheck{
+		futureTolerance:      24 * time.Hour,
+		zonedFutureTolerance: time.Hour,
+		maxAge:               30 * 24 * time.Hour,
+		now:                  func() time.Time { return now },
+	}
+
+	tests := []struct {
+		date          string
+		time          string
+		timeZone      string
+		expectedScore int
+	}{
+		{date: "2023-10-08", time: "10:00", expectedScore: 0},
+		// Without a time zone the receipt may be from a store ahead of UTC.
+		{date: "2023-10-08", time: "20:30", expectedScore: 0},
+		{date: "2023-10-09", time: "10:00", expectedScore: 0},
+		{date: "2023-10-12", time: "10:00", expectedScore: FutureDatetimeScore},
+		{date: "2023-10-08", time: "12:45", timeZone: "UTC", expectedScore: 0},
+		{date: "2023-10-08", time: "13:30", timeZone: "UTC", expectedScore: FutureDatetimeScore},
+		{date: "2023-10-08", time: "20:30", timeZone: "Asia/Tokyo", expectedScore: 0},
+		{date: "2023-10-08", time: "22:30", timeZone: "Asia/Tokyo", expectedScore: FutureDatetimeScore},
+		{date: "2023-08-01", time: "10:00", expectedScore: StaleDatetimeScore},
+	}
+
+	for _, tt := range tests {
+		name := tt.date + " " + tt.time + " " + tt.timeZone
+		receipt := &models.Receipt{PurchaseDate: tt.date, PurchaseTime: tt.time, TimeZone: tt.timeZone}
+		flag, err := check.Evaluate(context.Background(), receipt)
+		assert.NoError(t, err)
+		if tt.expectedScore == 0 {
+			assert.Nil(t, flag, name)
+		} else {
+			assert.Equal(t, tt.expectedScore, flag.Score, name)
+		}
+	}
+}
+
+func TestTotalOutlierCheck(t *testing.T) {
+	check := NewTotalOutlierCheck(1000, 3, 5)
+
+	flag, err := check.Evaluate(context.Background(), &models.Receipt{Retailer: "Target", Total: "5000.00"})
+	assert.NoError(t, err)
+	assert.Equal(t, AbsurdTotalScore, flag.Score)
+
+	for _, total := range []string{"10.00", "12.00", "11.00", "9.00", "10.50", "11.50"} {
+		flag, err = check.Evaluate(context.Background(), &models.Receipt{Retailer: "Target", Total: total})
+		assert.NoError(t, err)
+		assert.Nil(t, flag)
+	}
+
+	flag, err = check.Evaluate(context.Background(), &models.Receipt{Retailer: "TARGET", Total: "250.00"})
+	assert.NoError(t, err)
+	assert.Equal(t, OutlierTotalScore, flag.Score)
+
+	// 15000 yen are below the cap once converted.
+	yen := &models.ExchangeRate{Currency: "JPY", Base: "USD", Rate: 0.008383, Date: "2022-03-18"}
+	flag, err = check.Evaluate(context.Background(), &models.Receipt{Retailer: "Lawson", Total: "15000", Currency: "JPY", Exchange: yen})
+	assert.NoError(t, err)
+	assert.Nil(t, flag)
+
+	// Retailers without enough history are not compared.
+	flag, err = check.Evaluate(context.Background(), &models.Receipt{Retailer: "Walgreens", Total: "250.00"})
+	assert.NoError(t, err)
+	assert.Nil(t, flag)
+}
+
+func TestVelocityCheck(t *testing.T) {
+	now := time.Now()
+	check := NewVelocityCheck(2, time.Minute, func() time.Time { return now })
+	receipt := &models.Receipt{SubmittedBy: "ip:10.0.0.1"}
+
+	for i := 0; i < 2; i++ {
+		flag, err := check.Evaluate(context.Background(), receipt)
+		assert.NoError(t, err)
+		assert.Nil(t, flag)
+	}
+
+	flag, err := check.Evaluate(context.Background(), receipt)
+	assert.NoError(t, err)
+	assert.Equal(t, VelocityScore, flag.Score)
+
+	now = now.Add(2 * time.Minute)
+	flag, err = check.Evaluate(context.Background(), receipt)
+	assert.NoError(t, err)
+	assert.Nil(t, flag)
+
+	// The clients that stopped submitting are forgotten.
+	now = now.Add(2 * time.Minute)
+	_, err = check.Evaluate(context.Background(), &models.Receipt{SubmittedBy: "ip:10.0.0.2"})
+	assert.NoError(t, err)
+	assert.Len(t, check.submissions, 1)
+}
+
+func TestRepeatedItemsCheck(t *testing.T) {
+	now := time.Now()
+	check := NewRepeatedItemsCheck(1, time.Hour, func() time.Time { return now })
+
+	first := &models.Receipt{Items: []models.ReceiptItem{{ShortDescription: "Gatorade", Price: "2.25"}, {ShortDescription: "Doritos", Price: "3.35"}}}
+	second := &models.Receipt{Items: []models.ReceiptItem{{ShortDescription: "doritos ", Price: "3.35"}, {ShortDescription: "Gatorade", Price: "2.25"}}}
+
+	flag, err := check.Evaluate(context.Background(), first)
+	assert.NoError(t, err)
+	assert.Nil(t, flag)
+
+	flag, err = check.Evaluate(context.Background(), second)
+	assert.NoError(t, err)
+	assert.Equal(t, RepeatedItemsScore, flag.Score)
+
+	// The item lists that stopped being submitted are forgotten.
+	now = now.Add(2 * time.Hour)
+	_, err = check.Evaluate(context.Background(), &models.Receipt{Items: []models.ReceiptItem{{ShortDescription: "Pepsi", Price: "1.25"}}})
+	assert.NoError(t, err)
+	assert.Len(t, check.seen, 1)
+}
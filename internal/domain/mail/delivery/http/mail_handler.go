@@ -0,0 +1,80 @@
+package http
+
+import (
+	"errors"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/mail/service"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/repository"
+	receiptService "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/service"
+	signatureService "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/signature/service"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/dto"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/middleware"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/receiptio/email"
+	"github.com/CarlosMtz98/receipt-processor-challenge/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+)
+
+// maxMessageSize bounds the raw messages accepted by POST /receipts/email, attachments included.
+const maxMessageSize = 10 << 20
+
+type MailHandler interface {
+	Create(c *gin.Context)
+}
+
+type MailHandlerImpl struct {
+	mailSvc service.MailService
+}
+
+func NewMailHandler(mailService service.MailService) MailHandler {
+	return &MailHandlerImpl{
+		mailSvc: mailService,
+	}
+}
+
+// Create submits the receipt of the raw RFC 5322 message in the request body. The response
+// names the part the receipt was read from and the parts that were skipped.
+func (h MailHandlerImpl) Create(c *gin.Context) {
+	result, err := h.mailSvc.Ingest(c, http.MaxBytesReader(c.Writer, c.Request.Body, maxMessageSize), middleware.ClientKey(c))
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		utils.HandleBadRequest(c, "The message is too large", err)
+		return
+	case errors.Is(err, email.ErrInvalidMessage):
+		utils.HandleBadRequest(c, "The message is not a valid email", err)
+		return
+	case errors.Is(err, service.ErrNoReceipt):
+		reasons := make([]string, len(result.Skipped))
+		for i, skipped := range result.Skipped {
+			reasons[i] = skipped.Source + ": " + skipped.Reason
+		}
+		utils.HandleBadRequest(c, "The message holds no valid receipt", errors.New(strings.Join(reasons, "; ")))
+		return
+	case errors.Is(err, signatureService.ErrSignatureRequired),
+		errors.Is(err, signatureService.ErrUntrustedTerminal),
+		errors.Is(err, signatureService.ErrInvalidSignature):
+		utils.HandleBadRequest(c, "The receipt signature could not be verified", err)
+		return
+	case errors.Is(err, receiptService.ErrNoExchangeRate):
+		utils.HandleBadRequest(c, "There is no exchange rate for the receipt currency", err)
+		return
+	case errors.Is(err, repository.ErrDuplicateInvoice):
+		utils.HandleConflict(c, "The invoice was already submitted")
+		return
+	case err != nil:
+		utils.HandleInternalError(c, "Could not add new receipt", err)
+		return
+	}
+
+	response := dto.EmailReceiptResponse{
+		ID:      result.Receipt.ID.String(),
+		Sender:  result.Sender,
+		Source:  result.Source,
+		Skipped: make([]dto.SkippedPart, len(result.Skipped)),
+	}
+	for i, skipped := range result.Skipped {
+		response.Skipped[i] = dto.SkippedPart{Source: skipped.Source, Reason: skipped.Reason}
+	}
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,116 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/mail/mock"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/mail/service"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/repository"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/dto"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/middleware"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/receiptio/email"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMailHandlerImpl_Create(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockMailService := mock.NewMockMailService(ctrl)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	apiKeys := middleware.APIKeys{"secret": "abc"}
+	MapMailRoutes(router.Group("/receipts", apiKeys.Handler()), NewMailHandler(mockMailService), middleware.AllowAll{})
+	send := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/receipts/email", strings.NewReader(body))
+		req.Header.Set("Content-Type", "message/rfc822")
+		req.Header.Set(middleware.APIKeyHeader, "secret")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Submitted", func(t *testing.T) {
+		id := uuid.New()
+		mockMailService.EXPECT().Ingest(gomock.Any(), gomock.Any(), "key:abc").Return(&service.IngestResult{
+			Receipt: &models.Receipt{ID: id},
+			Sender:  "jane@example.com",
+			Source:  "attachment receipt.json",
+			Skipped: []service.SkippedPart{{Source: "attachment logo.png", Reason: "unsupported content type image/png"}},
+		}, nil)
+
+		w := send("From: jane@example.com\n\n")
+
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var response dto.EmailReceiptResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, dto.EmailReceiptResponse{
+			ID:      id.String(),
+			Sender:  "jane@example.com",
+			Source:  "attachment receipt.json",
+			Skipped: []dto.SkippedPart{{Source: "attachment logo.png", Reason: "unsupported content type image/png"}},
+		}, response)
+	})
+
+	t.Run("Nothing skipped", func(t *testing.T) {
+		mockMailService.EXPECT().Ingest(gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(&service.IngestResult{Receipt: &models.Receipt{ID: uuid.New()}, Source: "body text/plain"}, nil)
+
+		w := send("From: jane@example.com\n\n")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"skipped":[]`)
+	})
+
+	t.Run("Invalid message", func(t *testing.T) {
+		mockMailService.EXPECT().Ingest(gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(nil, fmt.Errorf("%w: the From header has no valid address", email.ErrInvalidMessage))
+
+		w := send("hello")
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "The message is not a valid email")
+	})
+
+	t.Run("No receipt lists the skipped parts", func(t *testing.T) {
+		mockMailService.EXPECT().Ingest(gomock.Any(), gomock.Any(), gomock.Any()).Return(&service.IngestResult{
+			Skipped: []service.SkippedPart{
+				{Source: "attachment logo.png", Reason: "unsupported content type image/png"},
+				{Source: "body text/plain", Reason: "no total found"},
+			},
+		}, service.ErrNoReceipt)
+
+		w := send("From: jane@example.com\n\n")
+
+		require.Equal(t, http.StatusBadRequest, w.Code)
+		var response dto.ResponseErrorModel
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "The message holds no valid receipt", response.Message)
+		assert.Equal(t, "attachment logo.png: unsupported content type image/png; body text/plain: no total found", response.Details)
+	})
+
+	t.Run("Duplicate invoice", func(t *testing.T) {
+		mockMailService.EXPECT().Ingest(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, repository.ErrDuplicateInvoice)
+
+		w := send("From: jane@example.com\n\n")
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("Internal error", func(t *testing.T) {
+		mockMailService.EXPECT().Ingest(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, fmt.Errorf("storage is down"))
+
+		w := send("From: jane@example.com\n\n")
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
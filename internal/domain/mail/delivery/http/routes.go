@@ -0,0 +1,12 @@
+package http
+
+import (
+	receiptHttp "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/delivery/http"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// MapMailRoutes accepts forwarded emails under the scope of submitting receipts.
+func MapMailRoutes(routesGroup *gin.RouterGroup, handler MailHandler, guard middleware.ScopeGuard) {
+	routesGroup.POST("/email", guard.Require(receiptHttp.ScopeReceiptsWrite), handler.Create)
+}
@@ -0,0 +1,52 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/domain/mail/service/mail_service.go
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	io "io"
+	reflect "reflect"
+
+	service "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/mail/service"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockMailService is a mock of MailService interface.
+type MockMailService struct {
+	ctrl     *gomock.Controller
+	recorder *MockMailServiceMockRecorder
+}
+
+// MockMailServiceMockRecorder is the mock recorder for MockMailService.
+type MockMailServiceMockRecorder struct {
+	mock *MockMailService
+}
+
+// NewMockMailService creates a new mock instance.
+func NewMockMailService(ctrl *gomock.Controller) *MockMailService {
+	mock := &MockMailService{ctrl: ctrl}
+	mock.recorder = &MockMailServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockMailService) EXPECT() *MockMailServiceMockRecorder {
+	return m.recorder
+}
+
+// Ingest mocks base method.
+func (m *MockMailService) Ingest(ctx context.Context, message io.Reader, submittedBy string) (*service.IngestResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Ingest", ctx, message, submittedBy)
+	ret0, _ := ret[0].(*service.IngestResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Ingest indicates an expected call of Ingest.
+func (mr *MockMailServiceMockRecorder) Ingest(ctx, message, submittedBy interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ingest", reflect.TypeOf((*MockMailService)(nil).Ingest), ctx, message, submittedBy)
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
+	receiptService "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/service"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/receiptio"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/receiptio/email"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/receiptio/plaintext"
+	"github.com/google/uuid"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DefaultMinConfidence is the confidence a receipt read from the text of an email needs to be
+// submitted.
+const DefaultMinConfidence = 0.8
+
+var (
+	// ErrNoReceipt is returned when no part of the message holds a valid receipt.
+	ErrNoReceipt = errors.New("the message holds no valid receipt")
+)
+
+type MailService interface {
+	// Ingest submits the receipt found in a raw RFC 5322 message on behalf of submittedBy. The
+	// sender of the message is not authenticated, so it is only stored as the Sender of the
+	// receipt. With ErrNoReceipt, the result still lists the parts that were skipped.
+	Ingest(ctx context.Context, message io.Reader, submittedBy string) (*IngestResult, error)
+}
+
+// IngestResult is the receipt created from a message and the parts that were not used.
+type IngestResult struct {
+	Receipt *models.Receipt
+	Sender  string
+	// Source names the part the receipt was read from, e.g. "attachment receipt.json".
+	Source  string
+	Skipped []SkippedPart
+}
+
+type SkippedPart struct {
+	Source string
+	Reason string
+}
+
+type MailServiceImpl struct {
+	receiptSvc    receiptService.ReceiptService
+	minConfidence float64
+}
+
+func NewMailService(receiptSvc receiptService.ReceiptService, minConfidence float64) MailService {
+	return &MailServiceImpl{
+		receiptSvc:    receiptSvc,
+		minConfidence: minConfidence,
+	}
+}
+
+// candidate is a part of the message that may hold a receipt. The lower the rank, the more
+// reliable the format: JSON and XML invoices are read as they are, texts are guessed.
+type candidate struct {
+	rank   int
+	source string
+	read   func() (*models.Receipt, error)
+}
+
+// Ingest tries the parts from the most to the least structured and submits the first receipt
+// that is valid. Text and HTML bodies also need the minimum confidence.
+func (s *MailServiceImpl) Ingest(ctx context.Context, message io.Reader, submittedBy string) (*IngestResult, error) {
+	parsed, err := email.ReadMessage(message)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &IngestResult{Sender: parsed.From}
+	var candidates []candidate
+	for _, part := range parsed.Parts {
+		if next, ok := s.candidate(part); ok {
+			candidates = append(candidates, next)
+		} else {
+			result.Skipped = append(result.Skipped, SkippedPart{Source: partSource(part), Reason: "unsupported content type " + part.MediaType})
+		}
+	}
+	// The parts of the same rank are tried in the order of the message.
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].rank < candidates[j].rank })
+
+	for _, next := range candidates {
+		receipt, err := next.read()
+		if err == nil {
+			err = s.receiptSvc.ValidateReceipt(ctx, receipt)
+		}
+		if err != nil {
+			result.Skipped = append(result.Skipped, SkippedPart{Source: next.source, Reason: strings.ReplaceAll(err.Error(), "\n", "; ")})
+			continue
+		}
+
+		receipt.Sender = parsed.From
+		receipt.SubmittedBy = submittedBy
+		created, err := s.receiptSvc.CreateReceipt(ctx, receipt)
+		if err != nil {
+			return nil, err
+		}
+		result.Receipt, result.Source = created, next.source
+		return result, nil
+	}
+	return result, ErrNoReceipt
+}
+
+func (s *MailServiceImpl) candidate(part email.Part) (candidate, bool) {
+	extension := strings.ToLower(filepath.Ext(part.Filename))
+	source := partSource(part)
+	switch {
+	case part.MediaType == "application/json" || extension == ".json":
+		return candidate{rank: 0, source: source, read: func() (*models.Receipt, error) { return readJSON(part.Content) }}, true
+	case part.MediaType == "application/xml" || part.MediaType == "text/xml" || extension == ".xml":
+		return candidate{rank: 0, source: source, read: func() (*models.Receipt, error) { return receiptio.ParseInvoice(part.Content, "") }}, true
+	case part.MediaType == "text/plain":
+		return candidate{rank: 1, source: source, read: func() (*models.Receipt, error) { return s.readText(part.Content) }}, true
+	case part.MediaType == "text/html":
+		return candidate{rank: 2, source: source, read: func() (*models.Receipt, error) {
+			return s.readText([]byte(email.HTMLText(part.Content)))
+		}}, true
+	}
+	return candidate{}, false
+}
+
+func (s *MailServiceImpl) readText(content []byte) (*models.Receipt, error) {
+	draft, err := plaintext.Parse(bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+	if draft.Confidence < s.minConfidence {
+		return nil, fmt.Errorf("the receipt read from the text has a confidence of %.2f, below %.2f: %s", draft.Confidence, s.minConfidence, strings.Join(draft.Warnings, "; "))
+	}
+	return draft.Receipt, nil
+}
+
+func readJSON(content []byte) (*models.Receipt, error) {
+	receipt := &models.Receipt{}
+	if err := json.Unmarshal(content, receipt); err != nil {
+		return nil, fmt.Errorf("could not parse the receipt: %w", err)
+	}
+	// The ID is assigned by the service.
+	receipt.ID = uuid.Nil
+	return receipt, nil
+}
+
+func partSource(part email.Part) string {
+	if part.Attachment {
+		return "attachment " + part.Filename
+	}
+	return "body " + part.MediaType
+}
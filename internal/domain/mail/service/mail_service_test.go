@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/repository"
+	receiptService "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/service"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/receiptio/email"
+	"github.com/CarlosMtz98/receipt-processor-challenge/pkg/currency"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"strings"
+	"testing"
+)
+
+// attachmentMessage forwards a text body and a base64 attachment from jane@example.com.
+func attachmentMessage(body, filename, mediaType string, content []byte) string {
+	return strings.Join([]string{
+		"From: Jane Doe <jane@example.com>",
+		"Subject: Fwd: receipt",
+		"Content-Type: multipart/mixed; boundary=b",
+		"",
+		"--b",
+		"Content-Type: text/plain",
+		"",
+		body,
+		"--b",
+		"Content-Type: " + mediaType,
+		`Content-Disposition: attachment; filename="` + filename + `"`,
+		"Content-Transfer-Encoding: base64",
+		"",
+		base64.StdEncoding.EncodeToString(content),
+		"--b--",
+		"",
+	}, "\r\n")
+}
+
+func TestMailServiceImpl_Ingest(t *testing.T) {
+	rates, err := currency.LoadRates("../../../../examples/exchange-rates.json", currency.DefaultCurrency)
+	require.NoError(t, err)
+	receiptSvc := receiptService.NewReceiptService(repository.InitReceiptRepository(), receiptService.WithExchangeRates(rates))
+	mailSvc := NewMailService(receiptSvc, DefaultMinConfidence)
+	ctx := context.Background()
+
+	t.Run("Text body", func(t *testing.T) {
+		file, err := os.Open("../../../../examples/email-receipt.eml")
+		require.NoError(t, err)
+		defer file.Close()
+
+		result, err := mailSvc.Ingest(ctx, file, "maildir:/var/mail/receipts")
+
+		require.NoError(t, err)
+		assert.Equal(t, "body text/plain", result.Source)
+		assert.Equal(t, "receipts@cornermarket.example", result.Sender)
+		assert.Empty(t, result.Skipped)
+		stored, err := receiptSvc.GetReceiptByID(ctx, result.Receipt.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "CORNER MARKET", stored.Retailer)
+		assert.Equal(t, "2022-03-20", stored.PurchaseDate)
+		assert.Equal(t, "14:33", stored.PurchaseTime)
+		assert.Equal(t, "20.00", stored.Total)
+		assert.Len(t, stored.Items, 3)
+		assert.Equal(t, "receipts@cornermarket.example", stored.Sender)
+		assert.Equal(t, "maildir:/var/mail/receipts", stored.SubmittedBy)
+	})
+
+	t.Run("HTML body", func(t *testing.T) {
+		message := "From: shop@example.com\nContent-Type: text/html\n\n" +
+			"<h1>Target</h1><p>2022-01-01 13:01</p><table><tr><td>Mountain Dew 12PK</td><td>6.49</td></tr>" +
+			"<tr><td>TOTAL</td><td>6.49</td></tr></table>"
+
+		result, err := mailSvc.Ingest(ctx, strings.NewReader(message), "key:abc")
+
+		require.NoError(t, err)
+		assert.Equal(t, "body text/html", result.Source)
+		assert.Equal(t, "Target", result.Receipt.Retailer)
+		assert.Equal(t, "key:abc", result.Receipt.SubmittedBy)
+	})
+
+	t.Run("Attachments are preferred to the body", func(t *testing.T) {
+		receipt := `{"id":"7fb1377b-b223-49d9-a31a-5a02701dd310","retailer":"Walgreens","purchaseDate":"2022-01-02",` +
+			`"purchaseTime":"08:13","total":"1.25","items":[{"shortDescription":"Pepsi - 12-oz","price":"1.25"}]}`
+		message := attachmentMessage("Target\n2022-01-01 13:01\nDasani 1.40\nTOTAL 1.40", "receipt.json", "application/octet-stream", []byte(receipt))
+
+		result, err := mailSvc.Ingest(ctx, strings.NewReader(message), "")
+
+		require.NoError(t, err)
+		assert.Equal(t, "attachment receipt.json", result.Source)
+		assert.Equal(t, "Walgreens", result.Receipt.Retailer)
+		assert.NotEqual(t, "7fb1377b-b223-49d9-a31a-5a02701dd310", result.Receipt.ID.String())
+	})
+
+	t.Run("Invalid attachment falls back to the body", func(t *testing.T) {
+		message := attachmentMessage("Target\n2022-01-01 13:01\nDasani 1.40\nTOTAL 1.40", "receipt.json", "application/json", []byte(`{"retailer":`))
+
+		result, err := mailSvc.Ingest(ctx, strings.NewReader(message), "")
+
+		require.NoError(t, err)
+		assert.Equal(t, "body text/plain", result.Source)
+		require.Len(t, result.Skipped, 1)
+		assert.Equal(t, "attachment receipt.json", result.Skipped[0].Source)
+		assert.Contains(t, result.Skipped[0].Reason, "could not parse the receipt")
+	})
+
+	t.Run("CFDI attachment is submitted once", func(t *testing.T) {
+		invoice, err := os.ReadFile("../../../../examples/cfdi-invoice.xml")
+		require.NoError(t, err)
+		message := attachmentMessage("Your invoice is attached.", "factura.xml", "application/xml", invoice)
+
+		result, err := mailSvc.Ingest(ctx, strings.NewReader(message), "")
+		require.NoError(t, err)
+		assert.Equal(t, "attachment factura.xml", result.Source)
+		assert.Equal(t, "TIENDA LA ESPERANZA", result.Receipt.Retailer)
+
+		_, err = mailSvc.Ingest(ctx, strings.NewReader(message), "")
+		assert.True(t, errors.Is(err, repository.ErrDuplicateInvoice), "got %v", err)
+	})
+
+	t.Run("No valid receipt", func(t *testing.T) {
+		message := attachmentMessage("Dasani 1.40\nTOTAL 1.40", "photo.png", "image/png", []byte{0x89, 'P', 'N', 'G'})
+
+		result, err := mailSvc.Ingest(ctx, strings.NewReader(message), "")
+
+		assert.True(t, errors.Is(err, ErrNoReceipt))
+		require.Len(t, result.Skipped, 2)
+		assert.Equal(t, SkippedPart{Source: "attachment photo.png", Reason: "unsupported content type image/png"}, result.Skipped[0])
+		assert.Equal(t, "body text/plain", result.Skipped[1].Source)
+		assert.Contains(t, result.Skipped[1].Reason, "below 0.80")
+	})
+
+	t.Run("Invalid message", func(t *testing.T) {
+		_, err := mailSvc.Ingest(ctx, strings.NewReader("TOTAL 1.40"), "")
+
+		assert.True(t, errors.Is(err, email.ErrInvalidMessage))
+	})
+}
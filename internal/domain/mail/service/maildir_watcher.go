@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Maildir info flags: "S" marks the messages that were submitted, "F" the ones that were not, so
+// that they can be found and submitted again by hand.
+const (
+	flagIngested = ":2,S"
+	flagFailed   = ":2,F"
+)
+
+// MaildirWatcher submits the messages delivered to a maildir. Every message in new/ is ingested
+// once and then moved to cur/, flagged with the outcome. Anyone can send mail to the mailbox with
+// any From, so its receipts are all submitted on behalf of the mailbox, "maildir:<dir>".
+type MaildirWatcher struct {
+	mailSvc      MailService
+	dir          string
+	submitter    string
+	pollInterval time.Duration
+}
+
+func NewMaildirWatcher(mailSvc MailService, dir string, pollInterval time.Duration) (*MaildirWatcher, error) {
+	for _, sub := range []string{"new", "cur"} {
+		info, err := os.Stat(filepath.Join(dir, sub))
+		if err != nil {
+			return nil, fmt.Errorf("the maildir %s cannot be read: %w", dir, err)
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("the maildir %s has no %s directory", dir, sub)
+		}
+	}
+
+	return &MaildirWatcher{
+		mailSvc:      mailSvc,
+		dir:          dir,
+		submitter:    "maildir:" + dir,
+		pollInterval: pollInterval,
+	}, nil
+}
+
+// Start polls the maildir until the context is cancelled.
+func (w *MaildirWatcher) Start(ctx context.Context) {
+	ticker := time.NewTicker(w.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.Poll(ctx); err != nil {
+			log.Printf("Error reading the maildir: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll ingests the messages in new/, oldest name first. A message that cannot be submitted is
+// logged and flagged; it does not stop the others.
+func (w *MaildirWatcher) Poll(ctx context.Context) error {
+	entries, err := os.ReadDir(filepath.Join(w.dir, "new"))
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		// Delivery agents write to tmp/ and move complete messages, so new/ only holds files;
+		// dot files are skipped as the maildir format asks.
+		if entry.Type().IsRegular() && !strings.HasPrefix(entry.Name(), ".") {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		flag := w.ingest(ctx, name)
+		if err := os.Rename(filepath.Join(w.dir, "new", name), filepath.Join(w.dir, "cur", name+flag)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *MaildirWatcher) ingest(ctx context.Context, name string) string {
+	file, err := os.Open(filepath.Join(w.dir, "new", name))
+	if err != nil {
+		log.Printf("Error opening the message %s: %v", name, err)
+		return flagFailed
+	}
+	defer file.Close()
+
+	result, err := w.mailSvc.Ingest(ctx, file, w.submitter)
+	if err != nil {
+		reasons := ""
+		if result != nil {
+			for _, skipped := range result.Skipped {
+				reasons += fmt.Sprintf("; %s: %s", skipped.Source, skipped.Reason)
+			}
+		}
+		log.Printf("Message %s was not submitted: %v%s", name, err, reasons)
+		return flagFailed
+	}
+	log.Printf("Message %s from %s submitted as receipt %s (%s)", name, result.Sender, result.Receipt.ID, result.Source)
+	return flagIngested
+}
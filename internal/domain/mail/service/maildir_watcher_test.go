@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/repository"
+	receiptService "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMaildirWatcher_Poll(t *testing.T) {
+	dir := t.TempDir()
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		require.NoError(t, os.Mkdir(filepath.Join(dir, sub), 0o755))
+	}
+	example, err := os.ReadFile("../../../../examples/email-receipt.eml")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "new", "1647805000.1.host"), example, 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "new", "1647805001.2.host"), []byte("From: a@example.com\n\nhello"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "new", ".hidden"), example, 0o644))
+
+	receiptRepo := repository.InitReceiptRepository()
+	receiptSvc := receiptService.NewReceiptService(receiptRepo)
+	watcher, err := NewMaildirWatcher(NewMailService(receiptSvc, DefaultMinConfidence), dir, time.Minute)
+	require.NoError(t, err)
+
+	require.NoError(t, watcher.Poll(context.Background()))
+
+	remaining, err := os.ReadDir(filepath.Join(dir, "new"))
+	require.NoError(t, err)
+	require.Len(t, remaining, 1)
+	assert.Equal(t, ".hidden", remaining[0].Name())
+	assert.FileExists(t, filepath.Join(dir, "cur", "1647805000.1.host:2,S"))
+	assert.FileExists(t, filepath.Join(dir, "cur", "1647805001.2.host:2,F"))
+	// The receipt is submitted by the mailbox, whatever the From of the message says.
+	receipts, err := receiptRepo.List(context.Background())
+	require.NoError(t, err)
+	require.Len(t, receipts, 1)
+	assert.Equal(t, "maildir:"+dir, receipts[0].SubmittedBy)
+	assert.Equal(t, "receipts@cornermarket.example", receipts[0].Sender)
+
+	// The messages are not read again.
+	require.NoError(t, watcher.Poll(context.Background()))
+	cur, err := os.ReadDir(filepath.Join(dir, "cur"))
+	require.NoError(t, err)
+	assert.Len(t, cur, 2)
+}
+
+func TestNewMaildirWatcher(t *testing.T) {
+	_, err := NewMaildirWatcher(nil, t.TempDir(), time.Minute)
+
+	assert.Error(t, err)
+}
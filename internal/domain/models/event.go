@@ -0,0 +1,66 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+type ReceiptEventType string
+
+const (
+	ReceiptCreated  ReceiptEventType = "receipt.created"
+	ReceiptScored   ReceiptEventType = "receipt.scored"
+	ReceiptRejected ReceiptEventType = "receipt.rejected"
+)
+
+// ReceiptEvent describes a change in the lifecycle of a receipt. Sequence is assigned when
+// the event is written to the outbox and orders events by commit. RetailerID and RetailerName
+// are set when the receipt matched a retailer of the registry, and OriginalID when it is a
+// return.
+type ReceiptEvent struct {
+	ID           uuid.UUID        `json:"id"`
+	Sequence     int64            `json:"sequence"`
+	Type         ReceiptEventType `json:"type"`
+	ReceiptID    uuid.UUID        `json:"receiptId"`
+	ReceiptType  ReceiptType      `json:"receiptType"`
+	OriginalID   *uuid.UUID       `json:"originalReceiptId,omitempty"`
+	Retailer     string           `json:"retailer"`
+	RetailerID   *uuid.UUID       `json:"retailerId,omitempty"`
+	RetailerName string           `json:"retailerName,omitempty"`
+	Total        string           `json:"total"`
+	Currency     string           `json:"currency"`
+	Points       *int             `json:"points,omitempty"`
+	Reason       string           `json:"reason,omitempty"`
+	OccurredAt   time.Time        `json:"occurredAt"`
+}
+
+// NewReceiptEvent builds an event of the given type from the receipt.
+func NewReceiptEvent(eventType ReceiptEventType, receipt *Receipt, occurredAt time.Time) *ReceiptEvent {
+	receiptType := ReceiptTypePurchase
+	if receipt.IsReturn() {
+		receiptType = ReceiptTypeReturn
+	}
+
+	return &ReceiptEvent{
+		ID:           uuid.New(),
+		Type:         eventType,
+		ReceiptID:    receipt.ID,
+		ReceiptType:  receiptType,
+		OriginalID:   receipt.OriginalID,
+		Retailer:     receipt.Retailer,
+		RetailerID:   receipt.RetailerID,
+		RetailerName: receipt.RetailerName,
+		Total:        receipt.Total,
+		Currency:     receipt.GetCurrency(),
+		OccurredAt:   occurredAt,
+	}
+}
+
+// IsValidReceiptEventType reports whether the type is one of the published events.
+func IsValidReceiptEventType(eventType ReceiptEventType) bool {
+	switch eventType {
+	case ReceiptCreated, ReceiptScored, ReceiptRejected:
+		return true
+	}
+	return false
+}
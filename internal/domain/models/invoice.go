@@ -0,0 +1,41 @@
+package models
+
+import (
+	"strings"
+)
+
+// InvoiceFormat is the electronic invoice standard a receipt was submitted in.
+type InvoiceFormat string
+
+const (
+	// InvoiceFormatCFDI is the Mexican CFDI issued through the SAT.
+	InvoiceFormatCFDI InvoiceFormat = "cfdi"
+	// InvoiceFormatUBL is the OASIS UBL 2.1 invoice, as exchanged over Peppol.
+	InvoiceFormatUBL InvoiceFormat = "ubl"
+)
+
+// InvoiceReference identifies the electronic invoice a receipt was created from. An invoice can
+// only be submitted once, whether it is recognized by its fiscal UUID or by its issuer and number.
+type InvoiceReference struct {
+	Format InvoiceFormat
+	// Issuer is the tax id of the retailer that issued the invoice.
+	Issuer string
+	Series string
+	Number string
+	// UUID is the identifier assigned by the tax authority, or by the issuer, when there is one.
+	UUID string
+	// Currency is the ISO 4217 code of the amounts of the invoice.
+	Currency string
+}
+
+// Keys returns the identities of the invoice that must be unique across receipts.
+func (r *InvoiceReference) Keys() []string {
+	var keys []string
+	if r.UUID != "" {
+		keys = append(keys, string(r.Format)+"|uuid|"+strings.ToUpper(r.UUID))
+	}
+	if r.Number != "" {
+		keys = append(keys, string(r.Format)+"|number|"+strings.ToUpper(r.Issuer)+"|"+r.Series+"|"+r.Number)
+	}
+	return keys
+}
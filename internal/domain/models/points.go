@@ -0,0 +1,24 @@
+package models
+
+// PointsRule is the number of points awarded by a single scoring rule.
+type PointsRule struct {
+	Rule        string
+	Description string
+	Points      int
+}
+
+// PointsBreakdown explains how the points of a receipt were computed. Total is the sum of
+// the rules' points.
+type PointsBreakdown struct {
+	Rules []PointsRule
+	Total int
+}
+
+// ReceiptFilter selects the receipts returned by a listing. Empty fields match every receipt
+// and a zero Limit returns every remaining receipt.
+type ReceiptFilter struct {
+	Retailer string
+	Status   ReceiptStatus
+	Offset   int
+	Limit    int
+}
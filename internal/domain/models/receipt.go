@@ -1,31 +1,192 @@
 package models
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/CarlosMtz98/receipt-processor-challenge/pkg/currency"
+	"github.com/CarlosMtz98/receipt-processor-challenge/pkg/timezone"
 	"github.com/google/uuid"
+	"math"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 )
 
 type Receipt struct {
-	ID           uuid.UUID
-	Retailer     string        `json:"retailer" validate:"required"`
-	PurchaseDate string        `json:"purchaseDate" validate:"required,datetime=2006-01-02"`
-	PurchaseTime string        `json:"purchaseTime" validate:"required,datetime=15:04"`
+	ID       uuid.UUID
+	Retailer string `json:"retailer" validate:"required"`
+	// PurchaseDate and PurchaseTime are the local date and time printed on the receipt. They
+	// can be left out for PurchasedAt, and are then filled in when the receipt is created.
+	PurchaseDate string        `json:"purchaseDate" validate:"required_without=PurchasedAt,omitempty,datetime=2006-01-02"`
+	PurchaseTime string        `json:"purchaseTime" validate:"required_without=PurchasedAt,omitempty,datetime=15:04"`
 	Items        []ReceiptItem `json:"items" validate:"required,min=1,dive"`
 	Total        string        `json:"total" validate:"required,currency"`
+	// Subtotal, Taxes, Discounts and Tip are optional. The total is the subtotal minus the
+	// discounts plus the taxes and the tip, and the subtotal defaults to the sum of the items.
+	Subtotal  string              `json:"subtotal,omitempty" validate:"omitempty,currency"`
+	Taxes     []ReceiptAdjustment `json:"taxes,omitempty" validate:"omitempty,dive"`
+	Discounts []ReceiptAdjustment `json:"discounts,omitempty" validate:"omitempty,dive"`
+	Tip       string              `json:"tip,omitempty" validate:"omitempty,currency"`
+	// Currency is the ISO 4217 code of the amounts, USD when it is not set. The amounts have the
+	// decimals of the currency, e.g. none for JPY.
+	Currency string `json:"currency,omitempty" validate:"omitempty,iso4217"`
+	// Type is ReceiptTypeReturn for the refund of items of an earlier purchase, the receipt
+	// OriginalID refers to. Its items and total are the amounts refunded, also positive.
+	Type       ReceiptType `json:"type,omitempty" validate:"omitempty,oneof=purchase return"`
+	OriginalID *uuid.UUID  `json:"originalReceiptId,omitempty"`
+	// TimeZone is where the store is, an IANA name such as America/Chicago or a UTC offset such
+	// as -05:00. Receipts without one are in the time zone of their retailer, or in UTC.
+	TimeZone string `json:"timeZone,omitempty" validate:"omitempty,zone"`
+	// PurchasedAt is the purchase as an RFC 3339 timestamp, e.g. 2022-01-01T20:30:00Z, for the
+	// terminals that don't print the local time.
+	PurchasedAt string `json:"purchasedAt,omitempty" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	// The fields below are set by the service and are never part of the payload.
+	Signature    *ReceiptSignature      `json:"-"`
+	Verification *SignatureVerification `json:"-"`
+	Status       ReceiptStatus          `json:"-"`
+	Risk         *RiskAssessment        `json:"-"`
+	Review       *ReviewDecision        `json:"-"`
+	Invoice      *InvoiceReference      `json:"-"`
+	// Exchange is the rate the amounts were converted to the base currency with for scoring.
+	Exchange    *ExchangeRate `json:"-"`
+	SubmittedBy string        `json:"-"`
+	// Sender is the address of the email the receipt was forwarded from.
+	Sender string `json:"-"`
+	// RetailerID and RetailerName are the entry of the retailer registry the receipt was
+	// matched with when it was created, Retailer keeping the name as printed.
+	RetailerID   *uuid.UUID `json:"-"`
+	RetailerName string     `json:"-"`
+	CreatedAt    time.Time  `json:"-"`
 }
 
+// ReceiptType tells purchases from returns. Receipts without a type are purchases.
+type ReceiptType string
+
+const (
+	ReceiptTypePurchase ReceiptType = "purchase"
+	ReceiptTypeReturn   ReceiptType = "return"
+)
+
+// ReceiptAdjustment is a tax or discount line of the receipt, e.g. "Sales tax 8.25%" or
+// "Coupon". Discount amounts are positive and taken from the subtotal.
+type ReceiptAdjustment struct {
+	Description string `json:"description" validate:"required"`
+	Amount      string `json:"amount" validate:"required,currency"`
+}
+
+// ReceiptAmounts are the amounts of a receipt in the minor unit of its currency, e.g. cents.
+// Subtotal is the sum of the items when the receipt does not state it.
+type ReceiptAmounts struct {
+	Items     int64
+	Subtotal  int64
+	Discounts int64
+	Taxes     int64
+	Tip       int64
+	Total     int64
+}
+
+// Expected is the total the other amounts add up to.
+func (a ReceiptAmounts) Expected() int64 {
+	return a.Subtotal - a.Discounts + a.Taxes + a.Tip
+}
+
+// PreTax is the amount paid for the items once discounted, before the taxes and the tip.
+func (a ReceiptAmounts) PreTax() int64 {
+	return a.Subtotal - a.Discounts
+}
+
+// TotalMatches tells whether the total is at most tolerance away from what the other amounts
+// add up to.
+func (a ReceiptAmounts) TotalMatches(tolerance int64) bool {
+	difference := a.Expected() - a.Total
+	return difference <= tolerance && -difference <= tolerance
+}
+
+// ExchangeRate is the rate a receipt in a foreign currency is scored with: one unit of Currency
+// is worth Rate units of Base. Date is the day the rate was published for, the purchase date or
+// a few days before when there is no rate on that day.
+type ExchangeRate struct {
+	Currency string
+	Base     string
+	Rate     float64
+	Date     string
+}
+
+// ReceiptItem is a line of the receipt. Price is always the amount paid for the whole line;
+// the optional Quantity and UnitPrice describe how it was computed, e.g. 3 x 1.99.
 type ReceiptItem struct {
 	ShortDescription string `json:"shortDescription" validate:"required"`
 	Price            string `json:"price" validate:"required,currency"`
+	// Quantity is a number of units or a weight, e.g. "3" or "0.455". It defaults to 1.
+	Quantity  string `json:"quantity,omitempty" validate:"omitempty,quantity"`
+	UnitPrice string `json:"unitPrice,omitempty" validate:"omitempty,currency"`
+	SKU       string `json:"sku,omitempty" validate:"omitempty,max=64"`
+	Category  string `json:"category,omitempty" validate:"omitempty,max=64"`
+}
+
+// ItemsFingerprint identifies the item list regardless of the order of the items.
+func (r *Receipt) ItemsFingerprint() string {
+	lines := make([]string, len(r.Items))
+	for i, item := range r.Items {
+		lines[i] = strings.ToLower(strings.TrimSpace(item.ShortDescription)) + "|" + item.Price
+	}
+	sort.Strings(lines)
+
+	sum := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+// GetRetailerName returns the canonical name of the retailer, the name printed on the receipt
+// when it matched no retailer of the registry.
+func (r *Receipt) GetRetailerName() string {
+	if r.RetailerName != "" {
+		return r.RetailerName
+	}
+	return strings.TrimSpace(r.Retailer)
+}
+
+// GetCurrency returns the currency of the receipt, USD when it is not set.
+func (r *Receipt) GetCurrency() string {
+	if r.Currency == "" {
+		return currency.DefaultCurrency
+	}
+	return r.Currency
+}
+
+// IsReturn tells whether the receipt refunds items of an earlier purchase.
+func (r *Receipt) IsReturn() bool {
+	return r.Type == ReceiptTypeReturn
 }
 
 func (r *Receipt) GetTotalAsFloat() (float64, error) {
 	return strconv.ParseFloat(r.Total, 64)
 }
 
+// GetBaseTotal returns the total converted to the base currency with the recorded exchange rate,
+// or the total itself for the receipts in the base currency.
+func (r *Receipt) GetBaseTotal() (float64, error) {
+	total, err := r.GetTotalAsFloat()
+	if err != nil || r.Exchange == nil {
+		return total, err
+	}
+	return total * r.Exchange.Rate, nil
+}
+
+// Location returns the time zone of the store, UTC when the receipt doesn't have one.
+func (r *Receipt) Location() (*time.Location, error) {
+	if r.TimeZone == "" {
+		return time.UTC, nil
+	}
+	return timezone.Load(r.TimeZone)
+}
+
+// GetReceiptDatetime returns the purchase date and time in the time zone of the store, so that
+// its clock is the one printed on the receipt.
 func (r *Receipt) GetReceiptDatetime() (time.Time, error) {
 	if r == nil {
 		return time.Time{}, errors.New("receipt is nil")
@@ -39,8 +200,13 @@ func (r *Receipt) GetReceiptDatetime() (time.Time, error) {
 		return time.Time{}, errors.New("receipt time is nil or empty")
 	}
 
+	location, err := r.Location()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to load the receipt time zone: %v", err)
+	}
+
 	receiptTimeStr := fmt.Sprintf("%s %s:00", r.PurchaseDate, r.PurchaseTime)
-	date, err := time.Parse(time.DateTime, receiptTimeStr)
+	date, err := time.ParseInLocation(time.DateTime, receiptTimeStr, location)
 
 	if err != nil {
 		return time.Time{}, fmt.Errorf("failed to parse receipt datetime: %v", err)
@@ -49,21 +215,179 @@ func (r *Receipt) GetReceiptDatetime() (time.Time, error) {
 	return date, nil
 }
 
-func (r *Receipt) IsValid() (bool, error) {
-	totalPrice := 0.0
+// CanonicalJSON serializes the receipt payload with sorted keys, no insignificant
+// whitespace and no HTML escaping. It is the message POS terminals sign.
+func (r *Receipt) CanonicalJSON() ([]byte, error) {
+	items := make([]map[string]string, len(r.Items))
+	for i, item := range r.Items {
+		items[i] = map[string]string{
+			"price":            item.Price,
+			"shortDescription": item.ShortDescription,
+		}
+		// The optional fields are only signed when they are set, so that the receipts signed
+		// before they existed keep their signature.
+		for key, value := range map[string]string{"quantity": item.Quantity, "unitPrice": item.UnitPrice, "sku": item.SKU, "category": item.Category} {
+			if value != "" {
+				items[i][key] = value
+			}
+		}
+	}
+
+	payload := map[string]interface{}{
+		"items":        items,
+		"purchaseDate": r.PurchaseDate,
+		"purchaseTime": r.PurchaseTime,
+		"retailer":     r.Retailer,
+		"total":        r.Total,
+	}
+	// Like the optional item fields, the amounts are only signed when they are set.
+	if r.Subtotal != "" {
+		payload["subtotal"] = r.Subtotal
+	}
+	if len(r.Taxes) > 0 {
+		payload["taxes"] = canonicalAdjustments(r.Taxes)
+	}
+	if len(r.Discounts) > 0 {
+		payload["discounts"] = canonicalAdjustments(r.Discounts)
+	}
+	if r.Tip != "" {
+		payload["tip"] = r.Tip
+	}
+	if r.Currency != "" {
+		payload["currency"] = r.Currency
+	}
+	if r.Type != "" {
+		payload["type"] = r.Type
+	}
+	if r.OriginalID != nil {
+		payload["originalReceiptId"] = r.OriginalID.String()
+	}
+	if r.TimeZone != "" {
+		payload["timeZone"] = r.TimeZone
+	}
+	if r.PurchasedAt != "" {
+		payload["purchasedAt"] = r.PurchasedAt
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(payload); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// canonicalAdjustments keys the adjustments with maps, so that their keys are sorted like the
+// ones of the items rather than in the order of the struct fields.
+func canonicalAdjustments(adjustments []ReceiptAdjustment) []map[string]string {
+	canonical := make([]map[string]string, len(adjustments))
+	for i, adjustment := range adjustments {
+		canonical[i] = map[string]string{
+			"amount":      adjustment.Amount,
+			"description": adjustment.Description,
+		}
+	}
+	return canonical
+}
+
+// IsValid tells whether the subtotal, when it is set, is the sum of the item prices and the
+// total is at most totalTolerance, in minor units, away from what the amounts add up to. The
+// amounts are compared in minor units, as adding up the prices as floats does not always give
+// back the exact total.
+func (r *Receipt) IsValid(totalTolerance int64) (bool, error) {
+	amounts, err := r.Amounts()
+	if err != nil {
+		return false, err
+	}
+	return amounts.Subtotal == amounts.Items && amounts.TotalMatches(totalTolerance), nil
+}
+
+// HasAdjustments tells whether the total is more than the sum of the items.
+func (r *Receipt) HasAdjustments() bool {
+	return r.Subtotal != "" || len(r.Taxes) > 0 || len(r.Discounts) > 0 || r.Tip != ""
+}
+
+// Amounts parses the amounts of the receipt.
+func (r *Receipt) Amounts() (ReceiptAmounts, error) {
+	var amounts ReceiptAmounts
+	code := r.GetCurrency()
 	for i := 0; i < len(r.Items); i++ {
-		price, err := r.Items[i].GetReceiptItemPrice()
+		price, err := currency.ParseAmount(r.Items[i].Price, code)
 		if err != nil {
-			return false, err
+			return amounts, err
 		}
-		totalPrice += price
+		amounts.Items += price
 	}
 
-	total, err := r.GetTotalAsFloat()
+	var err error
+	amounts.Subtotal = amounts.Items
+	if r.Subtotal != "" {
+		if amounts.Subtotal, err = currency.ParseAmount(r.Subtotal, code); err != nil {
+			return amounts, err
+		}
+	}
+	if amounts.Discounts, err = sumAdjustments(r.Discounts, code); err != nil {
+		return amounts, err
+	}
+	if amounts.Taxes, err = sumAdjustments(r.Taxes, code); err != nil {
+		return amounts, err
+	}
+	if r.Tip != "" {
+		if amounts.Tip, err = currency.ParseAmount(r.Tip, code); err != nil {
+			return amounts, err
+		}
+	}
+	amounts.Total, err = currency.ParseAmount(r.Total, code)
+	return amounts, err
+}
+
+func sumAdjustments(adjustments []ReceiptAdjustment, code string) (int64, error) {
+	var sum int64
+	for _, adjustment := range adjustments {
+		amount, err := currency.ParseAmount(adjustment.Amount, code)
+		if err != nil {
+			return 0, err
+		}
+		sum += amount
+	}
+	return sum, nil
+}
+
+// Converted returns a copy of the receipt with its amounts converted with the rate, rounded to
+// the minor unit of the base currency. The receipt is left untouched.
+func (r *Receipt) Converted(rate ExchangeRate) (*Receipt, error) {
+	from := r.GetCurrency()
+	var err error
+	convert := func(amount *string) {
+		if *amount == "" || err != nil {
+			return
+		}
+		*amount, err = currency.Convert(*amount, from, rate.Base, rate.Rate)
+	}
+
+	converted := *r
+	converted.Currency = rate.Base
+	converted.Items = append([]ReceiptItem(nil), r.Items...)
+	for i := range converted.Items {
+		convert(&converted.Items[i].Price)
+		convert(&converted.Items[i].UnitPrice)
+	}
+	converted.Taxes = append([]ReceiptAdjustment(nil), r.Taxes...)
+	for i := range converted.Taxes {
+		convert(&converted.Taxes[i].Amount)
+	}
+	converted.Discounts = append([]ReceiptAdjustment(nil), r.Discounts...)
+	for i := range converted.Discounts {
+		convert(&converted.Discounts[i].Amount)
+	}
+	convert(&converted.Subtotal)
+	convert(&converted.Tip)
+	convert(&converted.Total)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
-	return total == totalPrice, nil
+	return &converted, nil
 }
 
 func (ri *ReceiptItem) GetPriceAsFloat() (float64, error) {
@@ -73,3 +397,42 @@ func (ri *ReceiptItem) GetPriceAsFloat() (float64, error) {
 func (r1 *ReceiptItem) GetReceiptItemPrice() (float64, error) {
 	return strconv.ParseFloat(r1.Price, 64)
 }
+
+// GetQuantity returns the quantity of the item, 1 when it is not set.
+func (ri *ReceiptItem) GetQuantity() (float64, error) {
+	if ri.Quantity == "" {
+		return 1, nil
+	}
+	return strconv.ParseFloat(ri.Quantity, 64)
+}
+
+// Units is the number of items the line stands for: its quantity when it is a whole number,
+// and 1 for weights such as 0.455 kg of apples.
+func (ri *ReceiptItem) Units() int {
+	quantity, err := ri.GetQuantity()
+	if err != nil || quantity < 1 || quantity != math.Trunc(quantity) {
+		return 1
+	}
+	return int(quantity)
+}
+
+// HasConsistentPrice tells whether the price is the quantity times the unit price, rounded to
+// the minor unit of the currency. Items without a unit price are consistent.
+func (ri *ReceiptItem) HasConsistentPrice(code string) (bool, error) {
+	if ri.UnitPrice == "" {
+		return true, nil
+	}
+	quantity, err := ri.GetQuantity()
+	if err != nil {
+		return false, err
+	}
+	unitPrice, err := strconv.ParseFloat(ri.UnitPrice, 64)
+	if err != nil {
+		return false, err
+	}
+	price, err := ri.GetPriceAsFloat()
+	if err != nil {
+		return false, err
+	}
+	return currency.ToMinor(quantity*unitPrice, code) == currency.ToMinor(price, code), nil
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"testing"
@@ -49,6 +50,173 @@ func TestReceipt(t *testing.T) {
 		assert.Equal(t, expected, actual)
 	})
 
+	t.Run("CanonicalJSON", func(t *testing.T) {
+		receipt := Receipt{
+			Retailer:     "M&M Corner Market",
+			PurchaseDate: "2022-03-20",
+			PurchaseTime: "14:33",
+			Items:        []ReceiptItem{{ShortDescription: "Gatorade", Price: "2.25"}},
+			Total:        "2.25",
+		}
+		expected := `{"items":[{"price":"2.25","shortDescription":"Gatorade"}],"purchaseDate":"2022-03-20","purchaseTime":"14:33","retailer":"M&M Corner Market","total":"2.25"}`
+		actual, err := receipt.CanonicalJSON()
+		assert.NoError(t, err)
+		assert.Equal(t, expected, string(actual))
+	})
+
+	t.Run("CanonicalJSON with quantities", func(t *testing.T) {
+		receipt := Receipt{
+			Retailer:     "Target",
+			PurchaseDate: "2022-03-20",
+			PurchaseTime: "14:33",
+			Items:        []ReceiptItem{{ShortDescription: "Gatorade", Price: "6.75", Quantity: "3", UnitPrice: "2.25", SKU: "052000328660"}},
+			Total:        "6.75",
+		}
+		expected := `{"items":[{"price":"6.75","quantity":"3","shortDescription":"Gatorade","sku":"052000328660","unitPrice":"2.25"}],"purchaseDate":"2022-03-20","purchaseTime":"14:33","retailer":"Target","total":"6.75"}`
+		actual, err := receipt.CanonicalJSON()
+		assert.NoError(t, err)
+		assert.Equal(t, expected, string(actual))
+	})
+
+	t.Run("CanonicalJSON with amounts", func(t *testing.T) {
+		receipt := Receipt{
+			Retailer:     "Target",
+			PurchaseDate: "2022-03-20",
+			PurchaseTime: "14:33",
+			Items:        []ReceiptItem{{ShortDescription: "Gatorade", Price: "2.25"}},
+			Total:        "2.44",
+			Subtotal:     "2.25",
+			Taxes:        []ReceiptAdjustment{{Description: "Sales tax", Amount: "0.19"}},
+		}
+		expected := `{"items":[{"price":"2.25","shortDescription":"Gatorade"}],"purchaseDate":"2022-03-20","purchaseTime":"14:33","retailer":"Target","subtotal":"2.25","taxes":[{"amount":"0.19","description":"Sales tax"}],"total":"2.44"}`
+		actual, err := receipt.CanonicalJSON()
+		assert.NoError(t, err)
+		assert.Equal(t, expected, string(actual))
+	})
+
+	t.Run("CanonicalJSON of a return", func(t *testing.T) {
+		originalID := uuid.MustParse("7fb1377b-b223-49d9-a31a-5a02701dd310")
+		receipt := Receipt{
+			Retailer:     "Target",
+			PurchaseDate: "2022-03-22",
+			PurchaseTime: "10:05",
+			Items:        []ReceiptItem{{ShortDescription: "Gatorade", Price: "2.25"}},
+			Total:        "2.25",
+			Type:         ReceiptTypeReturn,
+			OriginalID:   &originalID,
+		}
+		expected := `{"items":[{"price":"2.25","shortDescription":"Gatorade"}],"originalReceiptId":"7fb1377b-b223-49d9-a31a-5a02701dd310","purchaseDate":"2022-03-22","purchaseTime":"10:05","retailer":"Target","total":"2.25","type":"return"}`
+		actual, err := receipt.CanonicalJSON()
+		assert.NoError(t, err)
+		assert.Equal(t, expected, string(actual))
+		assert.True(t, receipt.IsReturn())
+	})
+
+	t.Run("GetReceiptDatetime in the time zone of the store", func(t *testing.T) {
+		receipt := Receipt{PurchaseDate: "2022-01-01", PurchaseTime: "14:30", TimeZone: "America/Chicago"}
+		purchasedAt, err := receipt.GetReceiptDatetime()
+		assert.NoError(t, err)
+		assert.Equal(t, "14:30", purchasedAt.Format("15:04"))
+		assert.True(t, purchasedAt.Equal(time.Date(2022, time.January, 1, 20, 30, 0, 0, time.UTC)))
+
+		receipt.TimeZone = "+05:30"
+		purchasedAt, err = receipt.GetReceiptDatetime()
+		assert.NoError(t, err)
+		assert.True(t, purchasedAt.Equal(time.Date(2022, time.January, 1, 9, 0, 0, 0, time.UTC)))
+	})
+
+	t.Run("Amounts", func(t *testing.T) {
+		receipt := Receipt{
+			Items:     []ReceiptItem{{ShortDescription: "Pizza", Price: "12.25"}, {ShortDescription: "Soda", Price: "2.75"}},
+			Discounts: []ReceiptAdjustment{{Description: "Coupon", Amount: "1.00"}},
+			Taxes:     []ReceiptAdjustment{{Description: "State tax", Amount: "0.84"}, {Description: "City tax", Amount: "0.28"}},
+			Tip:       "2.00",
+			Total:     "17.12",
+		}
+		amounts, err := receipt.Amounts()
+		assert.NoError(t, err)
+		assert.Equal(t, ReceiptAmounts{Items: 1500, Subtotal: 1500, Discounts: 100, Taxes: 112, Tip: 200, Total: 1712}, amounts)
+		assert.Equal(t, int64(1712), amounts.Expected())
+		assert.Equal(t, int64(1400), amounts.PreTax())
+		assert.True(t, receipt.HasAdjustments())
+
+		isValidReceipt, err := receipt.IsValid(0)
+		assert.NoError(t, err)
+		assert.True(t, isValidReceipt)
+
+		receipt.Subtotal = "14.00"
+		isValidReceipt, err = receipt.IsValid(0)
+		assert.NoError(t, err)
+		assert.False(t, isValidReceipt)
+
+		receipt.Subtotal = ""
+		receipt.Tip = "two"
+		_, err = receipt.Amounts()
+		assert.Error(t, err)
+	})
+
+	t.Run("Amounts in yen", func(t *testing.T) {
+		receipt := Receipt{
+			Items:    []ReceiptItem{{ShortDescription: "Onigiri", Price: "360", Quantity: "2", UnitPrice: "180"}, {ShortDescription: "Tea", Price: "150"}},
+			Taxes:    []ReceiptAdjustment{{Description: "Consumption tax", Amount: "40"}},
+			Total:    "550",
+			Currency: "JPY",
+		}
+		amounts, err := receipt.Amounts()
+		assert.NoError(t, err)
+		assert.Equal(t, ReceiptAmounts{Items: 510, Subtotal: 510, Taxes: 40, Total: 550}, amounts)
+		consistent, err := receipt.Items[0].HasConsistentPrice(receipt.Currency)
+		assert.NoError(t, err)
+		assert.True(t, consistent)
+
+		expected := `{"currency":"JPY","items":[{"price":"360","quantity":"2","shortDescription":"Onigiri","unitPrice":"180"},{"price":"150","shortDescription":"Tea"}],"purchaseDate":"","purchaseTime":"","retailer":"","taxes":[{"amount":"40","description":"Consumption tax"}],"total":"550"}`
+		actual, err := receipt.CanonicalJSON()
+		assert.NoError(t, err)
+		assert.Equal(t, expected, string(actual))
+	})
+
+	t.Run("Converted", func(t *testing.T) {
+		receipt := Receipt{
+			Retailer: "Lawson",
+			Items:    []ReceiptItem{{ShortDescription: "Onigiri", Price: "360", Quantity: "2", UnitPrice: "180"}, {ShortDescription: "Tea", Price: "150"}},
+			Taxes:    []ReceiptAdjustment{{Description: "Consumption tax", Amount: "40"}},
+			Total:    "550",
+			Currency: "JPY",
+		}
+		converted, err := receipt.Converted(ExchangeRate{Currency: "JPY", Base: "USD", Rate: 0.008383, Date: "2022-03-18"})
+		assert.NoError(t, err)
+		assert.Equal(t, &Receipt{
+			Retailer: "Lawson",
+			Items:    []ReceiptItem{{ShortDescription: "Onigiri", Price: "3.02", Quantity: "2", UnitPrice: "1.51"}, {ShortDescription: "Tea", Price: "1.26"}},
+			Taxes:    []ReceiptAdjustment{{Description: "Consumption tax", Amount: "0.34"}},
+			Total:    "4.61",
+			Currency: "USD",
+		}, converted)
+		assert.Equal(t, "360", receipt.Items[0].Price)
+		assert.Equal(t, "USD", (&Receipt{}).GetCurrency())
+	})
+
+	t.Run("Item quantities", func(t *testing.T) {
+		var item ReceiptItem
+		assert.NoError(t, json.Unmarshal([]byte(`{"shortDescription":"Gatorade","price":"2.25"}`), &item))
+		assert.Equal(t, 1, item.Units())
+		consistent, err := item.HasConsistentPrice("")
+		assert.NoError(t, err)
+		assert.True(t, consistent)
+		encoded, err := json.Marshal(item)
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"shortDescription":"Gatorade","price":"2.25"}`, string(encoded))
+
+		assert.Equal(t, 3, (&ReceiptItem{Quantity: "3"}).Units())
+		assert.Equal(t, 1, (&ReceiptItem{Quantity: "2.5"}).Units())
+		consistent, err = (&ReceiptItem{Price: "1.36", Quantity: "0.455", UnitPrice: "2.99"}).HasConsistentPrice("")
+		assert.NoError(t, err)
+		assert.True(t, consistent)
+		consistent, err = (&ReceiptItem{Price: "6.50", Quantity: "3", UnitPrice: "2.25"}).HasConsistentPrice("")
+		assert.NoError(t, err)
+		assert.False(t, consistent)
+	})
+
 	t.Run("Valid receipt total", func(t *testing.T) {
 		receipt := Receipt{
 			ID:           uuid.New(),
@@ -68,7 +236,23 @@ func TestReceipt(t *testing.T) {
 			Total: "16.98",
 		}
 
-		isValidReceipt, err := receipt.IsValid()
+		isValidReceipt, err := receipt.IsValid(0)
+		assert.NoError(t, err)
+		assert.Equal(t, true, isValidReceipt)
+	})
+
+	t.Run("Valid receipt total that floats do not add up to", func(t *testing.T) {
+		receipt := Receipt{
+			Items: []ReceiptItem{
+				{ShortDescription: "Gatorade", Price: "12.59"},
+				{ShortDescription: "Gatorade", Price: "49.48"},
+				{ShortDescription: "Mountain Dew 12PK", Price: "28.89"},
+				{ShortDescription: "Emils Cheese Pizza", Price: "30.16"},
+			},
+			Total: "121.12",
+		}
+
+		isValidReceipt, err := receipt.IsValid(0)
 		assert.NoError(t, err)
 		assert.Equal(t, true, isValidReceipt)
 	})
@@ -91,8 +275,24 @@ func TestReceipt(t *testing.T) {
 			},
 			Total: "15.98",
 		}
-		isValidReceipt, err := receipt.IsValid()
+		isValidReceipt, err := receipt.IsValid(0)
 		assert.NoError(t, err)
 		assert.Equal(t, false, isValidReceipt)
 	})
+
+	t.Run("Total within the tolerance", func(t *testing.T) {
+		receipt := Receipt{
+			Items: []ReceiptItem{{ShortDescription: "Item 1", Price: "10.00"}},
+			Taxes: []ReceiptAdjustment{{Description: "VAT", Amount: "0.83"}},
+			Total: "10.84",
+		}
+
+		isValidReceipt, err := receipt.IsValid(1)
+		assert.NoError(t, err)
+		assert.True(t, isValidReceipt)
+
+		isValidReceipt, err = receipt.IsValid(0)
+		assert.NoError(t, err)
+		assert.False(t, isValidReceipt)
+	})
 }
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"strings"
+	"time"
+)
+
+// Retailer is an entry of the retailer registry: the canonical name receipts are scored and
+// reported under, and the names their stores print, e.g. "TARGET #1234" or "target.com".
+type Retailer struct {
+	ID   uuid.UUID
+	Name string
+	// Aliases are matched with the name of the receipt ignoring case and spacing.
+	Aliases []string
+	// Patterns are regular expressions matched with the name of the receipt as it was printed,
+	// e.g. `(?i)^target\s*#\d+$` for the store numbers.
+	Patterns  []string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// NormalizeRetailerName lowercases the name and collapses its spacing, so that "Target " and
+// "TARGET" are the same name.
+func NormalizeRetailerName(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// Names returns the canonical name and the aliases of the retailer, normalized.
+func (r *Retailer) Names() []string {
+	names := make([]string, 0, len(r.Aliases)+1)
+	names = append(names, NormalizeRetailerName(r.Name))
+	for _, alias := range r.Aliases {
+		names = append(names, NormalizeRetailerName(alias))
+	}
+	return names
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+type ReviewOutcome string
+
+const (
+	ReviewApproved ReviewOutcome = "approved"
+	ReviewRejected ReviewOutcome = "rejected"
+)
+
+// ReviewDecision is the audited decision a reviewer took on a held receipt.
+type ReviewDecision struct {
+	ID        uuid.UUID
+	ReceiptID uuid.UUID
+	Outcome   ReviewOutcome
+	Reason    string
+	Reviewer  string
+	Points    int
+	DecidedAt time.Time
+}
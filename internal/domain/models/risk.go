@@ -0,0 +1,40 @@
+package models
+
+// ReceiptStatus tracks whether a receipt can be scored.
+type ReceiptStatus string
+
+const (
+	// ReceiptStatusProcessed receipts are scored normally.
+	ReceiptStatusProcessed ReceiptStatus = "processed"
+	// ReceiptStatusHeld receipts were flagged as risky and are not scored until reviewed.
+	ReceiptStatusHeld ReceiptStatus = "held"
+	// ReceiptStatusRejected receipts were rejected by a reviewer and never earn points.
+	ReceiptStatusRejected ReceiptStatus = "rejected"
+)
+
+// RiskFlag is a single reason a fraud check raised against a receipt.
+type RiskFlag struct {
+	Check  string `json:"check"`
+	Score  int    `json:"score"`
+	Reason string `json:"reason"`
+}
+
+// RiskAssessment is the aggregated outcome of the fraud checks run on submission.
+type RiskAssessment struct {
+	Score int        `json:"score"`
+	Flags []RiskFlag `json:"flags"`
+	Held  bool       `json:"held"`
+}
+
+// Reasons returns the human readable reason of every flag.
+func (a *RiskAssessment) Reasons() []string {
+	if a == nil {
+		return nil
+	}
+
+	reasons := make([]string, len(a.Flags))
+	for i, flag := range a.Flags {
+		reasons[i] = flag.Reason
+	}
+	return reasons
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"strings"
+)
+
+type SignatureAlgorithm string
+
+const (
+	SignatureHMACSHA256 SignatureAlgorithm = "hmac-sha256"
+	SignatureEd25519    SignatureAlgorithm = "ed25519"
+)
+
+// SignaturePolicy decides how a retailer's receipts are treated when they are not signed.
+type SignaturePolicy string
+
+const (
+	// SignaturePolicyRequire rejects unsigned receipts.
+	SignaturePolicyRequire SignaturePolicy = "require"
+	// SignaturePolicyPrefer accepts unsigned receipts but awards them reduced points.
+	SignaturePolicyPrefer SignaturePolicy = "prefer"
+	// SignaturePolicyIgnore skips signature verification entirely.
+	SignaturePolicyIgnore SignaturePolicy = "ignore"
+)
+
+type SignatureStatus string
+
+const (
+	SignatureVerified SignatureStatus = "verified"
+	SignatureUnsigned SignatureStatus = "unsigned"
+	SignatureSkipped  SignatureStatus = "skipped"
+)
+
+// TerminalKey is a key a trusted POS terminal uses to sign the receipts of a retailer.
+type TerminalKey struct {
+	ID        string
+	Retailer  string
+	Algorithm SignatureAlgorithm
+	Key       []byte
+}
+
+// RetailerSignaturePolicy is the signature policy applied to one retailer.
+type RetailerSignaturePolicy struct {
+	Policy                SignaturePolicy
+	UnsignedPointsPercent int
+}
+
+// ReceiptSignature is the signature presented along with a receipt submission.
+type ReceiptSignature struct {
+	KeyID     string
+	Algorithm SignatureAlgorithm
+	Value     []byte
+}
+
+// SignatureVerification records the outcome of the signature check on a stored receipt.
+type SignatureVerification struct {
+	Status        SignatureStatus
+	Policy        SignaturePolicy
+	TerminalID    string
+	PointsPercent int
+}
+
+// MatchesRetailer reports whether the key belongs to the given retailer name.
+func (k *TerminalKey) MatchesRetailer(retailer string) bool {
+	return strings.EqualFold(strings.TrimSpace(k.Retailer), strings.TrimSpace(retailer))
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// WebhookSubscription is an endpoint registered to receive receipt events.
+type WebhookSubscription struct {
+	ID        uuid.UUID
+	URL       string
+	Events    []ReceiptEventType
+	Secret    string
+	CreatedAt time.Time
+}
+
+// Subscribes reports whether the subscription wants events of the given type.
+func (s *WebhookSubscription) Subscribes(eventType ReceiptEventType) bool {
+	for _, e := range s.Events {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliveryDelivered DeliveryStatus = "delivered"
+	// DeliveryDead deliveries exhausted their retries and wait in the dead-letter list.
+	DeliveryDead DeliveryStatus = "dead"
+)
+
+// WebhookDelivery is one attempt chain to deliver an event to a subscription.
+type WebhookDelivery struct {
+	ID             uuid.UUID
+	SubscriptionID uuid.UUID
+	EventID        uuid.UUID
+	EventType      ReceiptEventType
+	Payload        []byte
+	Status         DeliveryStatus
+	Attempts       int
+	LastError      string
+	NextAttemptAt  time.Time
+	CreatedAt      time.Time
+	DeliveredAt    *time.Time
+}
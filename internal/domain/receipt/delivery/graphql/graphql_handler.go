@@ -0,0 +1,103 @@
+package graphql
+
+import (
+	"context"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/service"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/middleware"
+	"github.com/CarlosMtz98/receipt-processor-challenge/pkg/utils"
+	"github.com/gin-gonic/gin"
+	gql "github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+	"net/http"
+)
+
+type GraphQLHandler interface {
+	Query(c *gin.Context)
+}
+
+type GraphQLHandlerImpl struct {
+	receiptSvc  service.ReceiptService
+	schema      gql.Schema
+	limits      Limits
+	rateLimiter *middleware.RateLimiter
+}
+
+// Option customizes the handler built by NewGraphQLHandler.
+type Option func(*GraphQLHandlerImpl)
+
+// WithQuota counts every submitReceipt mutation against the daily quota of the client, like
+// POST /receipts/process. Queries are never charged.
+func WithQuota(rateLimiter *middleware.RateLimiter) Option {
+	return func(h *GraphQLHandlerImpl) {
+		h.rateLimiter = rateLimiter
+	}
+}
+
+type graphQLRequest struct {
+	Query         string                 `json:"query" binding:"required"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+func NewGraphQLHandler(receiptService service.ReceiptService, limits Limits, opts ...Option) (GraphQLHandler, error) {
+	schema, err := NewSchema(receiptService)
+	if err != nil {
+		return nil, err
+	}
+
+	handler := &GraphQLHandlerImpl{
+		receiptSvc: receiptService,
+		schema:     schema,
+		limits:     limits,
+	}
+	for _, opt := range opts {
+		opt(handler)
+	}
+	return handler, nil
+}
+
+// Query executes a query or mutation. Documents that can't be parsed, don't validate
+// against the schema or exceed the limits are rejected with 400 before running any
+// resolver; errors raised while resolving fields are reported next to the data with 200.
+func (h GraphQLHandlerImpl) Query(c *gin.Context) {
+	var request graphQLRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.HandleBadRequest(c, "Could not parse the request body", err)
+		return
+	}
+
+	document, err := parser.Parse(parser.ParseParams{
+		Source: source.NewSource(&source.Source{Body: []byte(request.Query), Name: "GraphQL request"}),
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &gql.Result{Errors: gqlerrors.FormatErrors(err)})
+		return
+	}
+
+	if validation := gql.ValidateDocument(&h.schema, document, nil); !validation.IsValid {
+		c.JSON(http.StatusBadRequest, &gql.Result{Errors: validation.Errors})
+		return
+	}
+
+	if err := h.limits.Check(document, request.Variables); err != nil {
+		c.JSON(http.StatusBadRequest, &gql.Result{Errors: gqlerrors.FormatErrors(err)})
+		return
+	}
+
+	ctx := context.WithValue(c.Request.Context(), loaderContextKey, newReceiptLoader(h.receiptSvc))
+	ctx = context.WithValue(ctx, submitterContextKey, middleware.ClientKey(c))
+	if h.rateLimiter != nil {
+		ctx = context.WithValue(ctx, quotaContextKey, h.rateLimiter)
+	}
+
+	result := gql.Execute(gql.ExecuteParams{
+		Schema:        h.schema,
+		AST:           document,
+		OperationName: request.OperationName,
+		Args:          request.Variables,
+		Context:       ctx,
+	})
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,456 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/auth"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/mock"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/service"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/middleware"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/ratelimit"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// validateWithoutTolerance validates like a receipt service without a total tolerance, for the
+// ValidateReceipt calls of the mocks.
+func validateWithoutTolerance(ctx context.Context, receipt *models.Receipt) error {
+	return service.ValidateReceipt(ctx, receipt, 0)
+}
+
+type graphQLResponse struct {
+	Data   map[string]json.RawMessage `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+func newTestRouter(t *testing.T, receiptService *mock.MockReceiptService, principal *auth.Principal, opts ...Option) *gin.Engine {
+	receiptService.EXPECT().ValidateReceipt(gomock.Any(), gomock.Any()).DoAndReturn(validateWithoutTolerance).AnyTimes()
+	handler, err := NewGraphQLHandler(receiptService, Limits{MaxDepth: 3, MaxComplexity: 400}, opts...)
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	group := router.Group("/graphql", func(c *gin.Context) {
+		if principal != nil {
+			c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), principal))
+		}
+		c.Next()
+	})
+	MapGraphQLRoutes(group, handler)
+	return router
+}
+
+func postQuery(t *testing.T, router *gin.Engine, query string, variables map[string]interface{}) (int, graphQLResponse) {
+	payload, err := json.Marshal(graphQLRequest{Query: query, Variables: variables})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewBuffer(payload))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	var response graphQLResponse
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+	return resp.Code, response
+}
+
+func testReceipt(retailer string) *models.Receipt {
+	return &models.Receipt{
+		ID:           uuid.New(),
+		Retailer:     retailer,
+		PurchaseDate: "2022-01-01",
+		PurchaseTime: "13:01",
+		Total:        "6.49",
+		Items:        []models.ReceiptItem{{ShortDescription: "Mountain Dew 12PK", Price: "6.49"}},
+		Status:       models.ReceiptStatusProcessed,
+	}
+}
+
+func TestGraphQLHandlerImpl_Query(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	target, walgreens := testReceipt("Target"), testReceipt("Walgreens")
+
+	t.Run("Aliased receipts are loaded in one batch", func(t *testing.T) {
+		mockReceiptService := mock.NewMockReceiptService(ctrl)
+		mockReceiptService.EXPECT().
+			GetReceiptsByIDs(gomock.Any(), gomock.InAnyOrder([]uuid.UUID{target.ID, walgreens.ID})).
+			Return(map[uuid.UUID]*models.Receipt{target.ID: target, walgreens.ID: walgreens}, nil).
+			Times(1)
+		mockReceiptService.EXPECT().GetReceiptPoints(gomock.Any(), target).Return(6, nil)
+		mockReceiptService.EXPECT().GetReceiptPoints(gomock.Any(), walgreens).Return(9, nil)
+
+		router := newTestRouter(t, mockReceiptService, nil)
+		code, response := postQuery(t, router, `query($a: ID!, $b: ID!) {
+			a: receipt(id: $a) { retailer points items { price } }
+			b: receipt(id: $b) { retailer points }
+			again: receiptsByIds(ids: [$a]) { id }
+		}`, map[string]interface{}{"a": target.ID.String(), "b": walgreens.ID.String()})
+
+		assert.Equal(t, http.StatusOK, code)
+		assert.Empty(t, response.Errors)
+		assert.JSONEq(t, `{"retailer":"Target","points":6,"items":[{"price":"6.49"}]}`, string(response.Data["a"]))
+		assert.JSONEq(t, `{"retailer":"Walgreens","points":9}`, string(response.Data["b"]))
+		assert.JSONEq(t, `[{"id":"`+target.ID.String()+`"}]`, string(response.Data["again"]))
+	})
+
+	t.Run("Missing receipts resolve to null", func(t *testing.T) {
+		missing := uuid.New()
+		mockReceiptService := mock.NewMockReceiptService(ctrl)
+		mockReceiptService.EXPECT().
+			GetReceiptsByIDs(gomock.Any(), []uuid.UUID{target.ID, missing}).
+			Return(map[uuid.UUID]*models.Receipt{target.ID: target}, nil)
+
+		router := newTestRouter(t, mockReceiptService, nil)
+		code, response := postQuery(t, router, `{ receiptsByIds(ids: ["`+target.ID.String()+`", "`+missing.String()+`"]) { retailer } }`, nil)
+
+		assert.Equal(t, http.StatusOK, code)
+		assert.JSONEq(t, `[{"retailer":"Target"},null]`, string(response.Data["receiptsByIds"]))
+	})
+
+	t.Run("Returns are loaded and scored in one batch", func(t *testing.T) {
+		returnOf := func(purchase *models.Receipt) *models.Receipt {
+			r := testReceipt(purchase.Retailer)
+			r.Type, r.OriginalID = models.ReceiptTypeReturn, &purchase.ID
+			return r
+		}
+		targetReturns := []*models.Receipt{returnOf(target), returnOf(target)}
+		walgreensReturn := returnOf(walgreens)
+
+		mockReceiptService := mock.NewMockReceiptService(ctrl)
+		mockReceiptService.EXPECT().
+			ListReceipts(gomock.Any(), gomock.Any()).
+			Return([]*models.Receipt{target, walgreens, targetReturns[0], targetReturns[1], walgreensReturn}, nil)
+		mockReceiptService.EXPECT().GetReceiptPoints(gomock.Any(), target).Return(20, nil).Times(2)
+		mockReceiptService.EXPECT().GetReceiptPoints(gomock.Any(), walgreens).Return(30, nil).Times(2)
+		mockReceiptService.EXPECT().
+			GetReceiptsByIDs(gomock.Any(), gomock.InAnyOrder([]uuid.UUID{target.ID, walgreens.ID})).
+			Return(map[uuid.UUID]*models.Receipt{target.ID: target, walgreens.ID: walgreens}, nil).
+			Times(1)
+		mockReceiptService.EXPECT().
+			GetReturnsByOriginalIDs(gomock.Any(), gomock.InAnyOrder([]uuid.UUID{target.ID, walgreens.ID})).
+			Return(map[uuid.UUID][]*models.Receipt{target.ID: targetReturns, walgreens.ID: {walgreensReturn}}, nil).
+			Times(1)
+		mockReceiptService.EXPECT().GetReturnsPoints(gomock.Any(), target, targetReturns).Return([]int{-5, -7}, nil).Times(1)
+		mockReceiptService.EXPECT().GetReturnsPoints(gomock.Any(), walgreens, []*models.Receipt{walgreensReturn}).Return([]int{-4}, nil).Times(1)
+
+		router := newTestRouter(t, mockReceiptService, nil)
+		code, response := postQuery(t, router, `{ receipts(first: 10) { points netPoints } }`, nil)
+
+		assert.Equal(t, http.StatusOK, code)
+		assert.Empty(t, response.Errors)
+		assert.JSONEq(t, `[
+			{"points":20,"netPoints":8},
+			{"points":30,"netPoints":26},
+			{"points":-5,"netPoints":null},
+			{"points":-7,"netPoints":null},
+			{"points":-4,"netPoints":null}
+		]`, string(response.Data["receipts"]))
+	})
+
+	t.Run("Held receipts report an error on points", func(t *testing.T) {
+		mockReceiptService := mock.NewMockReceiptService(ctrl)
+		mockReceiptService.EXPECT().
+			ListReceipts(gomock.Any(), models.ReceiptFilter{Retailer: "Target", Limit: 100}).
+			Return([]*models.Receipt{target}, nil)
+		mockReceiptService.EXPECT().
+			GetReceiptPointsBreakdown(gomock.Any(), target).
+			Return(nil, service.ErrReceiptHeld)
+
+		router := newTestRouter(t, mockReceiptService, nil)
+		code, response := postQuery(t, router, `{ receipts(retailer: "Target", first: 500) { id breakdown { total } } }`, nil)
+
+		assert.Equal(t, http.StatusOK, code)
+		assert.JSONEq(t, `[{"id":"`+target.ID.String()+`","breakdown":null}]`, string(response.Data["receipts"]))
+		require.Len(t, response.Errors, 1)
+		assert.Equal(t, service.ErrReceiptHeld.Error(), response.Errors[0].Message)
+	})
+
+	t.Run("Too deep", func(t *testing.T) {
+		router := newTestRouter(t, mock.NewMockReceiptService(ctrl), nil)
+		code, response := postQuery(t, router, `query { ...A } fragment A on Query { receipt(id: "x") { breakdown { rules { points } } } }`, nil)
+
+		assert.Equal(t, http.StatusBadRequest, code)
+		require.Len(t, response.Errors, 1)
+		assert.Contains(t, response.Errors[0].Message, ErrQueryTooDeep.Error())
+	})
+
+	t.Run("Too complex", func(t *testing.T) {
+		router := newTestRouter(t, mock.NewMockReceiptService(ctrl), nil)
+		code, response := postQuery(t, router, `query($n: Int) { receipts(first: $n) { id retailer total items { price } } }`, map[string]interface{}{"n": 100})
+
+		assert.Equal(t, http.StatusBadRequest, code)
+		require.Len(t, response.Errors, 1)
+		assert.Contains(t, response.Errors[0].Message, ErrQueryTooComplex.Error())
+	})
+
+	t.Run("Net points cost more", func(t *testing.T) {
+		router := newTestRouter(t, mock.NewMockReceiptService(ctrl), nil)
+		code, response := postQuery(t, router, `{ receipts(first: 100) { netPoints } }`, nil)
+
+		assert.Equal(t, http.StatusBadRequest, code)
+		require.Len(t, response.Errors, 1)
+		assert.Contains(t, response.Errors[0].Message, ErrQueryTooComplex.Error())
+	})
+
+	t.Run("Invalid document", func(t *testing.T) {
+		router := newTestRouter(t, mock.NewMockReceiptService(ctrl), nil)
+		code, response := postQuery(t, router, `{ receipt(id: "x") { unknown } }`, nil)
+
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.NotEmpty(t, response.Errors)
+	})
+
+	t.Run("Missing scope", func(t *testing.T) {
+		router := newTestRouter(t, mock.NewMockReceiptService(ctrl), &auth.Principal{Subject: "pos-1", Scopes: []string{"receipts:write"}})
+		code, response := postQuery(t, router, `{ receipts { id } }`, nil)
+
+		assert.Equal(t, http.StatusOK, code)
+		require.Len(t, response.Errors, 1)
+		assert.Contains(t, response.Errors[0].Message, "receipts:read")
+	})
+}
+
+func TestGraphQLHandlerImpl_SubmitReceipt(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	input := map[string]interface{}{
+		"retailer":     "Target",
+		"purchaseDate": "2022-01-01",
+		"purchaseTime": "13:01",
+		"total":        "6.49",
+		"items":        []interface{}{map[string]interface{}{"shortDescription": "Mountain Dew 12PK", "price": "6.49"}},
+	}
+	mutation := `mutation($input: ReceiptInput!) { submitReceipt(input: $input) { id status } }`
+
+	t.Run("Success", func(t *testing.T) {
+		created := testReceipt("Target")
+		mockReceiptService := mock.NewMockReceiptService(ctrl)
+		mockReceiptService.EXPECT().
+			CreateReceipt(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ interface{}, receipt *models.Receipt) (*models.Receipt, error) {
+				assert.Equal(t, "Target", receipt.Retailer)
+				assert.Equal(t, "sub:pos-1", receipt.SubmittedBy)
+				return created, nil
+			})
+
+		router := newTestRouter(t, mockReceiptService, &auth.Principal{Subject: "pos-1", Scopes: []string{"receipts:write"}})
+		code, response := postQuery(t, router, mutation, map[string]interface{}{"input": input})
+
+		assert.Equal(t, http.StatusOK, code)
+		assert.Empty(t, response.Errors)
+		assert.JSONEq(t, `{"id":"`+created.ID.String()+`","status":"processed"}`, string(response.Data["submitReceipt"]))
+	})
+
+	t.Run("Item quantities", func(t *testing.T) {
+		mockReceiptService := mock.NewMockReceiptService(ctrl)
+		mockReceiptService.EXPECT().
+			CreateReceipt(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ interface{}, receipt *models.Receipt) (*models.Receipt, error) {
+				assert.Equal(t, []models.ReceiptItem{{ShortDescription: "Gatorade", Price: "6.75", Quantity: "3", UnitPrice: "2.25", SKU: "052000328660", Category: "beverages"}}, receipt.Items)
+				receipt.ID = uuid.New()
+				return receipt, nil
+			})
+		withQuantities := map[string]interface{}{}
+		for key, value := range input {
+			withQuantities[key] = value
+		}
+		withQuantities["total"] = "6.75"
+		withQuantities["items"] = []interface{}{map[string]interface{}{
+			"shortDescription": "Gatorade", "price": "6.75", "quantity": "3", "unitPrice": "2.25", "sku": "052000328660", "category": "beverages",
+		}}
+
+		router := newTestRouter(t, mockReceiptService, nil)
+		code, response := postQuery(t, router, `mutation($input: ReceiptInput!) { submitReceipt(input: $input) { items { quantity unitPrice sku category } } }`,
+			map[string]interface{}{"input": withQuantities})
+
+		assert.Equal(t, http.StatusOK, code)
+		assert.Empty(t, response.Errors)
+		assert.JSONEq(t, `{"items":[{"quantity":"3","unitPrice":"2.25","sku":"052000328660","category":"beverages"}]}`, string(response.Data["submitReceipt"]))
+	})
+
+	t.Run("Taxes, discounts and tip", func(t *testing.T) {
+		mockReceiptService := mock.NewMockReceiptService(ctrl)
+		mockReceiptService.EXPECT().
+			CreateReceipt(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ interface{}, receipt *models.Receipt) (*models.Receipt, error) {
+				assert.Equal(t, []models.ReceiptAdjustment{{Description: "Sales tax", Amount: "0.19"}}, receipt.Taxes)
+				assert.Equal(t, []models.ReceiptAdjustment{{Description: "Coupon", Amount: "0.25"}}, receipt.Discounts)
+				receipt.ID = uuid.New()
+				return receipt, nil
+			})
+		withAmounts := map[string]interface{}{}
+		for key, value := range input {
+			withAmounts[key] = value
+		}
+		withAmounts["total"] = "2.69"
+		withAmounts["items"] = []interface{}{map[string]interface{}{"shortDescription": "Gatorade", "price": "2.25"}}
+		withAmounts["subtotal"] = "2.25"
+		withAmounts["taxes"] = []interface{}{map[string]interface{}{"description": "Sales tax", "amount": "0.19"}}
+		withAmounts["discounts"] = []interface{}{map[string]interface{}{"description": "Coupon", "amount": "0.25"}}
+		withAmounts["tip"] = "0.50"
+
+		router := newTestRouter(t, mockReceiptService, nil)
+		code, response := postQuery(t, router, `mutation($input: ReceiptInput!) { submitReceipt(input: $input) { subtotal taxes { description amount } discounts { amount } tip } }`,
+			map[string]interface{}{"input": withAmounts})
+
+		assert.Equal(t, http.StatusOK, code)
+		assert.Empty(t, response.Errors)
+		assert.JSONEq(t, `{"subtotal":"2.25","taxes":[{"description":"Sales tax","amount":"0.19"}],"discounts":[{"amount":"0.25"}],"tip":"0.50"}`, string(response.Data["submitReceipt"]))
+	})
+
+	t.Run("Currency and exchange rate", func(t *testing.T) {
+		mockReceiptService := mock.NewMockReceiptService(ctrl)
+		mockReceiptService.EXPECT().
+			CreateReceipt(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ interface{}, receipt *models.Receipt) (*models.Receipt, error) {
+				assert.Equal(t, "JPY", receipt.Currency)
+				receipt.ID = uuid.New()
+				receipt.Exchange = &models.ExchangeRate{Currency: "JPY", Base: "USD", Rate: 0.008383, Date: "2022-03-18"}
+				return receipt, nil
+			})
+		inYen := map[string]interface{}{}
+		for key, value := range input {
+			inYen[key] = value
+		}
+		inYen["total"] = "150"
+		inYen["items"] = []interface{}{map[string]interface{}{"shortDescription": "Tea", "price": "150"}}
+		inYen["currency"] = "JPY"
+
+		router := newTestRouter(t, mockReceiptService, nil)
+		code, response := postQuery(t, router, `mutation($input: ReceiptInput!) { submitReceipt(input: $input) { currency exchangeRate { currency base rate date } } }`,
+			map[string]interface{}{"input": inYen})
+
+		assert.Equal(t, http.StatusOK, code)
+		assert.Empty(t, response.Errors)
+		assert.JSONEq(t, `{"currency":"JPY","exchangeRate":{"currency":"JPY","base":"USD","rate":0.008383,"date":"2022-03-18"}}`, string(response.Data["submitReceipt"]))
+	})
+
+	t.Run("Purchase timestamp and time zone", func(t *testing.T) {
+		mockReceiptService := mock.NewMockReceiptService(ctrl)
+		mockReceiptService.EXPECT().
+			CreateReceipt(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ interface{}, receipt *models.Receipt) (*models.Receipt, error) {
+				assert.Equal(t, "2022-01-01T20:30:00Z", receipt.PurchasedAt)
+				receipt.ID = uuid.New()
+				receipt.PurchaseDate, receipt.PurchaseTime = "2022-01-01", "14:30"
+				return receipt, nil
+			})
+		timestamped := map[string]interface{}{}
+		for key, value := range input {
+			if key != "purchaseDate" && key != "purchaseTime" {
+				timestamped[key] = value
+			}
+		}
+		timestamped["purchasedAt"] = "2022-01-01T20:30:00Z"
+		timestamped["timeZone"] = "America/Chicago"
+
+		router := newTestRouter(t, mockReceiptService, nil)
+		code, response := postQuery(t, router, `mutation($input: ReceiptInput!) { submitReceipt(input: $input) { purchaseDate purchaseTime timeZone purchasedAt } }`,
+			map[string]interface{}{"input": timestamped})
+
+		assert.Equal(t, http.StatusOK, code)
+		assert.Empty(t, response.Errors)
+		assert.JSONEq(t, `{"purchaseDate":"2022-01-01","purchaseTime":"14:30","timeZone":"America/Chicago","purchasedAt":"2022-01-01T14:30:00-06:00"}`, string(response.Data["submitReceipt"]))
+	})
+
+	t.Run("Return", func(t *testing.T) {
+		originalID := uuid.New()
+		var created *models.Receipt
+		mockReceiptService := mock.NewMockReceiptService(ctrl)
+		mockReceiptService.EXPECT().
+			CreateReceipt(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ interface{}, receipt *models.Receipt) (*models.Receipt, error) {
+				assert.Equal(t, models.ReceiptTypeReturn, receipt.Type)
+				assert.Equal(t, &originalID, receipt.OriginalID)
+				receipt.ID = uuid.New()
+				created = receipt
+				return receipt, nil
+			})
+		purchase := testReceipt("Target")
+		purchase.ID = originalID
+		mockReceiptService.EXPECT().
+			GetReceiptsByIDs(gomock.Any(), []uuid.UUID{originalID}).
+			Return(map[uuid.UUID]*models.Receipt{originalID: purchase}, nil)
+		mockReceiptService.EXPECT().
+			GetReturnsByOriginalIDs(gomock.Any(), []uuid.UUID{originalID}).
+			DoAndReturn(func(_ interface{}, _ []uuid.UUID) (map[uuid.UUID][]*models.Receipt, error) {
+				return map[uuid.UUID][]*models.Receipt{originalID: {created}}, nil
+			})
+		mockReceiptService.EXPECT().GetReturnsPoints(gomock.Any(), purchase, gomock.Any()).Return([]int{-10}, nil)
+		returned := map[string]interface{}{}
+		for key, value := range input {
+			returned[key] = value
+		}
+		returned["type"] = "return"
+		returned["originalReceiptId"] = originalID.String()
+
+		router := newTestRouter(t, mockReceiptService, nil)
+		code, response := postQuery(t, router, `mutation($input: ReceiptInput!) { submitReceipt(input: $input) { type originalReceiptId points netPoints } }`,
+			map[string]interface{}{"input": returned})
+
+		assert.Equal(t, http.StatusOK, code)
+		assert.Empty(t, response.Errors)
+		assert.JSONEq(t, `{"type":"return","originalReceiptId":"`+originalID.String()+`","points":-10,"netPoints":null}`, string(response.Data["submitReceipt"]))
+	})
+
+	t.Run("Total mismatch", func(t *testing.T) {
+		router := newTestRouter(t, mock.NewMockReceiptService(ctrl), nil)
+		invalid := map[string]interface{}{}
+		for key, value := range input {
+			invalid[key] = value
+		}
+		invalid["total"] = "10.00"
+
+		code, response := postQuery(t, router, mutation, map[string]interface{}{"input": invalid})
+
+		assert.Equal(t, http.StatusOK, code)
+		require.Len(t, response.Errors, 1)
+		assert.Contains(t, response.Errors[0].Message, "total must match")
+	})
+
+	t.Run("Daily quota", func(t *testing.T) {
+		created := testReceipt("Target")
+		mockReceiptService := mock.NewMockReceiptService(ctrl)
+		mockReceiptService.EXPECT().CreateReceipt(gomock.Any(), gomock.Any()).Return(created, nil)
+		mockReceiptService.EXPECT().ListReceipts(gomock.Any(), gomock.Any()).Return(nil, nil).Times(2)
+
+		rateLimiter := middleware.NewRateLimiter(ratelimit.Config{DailyQuota: 1})
+		router := newTestRouter(t, mockReceiptService, &auth.Principal{Subject: "pos-1", Scopes: []string{"receipts:read", "receipts:write"}}, WithQuota(rateLimiter))
+
+		// Queries don't consume the quota.
+		for i := 0; i < 2; i++ {
+			_, response := postQuery(t, router, `{ receipts { id } }`, nil)
+			assert.Empty(t, response.Errors)
+		}
+
+		_, response := postQuery(t, router, mutation, map[string]interface{}{"input": input})
+		assert.Empty(t, response.Errors)
+
+		code, response := postQuery(t, router, mutation, map[string]interface{}{"input": input})
+		assert.Equal(t, http.StatusOK, code)
+		require.Len(t, response.Errors, 1)
+		assert.Contains(t, response.Errors[0].Message, ErrQuotaExceeded.Error())
+	})
+
+	t.Run("Read only token", func(t *testing.T) {
+		router := newTestRouter(t, mock.NewMockReceiptService(ctrl), &auth.Principal{Subject: "pos-1", Scopes: []string{"receipts:read"}})
+		code, response := postQuery(t, router, mutation, map[string]interface{}{"input": input})
+
+		assert.Equal(t, http.StatusOK, code)
+		require.Len(t, response.Errors, 1)
+		assert.Contains(t, response.Errors[0].Message, "receipts:write")
+	})
+}
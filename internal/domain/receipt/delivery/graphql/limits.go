@@ -0,0 +1,171 @@
+package graphql
+
+import (
+	"errors"
+	"fmt"
+	"github.com/graphql-go/graphql/language/ast"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrQueryTooDeep is returned when the selections of a query are nested deeper than allowed
+	ErrQueryTooDeep = errors.New("the query exceeds the maximum depth")
+	// ErrQueryTooComplex is returned when a query could resolve more fields than allowed
+	ErrQueryTooComplex = errors.New("the query exceeds the maximum complexity")
+)
+
+// Limits bounds the cost of the queries accepted by the endpoint. Zero disables a limit.
+type Limits struct {
+	// MaxDepth is how deeply selections can be nested; root fields are at depth 1.
+	MaxDepth int
+	// MaxComplexity is the number of fields a query can resolve. Every field costs one, or its
+	// fieldCosts, and the selections of list fields are multiplied by the number of elements
+	// they can return.
+	MaxComplexity int
+}
+
+// fieldCosts are the fields that do more work than loading one value: netPoints loads the
+// returns of the purchase and scores every one of them.
+var fieldCosts = map[string]int{
+	"netPoints": 5,
+}
+
+// Check measures every operation of a validated document. Introspection fields are free so
+// that tools can load the schema regardless of the limits.
+func (l Limits) Check(document *ast.Document, variables map[string]interface{}) error {
+	w := &limitWalker{
+		fragments: make(map[string]*ast.FragmentDefinition),
+		measured:  make(map[string]measure),
+		variables: variables,
+	}
+	for _, definition := range document.Definitions {
+		if fragment, ok := definition.(*ast.FragmentDefinition); ok {
+			w.fragments[fragment.Name.Value] = fragment
+		}
+	}
+
+	for _, definition := range document.Definitions {
+		operation, ok := definition.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+
+		m := w.measure(operation.SelectionSet)
+		if l.MaxDepth > 0 && m.depth > l.MaxDepth {
+			return fmt.Errorf("%w: %d is over %d", ErrQueryTooDeep, m.depth, l.MaxDepth)
+		}
+		if l.MaxComplexity > 0 && m.complexity > l.MaxComplexity {
+			return fmt.Errorf("%w: %d is over %d", ErrQueryTooComplex, m.complexity, l.MaxComplexity)
+		}
+	}
+	return nil
+}
+
+type measure struct {
+	depth      int
+	complexity int
+}
+
+// limitWalker expects a document without fragment cycles, which validation guarantees.
+type limitWalker struct {
+	fragments map[string]*ast.FragmentDefinition
+	// measured caches the fragments already walked so that spreading them many times stays cheap.
+	measured  map[string]measure
+	variables map[string]interface{}
+}
+
+func (w *limitWalker) measure(selectionSet *ast.SelectionSet) measure {
+	var total measure
+	if selectionSet == nil {
+		return total
+	}
+
+	for _, selection := range selectionSet.Selections {
+		var m measure
+		switch s := selection.(type) {
+		case *ast.Field:
+			if strings.HasPrefix(s.Name.Value, "__") {
+				continue
+			}
+			children := w.measure(s.SelectionSet)
+			m.depth = children.depth + 1
+			cost, ok := fieldCosts[s.Name.Value]
+			if !ok {
+				cost = 1
+			}
+			m.complexity = cost + w.listSize(s)*children.complexity
+		case *ast.InlineFragment:
+			m = w.measure(s.SelectionSet)
+		case *ast.FragmentSpread:
+			m = w.measureFragment(s.Name.Value)
+		}
+
+		total.depth = max(total.depth, m.depth)
+		total.complexity += m.complexity
+	}
+	return total
+}
+
+func (w *limitWalker) measureFragment(name string) measure {
+	if m, ok := w.measured[name]; ok {
+		return m
+	}
+
+	var m measure
+	if fragment, ok := w.fragments[name]; ok {
+		m = w.measure(fragment.SelectionSet)
+	}
+	w.measured[name] = m
+	return m
+}
+
+// listSize is how many elements a field can return: the requested page for paged lists and
+// the number of ids for lookups by id.
+func (w *limitWalker) listSize(field *ast.Field) int {
+	for _, argument := range field.Arguments {
+		switch argument.Name.Value {
+		case "first":
+			if n, ok := w.intValue(argument.Value); ok {
+				return max(clampPageSize(n), 1)
+			}
+		case "ids":
+			if n, ok := w.listLength(argument.Value); ok {
+				return max(n, 1)
+			}
+		}
+	}
+
+	if field.Name.Value == "receipts" {
+		return defaultPageSize
+	}
+	return 1
+}
+
+func (w *limitWalker) intValue(value ast.Value) (int, bool) {
+	switch v := value.(type) {
+	case *ast.IntValue:
+		n, err := strconv.Atoi(v.Value)
+		return n, err == nil
+	case *ast.Variable:
+		switch n := w.variables[v.Name.Value].(type) {
+		case int:
+			return n, true
+		case float64:
+			return int(n), true
+		}
+	}
+	return 0, false
+}
+
+func (w *limitWalker) listLength(value ast.Value) (int, bool) {
+	switch v := value.(type) {
+	case *ast.ListValue:
+		return len(v.Values), true
+	case *ast.Variable:
+		if list, ok := w.variables[v.Name.Value].([]interface{}); ok {
+			return len(list), true
+		}
+	}
+	return 0, false
+}
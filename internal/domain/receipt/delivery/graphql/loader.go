@@ -0,0 +1,175 @@
+package graphql
+
+import (
+	"context"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/repository"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/service"
+	"github.com/google/uuid"
+	"sync"
+)
+
+// receiptLoader collects the receipt ids requested by the resolvers of one query level and
+// loads them with a single service call once the executor resolves the returned thunks. The
+// returns of the purchases are batched the same way, and their points computed once per
+// purchase. A loader lives for a single request, so its cache never serves stale receipts.
+type receiptLoader struct {
+	receiptSvc service.ReceiptService
+
+	mu      sync.Mutex
+	pending []uuid.UUID
+	loaded  map[uuid.UUID]*models.Receipt
+	failed  map[uuid.UUID]error
+
+	pendingReturns []uuid.UUID
+	returns        map[uuid.UUID][]*models.Receipt
+	returnsFailed  map[uuid.UUID]error
+	// returnPoints are the points of the returns of every purchase, in the order of returns.
+	returnPoints map[uuid.UUID][]int
+}
+
+func newReceiptLoader(receiptSvc service.ReceiptService) *receiptLoader {
+	return &receiptLoader{
+		receiptSvc:    receiptSvc,
+		loaded:        make(map[uuid.UUID]*models.Receipt),
+		failed:        make(map[uuid.UUID]error),
+		returns:       make(map[uuid.UUID][]*models.Receipt),
+		returnsFailed: make(map[uuid.UUID]error),
+		returnPoints:  make(map[uuid.UUID][]int),
+	}
+}
+
+// Load queues the id and returns a thunk resolving to the receipt.
+func (l *receiptLoader) Load(ctx context.Context, id uuid.UUID) func() (interface{}, error) {
+	l.enqueue(id)
+	return func() (interface{}, error) {
+		receipts, err := l.resolve(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if receipts[0] == nil {
+			return nil, repository.ErrReceiptNotFound
+		}
+		return receipts[0], nil
+	}
+}
+
+// LoadMany queues the ids and returns a thunk resolving to the receipts in the same order,
+// with nil in place of the receipts that don't exist.
+func (l *receiptLoader) LoadMany(ctx context.Context, ids []uuid.UUID) func() (interface{}, error) {
+	l.enqueue(ids...)
+	return func() (interface{}, error) {
+		receipts, err := l.resolve(ctx, ids...)
+		if err != nil {
+			return nil, err
+		}
+		return receipts, nil
+	}
+}
+
+func (l *receiptLoader) enqueue(ids ...uuid.UUID) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, id := range ids {
+		_, loaded := l.loaded[id]
+		_, failed := l.failed[id]
+		if !loaded && !failed {
+			l.pending = append(l.pending, id)
+		}
+	}
+}
+
+// resolve loads every pending id in one batch, then reads the requested ones from the cache.
+// A failed batch fails every field that asked for one of its ids without being retried.
+func (l *receiptLoader) resolve(ctx context.Context, ids ...uuid.UUID) ([]*models.Receipt, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.pending) > 0 {
+		batch := uniqueIDs(l.pending)
+		l.pending = nil
+
+		found, err := l.receiptSvc.GetReceiptsByIDs(ctx, batch)
+		for _, id := range batch {
+			if err != nil {
+				l.failed[id] = err
+			} else {
+				l.loaded[id] = found[id]
+			}
+		}
+	}
+
+	receipts := make([]*models.Receipt, len(ids))
+	for i, id := range ids {
+		if err := l.failed[id]; err != nil {
+			return nil, err
+		}
+		receipts[i] = l.loaded[id]
+	}
+	return receipts, nil
+}
+
+// QueueReturns queues the purchase so that its returns are loaded with the batch of the
+// other purchases of the query level.
+func (l *receiptLoader) QueueReturns(purchaseID uuid.UUID) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	_, loaded := l.returns[purchaseID]
+	_, failed := l.returnsFailed[purchaseID]
+	if !loaded && !failed {
+		l.pendingReturns = append(l.pendingReturns, purchaseID)
+	}
+}
+
+// ReturnsPoints gives the returns of the purchase, oldest first, and the points each takes
+// back. The purchase must have been queued with QueueReturns.
+func (l *receiptLoader) ReturnsPoints(ctx context.Context, purchase *models.Receipt) ([]*models.Receipt, []int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.pendingReturns) > 0 {
+		batch := uniqueIDs(l.pendingReturns)
+		l.pendingReturns = nil
+
+		found, err := l.receiptSvc.GetReturnsByOriginalIDs(ctx, batch)
+		for _, id := range batch {
+			if err != nil {
+				l.returnsFailed[id] = err
+			} else {
+				l.returns[id] = found[id]
+			}
+		}
+	}
+
+	if err := l.returnsFailed[purchase.ID]; err != nil {
+		return nil, nil, err
+	}
+	returns := l.returns[purchase.ID]
+	if len(returns) == 0 {
+		return nil, nil, nil
+	}
+	points, ok := l.returnPoints[purchase.ID]
+	if !ok {
+		var err error
+		if points, err = l.receiptSvc.GetReturnsPoints(ctx, purchase, returns); err != nil {
+			return nil, nil, err
+		}
+		l.returnPoints[purchase.ID] = points
+	}
+	return returns, points, nil
+}
+
+func uniqueIDs(ids []uuid.UUID) []uuid.UUID {
+	seen := make(map[uuid.UUID]struct{}, len(ids))
+	unique := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		unique = append(unique, id)
+	}
+	return unique
+}
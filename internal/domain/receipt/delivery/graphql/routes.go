@@ -0,0 +1,11 @@
+package graphql
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// MapGraphQLRoutes expects the group to be authenticated already. A single route serves
+// reads and writes, so the resolvers check receipts:read and receipts:write themselves.
+func MapGraphQLRoutes(routesGroup *gin.RouterGroup, handler GraphQLHandler) {
+	routesGroup.POST("", handler.Query)
+}
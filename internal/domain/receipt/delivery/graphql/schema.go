@@ -0,0 +1,550 @@
+package graphql
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/auth"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
+	receiptHttp "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/delivery/http"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/service"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/middleware"
+	"github.com/google/uuid"
+	gql "github.com/graphql-go/graphql"
+	"strings"
+	"time"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 100
+)
+
+var (
+	// ErrMissingScope is returned when the token of the caller lacks the scope of an operation
+	ErrMissingScope = errors.New("the token requires the scope")
+	// ErrQuotaExceeded is returned when the client used up its daily quota of submissions
+	ErrQuotaExceeded = errors.New("the daily quota of receipt submissions has been reached")
+)
+
+type contextKey int
+
+const (
+	loaderContextKey contextKey = iota
+	submitterContextKey
+	quotaContextKey
+)
+
+// NewSchema builds the receipts schema. Its resolvers expect the request context to carry
+// the loader, submitter and quota set by the handler.
+func NewSchema(receiptSvc service.ReceiptService) (gql.Schema, error) {
+	r := &resolver{receiptSvc: receiptSvc}
+
+	itemType := gql.NewObject(gql.ObjectConfig{
+		Name: "Item",
+		Fields: gql.Fields{
+			"shortDescription": &gql.Field{Type: gql.NewNonNull(gql.String)},
+			"price":            &gql.Field{Type: gql.NewNonNull(gql.String), Description: "The amount paid for the whole line."},
+			"quantity":         &gql.Field{Type: gql.String},
+			"unitPrice":        &gql.Field{Type: gql.String},
+			"sku":              &gql.Field{Type: gql.String},
+			"category":         &gql.Field{Type: gql.String},
+		},
+	})
+
+	adjustmentType := gql.NewObject(gql.ObjectConfig{
+		Name:        "Adjustment",
+		Description: "A tax or discount line of the receipt.",
+		Fields: gql.Fields{
+			"description": &gql.Field{Type: gql.NewNonNull(gql.String)},
+			"amount":      &gql.Field{Type: gql.NewNonNull(gql.String)},
+		},
+	})
+
+	exchangeRateType := gql.NewObject(gql.ObjectConfig{
+		Name:        "ExchangeRate",
+		Description: "The rate a receipt in a foreign currency is scored with: one unit of currency is worth rate units of base.",
+		Fields: gql.Fields{
+			"currency": &gql.Field{Type: gql.NewNonNull(gql.String)},
+			"base":     &gql.Field{Type: gql.NewNonNull(gql.String)},
+			"rate":     &gql.Field{Type: gql.NewNonNull(gql.Float)},
+			"date":     &gql.Field{Type: gql.NewNonNull(gql.String), Description: "The day the rate was published for."},
+		},
+	})
+
+	pointsRuleType := gql.NewObject(gql.ObjectConfig{
+		Name:        "PointsRule",
+		Description: "The points awarded by a single scoring rule.",
+		Fields: gql.Fields{
+			"rule":        &gql.Field{Type: gql.NewNonNull(gql.String)},
+			"description": &gql.Field{Type: gql.NewNonNull(gql.String)},
+			"points":      &gql.Field{Type: gql.NewNonNull(gql.Int)},
+		},
+	})
+
+	breakdownType := gql.NewObject(gql.ObjectConfig{
+		Name: "PointsBreakdown",
+		Fields: gql.Fields{
+			"rules": &gql.Field{Type: gql.NewNonNull(gql.NewList(gql.NewNonNull(pointsRuleType)))},
+			"total": &gql.Field{Type: gql.NewNonNull(gql.Int)},
+		},
+	})
+
+	receiptType := gql.NewObject(gql.ObjectConfig{
+		Name: "Receipt",
+		Fields: gql.Fields{
+			"id": &gql.Field{
+				Type: gql.NewNonNull(gql.ID),
+				Resolve: func(p gql.ResolveParams) (interface{}, error) {
+					return p.Source.(*models.Receipt).ID.String(), nil
+				},
+			},
+			"retailer": &gql.Field{Type: gql.NewNonNull(gql.String)},
+			"retailerId": &gql.Field{
+				Type:        gql.ID,
+				Description: "The retailer of the registry the receipt was matched with, null when none matched.",
+				Resolve: func(p gql.ResolveParams) (interface{}, error) {
+					if retailerID := p.Source.(*models.Receipt).RetailerID; retailerID != nil {
+						return retailerID.String(), nil
+					}
+					return nil, nil
+				},
+			},
+			"retailerName": &gql.Field{
+				Type:        gql.NewNonNull(gql.String),
+				Description: "The canonical name of the retailer, the one printed on the receipt when it matched none of the registry.",
+				Resolve: func(p gql.ResolveParams) (interface{}, error) {
+					return p.Source.(*models.Receipt).GetRetailerName(), nil
+				},
+			},
+			"purchaseDate": &gql.Field{Type: gql.NewNonNull(gql.String)},
+			"purchaseTime": &gql.Field{Type: gql.NewNonNull(gql.String)},
+			"timeZone": &gql.Field{
+				Type:        gql.String,
+				Description: "The time zone of the store, null when it is UTC.",
+				Resolve: func(p gql.ResolveParams) (interface{}, error) {
+					if timeZone := p.Source.(*models.Receipt).TimeZone; timeZone != "" {
+						return timeZone, nil
+					}
+					return nil, nil
+				},
+			},
+			"purchasedAt": &gql.Field{
+				Type:        gql.NewNonNull(gql.String),
+				Description: "The purchase as an RFC 3339 timestamp with the offset of the store.",
+				Resolve: func(p gql.ResolveParams) (interface{}, error) {
+					purchasedAt, err := p.Source.(*models.Receipt).GetReceiptDatetime()
+					if err != nil {
+						return nil, err
+					}
+					return purchasedAt.Format(time.RFC3339), nil
+				},
+			},
+			"total":     &gql.Field{Type: gql.NewNonNull(gql.String)},
+			"items":     &gql.Field{Type: gql.NewNonNull(gql.NewList(gql.NewNonNull(itemType)))},
+			"subtotal":  &gql.Field{Type: gql.String},
+			"taxes":     &gql.Field{Type: gql.NewList(gql.NewNonNull(adjustmentType))},
+			"discounts": &gql.Field{Type: gql.NewList(gql.NewNonNull(adjustmentType))},
+			"tip":       &gql.Field{Type: gql.String},
+			"currency": &gql.Field{
+				Type: gql.NewNonNull(gql.String),
+				Resolve: func(p gql.ResolveParams) (interface{}, error) {
+					return p.Source.(*models.Receipt).GetCurrency(), nil
+				},
+			},
+			"exchangeRate": &gql.Field{
+				Type:        exchangeRateType,
+				Description: "Null for the receipts in the base currency.",
+				Resolve: func(p gql.ResolveParams) (interface{}, error) {
+					if exchange := p.Source.(*models.Receipt).Exchange; exchange != nil {
+						return exchange, nil
+					}
+					return nil, nil
+				},
+			},
+			"type": &gql.Field{
+				Type:        gql.NewNonNull(gql.String),
+				Description: "purchase, or return for the refund of items of the original receipt.",
+				Resolve: func(p gql.ResolveParams) (interface{}, error) {
+					if p.Source.(*models.Receipt).IsReturn() {
+						return string(models.ReceiptTypeReturn), nil
+					}
+					return string(models.ReceiptTypePurchase), nil
+				},
+			},
+			"originalReceiptId": &gql.Field{
+				Type: gql.ID,
+				Resolve: func(p gql.ResolveParams) (interface{}, error) {
+					if originalID := p.Source.(*models.Receipt).OriginalID; originalID != nil {
+						return originalID.String(), nil
+					}
+					return nil, nil
+				},
+			},
+			"status": &gql.Field{
+				Type: gql.NewNonNull(gql.String),
+				Resolve: func(p gql.ResolveParams) (interface{}, error) {
+					return string(p.Source.(*models.Receipt).Status), nil
+				},
+			},
+			"createdAt": &gql.Field{
+				Type: gql.NewNonNull(gql.String),
+				Resolve: func(p gql.ResolveParams) (interface{}, error) {
+					return p.Source.(*models.Receipt).CreatedAt.Format(time.RFC3339), nil
+				},
+			},
+			"points": &gql.Field{
+				Type:        gql.Int,
+				Description: "Null with an error while the receipt is held for review or after it was rejected.",
+				Resolve:     r.points,
+			},
+			"netPoints": &gql.Field{
+				Type:        gql.Int,
+				Description: "The points of a purchase minus the points taken back by its returns. Null for returns.",
+				Resolve:     r.netPoints,
+			},
+			"breakdown": &gql.Field{
+				Type:        breakdownType,
+				Description: "Null with an error while the receipt is held for review or after it was rejected.",
+				Resolve:     r.breakdown,
+			},
+		},
+	})
+
+	query := gql.NewObject(gql.ObjectConfig{
+		Name: "Query",
+		Fields: gql.Fields{
+			"receipt": &gql.Field{
+				Type: receiptType,
+				Args: gql.FieldConfigArgument{
+					"id": &gql.ArgumentConfig{Type: gql.NewNonNull(gql.ID)},
+				},
+				Resolve: r.receipt,
+			},
+			"receiptsByIds": &gql.Field{
+				Type:        gql.NewNonNull(gql.NewList(receiptType)),
+				Description: "The receipts in the order of the ids, with null for the ones that don't exist.",
+				Args: gql.FieldConfigArgument{
+					"ids": &gql.ArgumentConfig{Type: gql.NewNonNull(gql.NewList(gql.NewNonNull(gql.ID)))},
+				},
+				Resolve: r.receiptsByIDs,
+			},
+			"receipts": &gql.Field{
+				Type:        gql.NewNonNull(gql.NewList(gql.NewNonNull(receiptType))),
+				Description: "The receipts oldest first, optionally filtered by retailer and status.",
+				Args: gql.FieldConfigArgument{
+					"retailer": &gql.ArgumentConfig{Type: gql.String},
+					"status":   &gql.ArgumentConfig{Type: gql.String},
+					"first":    &gql.ArgumentConfig{Type: gql.Int, DefaultValue: defaultPageSize},
+					"offset":   &gql.ArgumentConfig{Type: gql.Int, DefaultValue: 0},
+				},
+				Resolve: r.receipts,
+			},
+		},
+	})
+
+	itemInput := gql.NewInputObject(gql.InputObjectConfig{
+		Name: "ItemInput",
+		Fields: gql.InputObjectConfigFieldMap{
+			"shortDescription": &gql.InputObjectFieldConfig{Type: gql.NewNonNull(gql.String)},
+			"price":            &gql.InputObjectFieldConfig{Type: gql.NewNonNull(gql.String)},
+			"quantity":         &gql.InputObjectFieldConfig{Type: gql.String},
+			"unitPrice":        &gql.InputObjectFieldConfig{Type: gql.String},
+			"sku":              &gql.InputObjectFieldConfig{Type: gql.String},
+			"category":         &gql.InputObjectFieldConfig{Type: gql.String},
+		},
+	})
+
+	signatureInput := gql.NewInputObject(gql.InputObjectConfig{
+		Name:        "SignatureInput",
+		Description: "The POS terminal signature over the canonical receipt JSON, as in the X-Receipt-Signature header.",
+		Fields: gql.InputObjectConfigFieldMap{
+			"keyId":     &gql.InputObjectFieldConfig{Type: gql.NewNonNull(gql.String)},
+			"algorithm": &gql.InputObjectFieldConfig{Type: gql.NewNonNull(gql.String)},
+			"value":     &gql.InputObjectFieldConfig{Type: gql.NewNonNull(gql.String), Description: "Base64 encoded."},
+		},
+	})
+
+	adjustmentInput := gql.NewInputObject(gql.InputObjectConfig{
+		Name: "AdjustmentInput",
+		Fields: gql.InputObjectConfigFieldMap{
+			"description": &gql.InputObjectFieldConfig{Type: gql.NewNonNull(gql.String)},
+			"amount":      &gql.InputObjectFieldConfig{Type: gql.NewNonNull(gql.String)},
+		},
+	})
+
+	receiptInput := gql.NewInputObject(gql.InputObjectConfig{
+		Name: "ReceiptInput",
+		Fields: gql.InputObjectConfigFieldMap{
+			"retailer":          &gql.InputObjectFieldConfig{Type: gql.NewNonNull(gql.String)},
+			"purchaseDate":      &gql.InputObjectFieldConfig{Type: gql.String},
+			"purchaseTime":      &gql.InputObjectFieldConfig{Type: gql.String},
+			"purchasedAt":       &gql.InputObjectFieldConfig{Type: gql.String},
+			"timeZone":          &gql.InputObjectFieldConfig{Type: gql.String},
+			"total":             &gql.InputObjectFieldConfig{Type: gql.NewNonNull(gql.String)},
+			"items":             &gql.InputObjectFieldConfig{Type: gql.NewNonNull(gql.NewList(gql.NewNonNull(itemInput)))},
+			"subtotal":          &gql.InputObjectFieldConfig{Type: gql.String},
+			"taxes":             &gql.InputObjectFieldConfig{Type: gql.NewList(gql.NewNonNull(adjustmentInput))},
+			"discounts":         &gql.InputObjectFieldConfig{Type: gql.NewList(gql.NewNonNull(adjustmentInput))},
+			"tip":               &gql.InputObjectFieldConfig{Type: gql.String},
+			"currency":          &gql.InputObjectFieldConfig{Type: gql.String},
+			"type":              &gql.InputObjectFieldConfig{Type: gql.String},
+			"originalReceiptId": &gql.InputObjectFieldConfig{Type: gql.ID},
+			"signature":         &gql.InputObjectFieldConfig{Type: signatureInput},
+		},
+	})
+
+	mutation := gql.NewObject(gql.ObjectConfig{
+		Name: "Mutation",
+		Fields: gql.Fields{
+			"submitReceipt": &gql.Field{
+				Type: gql.NewNonNull(receiptType),
+				Args: gql.FieldConfigArgument{
+					"input": &gql.ArgumentConfig{Type: gql.NewNonNull(receiptInput)},
+				},
+				Resolve: r.submitReceipt,
+			},
+		},
+	})
+
+	return gql.NewSchema(gql.SchemaConfig{
+		Query:    query,
+		Mutation: mutation,
+	})
+}
+
+type resolver struct {
+	receiptSvc service.ReceiptService
+}
+
+func (r *resolver) receipt(p gql.ResolveParams) (interface{}, error) {
+	if err := requireScope(p.Context, receiptHttp.ScopeReceiptsRead); err != nil {
+		return nil, err
+	}
+
+	id, err := parseID(p.Args["id"])
+	if err != nil {
+		return nil, err
+	}
+	return loaderFromContext(p.Context, r.receiptSvc).Load(p.Context, id), nil
+}
+
+func (r *resolver) receiptsByIDs(p gql.ResolveParams) (interface{}, error) {
+	if err := requireScope(p.Context, receiptHttp.ScopeReceiptsRead); err != nil {
+		return nil, err
+	}
+
+	rawIDs, _ := p.Args["ids"].([]interface{})
+	ids := make([]uuid.UUID, len(rawIDs))
+	for i, rawID := range rawIDs {
+		id, err := parseID(rawID)
+		if err != nil {
+			return nil, err
+		}
+		ids[i] = id
+	}
+	return loaderFromContext(p.Context, r.receiptSvc).LoadMany(p.Context, ids), nil
+}
+
+func (r *resolver) receipts(p gql.ResolveParams) (interface{}, error) {
+	if err := requireScope(p.Context, receiptHttp.ScopeReceiptsRead); err != nil {
+		return nil, err
+	}
+
+	first, _ := p.Args["first"].(int)
+	offset, _ := p.Args["offset"].(int)
+	if first < 1 || offset < 0 {
+		return nil, errors.New("first must be positive and offset can't be negative")
+	}
+
+	retailer, _ := p.Args["retailer"].(string)
+	status, _ := p.Args["status"].(string)
+	return r.receiptSvc.ListReceipts(p.Context, models.ReceiptFilter{
+		Retailer: retailer,
+		Status:   models.ReceiptStatus(status),
+		Offset:   offset,
+		Limit:    clampPageSize(first),
+	})
+}
+
+// points scores the returns through the loader, which loads their purchases and the returns
+// of those in batches, instead of loading both for every return.
+func (r *resolver) points(p gql.ResolveParams) (interface{}, error) {
+	receipt := p.Source.(*models.Receipt)
+	if !receipt.IsReturn() || receipt.OriginalID == nil {
+		return r.receiptSvc.GetReceiptPoints(p.Context, receipt)
+	}
+
+	loader := loaderFromContext(p.Context, r.receiptSvc)
+	loadPurchase := loader.Load(p.Context, *receipt.OriginalID)
+	loader.QueueReturns(*receipt.OriginalID)
+	return func() (interface{}, error) {
+		purchase, err := loadPurchase()
+		if err != nil {
+			return nil, err
+		}
+		returns, points, err := loader.ReturnsPoints(p.Context, purchase.(*models.Receipt))
+		if err != nil {
+			return nil, err
+		}
+		for i, returned := range returns {
+			if returned.ID == receipt.ID {
+				return points[i], nil
+			}
+		}
+		return r.receiptSvc.GetReceiptPoints(p.Context, receipt)
+	}, nil
+}
+
+// netPoints loads the returns of the purchases of a query level in one batch.
+func (r *resolver) netPoints(p gql.ResolveParams) (interface{}, error) {
+	receipt := p.Source.(*models.Receipt)
+	if receipt.IsReturn() {
+		return nil, nil
+	}
+
+	loader := loaderFromContext(p.Context, r.receiptSvc)
+	loader.QueueReturns(receipt.ID)
+	return func() (interface{}, error) {
+		points, err := r.receiptSvc.GetReceiptPoints(p.Context, receipt)
+		if err != nil {
+			return nil, err
+		}
+		_, returnsPoints, err := loader.ReturnsPoints(p.Context, receipt)
+		if err != nil {
+			return nil, err
+		}
+		for _, returned := range returnsPoints {
+			points += returned
+		}
+		return points, nil
+	}, nil
+}
+
+func (r *resolver) breakdown(p gql.ResolveParams) (interface{}, error) {
+	return r.receiptSvc.GetReceiptPointsBreakdown(p.Context, p.Source.(*models.Receipt))
+}
+
+// submitReceipt applies the same validation as POST /receipts/process.
+func (r *resolver) submitReceipt(p gql.ResolveParams) (interface{}, error) {
+	if err := requireScope(p.Context, receiptHttp.ScopeReceiptsWrite); err != nil {
+		return nil, err
+	}
+
+	submitter, _ := p.Context.Value(submitterContextKey).(string)
+	if rateLimiter, ok := p.Context.Value(quotaContextKey).(*middleware.RateLimiter); ok {
+		if decision := rateLimiter.ConsumeQuota(submitter, "mutation submitReceipt"); !decision.Allowed {
+			return nil, fmt.Errorf("%w, retry in %s", ErrQuotaExceeded, decision.Reset.Round(time.Second))
+		}
+	}
+
+	input, _ := p.Args["input"].(map[string]interface{})
+	receipt, err := receiptFromInput(input)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.receiptSvc.ValidateReceipt(p.Context, receipt); err != nil {
+		return nil, err
+	}
+	receipt.SubmittedBy = submitter
+
+	return r.receiptSvc.CreateReceipt(p.Context, receipt)
+}
+
+func receiptFromInput(input map[string]interface{}) (*models.Receipt, error) {
+	receipt := &models.Receipt{}
+	receipt.Retailer, _ = input["retailer"].(string)
+	receipt.PurchaseDate, _ = input["purchaseDate"].(string)
+	receipt.PurchaseTime, _ = input["purchaseTime"].(string)
+	receipt.PurchasedAt, _ = input["purchasedAt"].(string)
+	receipt.TimeZone, _ = input["timeZone"].(string)
+	receipt.Total, _ = input["total"].(string)
+	receipt.Subtotal, _ = input["subtotal"].(string)
+	receipt.Tip, _ = input["tip"].(string)
+	receipt.Currency, _ = input["currency"].(string)
+	receiptType, _ := input["type"].(string)
+	receipt.Type = models.ReceiptType(receiptType)
+	if rawID, ok := input["originalReceiptId"]; ok && rawID != nil {
+		originalID, err := parseID(rawID)
+		if err != nil {
+			return nil, err
+		}
+		receipt.OriginalID = &originalID
+	}
+	receipt.Taxes = adjustmentsFromInput(input["taxes"])
+	receipt.Discounts = adjustmentsFromInput(input["discounts"])
+
+	items, _ := input["items"].([]interface{})
+	receipt.Items = make([]models.ReceiptItem, len(items))
+	for i, rawItem := range items {
+		item, _ := rawItem.(map[string]interface{})
+		receipt.Items[i].ShortDescription, _ = item["shortDescription"].(string)
+		receipt.Items[i].Price, _ = item["price"].(string)
+		receipt.Items[i].Quantity, _ = item["quantity"].(string)
+		receipt.Items[i].UnitPrice, _ = item["unitPrice"].(string)
+		receipt.Items[i].SKU, _ = item["sku"].(string)
+		receipt.Items[i].Category, _ = item["category"].(string)
+	}
+
+	if signature, ok := input["signature"].(map[string]interface{}); ok {
+		keyID, _ := signature["keyId"].(string)
+		algorithm, _ := signature["algorithm"].(string)
+		encoded, _ := signature["value"].(string)
+		value, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("the signature value is not valid base64: %w", err)
+		}
+		receipt.Signature = &models.ReceiptSignature{
+			KeyID:     keyID,
+			Algorithm: models.SignatureAlgorithm(strings.ToLower(algorithm)),
+			Value:     value,
+		}
+	}
+	return receipt, nil
+}
+
+// requireScope lets every operation through when authentication is disabled, since no
+// principal is stored in the context then.
+func requireScope(ctx context.Context, scope string) error {
+	principal, ok := auth.PrincipalFromContext(ctx)
+	if !ok || principal.HasScopes(scope) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrMissingScope, scope)
+}
+
+func loaderFromContext(ctx context.Context, receiptSvc service.ReceiptService) *receiptLoader {
+	if loader, ok := ctx.Value(loaderContextKey).(*receiptLoader); ok {
+		return loader
+	}
+	return newReceiptLoader(receiptSvc)
+}
+
+func parseID(rawID interface{}) (uuid.UUID, error) {
+	id, _ := rawID.(string)
+	receiptID, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("invalid ID format: %q", id)
+	}
+	return receiptID, nil
+}
+
+func clampPageSize(size int) int {
+	return min(size, maxPageSize)
+}
+
+func adjustmentsFromInput(input interface{}) []models.ReceiptAdjustment {
+	rawAdjustments, _ := input.([]interface{})
+	if len(rawAdjustments) == 0 {
+		return nil
+	}
+	adjustments := make([]models.ReceiptAdjustment, len(rawAdjustments))
+	for i, rawAdjustment := range rawAdjustments {
+		adjustment, _ := rawAdjustment.(map[string]interface{})
+		adjustments[i].Description, _ = adjustment["description"].(string)
+		adjustments[i].Amount, _ = adjustment["amount"].(string)
+	}
+	return adjustments
+}
@@ -0,0 +1,314 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
+	receiptHttp "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/delivery/http"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/repository"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/service"
+	signatureService "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/signature/service"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/middleware"
+	receiptv1 "github.com/CarlosMtz98/receipt-processor-challenge/pkg/pb/receipt/v1"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 100
+)
+
+// MethodScopes declares the scopes required by every method, matching the HTTP routes.
+var MethodScopes = map[string][]string{
+	receiptv1.ReceiptService_ProcessReceipt_FullMethodName:     {receiptHttp.ScopeReceiptsWrite},
+	receiptv1.ReceiptService_GetPoints_FullMethodName:          {receiptHttp.ScopeReceiptsRead},
+	receiptv1.ReceiptService_GetPointsBreakdown_FullMethodName: {receiptHttp.ScopeReceiptsRead},
+	receiptv1.ReceiptService_ListReceipts_FullMethodName:       {receiptHttp.ScopeReceiptsRead},
+}
+
+// MethodRoutes maps the methods to the HTTP routes they share their rate limits and quota with.
+var MethodRoutes = map[string]string{
+	receiptv1.ReceiptService_ProcessReceipt_FullMethodName: "POST /receipts/process",
+	receiptv1.ReceiptService_GetPoints_FullMethodName:      "GET /receipts/:id/points",
+}
+
+type ReceiptServer struct {
+	receiptv1.UnimplementedReceiptServiceServer
+	receiptSvc service.ReceiptService
+}
+
+func NewReceiptServer(receiptService service.ReceiptService) receiptv1.ReceiptServiceServer {
+	return &ReceiptServer{
+		receiptSvc: receiptService,
+	}
+}
+
+func (s *ReceiptServer) ProcessReceipt(ctx context.Context, req *receiptv1.ProcessReceiptRequest) (*receiptv1.ProcessReceiptResponse, error) {
+	if req.GetReceipt() == nil {
+		return nil, status.Error(codes.InvalidArgument, "the receipt is required")
+	}
+
+	receipt, err := receiptFromProto(req.GetReceipt())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := s.receiptSvc.ValidateReceipt(ctx, receipt); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if signature := req.GetSignature(); signature != nil {
+		receipt.Signature = &models.ReceiptSignature{
+			KeyID:     signature.GetKeyId(),
+			Algorithm: models.SignatureAlgorithm(strings.ToLower(signature.GetAlgorithm())),
+			Value:     signature.GetValue(),
+		}
+	}
+	receipt.SubmittedBy = middleware.GRPCClientKey(ctx)
+
+	createdReceipt, err := s.receiptSvc.CreateReceipt(ctx, receipt)
+	if err != nil {
+		return nil, statusFromError(err)
+	}
+
+	return &receiptv1.ProcessReceiptResponse{Id: createdReceipt.ID.String()}, nil
+}
+
+func (s *ReceiptServer) GetPoints(ctx context.Context, req *receiptv1.GetPointsRequest) (*receiptv1.GetPointsResponse, error) {
+	receipt, err := s.getReceipt(ctx, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	points, err := s.receiptSvc.GetReceiptPoints(ctx, receipt)
+	if err != nil {
+		return nil, statusFromError(err)
+	}
+
+	response := &receiptv1.GetPointsResponse{Points: int64(points)}
+	if !receipt.IsReturn() {
+		returned, err := s.receiptSvc.GetReturnedPoints(ctx, receipt)
+		if err != nil {
+			return nil, statusFromError(err)
+		}
+		netPoints := int64(points + returned)
+		response.NetPoints = &netPoints
+	}
+	return response, nil
+}
+
+func (s *ReceiptServer) GetPointsBreakdown(ctx context.Context, req *receiptv1.GetPointsBreakdownRequest) (*receiptv1.GetPointsBreakdownResponse, error) {
+	receipt, err := s.getReceipt(ctx, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	breakdown, err := s.receiptSvc.GetReceiptPointsBreakdown(ctx, receipt)
+	if err != nil {
+		return nil, statusFromError(err)
+	}
+
+	response := &receiptv1.GetPointsBreakdownResponse{
+		Rules: make([]*receiptv1.PointsRule, len(breakdown.Rules)),
+		Total: int64(breakdown.Total),
+	}
+	for i, rule := range breakdown.Rules {
+		response.Rules[i] = &receiptv1.PointsRule{
+			Rule:        rule.Rule,
+			Description: rule.Description,
+			Points:      int64(rule.Points),
+		}
+	}
+	return response, nil
+}
+
+// ListReceipts pages with an opaque token holding the offset of the next page.
+func (s *ReceiptServer) ListReceipts(ctx context.Context, req *receiptv1.ListReceiptsRequest) (*receiptv1.ListReceiptsResponse, error) {
+	pageSize := int(req.GetPageSize())
+	switch {
+	case pageSize < 0:
+		return nil, status.Error(codes.InvalidArgument, "the page size can't be negative")
+	case pageSize == 0:
+		pageSize = defaultPageSize
+	case pageSize > maxPageSize:
+		pageSize = maxPageSize
+	}
+
+	offset := 0
+	if token := req.GetPageToken(); token != "" {
+		parsed, err := strconv.Atoi(token)
+		if err != nil || parsed < 0 {
+			return nil, status.Error(codes.InvalidArgument, "the page token is not valid")
+		}
+		offset = parsed
+	}
+
+	// One extra receipt tells whether there is a next page.
+	receipts, err := s.receiptSvc.ListReceipts(ctx, models.ReceiptFilter{
+		Retailer: req.GetRetailer(),
+		Status:   models.ReceiptStatus(req.GetStatus()),
+		Offset:   offset,
+		Limit:    pageSize + 1,
+	})
+	if err != nil {
+		return nil, statusFromError(err)
+	}
+
+	response := &receiptv1.ListReceiptsResponse{}
+	if len(receipts) > pageSize {
+		receipts = receipts[:pageSize]
+		response.NextPageToken = strconv.Itoa(offset + pageSize)
+	}
+	response.Receipts = make([]*receiptv1.Receipt, len(receipts))
+	for i, receipt := range receipts {
+		response.Receipts[i] = receiptToProto(receipt)
+	}
+	return response, nil
+}
+
+func (s *ReceiptServer) getReceipt(ctx context.Context, id string) (*models.Receipt, error) {
+	receiptID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid ID format")
+	}
+
+	receipt, err := s.receiptSvc.GetReceiptByID(ctx, receiptID)
+	if err != nil {
+		return nil, statusFromError(err)
+	}
+	return receipt, nil
+}
+
+// statusFromError maps the service errors to the gRPC status codes.
+func statusFromError(err error) error {
+	switch {
+	case errors.Is(err, service.ErrMissingReceiptId),
+		errors.Is(err, service.ErrReceiptWithId),
+		errors.Is(err, service.ErrReceiptIsNil),
+		errors.Is(err, service.ErrNoExchangeRate),
+		errors.Is(err, service.ErrOriginalReceiptNotFound),
+		errors.Is(err, service.ErrInvalidReturn),
+		errors.Is(err, service.ErrInvalidReceiptParams),
+		errors.Is(err, service.ErrPurchaseTimeMismatch),
+		errors.Is(err, signatureService.ErrSignatureRequired),
+		errors.Is(err, signatureService.ErrUntrustedTerminal),
+		errors.Is(err, signatureService.ErrInvalidSignature):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, repository.ErrReceiptNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, service.ErrReceiptHeld), errors.Is(err, service.ErrReceiptRejected):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
+
+func receiptFromProto(receipt *receiptv1.Receipt) (*models.Receipt, error) {
+	items := make([]models.ReceiptItem, len(receipt.GetItems()))
+	for i, item := range receipt.GetItems() {
+		items[i] = models.ReceiptItem{
+			ShortDescription: item.GetShortDescription(),
+			Price:            item.GetPrice(),
+			Quantity:         item.GetQuantity(),
+			UnitPrice:        item.GetUnitPrice(),
+			SKU:              item.GetSku(),
+			Category:         item.GetCategory(),
+		}
+	}
+
+	var originalID *uuid.UUID
+	if id := receipt.GetOriginalReceiptId(); id != "" {
+		parsed, err := uuid.Parse(id)
+		if err != nil {
+			return nil, errors.New("the original receipt ID is not valid")
+		}
+		originalID = &parsed
+	}
+
+	return &models.Receipt{
+		Retailer:     receipt.GetRetailer(),
+		PurchaseDate: receipt.GetPurchaseDate(),
+		PurchaseTime: receipt.GetPurchaseTime(),
+		Items:        items,
+		Total:        receipt.GetTotal(),
+		Subtotal:     receipt.GetSubtotal(),
+		Taxes:        adjustmentsFromProto(receipt.GetTaxes()),
+		Discounts:    adjustmentsFromProto(receipt.GetDiscounts()),
+		Tip:          receipt.GetTip(),
+		Currency:     receipt.GetCurrency(),
+		Type:         models.ReceiptType(receipt.GetType()),
+		OriginalID:   originalID,
+		TimeZone:     receipt.GetTimeZone(),
+		PurchasedAt:  receipt.GetPurchasedAt(),
+	}, nil
+}
+
+func receiptToProto(receipt *models.Receipt) *receiptv1.Receipt {
+	items := make([]*receiptv1.Item, len(receipt.Items))
+	for i, item := range receipt.Items {
+		items[i] = &receiptv1.Item{
+			ShortDescription: item.ShortDescription,
+			Price:            item.Price,
+			Quantity:         item.Quantity,
+			UnitPrice:        item.UnitPrice,
+			Sku:              item.SKU,
+			Category:         item.Category,
+		}
+	}
+
+	converted := &receiptv1.Receipt{
+		Id:           receipt.ID.String(),
+		Retailer:     receipt.Retailer,
+		PurchaseDate: receipt.PurchaseDate,
+		PurchaseTime: receipt.PurchaseTime,
+		Items:        items,
+		Total:        receipt.Total,
+		Status:       string(receipt.Status),
+		Subtotal:     receipt.Subtotal,
+		Taxes:        adjustmentsToProto(receipt.Taxes),
+		Discounts:    adjustmentsToProto(receipt.Discounts),
+		Tip:          receipt.Tip,
+		Currency:     receipt.Currency,
+		Type:         string(receipt.Type),
+		TimeZone:     receipt.TimeZone,
+		PurchasedAt:  receipt.PurchasedAt,
+		RetailerName: receipt.RetailerName,
+	}
+	if receipt.OriginalID != nil {
+		converted.OriginalReceiptId = receipt.OriginalID.String()
+	}
+	if receipt.RetailerID != nil {
+		converted.RetailerId = receipt.RetailerID.String()
+	}
+	return converted
+}
+
+// adjustmentsFromProto leaves the receipts without adjustments with nil slices, so that they are
+// signed and stored like the JSON receipts without them.
+func adjustmentsFromProto(adjustments []*receiptv1.Adjustment) []models.ReceiptAdjustment {
+	if len(adjustments) == 0 {
+		return nil
+	}
+	converted := make([]models.ReceiptAdjustment, len(adjustments))
+	for i, adjustment := range adjustments {
+		converted[i] = models.ReceiptAdjustment{
+			Description: adjustment.GetDescription(),
+			Amount:      adjustment.GetAmount(),
+		}
+	}
+	return converted
+}
+
+func adjustmentsToProto(adjustments []models.ReceiptAdjustment) []*receiptv1.Adjustment {
+	converted := make([]*receiptv1.Adjustment, len(adjustments))
+	for i, adjustment := range adjustments {
+		converted[i] = &receiptv1.Adjustment{
+			Description: adjustment.Description,
+			Amount:      adjustment.Amount,
+		}
+	}
+	return converted
+}
@@ -0,0 +1,364 @@
+package grpc
+
+import (
+	"context"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/mock"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/repository"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/service"
+	receiptv1 "github.com/CarlosMtz98/receipt-processor-challenge/pkg/pb/receipt/v1"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+	"net"
+	"testing"
+)
+
+func newTestClient(t *testing.T, receiptService service.ReceiptService) receiptv1.ReceiptServiceClient {
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	receiptv1.RegisterReceiptServiceServer(server, NewReceiptServer(receiptService))
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return receiptv1.NewReceiptServiceClient(conn)
+}
+
+// validateWithoutTolerance validates like a receipt service without a total tolerance, for the
+// ValidateReceipt calls of the mocks.
+func validateWithoutTolerance(ctx context.Context, receipt *models.Receipt) error {
+	return service.ValidateReceipt(ctx, receipt, 0)
+}
+
+func TestReceiptServer_ProcessReceipt(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockReceiptService := mock.NewMockReceiptService(ctrl)
+	mockReceiptService.EXPECT().ValidateReceipt(gomock.Any(), gomock.Any()).DoAndReturn(validateWithoutTolerance).AnyTimes()
+	client := newTestClient(t, mockReceiptService)
+
+	receipt := &receiptv1.Receipt{
+		Retailer:     "Target",
+		PurchaseDate: "2022-01-01",
+		PurchaseTime: "13:01",
+		Items:        []*receiptv1.Item{{ShortDescription: "Mountain Dew 12PK", Price: "6.49"}},
+		Total:        "6.49",
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		id := uuid.New()
+		mockReceiptService.EXPECT().CreateReceipt(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, created *models.Receipt) (*models.Receipt, error) {
+			assert.Equal(t, "Target", created.Retailer)
+			assert.Equal(t, "6.49", created.Items[0].Price)
+			assert.Contains(t, created.SubmittedBy, "ip:")
+			created.ID = id
+			return created, nil
+		})
+
+		response, err := client.ProcessReceipt(context.Background(), &receiptv1.ProcessReceiptRequest{Receipt: receipt})
+		assert.NoError(t, err)
+		assert.Equal(t, id.String(), response.GetId())
+	})
+
+	t.Run("Item quantities", func(t *testing.T) {
+		mockReceiptService.EXPECT().CreateReceipt(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, created *models.Receipt) (*models.Receipt, error) {
+			assert.Equal(t, models.ReceiptItem{
+				ShortDescription: "Gatorade",
+				Price:            "6.75",
+				Quantity:         "3",
+				UnitPrice:        "2.25",
+				SKU:              "052000328660",
+				Category:         "beverages",
+			}, created.Items[0])
+			created.ID = uuid.New()
+			return created, nil
+		})
+
+		_, err := client.ProcessReceipt(context.Background(), &receiptv1.ProcessReceiptRequest{Receipt: &receiptv1.Receipt{
+			Retailer:     "Target",
+			PurchaseDate: "2022-01-01",
+			PurchaseTime: "13:01",
+			Items:        []*receiptv1.Item{{ShortDescription: "Gatorade", Price: "6.75", Quantity: "3", UnitPrice: "2.25", Sku: "052000328660", Category: "beverages"}},
+			Total:        "6.75",
+		}})
+		assert.NoError(t, err)
+	})
+
+	t.Run("Item price that is not the quantity times the unit price", func(t *testing.T) {
+		_, err := client.ProcessReceipt(context.Background(), &receiptv1.ProcessReceiptRequest{Receipt: &receiptv1.Receipt{
+			Retailer:     "Target",
+			PurchaseDate: "2022-01-01",
+			PurchaseTime: "13:01",
+			Items:        []*receiptv1.Item{{ShortDescription: "Gatorade", Price: "6.50", Quantity: "3", UnitPrice: "2.25"}},
+			Total:        "6.50",
+		}})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("Taxes, discounts and tip", func(t *testing.T) {
+		mockReceiptService.EXPECT().CreateReceipt(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, created *models.Receipt) (*models.Receipt, error) {
+			assert.Equal(t, "3.75", created.Subtotal)
+			assert.Equal(t, []models.ReceiptAdjustment{{Description: "Sales tax", Amount: "0.41"}}, created.Taxes)
+			assert.Equal(t, []models.ReceiptAdjustment{{Description: "Coupon", Amount: "0.50"}}, created.Discounts)
+			assert.Equal(t, "1.00", created.Tip)
+			created.ID = uuid.New()
+			return created, nil
+		})
+
+		adjusted := proto.Clone(receipt).(*receiptv1.Receipt)
+		adjusted.Items = []*receiptv1.Item{{ShortDescription: "Pepsi", Price: "3.75"}}
+		adjusted.Subtotal = "3.75"
+		adjusted.Taxes = []*receiptv1.Adjustment{{Description: "Sales tax", Amount: "0.41"}}
+		adjusted.Discounts = []*receiptv1.Adjustment{{Description: "Coupon", Amount: "0.50"}}
+		adjusted.Tip = "1.00"
+		adjusted.Total = "4.66"
+		_, err := client.ProcessReceipt(context.Background(), &receiptv1.ProcessReceiptRequest{Receipt: adjusted})
+		assert.NoError(t, err)
+
+		adjusted.Total = "4.16"
+		_, err = client.ProcessReceipt(context.Background(), &receiptv1.ProcessReceiptRequest{Receipt: adjusted})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("Currency", func(t *testing.T) {
+		mockReceiptService.EXPECT().CreateReceipt(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, created *models.Receipt) (*models.Receipt, error) {
+			assert.Equal(t, "JPY", created.Currency)
+			created.ID = uuid.New()
+			return created, nil
+		})
+
+		yen := proto.Clone(receipt).(*receiptv1.Receipt)
+		yen.Items = []*receiptv1.Item{{ShortDescription: "Onigiri", Price: "150"}}
+		yen.Total = "150"
+		yen.Currency = "JPY"
+		_, err := client.ProcessReceipt(context.Background(), &receiptv1.ProcessReceiptRequest{Receipt: yen})
+		assert.NoError(t, err)
+
+		// Yen have no decimals.
+		yen.Items[0].Price, yen.Total = "150.00", "150.00"
+		_, err = client.ProcessReceipt(context.Background(), &receiptv1.ProcessReceiptRequest{Receipt: yen})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("Return", func(t *testing.T) {
+		originalID := uuid.New()
+		mockReceiptService.EXPECT().CreateReceipt(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, created *models.Receipt) (*models.Receipt, error) {
+			assert.Equal(t, models.ReceiptTypeReturn, created.Type)
+			assert.Equal(t, &originalID, created.OriginalID)
+			created.ID = uuid.New()
+			return created, nil
+		})
+
+		refund := proto.Clone(receipt).(*receiptv1.Receipt)
+		refund.Type = "return"
+		refund.OriginalReceiptId = originalID.String()
+		_, err := client.ProcessReceipt(context.Background(), &receiptv1.ProcessReceiptRequest{Receipt: refund})
+		assert.NoError(t, err)
+
+		mockReceiptService.EXPECT().CreateReceipt(gomock.Any(), gomock.Any()).Return(nil, service.ErrInvalidReturn)
+		_, err = client.ProcessReceipt(context.Background(), &receiptv1.ProcessReceiptRequest{Receipt: refund})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+		mockReceiptService.EXPECT().CreateReceipt(gomock.Any(), gomock.Any()).Return(nil, service.ErrOriginalReceiptNotFound)
+		_, err = client.ProcessReceipt(context.Background(), &receiptv1.ProcessReceiptRequest{Receipt: refund})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+		refund.OriginalReceiptId = "not-a-uuid"
+		_, err = client.ProcessReceipt(context.Background(), &receiptv1.ProcessReceiptRequest{Receipt: refund})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+		// A return refers to a purchase and only a return does.
+		refund.OriginalReceiptId = ""
+		_, err = client.ProcessReceipt(context.Background(), &receiptv1.ProcessReceiptRequest{Receipt: refund})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("Purchase timestamp and time zone", func(t *testing.T) {
+		mockReceiptService.EXPECT().CreateReceipt(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, created *models.Receipt) (*models.Receipt, error) {
+			assert.Equal(t, "2022-01-01T20:30:00Z", created.PurchasedAt)
+			assert.Equal(t, "America/Chicago", created.TimeZone)
+			assert.Empty(t, created.PurchaseDate)
+			created.ID = uuid.New()
+			return created, nil
+		})
+
+		stamped := proto.Clone(receipt).(*receiptv1.Receipt)
+		stamped.PurchaseDate, stamped.PurchaseTime = "", ""
+		stamped.PurchasedAt = "2022-01-01T20:30:00Z"
+		stamped.TimeZone = "America/Chicago"
+		_, err := client.ProcessReceipt(context.Background(), &receiptv1.ProcessReceiptRequest{Receipt: stamped})
+		assert.NoError(t, err)
+
+		for _, createErr := range []error{service.ErrPurchaseTimeMismatch, service.ErrInvalidReceiptParams} {
+			mockReceiptService.EXPECT().CreateReceipt(gomock.Any(), gomock.Any()).Return(nil, createErr)
+			_, err = client.ProcessReceipt(context.Background(), &receiptv1.ProcessReceiptRequest{Receipt: stamped})
+			assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		}
+
+		stamped.TimeZone = "Mars/Olympus"
+		_, err = client.ProcessReceipt(context.Background(), &receiptv1.ProcessReceiptRequest{Receipt: stamped})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("Invalid receipt", func(t *testing.T) {
+		_, err := client.ProcessReceipt(context.Background(), &receiptv1.ProcessReceiptRequest{
+			Receipt: &receiptv1.Receipt{Retailer: "Target"},
+		})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("Mismatched total", func(t *testing.T) {
+		mismatched := proto.Clone(receipt).(*receiptv1.Receipt)
+		mismatched.Total = "10.00"
+		_, err := client.ProcessReceipt(context.Background(), &receiptv1.ProcessReceiptRequest{Receipt: mismatched})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
+func TestReceiptServer_GetPoints(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockReceiptService := mock.NewMockReceiptService(ctrl)
+	client := newTestClient(t, mockReceiptService)
+	receipt := &models.Receipt{ID: uuid.New(), Retailer: "Target"}
+
+	t.Run("Points", func(t *testing.T) {
+		mockReceiptService.EXPECT().GetReceiptByID(gomock.Any(), receipt.ID).Return(receipt, nil)
+		mockReceiptService.EXPECT().GetReceiptPoints(gomock.Any(), receipt).Return(28, nil)
+		mockReceiptService.EXPECT().GetReturnedPoints(gomock.Any(), receipt).Return(-10, nil)
+
+		response, err := client.GetPoints(context.Background(), &receiptv1.GetPointsRequest{Id: receipt.ID.String()})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(28), response.GetPoints())
+		assert.Equal(t, int64(18), response.GetNetPoints())
+	})
+
+	t.Run("Points of a return", func(t *testing.T) {
+		refund := &models.Receipt{ID: uuid.New(), Retailer: "Target", Type: models.ReceiptTypeReturn, OriginalID: &receipt.ID}
+		mockReceiptService.EXPECT().GetReceiptByID(gomock.Any(), refund.ID).Return(refund, nil)
+		mockReceiptService.EXPECT().GetReceiptPoints(gomock.Any(), refund).Return(-10, nil)
+
+		response, err := client.GetPoints(context.Background(), &receiptv1.GetPointsRequest{Id: refund.ID.String()})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(-10), response.GetPoints())
+		assert.Nil(t, response.NetPoints)
+	})
+
+	t.Run("Breakdown", func(t *testing.T) {
+		mockReceiptService.EXPECT().GetReceiptByID(gomock.Any(), receipt.ID).Return(receipt, nil)
+		mockReceiptService.EXPECT().GetReceiptPointsBreakdown(gomock.Any(), receipt).Return(&models.PointsBreakdown{
+			Rules: []models.PointsRule{{Rule: "retailer_name", Points: 6}, {Rule: "odd_day", Points: 6}},
+			Total: 12,
+		}, nil)
+
+		response, err := client.GetPointsBreakdown(context.Background(), &receiptv1.GetPointsBreakdownRequest{Id: receipt.ID.String()})
+		assert.NoError(t, err)
+		assert.Len(t, response.GetRules(), 2)
+		assert.Equal(t, "retailer_name", response.GetRules()[0].GetRule())
+		assert.Equal(t, int64(12), response.GetTotal())
+	})
+
+	tests := []struct {
+		name         string
+		id           string
+		err          error
+		pointsErr    error
+		expectedCode codes.Code
+	}{
+		{name: "Invalid ID", id: "not-a-uuid", expectedCode: codes.InvalidArgument},
+		{name: "Missing ID", id: uuid.Nil.String(), err: service.ErrMissingReceiptId, expectedCode: codes.InvalidArgument},
+		{name: "Not found", id: receipt.ID.String(), err: repository.ErrReceiptNotFound, expectedCode: codes.NotFound},
+		{name: "Held", id: receipt.ID.String(), pointsErr: service.ErrReceiptHeld, expectedCode: codes.FailedPrecondition},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if id, err := uuid.Parse(tt.id); err == nil {
+				if tt.err != nil {
+					mockReceiptService.EXPECT().GetReceiptByID(gomock.Any(), id).Return(nil, tt.err)
+				} else {
+					mockReceiptService.EXPECT().GetReceiptByID(gomock.Any(), id).Return(receipt, nil)
+					mockReceiptService.EXPECT().GetReceiptPoints(gomock.Any(), receipt).Return(0, tt.pointsErr)
+				}
+			}
+
+			_, err := client.GetPoints(context.Background(), &receiptv1.GetPointsRequest{Id: tt.id})
+			assert.Equal(t, tt.expectedCode, status.Code(err))
+		})
+	}
+}
+
+func TestReceiptServer_ListReceipts(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockReceiptService := mock.NewMockReceiptService(ctrl)
+	client := newTestClient(t, mockReceiptService)
+
+	receipts := []*models.Receipt{
+		{
+			ID:       uuid.New(),
+			Retailer: "Target",
+			Items:    []models.ReceiptItem{{ShortDescription: "Gatorade", Price: "6.75", Quantity: "3", UnitPrice: "2.25", SKU: "052000328660", Category: "beverages"}},
+			Taxes:    []models.ReceiptAdjustment{{Description: "Sales tax", Amount: "0.56"}},
+			Currency: "EUR",
+			Status:   models.ReceiptStatusProcessed,
+		},
+		{ID: uuid.New(), Retailer: "Target", Status: models.ReceiptStatusProcessed},
+		{ID: uuid.New(), Retailer: "Target", Status: models.ReceiptStatusProcessed},
+	}
+	receipts[1].Type, receipts[1].OriginalID = models.ReceiptTypeReturn, &receipts[0].ID
+	receipts[0].TimeZone, receipts[0].PurchasedAt = "America/Chicago", "2022-01-01T20:30:00Z"
+	retailerID := uuid.New()
+	receipts[0].RetailerID, receipts[0].RetailerName = &retailerID, "Target Corporation"
+
+	mockReceiptService.EXPECT().
+		ListReceipts(gomock.Any(), models.ReceiptFilter{Retailer: "Target", Limit: 3}).
+		Return(receipts, nil)
+	response, err := client.ListReceipts(context.Background(), &receiptv1.ListReceiptsRequest{Retailer: "Target", PageSize: 2})
+	assert.NoError(t, err)
+	assert.Len(t, response.GetReceipts(), 2)
+	assert.Equal(t, receipts[0].ID.String(), response.GetReceipts()[0].GetId())
+	assert.Equal(t, "processed", response.GetReceipts()[0].GetStatus())
+	item := response.GetReceipts()[0].GetItems()[0]
+	assert.Equal(t, []string{"3", "2.25", "052000328660", "beverages"}, []string{item.GetQuantity(), item.GetUnitPrice(), item.GetSku(), item.GetCategory()})
+	assert.Equal(t, "0.56", response.GetReceipts()[0].GetTaxes()[0].GetAmount())
+	assert.Equal(t, "EUR", response.GetReceipts()[0].GetCurrency())
+	assert.Equal(t, []string{"America/Chicago", "2022-01-01T20:30:00Z"}, []string{response.GetReceipts()[0].GetTimeZone(), response.GetReceipts()[0].GetPurchasedAt()})
+	assert.Equal(t, []string{retailerID.String(), "Target Corporation"}, []string{response.GetReceipts()[0].GetRetailerId(), response.GetReceipts()[0].GetRetailerName()})
+	assert.Equal(t, "return", response.GetReceipts()[1].GetType())
+	assert.Empty(t, response.GetReceipts()[1].GetRetailerId())
+	assert.Equal(t, receipts[0].ID.String(), response.GetReceipts()[1].GetOriginalReceiptId())
+	assert.Equal(t, "2", response.GetNextPageToken())
+
+	mockReceiptService.EXPECT().
+		ListReceipts(gomock.Any(), models.ReceiptFilter{Retailer: "Target", Offset: 2, Limit: 3}).
+		Return(receipts[2:], nil)
+	response, err = client.ListReceipts(context.Background(), &receiptv1.ListReceiptsRequest{Retailer: "Target", PageSize: 2, PageToken: "2"})
+	assert.NoError(t, err)
+	assert.Len(t, response.GetReceipts(), 1)
+	assert.Empty(t, response.GetNextPageToken())
+
+	_, err = client.ListReceipts(context.Background(), &receiptv1.ListReceiptsRequest{PageToken: "abc"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
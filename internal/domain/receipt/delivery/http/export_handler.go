@@ -0,0 +1,101 @@
+package http
+
+import (
+	"fmt"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/service"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/receiptio"
+	"github.com/CarlosMtz98/receipt-processor-challenge/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+)
+
+// exportPageSize is the number of receipts loaded from the service at a time while exporting.
+const exportPageSize = 500
+
+type ExportHandler interface {
+	Export(c *gin.Context)
+}
+
+type ExportHandlerImpl struct {
+	receiptSvc service.ReceiptService
+	pageSize   int
+}
+
+func NewExportHandler(receiptService service.ReceiptService) ExportHandler {
+	return &ExportHandlerImpl{
+		receiptSvc: receiptService,
+		pageSize:   exportPageSize,
+	}
+}
+
+// Export streams the receipts matching the retailer and status query parameters with their
+// points, as NDJSON or as CSV with a row per receipt or per item.
+func (h ExportHandlerImpl) Export(c *gin.Context) {
+	format, err := receiptio.ParseFormat(c.DefaultQuery("format", string(receiptio.FormatNDJSON)))
+	if err != nil {
+		utils.HandleBadRequest(c, "Invalid export format", err)
+		return
+	}
+	layout, err := receiptio.ParseLayout(c.DefaultQuery("layout", string(receiptio.LayoutReceipt)))
+	if err != nil {
+		utils.HandleBadRequest(c, "Invalid export layout", err)
+		return
+	}
+	filter := models.ReceiptFilter{
+		Retailer: c.Query("retailer"),
+		Status:   models.ReceiptStatus(c.Query("status")),
+		Limit:    h.pageSize,
+	}
+	switch filter.Status {
+	case "", models.ReceiptStatusProcessed, models.ReceiptStatusHeld, models.ReceiptStatusRejected:
+	default:
+		utils.HandleBadRequest(c, "Invalid receipt status", fmt.Errorf("unknown status %q", filter.Status))
+		return
+	}
+
+	// The first page is loaded before the headers are sent, so that a failing service can still
+	// be answered with an error status.
+	receipts, err := h.receiptSvc.ListReceipts(c, filter)
+	if err != nil {
+		utils.HandleInternalError(c, "Could not list the receipts", err)
+		return
+	}
+
+	writer, _ := receiptio.NewWriter(c.Writer, format, layout)
+	c.Header("Content-Type", writer.ContentType())
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="receipts.%s"`, format))
+	c.Status(http.StatusOK)
+
+	for {
+		for _, receipt := range receipts {
+			record := receiptio.Record{Receipt: receipt}
+			if receipt.Status == models.ReceiptStatusProcessed {
+				if points, err := h.receiptSvc.GetReceiptPoints(c, receipt); err == nil {
+					record.Points = &points
+				}
+			}
+			if err := writer.Write(record); err != nil {
+				log.Printf("Error exporting receipt %s: %v", receipt.ID, err)
+				return
+			}
+		}
+		if err := writer.Flush(); err != nil {
+			log.Printf("Error flushing the receipts export: %v", err)
+			return
+		}
+		c.Writer.Flush()
+
+		if len(receipts) < h.pageSize {
+			return
+		}
+		filter.Offset += len(receipts)
+		if receipts, err = h.receiptSvc.ListReceipts(c, filter); err != nil {
+			// The status is already sent: the client sees a truncated export.
+			log.Printf("Error listing the receipts to export: %v", err)
+			c.Abort()
+			return
+		}
+	}
+}
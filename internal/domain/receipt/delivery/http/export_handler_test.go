@@ -0,0 +1,106 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/mock"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/repository"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/service"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/middleware"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newExportRouter(handler ExportHandler) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	MapExportRoutes(router.Group("/receipts"), handler, middleware.AllowAll{})
+	return router
+}
+
+func TestExportHandlerImpl_Export(t *testing.T) {
+	receiptRepo := repository.InitReceiptRepository()
+	createdAt := time.Date(2024, time.May, 1, 10, 0, 0, 0, time.UTC)
+	for i, retailer := range []string{"Target", "Walgreens", "Target"} {
+		receipt := &models.Receipt{
+			ID:           uuid.New(),
+			Retailer:     retailer,
+			PurchaseDate: "2022-01-01",
+			PurchaseTime: "13:01",
+			Total:        "1.25",
+			Items:        []models.ReceiptItem{{ShortDescription: "Pepsi - 12-oz", Price: "1.25"}},
+			Status:       models.ReceiptStatusProcessed,
+			CreatedAt:    createdAt.Add(time.Duration(i) * time.Minute),
+		}
+		require.NoError(t, receiptRepo.Create(context.Background(), receipt))
+	}
+	held := &models.Receipt{ID: uuid.New(), Retailer: "Target", Status: models.ReceiptStatusHeld, CreatedAt: createdAt.Add(time.Hour)}
+	require.NoError(t, receiptRepo.Create(context.Background(), held))
+
+	// A page size smaller than the number of receipts exercises the pagination.
+	router := newExportRouter(&ExportHandlerImpl{receiptSvc: service.NewReceiptService(receiptRepo), pageSize: 2})
+	export := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/receipts/export"+query, nil))
+		return w
+	}
+
+	t.Run("NDJSON by default", func(t *testing.T) {
+		w := export("")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+		assert.Equal(t, `attachment; filename="receipts.ndjson"`, w.Header().Get("Content-Disposition"))
+		lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+		require.Len(t, lines, 4)
+		assert.Contains(t, lines[0], `"points":`)
+		assert.Contains(t, lines[3], held.ID.String())
+		assert.NotContains(t, lines[3], `"points":`)
+	})
+
+	t.Run("CSV filtered by retailer and status", func(t *testing.T) {
+		w := export("?format=csv&layout=item&retailer=target&status=processed")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+		lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+		require.Len(t, lines, 3)
+		assert.True(t, strings.HasPrefix(lines[0], "id,retailer,"))
+		assert.Contains(t, lines[1], ",Target,")
+		assert.Contains(t, lines[2], ",Target,")
+	})
+
+	t.Run("Empty CSV export still has a header", func(t *testing.T) {
+		w := export("?format=csv&status=rejected")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "id,retailer,purchaseDate,purchaseTime,total,status,createdAt,points,itemCount\n", w.Body.String())
+	})
+
+	t.Run("Invalid parameters", func(t *testing.T) {
+		for _, query := range []string{"?format=xml", "?format=csv&layout=row", "?status=pending"} {
+			assert.Equal(t, http.StatusBadRequest, export(query).Code, query)
+		}
+	})
+
+	t.Run("Service error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockReceiptService := mock.NewMockReceiptService(ctrl)
+		mockReceiptService.EXPECT().ListReceipts(gomock.Any(), gomock.Any()).Return(nil, errors.New("unavailable"))
+
+		w := httptest.NewRecorder()
+		newExportRouter(NewExportHandler(mockReceiptService)).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/receipts/export", nil))
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
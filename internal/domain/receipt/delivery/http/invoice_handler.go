@@ -0,0 +1,89 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/repository"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/service"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/dto"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/middleware"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/receiptio/cfdi"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/receiptio/ubl"
+	"github.com/CarlosMtz98/receipt-processor-challenge/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// maxInvoiceSize bounds the XML documents accepted by the invoice endpoints.
+const maxInvoiceSize = 1 << 20
+
+type InvoiceHandler interface {
+	CreateFromCFDI(c *gin.Context)
+}
+
+type InvoiceHandlerImpl struct {
+	receiptSvc service.ReceiptService
+}
+
+func NewInvoiceHandler(receiptService service.ReceiptService) InvoiceHandler {
+	return &InvoiceHandlerImpl{
+		receiptSvc: receiptService,
+	}
+}
+
+// CreateFromCFDI submits the receipt of a CFDI 4.0 XML invoice. A stamp UUID, or a folio of the
+// same issuer, that was already submitted is rejected.
+func (h InvoiceHandlerImpl) CreateFromCFDI(c *gin.Context) {
+	comprobante, err := cfdi.Parse(http.MaxBytesReader(c.Writer, c.Request.Body, maxInvoiceSize))
+	if err != nil {
+		utils.HandleBadRequest(c, "The CFDI document is not valid", err)
+		return
+	}
+
+	submitInvoice(c, h.receiptSvc, comprobante.Receipt(), "CFDI document")
+}
+
+// createFromUBL submits the receipt of a UBL 2.1 invoice. It serves the XML requests of
+// POST /receipts/process.
+func createFromUBL(c *gin.Context, receiptSvc service.ReceiptService) {
+	invoice, err := ubl.Parse(http.MaxBytesReader(c.Writer, c.Request.Body, maxInvoiceSize))
+	if errors.Is(err, ubl.ErrUnsupported) {
+		utils.HandleBadRequest(c, "The UBL invoice uses constructs that are not supported", err)
+		return
+	}
+	if err != nil {
+		utils.HandleBadRequest(c, "The UBL invoice is not valid", err)
+		return
+	}
+
+	submitInvoice(c, receiptSvc, invoice.Receipt(), "UBL invoice")
+}
+
+func submitInvoice(c *gin.Context, receiptSvc service.ReceiptService, receipt *models.Receipt, document string) {
+	if err := receiptSvc.ValidateReceipt(c, receipt); err != nil {
+		utils.HandleBadRequest(c, fmt.Sprintf("The %s does not describe a valid receipt", document), err)
+		return
+	}
+	receipt.SubmittedBy = middleware.ClientKey(c)
+
+	createdReceipt, err := receiptSvc.CreateReceipt(c, receipt)
+	if isSignatureError(err) {
+		utils.HandleBadRequest(c, "The receipt signature could not be verified", err)
+		return
+	}
+	if errors.Is(err, service.ErrNoExchangeRate) {
+		utils.HandleBadRequest(c, "There is no exchange rate for the invoice currency", err)
+		return
+	}
+	if errors.Is(err, repository.ErrDuplicateInvoice) {
+		utils.HandleConflict(c, "The invoice was already submitted")
+		return
+	}
+	if err != nil {
+		utils.HandleInternalError(c, "Could not add new receipt", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.CreateReceiptResponse{ID: createdReceipt.ID.String()})
+}
@@ -0,0 +1,131 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/repository"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/service"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/dto"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/middleware"
+	"github.com/CarlosMtz98/receipt-processor-challenge/pkg/currency"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestInvoiceHandlerImpl_CreateFromCFDI(t *testing.T) {
+	example, err := os.ReadFile("../../../../../examples/cfdi-invoice.xml")
+	require.NoError(t, err)
+
+	rates, err := currency.LoadRates("../../../../../examples/exchange-rates.json", currency.DefaultCurrency)
+	require.NoError(t, err)
+	receiptSvc := service.NewReceiptService(repository.InitReceiptRepository(), service.WithExchangeRates(rates))
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	MapInvoiceRoutes(router.Group("/receipts"), NewInvoiceHandler(receiptSvc), middleware.AllowAll{})
+	submit := func(document string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/receipts/cfdi", strings.NewReader(document))
+		req.Header.Set("Content-Type", "application/xml")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Valid invoice is scored like any receipt", func(t *testing.T) {
+		w := submit(string(example))
+
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var response dto.CreateReceiptResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		receipt, err := receiptSvc.GetReceiptByID(context.Background(), uuid.MustParse(response.ID))
+		require.NoError(t, err)
+		assert.Equal(t, "TIENDA LA ESPERANZA", receipt.Retailer)
+		points, err := receiptSvc.GetReceiptPoints(context.Background(), receipt)
+		require.NoError(t, err)
+		assert.Equal(t, 32, points)
+	})
+
+	t.Run("Same stamp UUID", func(t *testing.T) {
+		w := submit(strings.Replace(string(example), `Folio="1024"`, `Folio="1025"`, 1))
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("Same folio of the issuer", func(t *testing.T) {
+		w := submit(strings.Replace(string(example), "5FB2822E-396D-4725-8521-CDC4BDD20CCF", "0B9C3E47-7B5A-4F6E-9D55-6BDB2A1F4F0E", 1))
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("Invalid document", func(t *testing.T) {
+		w := submit(strings.Replace(string(example), `Version="4.0"`, `Version="3.3"`, 1))
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "Version must be 4.0")
+	})
+
+	t.Run("Total that does not match the lines", func(t *testing.T) {
+		document := strings.NewReplacer(`Total="77.99"`, `Total="99.99"`, "5FB2822E", "0B9C3E47", `Folio="1024"`, `Folio="2048"`).Replace(string(example))
+
+		w := submit(document)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "total")
+	})
+}
+
+func TestReceiptHandlerImpl_CreateFromUBL(t *testing.T) {
+	example, err := os.ReadFile("../../../../../examples/ubl-invoice.xml")
+	require.NoError(t, err)
+
+	rates, err := currency.LoadRates("../../../../../examples/exchange-rates.json", currency.DefaultCurrency)
+	require.NoError(t, err)
+	receiptSvc := service.NewReceiptService(repository.InitReceiptRepository(), service.WithExchangeRates(rates))
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/receipts/process", NewReceiptHandler(receiptSvc).Create)
+	submit := func(contentType, document string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/receipts/process", strings.NewReader(document))
+		req.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Valid invoice", func(t *testing.T) {
+		w := submit("application/xml; charset=utf-8", string(example))
+
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var response dto.CreateReceiptResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		receipt, err := receiptSvc.GetReceiptByID(context.Background(), uuid.MustParse(response.ID))
+		require.NoError(t, err)
+		assert.Equal(t, "De Hoek Supermarkt", receipt.Retailer)
+		assert.Equal(t, "EUR", receipt.Invoice.Currency)
+	})
+
+	t.Run("Same invoice number of the supplier", func(t *testing.T) {
+		w := submit("text/xml", string(example))
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("Unsupported construct", func(t *testing.T) {
+		w := submit("application/xml", strings.Replace(string(example), "<cbc:InvoiceTypeCode>380", "<cbc:InvoiceTypeCode>386", 1))
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "not supported")
+	})
+
+	t.Run("JSON is still accepted", func(t *testing.T) {
+		w := submit("application/json", `{"retailer":"Target","purchaseDate":"2022-01-02","purchaseTime":"13:13","total":"1.25","items":[{"shortDescription":"Pepsi - 12-oz","price":"1.25"}]}`)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+}
@@ -0,0 +1,69 @@
+package http
+
+import (
+	"errors"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/service"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/dto"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/receiptio/plaintext"
+	"github.com/CarlosMtz98/receipt-processor-challenge/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// maxReceiptTextSize bounds the receipt texts accepted by POST /receipts/parse.
+const maxReceiptTextSize = 64 << 10
+
+type ParseHandler interface {
+	Parse(c *gin.Context)
+}
+
+type ParseHandlerImpl struct {
+	receiptSvc service.ReceiptService
+}
+
+// NewParseHandler validates the drafts with the receipt service, so that they are only valid
+// when POST /receipts/process would accept them.
+func NewParseHandler(receiptService service.ReceiptService) ParseHandler {
+	return &ParseHandlerImpl{
+		receiptSvc: receiptService,
+	}
+}
+
+// Parse reads the receipt text of the request body and returns the draft receipt without
+// storing it, so that it can be reviewed before it is submitted.
+func (h ParseHandlerImpl) Parse(c *gin.Context) {
+	draft, err := plaintext.Parse(http.MaxBytesReader(c.Writer, c.Request.Body, maxReceiptTextSize))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		utils.HandleBadRequest(c, "The receipt text is too large", err)
+		return
+	}
+	if err != nil {
+		utils.HandleBadRequest(c, "Could not read the receipt text", err)
+		return
+	}
+
+	response := dto.ParseReceiptResponse{
+		Receipt:    dto.NewReceiptRequest(draft.Receipt),
+		Confidence: draft.Confidence,
+		Warnings:   draft.Warnings,
+		Unparsed:   make([]dto.UnparsedLine, len(draft.Unparsed)),
+	}
+	if err := h.receiptSvc.ValidateReceipt(c, draft.Receipt); err != nil {
+		response.Warnings = append(response.Warnings, "the draft is not a valid receipt: "+err.Error())
+	} else {
+		response.Valid = true
+	}
+	if response.Warnings == nil {
+		response.Warnings = []string{}
+	}
+	if response.Receipt.Items == nil {
+		response.Receipt.Items = []models.ReceiptItem{}
+	}
+	for i, line := range draft.Unparsed {
+		response.Unparsed[i] = dto.UnparsedLine{Line: line.Number, Text: line.Text, Reason: line.Reason}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,72 @@
+package http
+
+import (
+	"encoding/json"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/repository"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/service"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/dto"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/middleware"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseHandlerImpl_Parse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	MapParseRoutes(router.Group("/receipts"), NewParseHandler(service.NewReceiptService(repository.InitReceiptRepository())), middleware.AllowAll{})
+	parse := func(text string) (*httptest.ResponseRecorder, dto.ParseReceiptResponse) {
+		req := httptest.NewRequest(http.MethodPost, "/receipts/parse", strings.NewReader(text))
+		req.Header.Set("Content-Type", "text/plain")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var response dto.ParseReceiptResponse
+		if w.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		}
+		return w, response
+	}
+
+	t.Run("Complete receipt", func(t *testing.T) {
+		w, response := parse("TARGET\n01/02/2022 1:13 PM\nPepsi - 12-oz 1.25\nDasani 1.40\nTOTAL 2.65\nVISA 2.65\n")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.True(t, response.Valid)
+		assert.Equal(t, 1.0, response.Confidence)
+		assert.Equal(t, "TARGET", response.Receipt.Retailer)
+		assert.Equal(t, "13:13", response.Receipt.PurchaseTime)
+		assert.Len(t, response.Receipt.Items, 2)
+		assert.Empty(t, response.Unparsed)
+	})
+
+	t.Run("Incomplete receipt is returned as a draft", func(t *testing.T) {
+		w, response := parse("Pepsi 1.25\nsomething else\n")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.False(t, response.Valid)
+		assert.Equal(t, 0.15, response.Confidence)
+		assert.Contains(t, response.Warnings, "no total found")
+		assert.Equal(t, []dto.UnparsedLine{{Line: 2, Text: "something else", Reason: "not recognized"}}, response.Unparsed)
+		assert.Contains(t, w.Body.String(), `"warnings":[`)
+	})
+
+	t.Run("Empty text", func(t *testing.T) {
+		w, response := parse("")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Zero(t, response.Confidence)
+		assert.Contains(t, w.Body.String(), `"items":[]`)
+		assert.Contains(t, w.Body.String(), `"unparsed":[]`)
+	})
+
+	t.Run("Text too large", func(t *testing.T) {
+		w, _ := parse(strings.Repeat("Pepsi 1.25\n", maxReceiptTextSize/10))
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
@@ -1,16 +1,25 @@
 package http
 
 import (
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
 	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/service"
+	signatureService "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/signature/service"
 	"github.com/CarlosMtz98/receipt-processor-challenge/internal/dto"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/middleware"
 	"github.com/CarlosMtz98/receipt-processor-challenge/pkg/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"net/http"
+	"strings"
 )
 
+// SignatureHeader carries the POS terminal signature over the canonical receipt JSON, as
+// keyId="<terminal>",algorithm="hmac-sha256|ed25519",signature="<base64>".
+const SignatureHeader = "X-Receipt-Signature"
+
 type ReceiptHandler interface {
 	Create(c *gin.Context)
 	GetPoints(c *gin.Context)
@@ -26,7 +35,14 @@ func NewReceiptHandler(receiptService service.ReceiptService) ReceiptHandler {
 	}
 }
 
+// Create accepts the receipt as JSON or, with an XML content type, as a UBL 2.1 invoice.
 func (h ReceiptHandlerImpl) Create(c *gin.Context) {
+	switch c.ContentType() {
+	case gin.MIMEXML, gin.MIMEXML2:
+		createFromUBL(c, h.receiptSvc)
+		return
+	}
+
 	receipt := &models.Receipt{}
 
 	if err := c.Bind(&receipt); err != nil {
@@ -34,18 +50,51 @@ func (h ReceiptHandlerImpl) Create(c *gin.Context) {
 		return
 	}
 
-	if err := utils.ValidateStruct(c, receipt); err != nil {
+	if err := h.receiptSvc.ValidateReceipt(c, receipt); errors.Is(err, service.ErrTotalMismatch) {
+		utils.HandleBadRequest(c, "The receipt total must match with the items total", err)
+		return
+	} else if errors.Is(err, service.ErrSubtotalMismatch) {
+		utils.HandleBadRequest(c, "The receipt subtotal must match with the items total", err)
+		return
+	} else if errors.Is(err, service.ErrItemPriceMismatch) {
+		utils.HandleBadRequest(c, "The item price must match its quantity times its unit price", err)
+		return
+	} else if err != nil {
 		utils.HandleBadRequest(c, "The receipt params are not valid", err)
 		return
 	}
 
-	isValidReceipt, err := receipt.IsValid()
-	if !isValidReceipt || err != nil {
-		utils.HandleBadRequest(c, "The receipt total must match with the items total", err)
-		return
+	if header := c.GetHeader(SignatureHeader); header != "" {
+		signature, err := parseSignatureHeader(header)
+		if err != nil {
+			utils.HandleBadRequest(c, "Could not parse the receipt signature", err)
+			return
+		}
+		receipt.Signature = signature
 	}
+	receipt.SubmittedBy = middleware.ClientKey(c)
 
 	createdReceipt, err := h.receiptSvc.CreateReceipt(c, receipt)
+	if isSignatureError(err) {
+		utils.HandleBadRequest(c, "The receipt signature could not be verified", err)
+		return
+	}
+	if errors.Is(err, service.ErrNoExchangeRate) {
+		utils.HandleBadRequest(c, "There is no exchange rate for the receipt currency", err)
+		return
+	}
+	if errors.Is(err, service.ErrPurchaseTimeMismatch) {
+		utils.HandleBadRequest(c, "The purchase date and time must match purchasedAt", err)
+		return
+	}
+	if errors.Is(err, service.ErrOriginalReceiptNotFound) {
+		utils.HandleBadRequest(c, "The original receipt of the return was not found", err)
+		return
+	}
+	if errors.Is(err, service.ErrInvalidReturn) {
+		utils.HandleBadRequest(c, "The return does not match the original receipt", err)
+		return
+	}
 	if err != nil {
 		utils.HandleInternalError(c, "Could not add new receipt", err)
 		return
@@ -55,7 +104,7 @@ func (h ReceiptHandlerImpl) Create(c *gin.Context) {
 		ID: createdReceipt.ID.String(),
 	}
 
-	c.JSON(http.StatusCreated, response)
+	c.JSON(http.StatusOK, response)
 	return
 }
 
@@ -80,6 +129,18 @@ func (h ReceiptHandlerImpl) GetPoints(c *gin.Context) {
 	}
 
 	points, err := h.receiptSvc.GetReceiptPoints(c, receipt)
+	if errors.Is(err, service.ErrReceiptHeld) {
+		c.JSON(http.StatusAccepted, dto.PointsStatusResponse{Status: "pending"})
+		return
+	}
+	if errors.Is(err, service.ErrReceiptRejected) {
+		response := dto.PointsStatusResponse{Status: "rejected"}
+		if receipt.Review != nil {
+			response.Reason = receipt.Review.Reason
+		}
+		c.JSON(http.StatusConflict, response)
+		return
+	}
 	if err != nil {
 		utils.HandleInternalError(c, "Error calculating points", err)
 		return
@@ -88,6 +149,47 @@ func (h ReceiptHandlerImpl) GetPoints(c *gin.Context) {
 	response := dto.GetPointsResponse{
 		Points: points,
 	}
+	if !receipt.IsReturn() {
+		returned, err := h.receiptSvc.GetReturnedPoints(c, receipt)
+		if err != nil {
+			utils.HandleInternalError(c, "Error calculating points", err)
+			return
+		}
+		netPoints := points + returned
+		response.NetPoints = &netPoints
+	}
 	c.JSON(http.StatusOK, response)
 	return
 }
+
+func parseSignatureHeader(header string) (*models.ReceiptSignature, error) {
+	params := make(map[string]string)
+	for _, part := range strings.Split(header, ",") {
+		name, value, found := strings.Cut(strings.TrimSpace(part), "=")
+		if !found {
+			return nil, fmt.Errorf("malformed signature parameter %q", part)
+		}
+		params[strings.TrimSpace(name)] = strings.Trim(strings.TrimSpace(value), `"`)
+	}
+
+	if params["keyId"] == "" || params["algorithm"] == "" || params["signature"] == "" {
+		return nil, errors.New("the signature requires keyId, algorithm and signature parameters")
+	}
+
+	value, err := base64.StdEncoding.DecodeString(params["signature"])
+	if err != nil {
+		return nil, fmt.Errorf("the signature is not valid base64: %v", err)
+	}
+
+	return &models.ReceiptSignature{
+		KeyID:     params["keyId"],
+		Algorithm: models.SignatureAlgorithm(strings.ToLower(params["algorithm"])),
+		Value:     value,
+	}, nil
+}
+
+func isSignatureError(err error) bool {
+	return errors.Is(err, signatureService.ErrSignatureRequired) ||
+		errors.Is(err, signatureService.ErrUntrustedTerminal) ||
+		errors.Is(err, signatureService.ErrInvalidSignature)
+}
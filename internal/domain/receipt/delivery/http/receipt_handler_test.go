@@ -2,10 +2,13 @@ package http
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
 	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/mock"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/service"
+	signatureService "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/signature/service"
 	"github.com/CarlosMtz98/receipt-processor-challenge/internal/dto"
 	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
@@ -16,12 +19,19 @@ import (
 	"testing"
 )
 
+// validateWithoutTolerance validates like a receipt service without a total tolerance, for the
+// ValidateReceipt calls of the mocks.
+func validateWithoutTolerance(ctx context.Context, receipt *models.Receipt) error {
+	return service.ValidateReceipt(ctx, receipt, 0)
+}
+
 func TestReceiptHandlerImpl_Create(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	mockReceipt := buildRandomReceipt(true, "Target")
 	mockReceiptService := mock.NewMockReceiptService(ctrl)
+	mockReceiptService.EXPECT().ValidateReceipt(gomock.Any(), gomock.Any()).DoAndReturn(validateWithoutTolerance).AnyTimes()
 	mockReceiptService.EXPECT().
 		CreateReceipt(gomock.Any(), gomock.Any()).
 		Return(&mockReceipt, nil)
@@ -41,7 +51,7 @@ func TestReceiptHandlerImpl_Create(t *testing.T) {
 		resp := httptest.NewRecorder()
 		router.ServeHTTP(resp, req)
 		assert.NoError(t, err)
-		assert.Equal(t, http.StatusCreated, resp.Code)
+		assert.Equal(t, http.StatusOK, resp.Code)
 
 		var response = dto.CreateReceiptResponse{}
 		if err := json.Unmarshal(resp.Body.Bytes(), &response); err != nil {
@@ -81,6 +91,60 @@ func TestReceiptHandlerImpl_Create(t *testing.T) {
 	})
 }
 
+func TestReceiptHandlerImpl_CreateSigned(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockReceipt := buildRandomReceipt(true, "Target")
+	mockReceiptService := mock.NewMockReceiptService(ctrl)
+	mockReceiptService.EXPECT().ValidateReceipt(gomock.Any(), gomock.Any()).DoAndReturn(validateWithoutTolerance).AnyTimes()
+	receiptHandler := NewReceiptHandler(mockReceiptService)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/receipts/process", receiptHandler.Create)
+
+	send := func(signature string) *httptest.ResponseRecorder {
+		payload, err := json.Marshal(&mockReceipt)
+		assert.NoError(t, err)
+		req := httptest.NewRequest("POST", "/receipts/process", bytes.NewBuffer(payload))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(SignatureHeader, signature)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	t.Run("Signature is passed to the service", func(t *testing.T) {
+		mockReceiptService.EXPECT().
+			CreateReceipt(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ interface{}, receipt *models.Receipt) (*models.Receipt, error) {
+				assert.Equal(t, "target-pos-1", receipt.Signature.KeyID)
+				assert.Equal(t, models.SignatureEd25519, receipt.Signature.Algorithm)
+				assert.Equal(t, []byte("signature"), receipt.Signature.Value)
+				receipt.ID = uuid.New()
+				return receipt, nil
+			})
+
+		resp := send(`keyId="target-pos-1", algorithm="Ed25519", signature="c2lnbmF0dXJl"`)
+		assert.Equal(t, http.StatusOK, resp.Code)
+	})
+
+	t.Run("Malformed signature header", func(t *testing.T) {
+		resp := send(`keyId="target-pos-1"`)
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+	})
+
+	t.Run("Rejected signature", func(t *testing.T) {
+		mockReceiptService.EXPECT().
+			CreateReceipt(gomock.Any(), gomock.Any()).
+			Return(nil, signatureService.ErrInvalidSignature)
+
+		resp := send(`keyId="target-pos-1",algorithm="ed25519",signature="c2lnbmF0dXJl"`)
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+	})
+}
+
 func TestReceiptHandlerImpl_GetPoints(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -96,6 +160,10 @@ func TestReceiptHandlerImpl_GetPoints(t *testing.T) {
 		GetReceiptPoints(gomock.Any(), &mockReceipt).
 		Return(28, nil)
 
+	mockReceiptService.EXPECT().
+		GetReturnedPoints(gomock.Any(), &mockReceipt).
+		Return(-6, nil)
+
 	receiptHandler := NewReceiptHandler(mockReceiptService)
 
 	gin.SetMode(gin.TestMode)
@@ -120,9 +188,53 @@ func TestReceiptHandlerImpl_GetPoints(t *testing.T) {
 			t.Fatal(err)
 		}
 		assert.Equal(t, 28, response.Points)
+		assert.Equal(t, 22, *response.NetPoints)
 	})
 }
 
+func TestReceiptHandlerImpl_GetPointsHeld(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockReceipt := buildRandomReceipt(false, "Target")
+	mockReceipt.Status = models.ReceiptStatusHeld
+	mockReceiptService := mock.NewMockReceiptService(ctrl)
+	mockReceiptService.EXPECT().GetReceiptByID(gomock.Any(), mockReceipt.ID).Return(&mockReceipt, nil)
+	mockReceiptService.EXPECT().GetReceiptPoints(gomock.Any(), &mockReceipt).Return(0, service.ErrReceiptHeld)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/receipts/:id/points", NewReceiptHandler(mockReceiptService).GetPoints)
+
+	req := httptest.NewRequest("GET", fmt.Sprintf("/receipts/%s/points", mockReceipt.ID), nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusAccepted, resp.Code)
+	assert.JSONEq(t, `{"status":"pending"}`, resp.Body.String())
+}
+
+func TestReceiptHandlerImpl_GetPointsRejected(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockReceipt := buildRandomReceipt(false, "Target")
+	mockReceipt.Status = models.ReceiptStatusRejected
+	mockReceipt.Review = &models.ReviewDecision{Outcome: models.ReviewRejected, Reason: "duplicate submission"}
+	mockReceiptService := mock.NewMockReceiptService(ctrl)
+	mockReceiptService.EXPECT().GetReceiptByID(gomock.Any(), mockReceipt.ID).Return(&mockReceipt, nil)
+	mockReceiptService.EXPECT().GetReceiptPoints(gomock.Any(), &mockReceipt).Return(0, service.ErrReceiptRejected)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/receipts/:id/points", NewReceiptHandler(mockReceiptService).GetPoints)
+
+	req := httptest.NewRequest("GET", fmt.Sprintf("/receipts/%s/points", mockReceipt.ID), nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusConflict, resp.Code)
+	assert.JSONEq(t, `{"status":"rejected","reason":"duplicate submission"}`, resp.Body.String())
+}
+
 func buildRandomReceipt(isNewReceipt bool, retailer string) models.Receipt {
 	id := uuid.New()
 	if isNewReceipt {
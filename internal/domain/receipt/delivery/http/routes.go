@@ -1,8 +1,39 @@
 package http
 
-import "github.com/gin-gonic/gin"
+import (
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/middleware"
+	"github.com/gin-gonic/gin"
+)
 
-func MapReceiptRoutes(routesGroup *gin.RouterGroup, handler ReceiptHandler) {
-	routesGroup.POST("/process", handler.Create)
-	routesGroup.GET("/:id/points", handler.GetPoints)
+const (
+	// ScopeReceiptsWrite allows submitting receipts for processing.
+	ScopeReceiptsWrite = "receipts:write"
+	// ScopeReceiptsRead allows reading receipts and their points.
+	ScopeReceiptsRead = "receipts:read"
+)
+
+// MapReceiptRoutes expects the group to be authenticated already; each route declares its scopes.
+func MapReceiptRoutes(routesGroup *gin.RouterGroup, handler ReceiptHandler, guard middleware.ScopeGuard) {
+	routesGroup.POST("/process", guard.Require(ScopeReceiptsWrite), handler.Create)
+	routesGroup.GET("/:id/points", guard.Require(ScopeReceiptsRead), handler.GetPoints)
+}
+
+// MapStreamRoutes exposes the live feed of receipts under the same scope as reading them.
+func MapStreamRoutes(routesGroup *gin.RouterGroup, handler StreamHandler, guard middleware.ScopeGuard) {
+	routesGroup.GET("/stream", guard.Require(ScopeReceiptsRead), handler.Stream)
+}
+
+// MapExportRoutes exposes the bulk export under the same scope as reading receipts.
+func MapExportRoutes(routesGroup *gin.RouterGroup, handler ExportHandler, guard middleware.ScopeGuard) {
+	routesGroup.GET("/export", guard.Require(ScopeReceiptsRead), handler.Export)
+}
+
+// MapInvoiceRoutes accepts receipts in electronic invoice formats under the scope of submitting them.
+func MapInvoiceRoutes(routesGroup *gin.RouterGroup, handler InvoiceHandler, guard middleware.ScopeGuard) {
+	routesGroup.POST("/cfdi", guard.Require(ScopeReceiptsWrite), handler.CreateFromCFDI)
+}
+
+// MapParseRoutes exposes the receipt text parser to the clients that submit receipts.
+func MapParseRoutes(routesGroup *gin.RouterGroup, handler ParseHandler, guard middleware.ScopeGuard) {
+	routesGroup.POST("/parse", guard.Require(ScopeReceiptsWrite), handler.Parse)
 }
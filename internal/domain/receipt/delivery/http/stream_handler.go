@@ -0,0 +1,131 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/dto"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/stream"
+	"github.com/CarlosMtz98/receipt-processor-challenge/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LastEventIDHeader is sent by reconnecting EventSource clients with the last id they received.
+const LastEventIDHeader = "Last-Event-ID"
+
+const keepAliveInterval = 15 * time.Second
+
+type StreamHandler interface {
+	Stream(c *gin.Context)
+}
+
+type StreamHandlerImpl struct {
+	broker    *stream.Broker
+	keepAlive time.Duration
+}
+
+func NewStreamHandler(broker *stream.Broker) StreamHandler {
+	return &StreamHandlerImpl{
+		broker:    broker,
+		keepAlive: keepAliveInterval,
+	}
+}
+
+// Stream sends the created and scored receipts as Server-Sent Events until the client goes
+// away. The event id is the event sequence, which clients send back to resume.
+func (h StreamHandlerImpl) Stream(c *gin.Context) {
+	var lastSequence int64
+	if lastEventID := c.GetHeader(LastEventIDHeader); lastEventID != "" {
+		parsed, err := strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil || parsed < 0 {
+			utils.HandleBadRequest(c, "Invalid Last-Event-ID", err)
+			return
+		}
+		lastSequence = parsed
+	}
+
+	subscription, replay := h.broker.Subscribe(lastSequence, streamFilter(c.QueryArray("retailer")))
+	defer h.broker.Unsubscribe(subscription)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	for _, event := range replay {
+		if err := writeEvent(c.Writer, event); err != nil {
+			return
+		}
+	}
+	c.Writer.Flush()
+
+	ticker := time.NewTicker(h.keepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case event, ok := <-subscription.Events:
+			if !ok {
+				// The client fell behind; it resumes from the replay buffer when it reconnects.
+				return
+			}
+			if err := writeEvent(c.Writer, event); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if _, err := io.WriteString(c.Writer, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		}
+		c.Writer.Flush()
+	}
+}
+
+// streamFilter keeps the created and scored events of the given retailers, by their canonical
+// names or the names printed on the receipts, or of every retailer when none is given.
+func streamFilter(retailers []string) stream.Filter {
+	return func(event *models.ReceiptEvent) bool {
+		if event.Type != models.ReceiptCreated && event.Type != models.ReceiptScored {
+			return false
+		}
+		if len(retailers) == 0 {
+			return true
+		}
+		for _, retailer := range retailers {
+			retailer = strings.TrimSpace(retailer)
+			if strings.EqualFold(retailer, event.Retailer) || (event.RetailerName != "" && strings.EqualFold(retailer, event.RetailerName)) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func writeEvent(w io.Writer, event *models.ReceiptEvent) error {
+	data := dto.ReceiptStreamEvent{
+		ID:       event.ReceiptID.String(),
+		Type:     string(event.ReceiptType),
+		Retailer: event.Retailer,
+		Total:    event.Total,
+		Currency: event.Currency,
+		Points:   event.Points,
+	}
+	if event.OriginalID != nil {
+		data.OriginalID = event.OriginalID.String()
+	}
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.Sequence, event.Type, encoded)
+	return err
+}
@@ -0,0 +1,107 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/dto"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/middleware"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/stream"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readEvent reads the fields of the next event, skipping comments.
+func readEvent(t *testing.T, reader *bufio.Reader) map[string]string {
+	fields := make(map[string]string)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			if len(fields) > 0 {
+				return fields
+			}
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		key, value, _ := strings.Cut(line, ": ")
+		fields[key] = value
+	}
+}
+
+func TestStreamHandlerImpl_Stream(t *testing.T) {
+	broker := stream.NewBroker(10, stream.DefaultClientBuffer)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	MapStreamRoutes(router.Group("/receipts"), NewStreamHandler(broker), middleware.AllowAll{})
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	target := &models.Receipt{ID: uuid.New(), Retailer: "Target", Total: "35.35"}
+	walmart := &models.Receipt{ID: uuid.New(), Retailer: "Walmart", Total: "9.00"}
+	points := 28
+	publish := func(sequence int64, eventType models.ReceiptEventType, receipt *models.Receipt) {
+		event := models.NewReceiptEvent(eventType, receipt, time.Now())
+		event.Sequence = sequence
+		if eventType == models.ReceiptScored {
+			event.Points = &points
+		}
+		assert.NoError(t, broker.Publish(context.Background(), event))
+	}
+	publish(1, models.ReceiptCreated, target)
+	publish(2, models.ReceiptCreated, walmart)
+	publish(3, models.ReceiptScored, target)
+	publish(4, models.ReceiptRejected, target)
+
+	t.Run("Resume and follow", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		req, _ := http.NewRequestWithContext(ctx, "GET", server.URL+"/receipts/stream?retailer=target", nil)
+		req.Header.Set(LastEventIDHeader, "1")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+		reader := bufio.NewReader(resp.Body)
+
+		replayed := readEvent(t, reader)
+		assert.Equal(t, "3", replayed["id"])
+		assert.Equal(t, "receipt.scored", replayed["event"])
+		var data dto.ReceiptStreamEvent
+		assert.NoError(t, json.Unmarshal([]byte(replayed["data"]), &data))
+		assert.Equal(t, dto.ReceiptStreamEvent{ID: target.ID.String(), Type: "purchase", Retailer: "Target", Total: "35.35", Currency: "USD", Points: &points}, data)
+
+		publish(5, models.ReceiptCreated, walmart)
+		publish(6, models.ReceiptCreated, target)
+		live := readEvent(t, reader)
+		assert.Equal(t, "6", live["id"])
+		assert.Equal(t, "receipt.created", live["event"])
+	})
+
+	t.Run("Invalid Last-Event-ID", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", server.URL+"/receipts/stream", nil)
+		req.Header.Set(LastEventIDHeader, "abc")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
@@ -50,6 +50,20 @@ func (mr *MockReceiptRepositoryMockRecorder) Create(ctx, receipt interface{}) *g
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockReceiptRepository)(nil).Create), ctx, receipt)
 }
 
+// CreateWithEvents mocks base method.
+func (m *MockReceiptRepository) CreateWithEvents(ctx context.Context, receipt *models.Receipt, events []*models.ReceiptEvent) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateWithEvents", ctx, receipt, events)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateWithEvents indicates an expected call of CreateWithEvents.
+func (mr *MockReceiptRepositoryMockRecorder) CreateWithEvents(ctx, receipt, events interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateWithEvents", reflect.TypeOf((*MockReceiptRepository)(nil).CreateWithEvents), ctx, receipt, events)
+}
+
 // GetByID mocks base method.
 func (m *MockReceiptRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Receipt, error) {
 	m.ctrl.T.Helper()
@@ -64,3 +78,187 @@ func (mr *MockReceiptRepositoryMockRecorder) GetByID(ctx, id interface{}) *gomoc
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByID", reflect.TypeOf((*MockReceiptRepository)(nil).GetByID), ctx, id)
 }
+
+// GetByIDs mocks base method.
+func (m *MockReceiptRepository) GetByIDs(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]*models.Receipt, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByIDs", ctx, ids)
+	ret0, _ := ret[0].(map[uuid.UUID]*models.Receipt)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByIDs indicates an expected call of GetByIDs.
+func (mr *MockReceiptRepositoryMockRecorder) GetByIDs(ctx, ids interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByIDs", reflect.TypeOf((*MockReceiptRepository)(nil).GetByIDs), ctx, ids)
+}
+
+// List mocks base method.
+func (m *MockReceiptRepository) List(ctx context.Context) ([]*models.Receipt, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx)
+	ret0, _ := ret[0].([]*models.Receipt)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockReceiptRepositoryMockRecorder) List(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockReceiptRepository)(nil).List), ctx)
+}
+
+// ListByStatus mocks base method.
+func (m *MockReceiptRepository) ListByStatus(ctx context.Context, status models.ReceiptStatus) ([]*models.Receipt, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListByStatus", ctx, status)
+	ret0, _ := ret[0].([]*models.Receipt)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListByStatus indicates an expected call of ListByStatus.
+func (mr *MockReceiptRepositoryMockRecorder) ListByStatus(ctx, status interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListByStatus", reflect.TypeOf((*MockReceiptRepository)(nil).ListByStatus), ctx, status)
+}
+
+// ListPendingEvents mocks base method.
+func (m *MockReceiptRepository) ListPendingEvents(ctx context.Context, limit int) ([]*models.ReceiptEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListPendingEvents", ctx, limit)
+	ret0, _ := ret[0].([]*models.ReceiptEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListPendingEvents indicates an expected call of ListPendingEvents.
+func (mr *MockReceiptRepositoryMockRecorder) ListPendingEvents(ctx, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListPendingEvents", reflect.TypeOf((*MockReceiptRepository)(nil).ListPendingEvents), ctx, limit)
+}
+
+// ListReturns mocks base method.
+func (m *MockReceiptRepository) ListReturns(ctx context.Context, originalID uuid.UUID) ([]*models.Receipt, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListReturns", ctx, originalID)
+	ret0, _ := ret[0].([]*models.Receipt)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListReturns indicates an expected call of ListReturns.
+func (mr *MockReceiptRepositoryMockRecorder) ListReturns(ctx, originalID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListReturns", reflect.TypeOf((*MockReceiptRepository)(nil).ListReturns), ctx, originalID)
+}
+
+// ListReturnsByOriginalIDs mocks base method.
+func (m *MockReceiptRepository) ListReturnsByOriginalIDs(ctx context.Context, originalIDs []uuid.UUID) (map[uuid.UUID][]*models.Receipt, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListReturnsByOriginalIDs", ctx, originalIDs)
+	ret0, _ := ret[0].(map[uuid.UUID][]*models.Receipt)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListReturnsByOriginalIDs indicates an expected call of ListReturnsByOriginalIDs.
+func (mr *MockReceiptRepositoryMockRecorder) ListReturnsByOriginalIDs(ctx, originalIDs interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListReturnsByOriginalIDs", reflect.TypeOf((*MockReceiptRepository)(nil).ListReturnsByOriginalIDs), ctx, originalIDs)
+}
+
+// MarkEventPublished mocks base method.
+func (m *MockReceiptRepository) MarkEventPublished(ctx context.Context, id uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkEventPublished", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkEventPublished indicates an expected call of MarkEventPublished.
+func (mr *MockReceiptRepositoryMockRecorder) MarkEventPublished(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkEventPublished", reflect.TypeOf((*MockReceiptRepository)(nil).MarkEventPublished), ctx, id)
+}
+
+// Update mocks base method.
+func (m *MockReceiptRepository) Update(ctx context.Context, receipt *models.Receipt) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", ctx, receipt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockReceiptRepositoryMockRecorder) Update(ctx, receipt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockReceiptRepository)(nil).Update), ctx, receipt)
+}
+
+// UpdateWithEvents mocks base method.
+func (m *MockReceiptRepository) UpdateWithEvents(ctx context.Context, receipt *models.Receipt, events []*models.ReceiptEvent) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateWithEvents", ctx, receipt, events)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateWithEvents indicates an expected call of UpdateWithEvents.
+func (mr *MockReceiptRepositoryMockRecorder) UpdateWithEvents(ctx, receipt, events interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateWithEvents", reflect.TypeOf((*MockReceiptRepository)(nil).UpdateWithEvents), ctx, receipt, events)
+}
+
+// MockOutboxRepository is a mock of OutboxRepository interface.
+type MockOutboxRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockOutboxRepositoryMockRecorder
+}
+
+// MockOutboxRepositoryMockRecorder is the mock recorder for MockOutboxRepository.
+type MockOutboxRepositoryMockRecorder struct {
+	mock *MockOutboxRepository
+}
+
+// NewMockOutboxRepository creates a new mock instance.
+func NewMockOutboxRepository(ctrl *gomock.Controller) *MockOutboxRepository {
+	mock := &MockOutboxRepository{ctrl: ctrl}
+	mock.recorder = &MockOutboxRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOutboxRepository) EXPECT() *MockOutboxRepositoryMockRecorder {
+	return m.recorder
+}
+
+// ListPendingEvents mocks base method.
+func (m *MockOutboxRepository) ListPendingEvents(ctx context.Context, limit int) ([]*models.ReceiptEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListPendingEvents", ctx, limit)
+	ret0, _ := ret[0].([]*models.ReceiptEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListPendingEvents indicates an expected call of ListPendingEvents.
+func (mr *MockOutboxRepositoryMockRecorder) ListPendingEvents(ctx, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListPendingEvents", reflect.TypeOf((*MockOutboxRepository)(nil).ListPendingEvents), ctx, limit)
+}
+
+// MarkEventPublished mocks base method.
+func (m *MockOutboxRepository) MarkEventPublished(ctx context.Context, id uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkEventPublished", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkEventPublished indicates an expected call of MarkEventPublished.
+func (mr *MockOutboxRepositoryMockRecorder) MarkEventPublished(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkEventPublished", reflect.TypeOf((*MockOutboxRepository)(nil).MarkEventPublished), ctx, id)
+}
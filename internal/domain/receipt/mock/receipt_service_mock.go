@@ -80,3 +80,107 @@ func (mr *MockReceiptServiceMockRecorder) GetReceiptPoints(ctx, receipt interfac
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetReceiptPoints", reflect.TypeOf((*MockReceiptService)(nil).GetReceiptPoints), ctx, receipt)
 }
+
+// GetReceiptPointsBreakdown mocks base method.
+func (m *MockReceiptService) GetReceiptPointsBreakdown(ctx context.Context, receipt *models.Receipt) (*models.PointsBreakdown, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetReceiptPointsBreakdown", ctx, receipt)
+	ret0, _ := ret[0].(*models.PointsBreakdown)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetReceiptPointsBreakdown indicates an expected call of GetReceiptPointsBreakdown.
+func (mr *MockReceiptServiceMockRecorder) GetReceiptPointsBreakdown(ctx, receipt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetReceiptPointsBreakdown", reflect.TypeOf((*MockReceiptService)(nil).GetReceiptPointsBreakdown), ctx, receipt)
+}
+
+// GetReceiptsByIDs mocks base method.
+func (m *MockReceiptService) GetReceiptsByIDs(ctx context.Context, receiptIDs []uuid.UUID) (map[uuid.UUID]*models.Receipt, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetReceiptsByIDs", ctx, receiptIDs)
+	ret0, _ := ret[0].(map[uuid.UUID]*models.Receipt)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetReceiptsByIDs indicates an expected call of GetReceiptsByIDs.
+func (mr *MockReceiptServiceMockRecorder) GetReceiptsByIDs(ctx, receiptIDs interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetReceiptsByIDs", reflect.TypeOf((*MockReceiptService)(nil).GetReceiptsByIDs), ctx, receiptIDs)
+}
+
+// GetReturnedPoints mocks base method.
+func (m *MockReceiptService) GetReturnedPoints(ctx context.Context, receipt *models.Receipt) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetReturnedPoints", ctx, receipt)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetReturnedPoints indicates an expected call of GetReturnedPoints.
+func (mr *MockReceiptServiceMockRecorder) GetReturnedPoints(ctx, receipt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetReturnedPoints", reflect.TypeOf((*MockReceiptService)(nil).GetReturnedPoints), ctx, receipt)
+}
+
+// GetReturnsByOriginalIDs mocks base method.
+func (m *MockReceiptService) GetReturnsByOriginalIDs(ctx context.Context, originalIDs []uuid.UUID) (map[uuid.UUID][]*models.Receipt, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetReturnsByOriginalIDs", ctx, originalIDs)
+	ret0, _ := ret[0].(map[uuid.UUID][]*models.Receipt)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetReturnsByOriginalIDs indicates an expected call of GetReturnsByOriginalIDs.
+func (mr *MockReceiptServiceMockRecorder) GetReturnsByOriginalIDs(ctx, originalIDs interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetReturnsByOriginalIDs", reflect.TypeOf((*MockReceiptService)(nil).GetReturnsByOriginalIDs), ctx, originalIDs)
+}
+
+// GetReturnsPoints mocks base method.
+func (m *MockReceiptService) GetReturnsPoints(ctx context.Context, purchase *models.Receipt, returns []*models.Receipt) ([]int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetReturnsPoints", ctx, purchase, returns)
+	ret0, _ := ret[0].([]int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetReturnsPoints indicates an expected call of GetReturnsPoints.
+func (mr *MockReceiptServiceMockRecorder) GetReturnsPoints(ctx, purchase, returns interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetReturnsPoints", reflect.TypeOf((*MockReceiptService)(nil).GetReturnsPoints), ctx, purchase, returns)
+}
+
+// ListReceipts mocks base method.
+func (m *MockReceiptService) ListReceipts(ctx context.Context, filter models.ReceiptFilter) ([]*models.Receipt, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListReceipts", ctx, filter)
+	ret0, _ := ret[0].([]*models.Receipt)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListReceipts indicates an expected call of ListReceipts.
+func (mr *MockReceiptServiceMockRecorder) ListReceipts(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListReceipts", reflect.TypeOf((*MockReceiptService)(nil).ListReceipts), ctx, filter)
+}
+
+// ValidateReceipt mocks base method.
+func (m *MockReceiptService) ValidateReceipt(ctx context.Context, receipt *models.Receipt) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ValidateReceipt", ctx, receipt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ValidateReceipt indicates an expected call of ValidateReceipt.
+func (mr *MockReceiptServiceMockRecorder) ValidateReceipt(ctx, receipt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidateReceipt", reflect.TypeOf((*MockReceiptService)(nil).ValidateReceipt), ctx, receipt)
+}
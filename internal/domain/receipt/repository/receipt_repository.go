@@ -5,7 +5,9 @@ import (
 	"errors"
 	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
 	"github.com/google/uuid"
+	"sort"
 	"sync"
+	"time"
 )
 
 var (
@@ -13,38 +15,107 @@ var (
 	ErrReceiptNotFound = errors.New("the receipt was not found in the repository")
 	// ErrFailedToAddReceipt is returned when the receipt could not be added to the repository.
 	ErrFailedToAddReceipt = errors.New("failed to add a new receipt to the repository")
+	// ErrEventNotFound is returned when an outbox event is not found.
+	ErrEventNotFound = errors.New("the event was not found in the outbox")
+	// ErrDuplicateInvoice is returned when the invoice of a receipt was already submitted.
+	ErrDuplicateInvoice = errors.New("the invoice was already submitted")
 )
 
 type ReceiptRepository interface {
 	Create(ctx context.Context, receipt *models.Receipt) error
+	// CreateWithEvents stores the receipt and appends the events to the outbox atomically. It
+	// fails with ErrDuplicateInvoice when a stored receipt has the same invoice.
+	CreateWithEvents(ctx context.Context, receipt *models.Receipt, events []*models.ReceiptEvent) error
 	GetByID(ctx context.Context, id uuid.UUID) (*models.Receipt, error)
+	// GetByIDs returns the receipts found among the ids, keyed by id; missing ids are left out.
+	GetByIDs(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]*models.Receipt, error)
+	Update(ctx context.Context, receipt *models.Receipt) error
+	// UpdateWithEvents replaces the receipt and appends the events to the outbox atomically.
+	UpdateWithEvents(ctx context.Context, receipt *models.Receipt, events []*models.ReceiptEvent) error
+	ListByStatus(ctx context.Context, status models.ReceiptStatus) ([]*models.Receipt, error)
+	// List returns every receipt, oldest first.
+	List(ctx context.Context) ([]*models.Receipt, error)
+	// ListReturns returns the return receipts of the purchase, oldest first.
+	ListReturns(ctx context.Context, originalID uuid.UUID) ([]*models.Receipt, error)
+	// ListReturnsByOriginalIDs returns the return receipts of every purchase, oldest first,
+	// keyed by purchase id; purchases without returns are left out.
+	ListReturnsByOriginalIDs(ctx context.Context, originalIDs []uuid.UUID) (map[uuid.UUID][]*models.Receipt, error)
+	OutboxRepository
+}
+
+// OutboxRepository exposes the events written alongside the receipts so that they can be
+// relayed to subscribers once committed.
+type OutboxRepository interface {
+	// ListPendingEvents returns the events not yet marked as published, in sequence order.
+	ListPendingEvents(ctx context.Context, limit int) ([]*models.ReceiptEvent, error)
+	MarkEventPublished(ctx context.Context, id uuid.UUID) error
+}
+
+type outboxEntry struct {
+	event       *models.ReceiptEvent
+	publishedAt *time.Time
 }
 
 type InMemoryReceiptRepository struct {
 	mu       sync.RWMutex
 	receipts map[uuid.UUID]*models.Receipt
+	// invoices indexes the receipts by the keys of their invoice.
+	invoices map[string]uuid.UUID
+	// returns indexes the return receipts by the purchase they refer to.
+	returns map[uuid.UUID][]uuid.UUID
+	outbox  []*outboxEntry
+	// pendingFrom is the index of the oldest entry that may still be unpublished.
+	pendingFrom int
 }
 
 func InitReceiptRepository() ReceiptRepository {
 	return &InMemoryReceiptRepository{
 		receipts: make(map[uuid.UUID]*models.Receipt),
+		invoices: make(map[string]uuid.UUID),
+		returns:  make(map[uuid.UUID][]uuid.UUID),
 	}
 }
 
 func (memoryRepo *InMemoryReceiptRepository) Create(ctx context.Context, receipt *models.Receipt) error {
+	return memoryRepo.CreateWithEvents(ctx, receipt, nil)
+}
+
+func (memoryRepo *InMemoryReceiptRepository) CreateWithEvents(ctx context.Context, receipt *models.Receipt, events []*models.ReceiptEvent) error {
+	memoryRepo.mu.Lock()
+	defer memoryRepo.mu.Unlock()
+
 	if memoryRepo.receipts == nil {
-		memoryRepo.mu.Lock()
 		memoryRepo.receipts = make(map[uuid.UUID]*models.Receipt)
-		memoryRepo.mu.Unlock()
+	}
+	if memoryRepo.invoices == nil {
+		memoryRepo.invoices = make(map[string]uuid.UUID)
+	}
+	if memoryRepo.returns == nil {
+		memoryRepo.returns = make(map[uuid.UUID][]uuid.UUID)
 	}
 
 	if _, ok := memoryRepo.receipts[receipt.ID]; ok {
 		return ErrFailedToAddReceipt
 	}
 
-	memoryRepo.mu.Lock()
+	var invoiceKeys []string
+	if receipt.Invoice != nil {
+		invoiceKeys = receipt.Invoice.Keys()
+	}
+	for _, key := range invoiceKeys {
+		if _, ok := memoryRepo.invoices[key]; ok {
+			return ErrDuplicateInvoice
+		}
+	}
+
 	memoryRepo.receipts[receipt.ID] = receipt
-	memoryRepo.mu.Unlock()
+	for _, key := range invoiceKeys {
+		memoryRepo.invoices[key] = receipt.ID
+	}
+	if receipt.OriginalID != nil {
+		memoryRepo.returns[*receipt.OriginalID] = append(memoryRepo.returns[*receipt.OriginalID], receipt.ID)
+	}
+	memoryRepo.appendEvents(events)
 	return nil
 }
 
@@ -58,3 +129,153 @@ func (memoryRepo *InMemoryReceiptRepository) GetByID(ctx context.Context, id uui
 
 	return nil, ErrReceiptNotFound
 }
+
+func (memoryRepo *InMemoryReceiptRepository) GetByIDs(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]*models.Receipt, error) {
+	memoryRepo.mu.RLock()
+	defer memoryRepo.mu.RUnlock()
+
+	receipts := make(map[uuid.UUID]*models.Receipt, len(ids))
+	for _, id := range ids {
+		if receipt, ok := memoryRepo.receipts[id]; ok {
+			receipts[id] = receipt
+		}
+	}
+
+	return receipts, nil
+}
+
+func (memoryRepo *InMemoryReceiptRepository) Update(ctx context.Context, receipt *models.Receipt) error {
+	return memoryRepo.UpdateWithEvents(ctx, receipt, nil)
+}
+
+func (memoryRepo *InMemoryReceiptRepository) UpdateWithEvents(ctx context.Context, receipt *models.Receipt, events []*models.ReceiptEvent) error {
+	memoryRepo.mu.Lock()
+	defer memoryRepo.mu.Unlock()
+
+	if _, ok := memoryRepo.receipts[receipt.ID]; !ok {
+		return ErrReceiptNotFound
+	}
+
+	memoryRepo.receipts[receipt.ID] = receipt
+	memoryRepo.appendEvents(events)
+	return nil
+}
+
+// ListByStatus returns the receipts with the given status, oldest first.
+func (memoryRepo *InMemoryReceiptRepository) ListByStatus(ctx context.Context, status models.ReceiptStatus) ([]*models.Receipt, error) {
+	memoryRepo.mu.RLock()
+	defer memoryRepo.mu.RUnlock()
+
+	receipts := make([]*models.Receipt, 0)
+	for _, receipt := range memoryRepo.receipts {
+		if receipt.Status == status {
+			receipts = append(receipts, receipt)
+		}
+	}
+
+	sortByCreatedAt(receipts)
+	return receipts, nil
+}
+
+func (memoryRepo *InMemoryReceiptRepository) List(ctx context.Context) ([]*models.Receipt, error) {
+	memoryRepo.mu.RLock()
+	defer memoryRepo.mu.RUnlock()
+
+	receipts := make([]*models.Receipt, 0, len(memoryRepo.receipts))
+	for _, receipt := range memoryRepo.receipts {
+		receipts = append(receipts, receipt)
+	}
+
+	sortByCreatedAt(receipts)
+	return receipts, nil
+}
+
+func (memoryRepo *InMemoryReceiptRepository) ListReturns(ctx context.Context, originalID uuid.UUID) ([]*models.Receipt, error) {
+	memoryRepo.mu.RLock()
+	defer memoryRepo.mu.RUnlock()
+
+	receipts := make([]*models.Receipt, 0, len(memoryRepo.returns[originalID]))
+	for _, id := range memoryRepo.returns[originalID] {
+		receipts = append(receipts, memoryRepo.receipts[id])
+	}
+
+	sortByCreatedAt(receipts)
+	return receipts, nil
+}
+
+func (memoryRepo *InMemoryReceiptRepository) ListReturnsByOriginalIDs(ctx context.Context, originalIDs []uuid.UUID) (map[uuid.UUID][]*models.Receipt, error) {
+	memoryRepo.mu.RLock()
+	defer memoryRepo.mu.RUnlock()
+
+	returns := make(map[uuid.UUID][]*models.Receipt, len(originalIDs))
+	for _, originalID := range originalIDs {
+		ids := memoryRepo.returns[originalID]
+		if len(ids) == 0 {
+			continue
+		}
+		receipts := make([]*models.Receipt, 0, len(ids))
+		for _, id := range ids {
+			receipts = append(receipts, memoryRepo.receipts[id])
+		}
+		sortByCreatedAt(receipts)
+		returns[originalID] = receipts
+	}
+
+	return returns, nil
+}
+
+func (memoryRepo *InMemoryReceiptRepository) ListPendingEvents(ctx context.Context, limit int) ([]*models.ReceiptEvent, error) {
+	memoryRepo.mu.RLock()
+	defer memoryRepo.mu.RUnlock()
+
+	events := make([]*models.ReceiptEvent, 0)
+	for _, entry := range memoryRepo.outbox[memoryRepo.pendingFrom:] {
+		if limit > 0 && len(events) >= limit {
+			break
+		}
+		if entry.publishedAt == nil {
+			events = append(events, entry.event)
+		}
+	}
+	return events, nil
+}
+
+func (memoryRepo *InMemoryReceiptRepository) MarkEventPublished(ctx context.Context, id uuid.UUID) error {
+	memoryRepo.mu.Lock()
+	defer memoryRepo.mu.Unlock()
+
+	for _, entry := range memoryRepo.outbox[memoryRepo.pendingFrom:] {
+		if entry.event.ID != id {
+			continue
+		}
+
+		if entry.publishedAt == nil {
+			now := time.Now()
+			entry.publishedAt = &now
+		}
+		for memoryRepo.pendingFrom < len(memoryRepo.outbox) && memoryRepo.outbox[memoryRepo.pendingFrom].publishedAt != nil {
+			memoryRepo.pendingFrom++
+		}
+		return nil
+	}
+
+	return ErrEventNotFound
+}
+
+// appendEvents must be called with the write lock held. Events are numbered in commit order.
+func (memoryRepo *InMemoryReceiptRepository) appendEvents(events []*models.ReceiptEvent) {
+	for _, event := range events {
+		event.Sequence = int64(len(memoryRepo.outbox) + 1)
+		memoryRepo.outbox = append(memoryRepo.outbox, &outboxEntry{event: event})
+	}
+}
+
+// sortByCreatedAt orders the receipts oldest first, breaking ties by ID so that pages are stable.
+func sortByCreatedAt(receipts []*models.Receipt) {
+	sort.Slice(receipts, func(i, j int) bool {
+		if receipts[i].CreatedAt.Equal(receipts[j].CreatedAt) {
+			return receipts[i].ID.String() < receipts[j].ID.String()
+		}
+		return receipts[i].CreatedAt.Before(receipts[j].CreatedAt)
+	})
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"sync"
 	"testing"
+	"time"
 )
 
 func TestInMemoryReceiptRepository(t *testing.T) {
@@ -25,6 +26,19 @@ func TestInMemoryReceiptRepository(t *testing.T) {
 		assert.Equal(t, receipt, retrievedReceipt)
 	})
 
+	t.Run("GetByIDs skips missing receipts", func(t *testing.T) {
+		repo := InitReceiptRepository()
+
+		receipt := &models.Receipt{
+			ID: uuid.New(),
+		}
+		assert.NoError(t, repo.Create(context.Background(), receipt))
+
+		receipts, err := repo.GetByIDs(context.Background(), []uuid.UUID{receipt.ID, uuid.New()})
+		assert.NoError(t, err)
+		assert.Equal(t, map[uuid.UUID]*models.Receipt{receipt.ID: receipt}, receipts)
+	})
+
 	t.Run("Create Duplicate", func(t *testing.T) {
 		repo := InitReceiptRepository().(*InMemoryReceiptRepository)
 
@@ -39,6 +53,69 @@ func TestInMemoryReceiptRepository(t *testing.T) {
 		assert.Error(t, err)
 		assert.Equal(t, ErrFailedToAddReceipt, err)
 	})
+
+	t.Run("Create Duplicate Invoice", func(t *testing.T) {
+		repo := InitReceiptRepository()
+		invoice := func(uuid, folio string) *models.InvoiceReference {
+			return &models.InvoiceReference{Format: models.InvoiceFormatCFDI, Issuer: "EKU9003173C9", Series: "A", Number: folio, UUID: uuid}
+		}
+
+		first := &models.Receipt{ID: uuid.New(), Invoice: invoice("5fb2822e-396d-4725-8521-cdc4bdd20ccf", "100")}
+		assert.NoError(t, repo.Create(context.Background(), first))
+
+		sameUUID := &models.Receipt{ID: uuid.New(), Invoice: invoice("5FB2822E-396D-4725-8521-CDC4BDD20CCF", "101")}
+		assert.Equal(t, ErrDuplicateInvoice, repo.Create(context.Background(), sameUUID))
+
+		sameFolio := &models.Receipt{ID: uuid.New(), Invoice: invoice("0b9c3e47-7b5a-4f6e-9d55-6bdb2a1f4f0e", "100")}
+		assert.Equal(t, ErrDuplicateInvoice, repo.Create(context.Background(), sameFolio))
+
+		other := &models.Receipt{ID: uuid.New(), Invoice: invoice("0b9c3e47-7b5a-4f6e-9d55-6bdb2a1f4f0e", "101")}
+		assert.NoError(t, repo.Create(context.Background(), other))
+		_, err := repo.GetByID(context.Background(), sameFolio.ID)
+		assert.Equal(t, ErrReceiptNotFound, err)
+	})
+}
+
+func TestInMemoryReceiptRepository_ListByStatus(t *testing.T) {
+	repo := InitReceiptRepository()
+	now := time.Now()
+
+	newer := &models.Receipt{ID: uuid.New(), Status: models.ReceiptStatusHeld, CreatedAt: now}
+	older := &models.Receipt{ID: uuid.New(), Status: models.ReceiptStatusHeld, CreatedAt: now.Add(-time.Hour)}
+	processed := &models.Receipt{ID: uuid.New(), Status: models.ReceiptStatusProcessed, CreatedAt: now}
+	for _, receipt := range []*models.Receipt{newer, older, processed} {
+		assert.NoError(t, repo.Create(context.Background(), receipt))
+	}
+
+	held, err := repo.ListByStatus(context.Background(), models.ReceiptStatusHeld)
+	assert.NoError(t, err)
+	assert.Equal(t, []*models.Receipt{older, newer}, held)
+}
+
+func TestInMemoryReceiptRepository_ListReturns(t *testing.T) {
+	repo := InitReceiptRepository()
+	now := time.Now()
+
+	purchase := &models.Receipt{ID: uuid.New(), CreatedAt: now.Add(-time.Hour)}
+	second := &models.Receipt{ID: uuid.New(), Type: models.ReceiptTypeReturn, OriginalID: &purchase.ID, CreatedAt: now}
+	first := &models.Receipt{ID: uuid.New(), Type: models.ReceiptTypeReturn, OriginalID: &purchase.ID, CreatedAt: now.Add(-time.Minute)}
+	otherID := uuid.New()
+	other := &models.Receipt{ID: uuid.New(), Type: models.ReceiptTypeReturn, OriginalID: &otherID, CreatedAt: now}
+	for _, receipt := range []*models.Receipt{purchase, second, first, other} {
+		assert.NoError(t, repo.Create(context.Background(), receipt))
+	}
+
+	returns, err := repo.ListReturns(context.Background(), purchase.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []*models.Receipt{first, second}, returns)
+
+	returns, err = repo.ListReturns(context.Background(), first.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, returns)
+
+	byPurchase, err := repo.ListReturnsByOriginalIDs(context.Background(), []uuid.UUID{purchase.ID, otherID, first.ID})
+	assert.NoError(t, err)
+	assert.Equal(t, map[uuid.UUID][]*models.Receipt{purchase.ID: {first, second}, otherID: {other}}, byPurchase)
 }
 
 func TestInMemoryReceiptRepository_EdgeCases(t *testing.T) {
@@ -73,3 +150,42 @@ func TestInMemoryReceiptRepository_EdgeCases(t *testing.T) {
 		assert.Equal(t, ErrReceiptNotFound, err)
 	})
 }
+
+func TestInMemoryReceiptRepository_Outbox(t *testing.T) {
+	ctx := context.Background()
+	repo := InitReceiptRepository()
+
+	receipt := &models.Receipt{ID: uuid.New()}
+	created := models.NewReceiptEvent(models.ReceiptCreated, receipt, time.Now())
+	assert.NoError(t, repo.CreateWithEvents(ctx, receipt, []*models.ReceiptEvent{created}))
+
+	// A failed write does not leave its events behind.
+	duplicate := models.NewReceiptEvent(models.ReceiptCreated, receipt, time.Now())
+	assert.Equal(t, ErrFailedToAddReceipt, repo.CreateWithEvents(ctx, receipt, []*models.ReceiptEvent{duplicate}))
+	missing := models.NewReceiptEvent(models.ReceiptScored, &models.Receipt{ID: uuid.New()}, time.Now())
+	assert.Equal(t, ErrReceiptNotFound, repo.UpdateWithEvents(ctx, &models.Receipt{ID: missing.ReceiptID}, []*models.ReceiptEvent{missing}))
+
+	scored := models.NewReceiptEvent(models.ReceiptScored, receipt, time.Now())
+	assert.NoError(t, repo.UpdateWithEvents(ctx, receipt, []*models.ReceiptEvent{scored}))
+
+	pending, err := repo.ListPendingEvents(ctx, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []*models.ReceiptEvent{created, scored}, pending)
+	assert.Equal(t, int64(2), scored.Sequence)
+
+	pending, err = repo.ListPendingEvents(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []*models.ReceiptEvent{created}, pending)
+
+	assert.NoError(t, repo.MarkEventPublished(ctx, scored.ID))
+	pending, err = repo.ListPendingEvents(ctx, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []*models.ReceiptEvent{created}, pending)
+
+	assert.NoError(t, repo.MarkEventPublished(ctx, created.ID))
+	pending, err = repo.ListPendingEvents(ctx, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, pending)
+
+	assert.Equal(t, ErrEventNotFound, repo.MarkEventPublished(ctx, uuid.New()))
+}
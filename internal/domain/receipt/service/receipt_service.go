@@ -3,11 +3,21 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
+	fraudService "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/fraud/service"
 	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
 	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/repository"
+	retailerService "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/retailer/service"
+	signatureService "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/signature/service"
+	"github.com/CarlosMtz98/receipt-processor-challenge/pkg/currency"
+	"github.com/CarlosMtz98/receipt-processor-challenge/pkg/timezone"
+	"github.com/CarlosMtz98/receipt-processor-challenge/pkg/utils"
 	"github.com/google/uuid"
 	"math"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 	"unicode"
 )
 
@@ -18,6 +28,26 @@ var (
 	ErrMissingReceiptId = errors.New("the receipt id can't be null")
 	// ErrReceiptIsNil is returned when there is no receipt passed through the param
 	ErrReceiptIsNil = errors.New("the receipt is null")
+	// ErrReceiptHeld is returned when points are requested for a receipt held for review
+	ErrReceiptHeld = errors.New("the receipt is held for review")
+	// ErrReceiptRejected is returned when points are requested for a receipt rejected by a reviewer
+	ErrReceiptRejected = errors.New("the receipt was rejected")
+	// ErrInvalidReceiptParams is returned when the receipt fields don't pass validation
+	ErrInvalidReceiptParams = errors.New("the receipt params are not valid")
+	// ErrTotalMismatch is returned when the receipt total doesn't match the sum of its items
+	ErrTotalMismatch = errors.New("the receipt total must match with the items total")
+	// ErrItemPriceMismatch is returned when the price of an item is not its quantity times its unit price
+	ErrItemPriceMismatch = errors.New("the item price must match its quantity times its unit price")
+	// ErrSubtotalMismatch is returned when the subtotal of the receipt is not the sum of its items
+	ErrSubtotalMismatch = errors.New("the receipt subtotal must match with the items total")
+	// ErrNoExchangeRate is returned when a receipt in a foreign currency cannot be converted to the base currency
+	ErrNoExchangeRate = errors.New("there is no exchange rate for the receipt currency")
+	// ErrOriginalReceiptNotFound is returned when the purchase a return refers to does not exist
+	ErrOriginalReceiptNotFound = errors.New("the original receipt of the return was not found")
+	// ErrPurchaseTimeMismatch is returned when the purchase date or time is not the local time of purchasedAt
+	ErrPurchaseTimeMismatch = errors.New("the purchase date and time must match purchasedAt")
+	// ErrInvalidReturn is returned when the items of a return were not bought on the original receipt or were already returned
+	ErrInvalidReturn = errors.New("the return does not match the original receipt")
 )
 
 const (
@@ -29,19 +59,167 @@ const (
 )
 
 type ReceiptService interface {
+	// ValidateReceipt checks a submitted receipt like the ValidateReceipt function, with the
+	// total tolerance of the service.
+	ValidateReceipt(ctx context.Context, receipt *models.Receipt) error
 	CreateReceipt(ctx context.Context, receipt *models.Receipt) (*models.Receipt, error)
 	GetReceiptByID(ctx context.Context, receiptID uuid.UUID) (*models.Receipt, error)
+	GetReceiptsByIDs(ctx context.Context, receiptIDs []uuid.UUID) (map[uuid.UUID]*models.Receipt, error)
 	GetReceiptPoints(ctx context.Context, receipt *models.Receipt) (int, error)
+	GetReceiptPointsBreakdown(ctx context.Context, receipt *models.Receipt) (*models.PointsBreakdown, error)
+	// GetReturnedPoints returns the points taken back by the returns of the purchase, zero or
+	// less, so that its net points are its points plus the returned ones.
+	GetReturnedPoints(ctx context.Context, receipt *models.Receipt) (int, error)
+	// GetReturnsByOriginalIDs loads the returns of several purchases, oldest first, with a
+	// single repository call.
+	GetReturnsByOriginalIDs(ctx context.Context, originalIDs []uuid.UUID) (map[uuid.UUID][]*models.Receipt, error)
+	// GetReturnsPoints returns the points of every return of the purchase, given all of its
+	// returns oldest first, without loading anything.
+	GetReturnsPoints(ctx context.Context, purchase *models.Receipt, returns []*models.Receipt) ([]int, error)
+	ListReceipts(ctx context.Context, filter models.ReceiptFilter) ([]*models.Receipt, error)
+}
+
+// RuleSet selects variants of the scoring rules. The zero value is the original rule set.
+type RuleSet struct {
+	// QuantityAware scores an item with a whole quantity like that many separate lines in the
+	// item_pairs and item_descriptions rules, so "3 x Gatorade" earns what three Gatorade lines do.
+	QuantityAware bool
+	// PreTax scores the round_total and total_multiple rules on the total before taxes and tip,
+	// the subtotal minus the discounts, instead of the total paid.
+	PreTax bool
+	// CanonicalRetailer scores the retailer_name rule on the name of the retailer the receipt
+	// matched in the registry instead of the name printed on it.
+	CanonicalRetailer bool
 }
 
 type ReceiptServiceImpl struct {
 	receiptRepository repository.ReceiptRepository
+	signatureSvc      signatureService.SignatureService
+	fraudSvc          fraudService.FraudService
+	retailerSvc       retailerService.RetailerService
+	ruleSet           RuleSet
+	// totalTolerance is how far, in the main unit of the currency of the receipt, the total
+	// can be from what the amounts of the receipt add up to.
+	totalTolerance float64
+	rates          *currency.Rates
+	// timeZones are the time zones of the retailers, keyed by lowercase name.
+	timeZones map[string]string
+	now       func() time.Time
+	// returnsMu serializes the creation of returns, so that two returns cannot both take back
+	// the last unit of an item.
+	returnsMu sync.Mutex
+}
+
+// Option configures optional collaborators of the receipt service.
+type Option func(*ReceiptServiceImpl)
+
+// WithSignatureService verifies POS terminal signatures when receipts are created.
+func WithSignatureService(signatureSvc signatureService.SignatureService) Option {
+	return func(s *ReceiptServiceImpl) {
+		s.signatureSvc = signatureSvc
+	}
+}
+
+// WithFraudService scores the risk of receipts on creation and holds risky ones from scoring.
+func WithFraudService(fraudSvc fraudService.FraudService) Option {
+	return func(s *ReceiptServiceImpl) {
+		s.fraudSvc = fraudSvc
+	}
+}
+
+// WithRetailerRegistry matches the receipts with the retailers of the registry on creation, so
+// that they are scored and reported under the canonical name of their retailer.
+func WithRetailerRegistry(retailerSvc retailerService.RetailerService) Option {
+	return func(s *ReceiptServiceImpl) {
+		s.retailerSvc = retailerSvc
+	}
+}
+
+// WithRuleSet scores the receipts with the given variants of the rules.
+func WithRuleSet(ruleSet RuleSet) Option {
+	return func(s *ReceiptServiceImpl) {
+		s.ruleSet = ruleSet
+	}
+}
+
+// WithExchangeRates scores the receipts in the base currency of the rates, converting the
+// amounts of the receipts in other currencies with the rate of their purchase date.
+func WithExchangeRates(rates *currency.Rates) Option {
+	return func(s *ReceiptServiceImpl) {
+		s.rates = rates
+	}
+}
+
+// WithRetailerTimeZones sets the time zone of the receipts of a retailer that don't have one,
+// keyed by the lowercase retailer name.
+func WithRetailerTimeZones(timeZones map[string]string) Option {
+	return func(s *ReceiptServiceImpl) {
+		s.timeZones = timeZones
+	}
+}
+
+// WithTotalTolerance accepts receipts whose total is at most tolerance away from the subtotal
+// minus the discounts plus the taxes and the tip, e.g. 0.01 to accept the rounding of taxes
+// computed per line.
+func WithTotalTolerance(tolerance float64) Option {
+	return func(s *ReceiptServiceImpl) {
+		s.totalTolerance = tolerance
+	}
 }
 
-func NewReceiptService(receiptRepository repository.ReceiptRepository) ReceiptService {
-	return &ReceiptServiceImpl{
+// ValidateReceipt runs the checks every submitted receipt goes through before it is created,
+// whatever the API or tool it comes from. totalTolerance is the largest difference accepted,
+// in the main unit of the currency of the receipt, between the total and what the other
+// amounts add up to.
+func ValidateReceipt(ctx context.Context, receipt *models.Receipt, totalTolerance float64) error {
+	if receipt == nil {
+		return ErrReceiptIsNil
+	}
+
+	if err := utils.ValidateStruct(ctx, receipt); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidReceiptParams, err)
+	}
+	if receipt.IsReturn() != (receipt.OriginalID != nil) {
+		return fmt.Errorf("%w: a return, and only a return, refers to an original receipt", ErrInvalidReceiptParams)
+	}
+
+	code := receipt.GetCurrency()
+	for i := range receipt.Items {
+		if consistent, err := receipt.Items[i].HasConsistentPrice(code); !consistent || err != nil {
+			return fmt.Errorf("%w: item %d", ErrItemPriceMismatch, i+1)
+		}
+	}
+
+	amounts, err := receipt.Amounts()
+	if err != nil {
+		return ErrTotalMismatch
+	}
+	if amounts.Subtotal != amounts.Items {
+		return fmt.Errorf("%w: the items add up to %s", ErrSubtotalMismatch, currency.FormatAmount(amounts.Items, code))
+	}
+	if !amounts.TotalMatches(currency.ToMinor(totalTolerance, code)) {
+		if receipt.HasAdjustments() {
+			return fmt.Errorf("%w: the subtotal minus the discounts plus the taxes and the tip is %s", ErrTotalMismatch, currency.FormatAmount(amounts.Expected(), code))
+		}
+		return ErrTotalMismatch
+	}
+	return nil
+}
+
+func NewReceiptService(receiptRepository repository.ReceiptRepository, opts ...Option) ReceiptService {
+	s := &ReceiptServiceImpl{
 		receiptRepository: receiptRepository,
+		rates:             currency.NewRates(currency.DefaultCurrency),
+		now:               time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	return s
+}
+
+func (s *ReceiptServiceImpl) ValidateReceipt(ctx context.Context, receipt *models.Receipt) error {
+	return ValidateReceipt(ctx, receipt, s.totalTolerance)
 }
 
 func (s *ReceiptServiceImpl) CreateReceipt(ctx context.Context, receipt *models.Receipt) (*models.Receipt, error) {
@@ -49,8 +227,59 @@ func (s *ReceiptServiceImpl) CreateReceipt(ctx context.Context, receipt *models.
 		return nil, ErrReceiptWithId
 	}
 
+	if err := s.matchRetailer(ctx, receipt); err != nil {
+		return nil, err
+	}
+
+	// The signature covers the receipt as it was submitted, before its time zone and its
+	// purchase date and time are filled in.
+	if s.signatureSvc != nil {
+		verification, err := s.signatureSvc.Verify(ctx, receipt)
+		if err != nil {
+			return nil, err
+		}
+		receipt.Verification = verification
+	}
+
+	if err := s.localize(receipt); err != nil {
+		return nil, err
+	}
+
+	exchange, err := s.exchangeRate(receipt)
+	if err != nil {
+		return nil, err
+	}
+	receipt.Exchange = exchange
+
+	if receipt.IsReturn() {
+		s.returnsMu.Lock()
+		defer s.returnsMu.Unlock()
+		if err := s.validateReturn(ctx, receipt); err != nil {
+			return nil, err
+		}
+	}
+
+	receipt.Status = models.ReceiptStatusProcessed
+	// Returns only take points back, there is nothing to hold them for.
+	if s.fraudSvc != nil && !receipt.IsReturn() {
+		assessment, err := s.fraudSvc.Assess(ctx, receipt)
+		if err != nil {
+			return nil, err
+		}
+		receipt.Risk = assessment
+		if assessment.Held {
+			receipt.Status = models.ReceiptStatusHeld
+		}
+	}
+
 	receipt.ID = uuid.New()
-	err := s.receiptRepository.Create(ctx, receipt)
+	receipt.CreatedAt = s.now()
+	events, err := s.lifecycleEvents(ctx, receipt)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.receiptRepository.CreateWithEvents(ctx, receipt, events)
 	if err != nil {
 		return nil, err
 	}
@@ -71,29 +300,311 @@ func (s *ReceiptServiceImpl) GetReceiptByID(ctx context.Context, id uuid.UUID) (
 	return receipt, nil
 }
 
+// GetReceiptsByIDs loads several receipts with a single repository call. Receipts that
+// don't exist are missing from the result instead of failing the whole batch.
+func (s *ReceiptServiceImpl) GetReceiptsByIDs(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]*models.Receipt, error) {
+	for _, id := range ids {
+		if id == uuid.Nil {
+			return nil, ErrMissingReceiptId
+		}
+	}
+
+	return s.receiptRepository.GetByIDs(ctx, ids)
+}
+
 func (s *ReceiptServiceImpl) GetReceiptPoints(ctx context.Context, receipt *models.Receipt) (int, error) {
+	breakdown, err := s.GetReceiptPointsBreakdown(ctx, receipt)
+	if err != nil {
+		return 0, err
+	}
+
+	return breakdown.Total, nil
+}
+
+// GetReceiptPointsBreakdown lists the points awarded by every rule, including the reduction
+// applied to unsigned receipts.
+func (s *ReceiptServiceImpl) GetReceiptPointsBreakdown(ctx context.Context, receipt *models.Receipt) (*models.PointsBreakdown, error) {
 	if receipt == nil {
-		return 0, ErrReceiptIsNil
+		return nil, ErrReceiptIsNil
+	}
+	switch receipt.Status {
+	case models.ReceiptStatusHeld:
+		return nil, ErrReceiptHeld
+	case models.ReceiptStatusRejected:
+		return nil, ErrReceiptRejected
+	}
+
+	if receipt.ID == uuid.Nil {
+		// Receipts scored without being created, e.g. by receiptctl, are matched and localized
+		// on a copy.
+		localized := *receipt
+		if err := s.matchRetailer(ctx, &localized); err != nil {
+			return nil, err
+		}
+		if err := s.localize(&localized); err != nil {
+			return nil, err
+		}
+		receipt = &localized
 	}
+	if receipt.IsReturn() {
+		return s.returnPointsBreakdown(ctx, receipt)
+	}
+
+	scored, err := s.scoredReceipt(receipt)
+	if err != nil {
+		return nil, err
+	}
+
+	breakdown := &models.PointsBreakdown{
+		Rules: []models.PointsRule{
+			{Rule: "retailer_name", Description: "One point for every alphanumeric character in the " + s.retailerLabel(), Points: countAlphanumerics(s.scoredRetailerName(scored))},
+			{Rule: "round_total", Description: "The " + s.totalName() + " is a round dollar amount with no cents", Points: isTotalRoundAmount(scored, s.ruleSet.PreTax)},
+			{Rule: "total_multiple", Description: "The " + s.totalName() + " is a multiple of 0.25", Points: getReceiptTotalIfIsMultiplePoints(scored, s.ruleSet.PreTax)},
+			{Rule: "item_pairs", Description: s.describe("5 points for every two items on the receipt"), Points: sumItemsPoints(scored, s.ruleSet.QuantityAware)},
+			{Rule: "item_descriptions", Description: s.describe("Items whose trimmed description length is a multiple of 3"), Points: getReceiptItemsDescriptionPoints(scored, s.ruleSet.QuantityAware)},
+			{Rule: "odd_day", Description: "The day in the purchase date is odd", Points: getDatePoints(scored)},
+			{Rule: "afternoon_time", Description: "The time of purchase is after 2:00pm and before 4:00pm", Points: getTimePoints(scored)},
+		},
+	}
+	for _, rule := range breakdown.Rules {
+		breakdown.Total += rule.Points
+	}
+
+	if adjusted := applySignaturePolicy(receipt, breakdown.Total); adjusted != breakdown.Total {
+		breakdown.Rules = append(breakdown.Rules, models.PointsRule{
+			Rule:        "signature_policy",
+			Description: "Unsigned receipts earn a share of the points",
+			Points:      adjusted - breakdown.Total,
+		})
+		breakdown.Total = adjusted
+	}
+
+	return breakdown, nil
+}
+
+func (s *ReceiptServiceImpl) GetReturnedPoints(ctx context.Context, receipt *models.Receipt) (int, error) {
 	if receipt == nil {
 		return 0, ErrReceiptIsNil
 	}
+	if receipt.IsReturn() {
+		return 0, nil
+	}
 
-	points := 0
-	points += countAlphanumerics(receipt.Retailer)
-	points += isTotalRoundAmount(receipt)
-	points += getReceiptTotalIfIsMultiplePoints(receipt)
-	points += sumItemsPoints(receipt)
-	points += getReceiptItemsDescriptionPoints(receipt)
-	points += getDatePoints(receipt)
-	points += getTimePoints(receipt)
+	returns, err := s.receiptRepository.ListReturns(ctx, receipt.ID)
+	if err != nil || len(returns) == 0 {
+		return 0, err
+	}
+	points, err := s.GetReturnsPoints(ctx, receipt, returns)
+	if err != nil {
+		return 0, err
+	}
+	returned := 0
+	for _, p := range points {
+		returned += p
+	}
+	return returned, nil
+}
+
+// GetReturnsByOriginalIDs leaves out the purchases without returns.
+func (s *ReceiptServiceImpl) GetReturnsByOriginalIDs(ctx context.Context, originalIDs []uuid.UUID) (map[uuid.UUID][]*models.Receipt, error) {
+	return s.receiptRepository.ListReturnsByOriginalIDs(ctx, originalIDs)
+}
+
+// ListReceipts returns the receipts matching the filter, oldest first.
+func (s *ReceiptServiceImpl) ListReceipts(ctx context.Context, filter models.ReceiptFilter) ([]*models.Receipt, error) {
+	receipts, err := s.receiptRepository.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	matching := make([]*models.Receipt, 0)
+	for _, receipt := range receipts {
+		if filter.Retailer != "" && !matchesRetailer(receipt, filter.Retailer) {
+			continue
+		}
+		if filter.Status != "" && receipt.Status != filter.Status {
+			continue
+		}
+		matching = append(matching, receipt)
+	}
 
-	return points, nil
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	if filter.Offset >= len(matching) {
+		return []*models.Receipt{}, nil
+	}
+	matching = matching[filter.Offset:]
+	if filter.Limit > 0 && filter.Limit < len(matching) {
+		matching = matching[:filter.Limit]
+	}
+	return matching, nil
+}
+
+// describe tells in the description of the item rules whether quantities are counted.
+func (s *ReceiptServiceImpl) describe(description string) string {
+	if s.ruleSet.QuantityAware {
+		return description + ", counting quantities"
+	}
+	return description
+}
+
+// totalName names the amount the total rules are scored on.
+func (s *ReceiptServiceImpl) totalName() string {
+	if s.ruleSet.PreTax {
+		return "total before taxes and tip"
+	}
+	return "total"
+}
+
+// retailerLabel names the name the retailer_name rule is scored on.
+func (s *ReceiptServiceImpl) retailerLabel() string {
+	if s.ruleSet.CanonicalRetailer {
+		return "registered retailer name"
+	}
+	return "retailer name"
+}
+
+// scoredTotal is the amount the total rules are scored on: the total paid, or the subtotal
+// minus the discounts for the pre-tax rule set.
+func scoredTotal(receipt *models.Receipt, preTax bool) (float64, error) {
+	if !preTax {
+		return receipt.GetTotalAsFloat()
+	}
+	amounts, err := receipt.Amounts()
+	if err != nil {
+		return 0, err
+	}
+	return currency.ToMajor(amounts.PreTax(), receipt.GetCurrency()), nil
+}
+
+// scoredRetailerName is the name the retailer_name rule counts, the one printed on the receipt
+// unless the rule set scores the canonical name.
+func (s *ReceiptServiceImpl) scoredRetailerName(receipt *models.Receipt) string {
+	if s.ruleSet.CanonicalRetailer {
+		return receipt.GetRetailerName()
+	}
+	return receipt.Retailer
+}
+
+// matchRetailer records the retailer of the registry the name printed on the receipt belongs
+// to, if any.
+func (s *ReceiptServiceImpl) matchRetailer(ctx context.Context, receipt *models.Receipt) error {
+	if s.retailerSvc == nil || receipt.RetailerID != nil {
+		return nil
+	}
+	retailer, err := s.retailerSvc.Resolve(ctx, receipt.Retailer)
+	if err != nil || retailer == nil {
+		return err
+	}
+	id := retailer.ID
+	receipt.RetailerID, receipt.RetailerName = &id, retailer.Name
+	return nil
+}
+
+// matchesRetailer tells whether the receipt is of the retailer, by its canonical name or by
+// the name printed on it.
+func matchesRetailer(receipt *models.Receipt, retailer string) bool {
+	retailer = models.NormalizeRetailerName(retailer)
+	return models.NormalizeRetailerName(receipt.GetRetailerName()) == retailer ||
+		models.NormalizeRetailerName(receipt.Retailer) == retailer
+}
+
+// localize records the time zone of the store, the one of the retailer when the receipt has
+// none, and fills in the purchase date and time with the local time of PurchasedAt. Without a
+// time zone, the offset of PurchasedAt is the one of the store.
+func (s *ReceiptServiceImpl) localize(receipt *models.Receipt) error {
+	if receipt.TimeZone == "" {
+		receipt.TimeZone = s.timeZones[strings.ToLower(receipt.GetRetailerName())]
+	}
+	if receipt.TimeZone == "" {
+		receipt.TimeZone = s.timeZones[strings.ToLower(strings.TrimSpace(receipt.Retailer))]
+	}
+	if receipt.PurchasedAt == "" {
+		return nil
+	}
+
+	purchasedAt, err := time.Parse(time.RFC3339, receipt.PurchasedAt)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidReceiptParams, err)
+	}
+	if receipt.TimeZone == "" {
+		_, offset := purchasedAt.Zone()
+		receipt.TimeZone = timezone.Offset(offset)
+	}
+	location, err := receipt.Location()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidReceiptParams, err)
+	}
+
+	local := purchasedAt.In(location)
+	date, clock := local.Format(time.DateOnly), local.Format("15:04")
+	if (receipt.PurchaseDate != "" && receipt.PurchaseDate != date) || (receipt.PurchaseTime != "" && receipt.PurchaseTime != clock) {
+		return fmt.Errorf("%w: it is %s %s in %s", ErrPurchaseTimeMismatch, date, clock, receipt.TimeZone)
+	}
+	receipt.PurchaseDate, receipt.PurchaseTime = date, clock
+	return nil
+}
+
+// exchangeRate finds the rate the receipt is converted to the base currency with, none when it
+// is already in the base currency.
+func (s *ReceiptServiceImpl) exchangeRate(receipt *models.Receipt) (*models.ExchangeRate, error) {
+	if receipt.GetCurrency() == s.rates.Base() {
+		return nil, nil
+	}
+	rate, err := s.rates.Lookup(receipt.GetCurrency(), receipt.PurchaseDate)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNoExchangeRate, err)
+	}
+	return &models.ExchangeRate{Currency: rate.Currency, Base: rate.Base, Rate: rate.Rate, Date: rate.Date}, nil
+}
+
+// scoredReceipt is the receipt the rules are scored on: the receipt itself when it is in the
+// base currency, or a copy converted with the rate recorded when it was created. Receipts
+// recorded with another base currency, or not created yet, are converted with today's table.
+func (s *ReceiptServiceImpl) scoredReceipt(receipt *models.Receipt) (*models.Receipt, error) {
+	exchange := receipt.Exchange
+	if exchange == nil || exchange.Base != s.rates.Base() {
+		var err error
+		if exchange, err = s.exchangeRate(receipt); err != nil {
+			return nil, err
+		}
+		if exchange == nil {
+			return receipt, nil
+		}
+	}
+	return receipt.Converted(*exchange)
+}
+
+// lifecycleEvents builds the events recorded with a new receipt: receipt.created, followed
+// by receipt.scored when the receipt was not held for review.
+func (s *ReceiptServiceImpl) lifecycleEvents(ctx context.Context, receipt *models.Receipt) ([]*models.ReceiptEvent, error) {
+	events := []*models.ReceiptEvent{models.NewReceiptEvent(models.ReceiptCreated, receipt, receipt.CreatedAt)}
+	if receipt.Status != models.ReceiptStatusProcessed {
+		return events, nil
+	}
+
+	points, err := s.GetReceiptPoints(ctx, receipt)
+	if err != nil {
+		return nil, err
+	}
+	scored := models.NewReceiptEvent(models.ReceiptScored, receipt, receipt.CreatedAt)
+	scored.Points = &points
+	return append(events, scored), nil
+}
+
+// Unsigned receipts from retailers that prefer signatures earn a share of the points.
+func applySignaturePolicy(receipt *models.Receipt, points int) int {
+	if receipt.Verification == nil || receipt.Verification.Status != models.SignatureUnsigned {
+		return points
+	}
+
+	return points * receipt.Verification.PointsPercent / 100
 }
 
 // 50 points if the total is a round dollar amount with no cents.
-func isTotalRoundAmount(receipt *models.Receipt) int {
-	receiptTotal, err := receipt.GetTotalAsFloat()
+func isTotalRoundAmount(receipt *models.Receipt, preTax bool) int {
+	receiptTotal, err := scoredTotal(receipt, preTax)
 	if err != nil {
 		return 0
 	}
@@ -119,12 +630,12 @@ func countAlphanumerics(str string) int {
 }
 
 // 25 points if the total is a multiple of 0.25.
-func getReceiptTotalIfIsMultiplePoints(receipt *models.Receipt) int {
+func getReceiptTotalIfIsMultiplePoints(receipt *models.Receipt, preTax bool) int {
 	if receipt == nil {
 		return 0
 	}
 
-	receiptTotal, err := receipt.GetTotalAsFloat()
+	receiptTotal, err := scoredTotal(receipt, preTax)
 	if err != nil {
 		return 0
 	}
@@ -139,16 +650,23 @@ func isMultipleOf(a, b float64) bool {
 	return math.Mod(a, b) == 0.0
 }
 
-// 5 points for every two items on the receipt.
-func sumItemsPoints(receipt *models.Receipt) int {
+// 5 points for every two items on the receipt. With quantities, every unit of a line counts.
+func sumItemsPoints(receipt *models.Receipt, quantityAware bool) int {
 	if receipt == nil || receipt.Items == nil {
 		return 0
 	}
 
-	return len(receipt.Items) / 2 * ItemsPointsPerTwoItems
+	count := len(receipt.Items)
+	if quantityAware {
+		count = 0
+		for i := range receipt.Items {
+			count += receipt.Items[i].Units()
+		}
+	}
+	return count / 2 * ItemsPointsPerTwoItems
 }
 
-// 6 points if the day in the purchase date is odd.
+// 6 points if the day in the purchase date is odd, in the time zone of the store.
 func getDatePoints(receipt *models.Receipt) int {
 	receiptDate, err := receipt.GetReceiptDatetime()
 	if err != nil {
@@ -162,7 +680,8 @@ func getDatePoints(receipt *models.Receipt) int {
 	return DateOddPoints
 }
 
-// 10 points if the time of purchase is after 2:00pm and before 4:00pm.
+// 10 points if the time of purchase is after 2:00pm and before 4:00pm, in the time zone of the
+// store.
 func getTimePoints(receipt *models.Receipt) int {
 	receiptDate, err := receipt.GetReceiptDatetime()
 	if err != nil {
@@ -178,10 +697,8 @@ func getTimePoints(receipt *models.Receipt) int {
 	return 0
 }
 
-// If the trimmed length of the item description is a multiple of 3,
-// multiply the price by 0.2 and round up to the nearest integer.
-// The result is the number of points earned.
-func getReceiptItemsDescriptionPoints(receipt *models.Receipt) int {
+// getReceiptItemsDescriptionPoints adds up the description points of the items.
+func getReceiptItemsDescriptionPoints(receipt *models.Receipt, quantityAware bool) int {
 	if receipt == nil {
 		return 0
 	}
@@ -191,17 +708,40 @@ func getReceiptItemsDescriptionPoints(receipt *models.Receipt) int {
 
 	points := 0
 
-	for _, item := range receipt.Items {
-		trimmedDesc := strings.TrimSpace(item.ShortDescription)
-		if len(trimmedDesc)%3 == 0 {
-			price, err := item.GetPriceAsFloat()
-			if err != nil {
-				// errMsg := fmt.Errorf("error parsing price for item: %v", err)
-				return 0
-			}
-			points += int(math.Ceil(price * 0.2))
+	for i := range receipt.Items {
+		itemPoints, err := getItemDescriptionPoints(&receipt.Items[i], quantityAware)
+		if err != nil {
+			return 0
 		}
+		points += itemPoints
 	}
 
 	return points
 }
+
+// If the trimmed length of the item description is a multiple of 3,
+// multiply the price by 0.2 and round up to the nearest integer.
+// The result is the number of points earned. With quantities, a line of several units earns
+// the points of its unit price once per unit.
+func getItemDescriptionPoints(item *models.ReceiptItem, quantityAware bool) (int, error) {
+	trimmedDesc := strings.TrimSpace(item.ShortDescription)
+	if len(trimmedDesc)%3 != 0 {
+		return 0, nil
+	}
+
+	price, err := item.GetPriceAsFloat()
+	if err != nil {
+		return 0, err
+	}
+	units := 1
+	if quantityAware && item.Units() > 1 {
+		units = item.Units()
+		unitPrice, err := strconv.ParseFloat(item.UnitPrice, 64)
+		if err != nil {
+			// Without a unit price, the price is split between the units.
+			unitPrice = math.Round(price/float64(units)*100) / 100
+		}
+		price = unitPrice
+	}
+	return units * int(math.Ceil(price*0.2)), nil
+}
@@ -2,12 +2,22 @@ package service
 
 import (
 	"context"
+	"fmt"
+	fraudMock "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/fraud/mock"
 	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
 	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/mock"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/repository"
+	retailerRepository "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/retailer/repository"
+	retailerService "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/retailer/service"
+	signatureMock "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/signature/mock"
+	signatureRepository "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/signature/repository"
+	signatureService "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/signature/service"
+	"github.com/CarlosMtz98/receipt-processor-challenge/pkg/currency"
 	"github.com/golang/mock/gomock"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
 func TestReceiptServiceImpl_CreateReceipt(t *testing.T) {
@@ -22,7 +32,7 @@ func TestReceiptServiceImpl_CreateReceipt(t *testing.T) {
 		ID: uuid.Nil,
 	}
 	repo.EXPECT().
-		Create(gomock.Any(), receipt).
+		CreateWithEvents(gomock.Any(), receipt, gomock.Any()).
 		Return(nil)
 
 	t.Run("Create Receipt", func(t *testing.T) {
@@ -34,6 +44,124 @@ func TestReceiptServiceImpl_CreateReceipt(t *testing.T) {
 	})
 }
 
+func TestReceiptServiceImpl_CreateReceiptWithSignature(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := mock.NewMockReceiptRepository(ctrl)
+	signatureSvc := signatureMock.NewMockSignatureService(ctrl)
+	service := NewReceiptService(repo, WithSignatureService(signatureSvc))
+
+	t.Run("Verified receipt", func(t *testing.T) {
+		receipt := &models.Receipt{Retailer: "Target"}
+		verification := &models.SignatureVerification{Status: models.SignatureVerified, PointsPercent: 100}
+		signatureSvc.EXPECT().Verify(gomock.Any(), receipt).Return(verification, nil)
+		repo.EXPECT().CreateWithEvents(gomock.Any(), receipt, gomock.Any()).Return(nil)
+
+		createdReceipt, err := service.CreateReceipt(context.Background(), receipt)
+		assert.NoError(t, err)
+		assert.Equal(t, verification, createdReceipt.Verification)
+	})
+
+	t.Run("Rejected signature", func(t *testing.T) {
+		receipt := &models.Receipt{Retailer: "Target"}
+		signatureSvc.EXPECT().Verify(gomock.Any(), receipt).Return(nil, signatureService.ErrSignatureRequired)
+
+		createdReceipt, err := service.CreateReceipt(context.Background(), receipt)
+		assert.Nil(t, createdReceipt)
+		assert.Equal(t, signatureService.ErrSignatureRequired, err)
+		assert.Equal(t, uuid.Nil, receipt.ID)
+	})
+}
+
+func TestReceiptServiceImpl_CreateReceiptWithFraudChecks(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := mock.NewMockReceiptRepository(ctrl)
+	fraudSvc := fraudMock.NewMockFraudService(ctrl)
+	service := NewReceiptService(repo, WithFraudService(fraudSvc))
+
+	t.Run("Low risk receipt is processed", func(t *testing.T) {
+		receipt := &models.Receipt{Retailer: "Target"}
+		fraudSvc.EXPECT().Assess(gomock.Any(), receipt).Return(&models.RiskAssessment{Score: 10}, nil)
+		repo.EXPECT().CreateWithEvents(gomock.Any(), receipt, gomock.Any()).Return(nil)
+
+		createdReceipt, err := service.CreateReceipt(context.Background(), receipt)
+		assert.NoError(t, err)
+		assert.Equal(t, models.ReceiptStatusProcessed, createdReceipt.Status)
+		assert.Equal(t, 10, createdReceipt.Risk.Score)
+		assert.False(t, createdReceipt.CreatedAt.IsZero())
+	})
+
+	t.Run("High risk receipt is held from scoring", func(t *testing.T) {
+		receipt := &models.Receipt{Retailer: "Target"}
+		assessment := &models.RiskAssessment{
+			Score: 70,
+			Flags: []models.RiskFlag{{Check: "purchase_datetime", Score: 70, Reason: "the purchase datetime is in the future"}},
+			Held:  true,
+		}
+		fraudSvc.EXPECT().Assess(gomock.Any(), receipt).Return(assessment, nil)
+		repo.EXPECT().CreateWithEvents(gomock.Any(), receipt, gomock.Any()).Return(nil)
+
+		createdReceipt, err := service.CreateReceipt(context.Background(), receipt)
+		assert.NoError(t, err)
+		assert.Equal(t, models.ReceiptStatusHeld, createdReceipt.Status)
+
+		points, err := service.GetReceiptPoints(context.Background(), createdReceipt)
+		assert.Equal(t, ErrReceiptHeld, err)
+		assert.Equal(t, 0, points)
+	})
+}
+
+func TestReceiptServiceImpl_CreateReceiptRecordsEvents(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := mock.NewMockReceiptRepository(ctrl)
+	fraudSvc := fraudMock.NewMockFraudService(ctrl)
+	service := NewReceiptService(repo, WithFraudService(fraudSvc))
+
+	t.Run("Processed receipt is created and scored", func(t *testing.T) {
+		receipt := &models.Receipt{Retailer: "Target", PurchaseDate: "2022-01-01", PurchaseTime: "13:01", Total: "1.00"}
+		fraudSvc.EXPECT().Assess(gomock.Any(), receipt).Return(&models.RiskAssessment{}, nil)
+
+		var events []*models.ReceiptEvent
+		repo.EXPECT().CreateWithEvents(gomock.Any(), receipt, gomock.Any()).DoAndReturn(func(_ context.Context, _ *models.Receipt, recorded []*models.ReceiptEvent) error {
+			events = recorded
+			return nil
+		})
+
+		createdReceipt, err := service.CreateReceipt(context.Background(), receipt)
+		assert.NoError(t, err)
+		assert.Len(t, events, 2)
+		assert.Equal(t, models.ReceiptCreated, events[0].Type)
+		assert.Equal(t, createdReceipt.ID, events[0].ReceiptID)
+		assert.Nil(t, events[0].Points)
+		assert.Equal(t, models.ReceiptScored, events[1].Type)
+		assert.Equal(t, 87, *events[1].Points)
+	})
+
+	t.Run("Held receipt is only created", func(t *testing.T) {
+		receipt := &models.Receipt{Retailer: "Target"}
+		fraudSvc.EXPECT().Assess(gomock.Any(), receipt).Return(&models.RiskAssessment{Score: 70, Held: true}, nil)
+		repo.EXPECT().CreateWithEvents(gomock.Any(), receipt, gomock.Any()).DoAndReturn(func(_ context.Context, _ *models.Receipt, recorded []*models.ReceiptEvent) error {
+			assert.Len(t, recorded, 1)
+			assert.Equal(t, models.ReceiptCreated, recorded[0].Type)
+			return nil
+		})
+
+		_, err := service.CreateReceipt(context.Background(), receipt)
+		assert.NoError(t, err)
+	})
+}
+
 func TestReceiptServiceImpl_GetReceiptByID(t *testing.T) {
 	t.Parallel()
 
@@ -106,29 +234,101 @@ func TestReceiptServiceImpl_GetReceiptPoints(t *testing.T) {
 	assert.NotNil(t, points)
 }
 
+func TestReceiptServiceImpl_GetReceiptPointsBreakdown(t *testing.T) {
+	t.Parallel()
+
+	service := NewReceiptService(repository.InitReceiptRepository())
+	receipt := &models.Receipt{
+		Retailer:     "Target",
+		PurchaseDate: "2022-01-01",
+		PurchaseTime: "13:01",
+		Total:        "1.00",
+		Verification: &models.SignatureVerification{Status: models.SignatureUnsigned, PointsPercent: 50},
+	}
+
+	breakdown, err := service.GetReceiptPointsBreakdown(context.Background(), receipt)
+	assert.NoError(t, err)
+	assert.Equal(t, 43, breakdown.Total)
+	sum := 0
+	for _, rule := range breakdown.Rules {
+		sum += rule.Points
+	}
+	assert.Equal(t, breakdown.Total, sum)
+	assert.Equal(t, models.PointsRule{Rule: "signature_policy", Description: "Unsigned receipts earn a share of the points", Points: -44}, breakdown.Rules[len(breakdown.Rules)-1])
+
+	points, err := service.GetReceiptPoints(context.Background(), receipt)
+	assert.NoError(t, err)
+	assert.Equal(t, breakdown.Total, points)
+}
+
+func TestReceiptServiceImpl_ListReceipts(t *testing.T) {
+	t.Parallel()
+
+	repo := repository.InitReceiptRepository()
+	service := NewReceiptService(repo)
+	now := time.Now()
+	for i, retailer := range []string{"Target", "Walmart", "target", "Target"} {
+		receipt := &models.Receipt{ID: uuid.New(), Retailer: retailer, Status: models.ReceiptStatusProcessed, CreatedAt: now.Add(time.Duration(i) * time.Second)}
+		if i == 3 {
+			receipt.Status = models.ReceiptStatusHeld
+		}
+		assert.NoError(t, repo.Create(context.Background(), receipt))
+	}
+
+	receipts, err := service.ListReceipts(context.Background(), models.ReceiptFilter{Retailer: "TARGET"})
+	assert.NoError(t, err)
+	assert.Len(t, receipts, 3)
+
+	receipts, err = service.ListReceipts(context.Background(), models.ReceiptFilter{Retailer: "Target", Status: models.ReceiptStatusProcessed, Offset: 1, Limit: 5})
+	assert.NoError(t, err)
+	assert.Len(t, receipts, 1)
+	assert.Equal(t, "target", receipts[0].Retailer)
+
+	receipts, err = service.ListReceipts(context.Background(), models.ReceiptFilter{Offset: 10})
+	assert.NoError(t, err)
+	assert.Empty(t, receipts)
+}
+
+func TestApplySignaturePolicy(t *testing.T) {
+	receipt := &models.Receipt{}
+	if points := applySignaturePolicy(receipt, 109); points != 109 {
+		t.Errorf("Expected 109 points, but got %d", points)
+	}
+
+	receipt.Verification = &models.SignatureVerification{Status: models.SignatureUnsigned, PointsPercent: 50}
+	if points := applySignaturePolicy(receipt, 109); points != 54 {
+		t.Errorf("Expected 54 points, but got %d", points)
+	}
+
+	receipt.Verification = &models.SignatureVerification{Status: models.SignatureVerified, PointsPercent: 100}
+	if points := applySignaturePolicy(receipt, 109); points != 109 {
+		t.Errorf("Expected 109 points, but got %d", points)
+	}
+}
+
 func TestIsTotalRoundAmount(t *testing.T) {
 	// Create a test receipt with a round total amount (50 points)
 	receipt := &models.Receipt{Total: "100.00"}
-	points := isTotalRoundAmount(receipt)
+	points := isTotalRoundAmount(receipt, false)
 	if points != 50 {
 		t.Errorf("Expected 50 points, but got %d", points)
 	}
 
 	// Create a test receipt with a non-round total amount (0 points)
 	receipt = &models.Receipt{Total: "99.99"}
-	points = isTotalRoundAmount(receipt)
+	points = isTotalRoundAmount(receipt, false)
 	if points != 0 {
 		t.Errorf("Expected 0 points, but got %d", points)
 	}
 
 	receipt = &models.Receipt{Total: "1.50"}
-	points = isTotalRoundAmount(receipt)
+	points = isTotalRoundAmount(receipt, false)
 	if points != 0 {
 		t.Errorf("Expected 0 points, but got %d", points)
 	}
 
 	receipt = &models.Receipt{Total: "0.50"}
-	points = isTotalRoundAmount(receipt)
+	points = isTotalRoundAmount(receipt, false)
 	if points != 0 {
 		t.Errorf("Expected 0 points, but got %d", points)
 	}
@@ -153,20 +353,20 @@ func TestCountAlphanumerics(t *testing.T) {
 func TestGetReceiptTotalIfIsMultiplePoints(t *testing.T) {
 	// Create a test receipt with a total that is a multiple of 0.25 (25 points)
 	receipt := &models.Receipt{Total: "25.00"}
-	points := getReceiptTotalIfIsMultiplePoints(receipt)
+	points := getReceiptTotalIfIsMultiplePoints(receipt, false)
 	if points != 25 {
 		t.Errorf("Expected 25 points, but got %d", points)
 	}
 
 	receipt = &models.Receipt{Total: "100.25"}
-	points = getReceiptTotalIfIsMultiplePoints(receipt)
+	points = getReceiptTotalIfIsMultiplePoints(receipt, false)
 	if points != 25 {
 		t.Errorf("Expected 25 points, but got %d", points)
 	}
 
 	// Create a test receipt with a total that is not a multiple of 0.25 (0 points)
 	receipt = &models.Receipt{Total: "33.33"}
-	points = getReceiptTotalIfIsMultiplePoints(receipt)
+	points = getReceiptTotalIfIsMultiplePoints(receipt, false)
 	if points != 0 {
 		t.Errorf("Expected 0 points, but got %d", points)
 	}
@@ -261,7 +461,7 @@ func TestItemsDescriptionsPoints(t *testing.T) {
 			},
 		},
 	}
-	points := getReceiptItemsDescriptionPoints(receipt)
+	points := getReceiptItemsDescriptionPoints(receipt, false)
 	if points != 6 {
 		t.Errorf("Expected 6 points, but got %d", points)
 	}
@@ -289,7 +489,7 @@ func TestReceiptSumOfItemsPoints(t *testing.T) {
 			},
 		},
 	}
-	points := sumItemsPoints(receipt)
+	points := sumItemsPoints(receipt, false)
 	if points != 10 {
 		t.Errorf("Expected 10 points, but got %d", points)
 	}
@@ -303,8 +503,574 @@ func TestReceiptSumOfItemsPoints(t *testing.T) {
 			},
 		},
 	}
-	points = sumItemsPoints(receipt)
+	points = sumItemsPoints(receipt, false)
 	if points != 0 {
 		t.Errorf("Expected 0 points, but got %d", points)
 	}
 }
+
+func TestQuantityAwareItemRules(t *testing.T) {
+	lines := &models.Receipt{Items: []models.ReceiptItem{
+		{ShortDescription: "Gatorade", Price: "2.25"},
+		{ShortDescription: "Gatorade", Price: "2.25"},
+		{ShortDescription: "Gatorade", Price: "2.25"},
+		{ShortDescription: "Emils Cheese Pizza", Price: "12.25"},
+	}}
+	quantities := &models.Receipt{Items: []models.ReceiptItem{
+		{ShortDescription: "Gatorade", Price: "6.75", Quantity: "3", UnitPrice: "2.25"},
+		{ShortDescription: "Emils Cheese Pizza", Price: "12.25"},
+	}}
+
+	assert.Equal(t, 10, sumItemsPoints(lines, true))
+	assert.Equal(t, 10, sumItemsPoints(quantities, true))
+	assert.Equal(t, 5, sumItemsPoints(quantities, false))
+
+	// "Emils Cheese Pizza" has 18 characters: ceil(12.25 * 0.2) = 3, and the 8 characters of
+	// "Gatorade" earn nothing.
+	assert.Equal(t, 3, getReceiptItemsDescriptionPoints(quantities, true))
+
+	threeOfThree := &models.Receipt{Items: []models.ReceiptItem{
+		{ShortDescription: "Tea", Price: "7.50", Quantity: "3", UnitPrice: "2.50"},
+		{ShortDescription: "Pie", Price: "0.91", Quantity: "0.455"},
+	}}
+	// Three teas earn ceil(2.50 * 0.2) = 1 each instead of ceil(7.50 * 0.2) = 2 for the line,
+	// and a weight counts as one unit.
+	assert.Equal(t, 3, getReceiptItemsDescriptionPoints(threeOfThree, false))
+	assert.Equal(t, 4, getReceiptItemsDescriptionPoints(threeOfThree, true))
+}
+
+func TestValidateReceiptItemQuantities(t *testing.T) {
+	receipt := func(items ...models.ReceiptItem) *models.Receipt {
+		total := 0.0
+		for i := range items {
+			price, _ := items[i].GetPriceAsFloat()
+			total += price
+		}
+		return &models.Receipt{
+			Retailer:     "Target",
+			PurchaseDate: "2022-01-01",
+			PurchaseTime: "13:01",
+			Items:        items,
+			Total:        fmt.Sprintf("%.2f", total),
+		}
+	}
+
+	assert.NoError(t, ValidateReceipt(context.Background(), receipt(models.ReceiptItem{ShortDescription: "Gatorade", Price: "2.25"}), 0))
+	assert.NoError(t, ValidateReceipt(context.Background(), receipt(
+		models.ReceiptItem{ShortDescription: "Gatorade", Price: "6.75", Quantity: "3", UnitPrice: "2.25", SKU: "052000328660", Category: "beverages"},
+		models.ReceiptItem{ShortDescription: "Apples", Price: "1.36", Quantity: "0.455", UnitPrice: "2.99"},
+		models.ReceiptItem{ShortDescription: "Bread", Price: "3.49", UnitPrice: "3.49"},
+	), 0))
+
+	err := ValidateReceipt(context.Background(), receipt(models.ReceiptItem{ShortDescription: "Gatorade", Price: "6.50", Quantity: "3", UnitPrice: "2.25"}), 0)
+	assert.ErrorIs(t, err, ErrItemPriceMismatch)
+	assert.EqualError(t, err, "the item price must match its quantity times its unit price: item 1")
+
+	for _, quantity := range []string{"0", "-1", "1.2345", "three"} {
+		err := ValidateReceipt(context.Background(), receipt(models.ReceiptItem{ShortDescription: "Gatorade", Price: "2.25", Quantity: quantity}), 0)
+		assert.ErrorIs(t, err, ErrInvalidReceiptParams, quantity)
+	}
+}
+
+func TestValidateReceiptAmounts(t *testing.T) {
+	// Not parallel: the tolerance is shared by every receipt.
+	receipt := func() *models.Receipt {
+		return &models.Receipt{
+			Retailer:     "Target",
+			PurchaseDate: "2022-01-01",
+			PurchaseTime: "13:01",
+			Items:        []models.ReceiptItem{{ShortDescription: "Pizza", Price: "12.25"}, {ShortDescription: "Soda", Price: "2.75"}},
+			Subtotal:     "15.00",
+			Discounts:    []models.ReceiptAdjustment{{Description: "Coupon", Amount: "1.00"}},
+			Taxes:        []models.ReceiptAdjustment{{Description: "Sales tax 8.25%", Amount: "1.16"}},
+			Tip:          "2.00",
+			Total:        "17.16",
+		}
+	}
+
+	assert.NoError(t, ValidateReceipt(context.Background(), receipt(), 0))
+
+	subtotal := receipt()
+	subtotal.Subtotal = "14.00"
+	err := ValidateReceipt(context.Background(), subtotal, 0)
+	assert.ErrorIs(t, err, ErrSubtotalMismatch)
+	assert.EqualError(t, err, "the receipt subtotal must match with the items total: the items add up to 15.00")
+
+	total := receipt()
+	total.Total = "17.15"
+	err = ValidateReceipt(context.Background(), total, 0)
+	assert.ErrorIs(t, err, ErrTotalMismatch)
+	assert.EqualError(t, err, "the receipt total must match with the items total: the subtotal minus the discounts plus the taxes and the tip is 17.16")
+
+	invalid := receipt()
+	invalid.Taxes = []models.ReceiptAdjustment{{Amount: "1.16"}}
+	assert.ErrorIs(t, ValidateReceipt(context.Background(), invalid, 0), ErrInvalidReceiptParams)
+
+	tolerant := NewReceiptService(repository.InitReceiptRepository(), WithTotalTolerance(0.01))
+	assert.NoError(t, tolerant.ValidateReceipt(context.Background(), total))
+	total.Total = "17.14"
+	assert.ErrorIs(t, tolerant.ValidateReceipt(context.Background(), total), ErrTotalMismatch)
+	// The tolerance does not apply to the subtotal, which is printed from the same prices.
+	assert.ErrorIs(t, tolerant.ValidateReceipt(context.Background(), subtotal), ErrSubtotalMismatch)
+}
+
+func TestPreTaxTotalRules(t *testing.T) {
+	t.Parallel()
+
+	receipt := &models.Receipt{
+		Retailer:     "Target",
+		PurchaseDate: "2022-01-02",
+		PurchaseTime: "13:01",
+		Items:        []models.ReceiptItem{{ShortDescription: "Pizza", Price: "12.25"}, {ShortDescription: "Soda", Price: "2.75"}},
+		Discounts:    []models.ReceiptAdjustment{{Description: "Coupon", Amount: "1.00"}},
+		Taxes:        []models.ReceiptAdjustment{{Description: "Sales tax 8.25%", Amount: "1.16"}},
+		Total:        "15.16",
+	}
+
+	assert.Equal(t, 0, isTotalRoundAmount(receipt, false))
+	assert.Equal(t, 0, getReceiptTotalIfIsMultiplePoints(receipt, false))
+	assert.Equal(t, 50, isTotalRoundAmount(receipt, true))
+	assert.Equal(t, 25, getReceiptTotalIfIsMultiplePoints(receipt, true))
+
+	postTax, err := NewReceiptService(repository.InitReceiptRepository()).GetReceiptPointsBreakdown(context.Background(), receipt)
+	assert.NoError(t, err)
+	preTax, err := NewReceiptService(repository.InitReceiptRepository(), WithRuleSet(RuleSet{PreTax: true})).GetReceiptPointsBreakdown(context.Background(), receipt)
+	assert.NoError(t, err)
+	assert.Equal(t, postTax.Total+75, preTax.Total)
+	assert.Equal(t, models.PointsRule{Rule: "round_total", Description: "The total before taxes and tip is a round dollar amount with no cents", Points: 50}, preTax.Rules[1])
+}
+
+func TestValidateReceiptCurrencies(t *testing.T) {
+	t.Parallel()
+
+	receipt := func(currency, price string) *models.Receipt {
+		return &models.Receipt{
+			Retailer:     "Lawson",
+			PurchaseDate: "2022-03-20",
+			PurchaseTime: "13:01",
+			Items:        []models.ReceiptItem{{ShortDescription: "Tea", Price: price}},
+			Total:        price,
+			Currency:     currency,
+		}
+	}
+
+	assert.NoError(t, ValidateReceipt(context.Background(), receipt("JPY", "150"), 0))
+	assert.NoError(t, ValidateReceipt(context.Background(), receipt("KWD", "1.250"), 0))
+	assert.NoError(t, ValidateReceipt(context.Background(), receipt("", "1.25"), 0))
+	assert.ErrorIs(t, ValidateReceipt(context.Background(), receipt("JPY", "150.00"), 0), ErrInvalidReceiptParams)
+	assert.ErrorIs(t, ValidateReceipt(context.Background(), receipt("KWD", "1.25"), 0), ErrInvalidReceiptParams)
+	assert.ErrorIs(t, ValidateReceipt(context.Background(), receipt("ABC", "1.25"), 0), ErrInvalidReceiptParams)
+	assert.ErrorIs(t, ValidateReceipt(context.Background(), receipt("usd", "1.25"), 0), ErrInvalidReceiptParams)
+}
+
+func TestReceiptServiceImpl_CreateReceiptInOtherCurrency(t *testing.T) {
+	t.Parallel()
+
+	rates, err := currency.LoadRates("../../../../examples/exchange-rates.json", currency.DefaultCurrency)
+	assert.NoError(t, err)
+	service := NewReceiptService(repository.InitReceiptRepository(), WithExchangeRates(rates))
+	receipt := func() *models.Receipt {
+		return &models.Receipt{
+			Retailer:     "Lawson",
+			PurchaseDate: "2022-03-20",
+			PurchaseTime: "13:01",
+			Items:        []models.ReceiptItem{{ShortDescription: "Onigiri", Price: "360", Quantity: "2", UnitPrice: "180"}, {ShortDescription: "Tea", Price: "150"}},
+			Taxes:        []models.ReceiptAdjustment{{Description: "Consumption tax", Amount: "40"}},
+			Total:        "550",
+			Currency:     "JPY",
+		}
+	}
+
+	created, err := service.CreateReceipt(context.Background(), receipt())
+	assert.NoError(t, err)
+	// The rate of Friday is used for a purchase on Sunday.
+	assert.Equal(t, &models.ExchangeRate{Currency: "JPY", Base: "USD", Rate: 0.008383, Date: "2022-03-18"}, created.Exchange)
+
+	// 550 yen are 4.61 dollars, neither round nor a multiple of 0.25, and the tea earns
+	// ceil(1.26 * 0.2) = 1 point instead of ceil(150 * 0.2) = 30.
+	points, err := service.GetReceiptPoints(context.Background(), created)
+	assert.NoError(t, err)
+	assert.Equal(t, 12, points)
+
+	// The recorded rate is used even when the table no longer has it.
+	withoutRates := NewReceiptService(repository.InitReceiptRepository())
+	_, err = withoutRates.CreateReceipt(context.Background(), receipt())
+	assert.ErrorIs(t, err, ErrNoExchangeRate)
+	points, err = withoutRates.GetReceiptPoints(context.Background(), created)
+	assert.NoError(t, err)
+	assert.Equal(t, 12, points)
+
+	inYen := NewReceiptService(repository.InitReceiptRepository(), WithExchangeRates(currency.NewRates("JPY")))
+	points, err = inYen.GetReceiptPoints(context.Background(), created)
+	assert.NoError(t, err)
+	assert.Equal(t, 116, points)
+}
+
+func TestReceiptServiceImpl_CreateReturn(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	service := NewReceiptService(repository.InitReceiptRepository())
+	purchase, err := service.CreateReceipt(ctx, &models.Receipt{
+		Retailer:     "Target",
+		PurchaseDate: "2022-01-01",
+		PurchaseTime: "13:01",
+		Items: []models.ReceiptItem{
+			{ShortDescription: "Mountain Dew 12PK", Price: "6.49"},
+			{ShortDescription: "Emils Cheese Pizza", Price: "12.25"},
+			{ShortDescription: "Knorr Creamy Chicken", Price: "1.26"},
+			{ShortDescription: "Doritos Nacho Cheese", Price: "3.35"},
+			{ShortDescription: "   Klarbrunn 12-PK 12 FL OZ  ", Price: "12.00"},
+		},
+		Total:       "35.35",
+		SubmittedBy: "sub:pos-1",
+	})
+	assert.NoError(t, err)
+	returnOf := func(items ...models.ReceiptItem) *models.Receipt {
+		var total int64
+		for _, item := range items {
+			price, _ := currency.ParseAmount(item.Price, currency.DefaultCurrency)
+			total += price
+		}
+		return &models.Receipt{
+			Retailer:     "Target",
+			PurchaseDate: "2022-01-05",
+			PurchaseTime: "10:00",
+			Items:        items,
+			Total:        currency.FormatAmount(total, currency.DefaultCurrency),
+			Type:         models.ReceiptTypeReturn,
+			OriginalID:   &purchase.ID,
+			SubmittedBy:  "sub:pos-1",
+		}
+	}
+
+	t.Run("Validation", func(t *testing.T) {
+		withoutOriginal := returnOf(models.ReceiptItem{ShortDescription: "Emils Cheese Pizza", Price: "12.25"})
+		withoutOriginal.OriginalID = nil
+		assert.ErrorIs(t, ValidateReceipt(ctx, withoutOriginal, 0), ErrInvalidReceiptParams)
+		notAReturn := returnOf(models.ReceiptItem{ShortDescription: "Emils Cheese Pizza", Price: "12.25"})
+		notAReturn.Type = ""
+		assert.ErrorIs(t, ValidateReceipt(ctx, notAReturn, 0), ErrInvalidReceiptParams)
+	})
+
+	// 28 points: 6 for the retailer, 10 for the five items, 3 for the pizza and 3 for the
+	// Klarbrunn descriptions, and 6 for the odd day.
+	points, err := service.GetReceiptPoints(ctx, purchase)
+	assert.NoError(t, err)
+	assert.Equal(t, 28, points)
+
+	// The pizza takes back its 3 description points and 12.25 / 35.35 of the other 22 points.
+	first, err := service.CreateReceipt(ctx, returnOf(models.ReceiptItem{ShortDescription: "emils cheese pizza", Price: "12.25"}))
+	assert.NoError(t, err)
+	breakdown, err := service.GetReceiptPointsBreakdown(ctx, first)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.PointsRule{
+		{Rule: "returned_item_descriptions", Description: "The description points of the returned items", Points: -3},
+		{Rule: "returned_share", Description: "The share of the other points of the original receipt for the amount returned", Points: -7},
+	}, breakdown.Rules)
+	returned, err := service.GetReturnedPoints(ctx, purchase)
+	assert.NoError(t, err)
+	assert.Equal(t, -10, returned)
+
+	tests := []struct {
+		name    string
+		receipt func() *models.Receipt
+		err     error
+	}{
+		{
+			name: "Item already returned",
+			receipt: func() *models.Receipt {
+				return returnOf(models.ReceiptItem{ShortDescription: "Emils Cheese Pizza", Price: "12.25"})
+			},
+			err: ErrInvalidReturn,
+		},
+		{
+			name: "Item not on the purchase",
+			receipt: func() *models.Receipt {
+				return returnOf(models.ReceiptItem{ShortDescription: "Gatorade", Price: "2.25"})
+			},
+			err: ErrInvalidReturn,
+		},
+		{
+			name: "More than the price paid",
+			receipt: func() *models.Receipt {
+				return returnOf(models.ReceiptItem{ShortDescription: "Doritos Nacho Cheese", Price: "3.50"})
+			},
+			err: ErrInvalidReturn,
+		},
+		{
+			name: "More units than bought",
+			receipt: func() *models.Receipt {
+				return returnOf(models.ReceiptItem{ShortDescription: "Doritos Nacho Cheese", Price: "3.35", Quantity: "2"})
+			},
+			err: ErrInvalidReturn,
+		},
+		{
+			name: "Dated before the purchase",
+			receipt: func() *models.Receipt {
+				r := returnOf(models.ReceiptItem{ShortDescription: "Doritos Nacho Cheese", Price: "3.35"})
+				r.PurchaseDate = "2021-12-31"
+				return r
+			},
+			err: ErrInvalidReturn,
+		},
+		{
+			name: "Another retailer",
+			receipt: func() *models.Receipt {
+				r := returnOf(models.ReceiptItem{ShortDescription: "Doritos Nacho Cheese", Price: "3.35"})
+				r.Retailer = "Walgreens"
+				return r
+			},
+			err: ErrInvalidReturn,
+		},
+		{
+			name: "Another client",
+			receipt: func() *models.Receipt {
+				r := returnOf(models.ReceiptItem{ShortDescription: "Doritos Nacho Cheese", Price: "3.35"})
+				r.SubmittedBy = "sub:pos-2"
+				return r
+			},
+			err: ErrInvalidReturn,
+		},
+		{
+			name: "Return of a return",
+			receipt: func() *models.Receipt {
+				r := returnOf(models.ReceiptItem{ShortDescription: "Emils Cheese Pizza", Price: "12.25"})
+				r.OriginalID = &first.ID
+				return r
+			},
+			err: ErrInvalidReturn,
+		},
+		{
+			name: "Unknown purchase",
+			receipt: func() *models.Receipt {
+				r := returnOf(models.ReceiptItem{ShortDescription: "Doritos Nacho Cheese", Price: "3.35"})
+				id := uuid.New()
+				r.OriginalID = &id
+				return r
+			},
+			err: ErrOriginalReceiptNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.CreateReceipt(ctx, tt.receipt())
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+
+	// Returning everything else takes back exactly the remaining points.
+	second, err := service.CreateReceipt(ctx, returnOf(
+		models.ReceiptItem{ShortDescription: "Klarbrunn 12-PK 12 FL OZ", Price: "12.00"},
+		models.ReceiptItem{ShortDescription: "Mountain Dew 12PK", Price: "6.49"},
+		models.ReceiptItem{ShortDescription: "Knorr Creamy Chicken", Price: "1.26"},
+		models.ReceiptItem{ShortDescription: "Doritos Nacho Cheese", Price: "3.35"},
+	))
+	assert.NoError(t, err)
+	points, err = service.GetReceiptPoints(ctx, second)
+	assert.NoError(t, err)
+	assert.Equal(t, -18, points)
+	returned, err = service.GetReturnedPoints(ctx, purchase)
+	assert.NoError(t, err)
+	assert.Equal(t, -28, returned)
+
+	// The returns of a purchase are scored in one pass like they are one by one.
+	returns, err := service.GetReturnsByOriginalIDs(ctx, []uuid.UUID{purchase.ID, first.ID})
+	assert.NoError(t, err)
+	assert.Equal(t, map[uuid.UUID][]*models.Receipt{purchase.ID: {first, second}}, returns)
+	returnsPoints, err := service.GetReturnsPoints(ctx, purchase, returns[purchase.ID])
+	assert.NoError(t, err)
+	assert.Equal(t, []int{-10, -18}, returnsPoints)
+}
+
+func TestReceiptServiceImpl_CreateSignedReceiptOfRegisteredRetailer(t *testing.T) {
+	t.Parallel()
+
+	registry := retailerService.NewRetailerService(retailerRepository.InitRetailerRepository())
+	_, err := registry.CreateRetailer(context.Background(), "Target", []string{"target.com"}, nil)
+	assert.NoError(t, err)
+	terminals := signatureRepository.InitTerminalKeyRepository(models.RetailerSignaturePolicy{Policy: models.SignaturePolicyIgnore})
+	terminals.SetPolicy("target.com", models.RetailerSignaturePolicy{Policy: models.SignaturePolicyRequire})
+	service := NewReceiptService(repository.InitReceiptRepository(),
+		WithRetailerRegistry(registry),
+		WithSignatureService(signatureService.NewSignatureService(terminals)))
+
+	// The policy of the printed name still holds once the receipt matches Target.
+	created, err := service.CreateReceipt(context.Background(), &models.Receipt{
+		Retailer:     "target.com",
+		PurchaseDate: "2022-01-01",
+		PurchaseTime: "13:01",
+		Items:        []models.ReceiptItem{{ShortDescription: "Pepsi", Price: "1.25"}},
+		Total:        "1.25",
+	})
+	assert.Nil(t, created)
+	assert.Equal(t, signatureService.ErrSignatureRequired, err)
+}
+
+func TestReceiptServiceImpl_CreateReturnOfRegisteredRetailer(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	registry := retailerService.NewRetailerService(retailerRepository.InitRetailerRepository())
+	_, err := registry.CreateRetailer(ctx, "Target", []string{"target.com"}, []string{`(?i)^target\s*#\d+$`})
+	assert.NoError(t, err)
+	service := NewReceiptService(repository.InitReceiptRepository(), WithRetailerRegistry(registry))
+
+	purchase, err := service.CreateReceipt(ctx, &models.Receipt{
+		Retailer:     "target.com",
+		PurchaseDate: "2022-01-01",
+		PurchaseTime: "13:01",
+		Items:        []models.ReceiptItem{{ShortDescription: "Pepsi", Price: "1.25"}, {ShortDescription: "Dasani", Price: "1.40"}},
+		Total:        "2.65",
+	})
+	assert.NoError(t, err)
+	returnOf := func(retailer string, item models.ReceiptItem) *models.Receipt {
+		return &models.Receipt{
+			Retailer:     retailer,
+			PurchaseDate: "2022-01-05",
+			PurchaseTime: "10:00",
+			Items:        []models.ReceiptItem{item},
+			Total:        item.Price,
+			Type:         models.ReceiptTypeReturn,
+			OriginalID:   &purchase.ID,
+		}
+	}
+
+	// The store prints its name another way, but it is the same retailer.
+	_, err = service.CreateReceipt(ctx, returnOf("TARGET #1234", models.ReceiptItem{ShortDescription: "Pepsi", Price: "1.25"}))
+	assert.NoError(t, err)
+	_, err = service.CreateReceipt(ctx, returnOf("Walgreens", models.ReceiptItem{ShortDescription: "Dasani", Price: "1.40"}))
+	assert.ErrorIs(t, err, ErrInvalidReturn)
+}
+
+func TestReceiptServiceImpl_CreateReceiptInTimeZone(t *testing.T) {
+	t.Parallel()
+
+	receipt := func(purchasedAt, timeZone string) *models.Receipt {
+		return &models.Receipt{
+			Retailer:    "Target",
+			PurchasedAt: purchasedAt,
+			TimeZone:    timeZone,
+			Items:       []models.ReceiptItem{{ShortDescription: "Pepsi", Price: "1.25"}},
+			Total:       "1.25",
+		}
+	}
+	withZones := NewReceiptService(repository.InitReceiptRepository(), WithRetailerTimeZones(map[string]string{"target": "America/Chicago"}))
+	withoutZones := NewReceiptService(repository.InitReceiptRepository())
+
+	// Every receipt earns 6 points for the retailer and 25 for the total, and the odd day and
+	// afternoon points of its local time.
+	tests := []struct {
+		name    string
+		service ReceiptService
+		receipt *models.Receipt
+		zone    string
+		date    string
+		time    string
+		points  int
+	}{
+		{name: "Time zone of the retailer", service: withZones, receipt: receipt("2022-01-01T20:30:00Z", ""), zone: "America/Chicago", date: "2022-01-01", time: "14:30", points: 47},
+		{name: "UTC timestamp", service: withoutZones, receipt: receipt("2022-01-01T20:30:00Z", ""), zone: "+00:00", date: "2022-01-01", time: "20:30", points: 37},
+		{name: "Offset of the timestamp", service: withoutZones, receipt: receipt("2022-01-02T05:30:00+09:00", ""), zone: "+09:00", date: "2022-01-02", time: "05:30", points: 31},
+		{name: "Time zone of the receipt", service: withZones, receipt: receipt("2022-01-02T03:30:00+09:00", "America/New_York"), zone: "America/New_York", date: "2022-01-01", time: "13:30", points: 37},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.NoError(t, ValidateReceipt(context.Background(), tt.receipt, 0))
+			created, err := tt.service.CreateReceipt(context.Background(), tt.receipt)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.zone, created.TimeZone)
+			assert.Equal(t, tt.date, created.PurchaseDate)
+			assert.Equal(t, tt.time, created.PurchaseTime)
+
+			points, err := tt.service.GetReceiptPoints(context.Background(), created)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.points, points)
+		})
+	}
+
+	t.Run("Local time of the retailer", func(t *testing.T) {
+		printed := receipt("", "")
+		printed.PurchaseDate, printed.PurchaseTime = "2022-01-01", "14:30"
+		created, err := withZones.CreateReceipt(context.Background(), printed)
+		assert.NoError(t, err)
+		assert.Equal(t, "America/Chicago", created.TimeZone)
+		points, err := withZones.GetReceiptPoints(context.Background(), created)
+		assert.NoError(t, err)
+		assert.Equal(t, 47, points)
+	})
+
+	t.Run("Purchase time that is not the local time of the timestamp", func(t *testing.T) {
+		mismatch := receipt("2022-01-01T20:30:00Z", "")
+		mismatch.PurchaseTime = "20:30"
+		_, err := withZones.CreateReceipt(context.Background(), mismatch)
+		assert.ErrorIs(t, err, ErrPurchaseTimeMismatch)
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		assert.ErrorIs(t, ValidateReceipt(context.Background(), receipt("", ""), 0), ErrInvalidReceiptParams)
+		assert.ErrorIs(t, ValidateReceipt(context.Background(), receipt("2022-01-01 20:30", ""), 0), ErrInvalidReceiptParams)
+		assert.ErrorIs(t, ValidateReceipt(context.Background(), receipt("2022-01-01T20:30:00Z", "Mars/Olympus"), 0), ErrInvalidReceiptParams)
+	})
+}
+
+func TestReceiptServiceImpl_CreateReceiptOfRegisteredRetailer(t *testing.T) {
+	t.Parallel()
+
+	registry := retailerService.NewRetailerService(retailerRepository.InitRetailerRepository())
+	target, err := registry.CreateRetailer(context.Background(), "Target", []string{"target.com"}, []string{`(?i)^target\s*#\d+$`})
+	assert.NoError(t, err)
+	service := NewReceiptService(repository.InitReceiptRepository(),
+		WithRetailerRegistry(registry),
+		WithRetailerTimeZones(map[string]string{"target": "America/Chicago"}))
+	canonical := NewReceiptService(repository.InitReceiptRepository(),
+		WithRetailerRegistry(registry),
+		WithRetailerTimeZones(map[string]string{"target": "America/Chicago"}),
+		WithRuleSet(RuleSet{CanonicalRetailer: true}))
+
+	// The receipts of Target earn the afternoon points in Chicago and, with the canonical rule,
+	// 6 points for the registered name whatever name they print.
+	tests := []struct {
+		retailer        string
+		name            string
+		points          int
+		canonicalPoints int
+	}{
+		{retailer: "TARGET #1234", name: "Target", points: 51, canonicalPoints: 47},
+		{retailer: "target.com", name: "Target", points: 50, canonicalPoints: 47},
+		{retailer: "Walgreens", name: "Walgreens", points: 40, canonicalPoints: 40},
+	}
+	for _, tt := range tests {
+		t.Run(tt.retailer, func(t *testing.T) {
+			created, err := service.CreateReceipt(context.Background(), &models.Receipt{
+				Retailer:    tt.retailer,
+				PurchasedAt: "2022-01-01T20:30:00Z",
+				Items:       []models.ReceiptItem{{ShortDescription: "Pepsi", Price: "1.25"}},
+				Total:       "1.25",
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.retailer, created.Retailer)
+			assert.Equal(t, tt.name, created.GetRetailerName())
+			if tt.name == "Target" {
+				assert.Equal(t, &target.ID, created.RetailerID)
+			} else {
+				assert.Nil(t, created.RetailerID)
+			}
+
+			points, err := service.GetReceiptPoints(context.Background(), created)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.points, points)
+
+			points, err = canonical.GetReceiptPoints(context.Background(), created)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.canonicalPoints, points)
+		})
+	}
+
+	receipts, err := service.ListReceipts(context.Background(), models.ReceiptFilter{Retailer: "target"})
+	assert.NoError(t, err)
+	assert.Len(t, receipts, 2)
+	receipts, err = service.ListReceipts(context.Background(), models.ReceiptFilter{Retailer: "TARGET #1234"})
+	assert.NoError(t, err)
+	assert.Len(t, receipts, 1)
+}
@@ -0,0 +1,281 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/repository"
+	"github.com/CarlosMtz98/receipt-processor-challenge/pkg/currency"
+	"math"
+	"strings"
+)
+
+// returnLedger follows what the returns of a purchase took back from each of its lines: the
+// amounts in the minor unit of the purchase currency, and the quantities in thousandths.
+type returnLedger struct {
+	purchase           *models.Receipt
+	prices             []int64
+	quantities         []int64
+	returned           []int64
+	returnedQuantities []int64
+}
+
+func newReturnLedger(purchase *models.Receipt) (*returnLedger, error) {
+	ledger := &returnLedger{
+		purchase:           purchase,
+		prices:             make([]int64, len(purchase.Items)),
+		quantities:         make([]int64, len(purchase.Items)),
+		returned:           make([]int64, len(purchase.Items)),
+		returnedQuantities: make([]int64, len(purchase.Items)),
+	}
+	for i := range purchase.Items {
+		price, err := currency.ParseAmount(purchase.Items[i].Price, purchase.GetCurrency())
+		if err != nil {
+			return nil, err
+		}
+		quantity, err := purchase.Items[i].GetQuantity()
+		if err != nil {
+			return nil, err
+		}
+		ledger.prices[i] = price
+		ledger.quantities[i] = thousandths(quantity)
+	}
+	return ledger, nil
+}
+
+// record matches every item of the return with the first line of the purchase for the same
+// product that still has enough left to give back, and takes the item from that line. It
+// returns the matched line of every item.
+func (l *returnLedger) record(r *models.Receipt) ([]int, error) {
+	lines := make([]int, len(r.Items))
+	for i := range r.Items {
+		item := &r.Items[i]
+		price, err := currency.ParseAmount(item.Price, l.purchase.GetCurrency())
+		if err != nil {
+			return nil, err
+		}
+		// Only the quantities stated on the return are counted, a partial refund of a line
+		// does not have to say how many units it is for.
+		var quantity int64
+		if item.Quantity != "" {
+			value, err := item.GetQuantity()
+			if err != nil {
+				return nil, err
+			}
+			quantity = thousandths(value)
+		}
+
+		lines[i] = -1
+		for j := range l.purchase.Items {
+			if !sameProduct(&l.purchase.Items[j], item) ||
+				l.returned[j]+price > l.prices[j] ||
+				l.returnedQuantities[j]+quantity > l.quantities[j] {
+				continue
+			}
+			l.returned[j] += price
+			l.returnedQuantities[j] += quantity
+			lines[i] = j
+			break
+		}
+		if lines[i] < 0 {
+			return nil, fmt.Errorf("item %d was not bought on the original receipt or was already returned", i+1)
+		}
+	}
+	return lines, nil
+}
+
+// returnLedger replays the returns of the purchase recorded before the receipt.
+func (s *ReceiptServiceImpl) returnLedger(ctx context.Context, purchase *models.Receipt, receipt *models.Receipt) (*returnLedger, error) {
+	ledger, err := newReturnLedger(purchase)
+	if err != nil {
+		return nil, err
+	}
+	returns, err := s.receiptRepository.ListReturns(ctx, purchase.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, earlier := range returns {
+		if earlier.ID == receipt.ID {
+			break
+		}
+		if _, err := ledger.record(earlier); err != nil {
+			return nil, err
+		}
+	}
+	return ledger, nil
+}
+
+// validateReturn checks a new return against its purchase and the returns already recorded.
+func (s *ReceiptServiceImpl) validateReturn(ctx context.Context, receipt *models.Receipt) error {
+	purchase, err := s.receiptRepository.GetByID(ctx, *receipt.OriginalID)
+	if errors.Is(err, repository.ErrReceiptNotFound) {
+		return ErrOriginalReceiptNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case purchase.IsReturn():
+		return fmt.Errorf("%w: the original receipt is a return", ErrInvalidReturn)
+	case purchase.Status != models.ReceiptStatusProcessed:
+		return fmt.Errorf("%w: the original receipt has not been scored", ErrInvalidReturn)
+	case !strings.EqualFold(receipt.GetRetailerName(), purchase.GetRetailerName()):
+		return fmt.Errorf("%w: the return must be from %s like the original receipt", ErrInvalidReturn, purchase.GetRetailerName())
+	case receipt.SubmittedBy != purchase.SubmittedBy:
+		return fmt.Errorf("%w: the return must be submitted by the client of the original receipt", ErrInvalidReturn)
+	case receipt.GetCurrency() != purchase.GetCurrency():
+		return fmt.Errorf("%w: the return must be in %s like the original receipt", ErrInvalidReturn, purchase.GetCurrency())
+	case receipt.PurchaseDate < purchase.PurchaseDate:
+		return fmt.Errorf("%w: the return is dated before the purchase", ErrInvalidReturn)
+	}
+
+	ledger, err := s.returnLedger(ctx, purchase, receipt)
+	if err != nil {
+		return err
+	}
+	if _, err := ledger.record(receipt); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidReturn, err)
+	}
+	return nil
+}
+
+// returnPointsBreakdown takes back the points the returned items earned on the purchase: the
+// description points of their lines, and the share of the other points of the purchase that
+// the amount returned is of its items. Both are rounded down on the running total of the
+// returns, so that returning everything in several returns takes back exactly the points of
+// the purchase.
+func (s *ReceiptServiceImpl) returnPointsBreakdown(ctx context.Context, receipt *models.Receipt) (*models.PointsBreakdown, error) {
+	purchase, err := s.receiptRepository.GetByID(ctx, *receipt.OriginalID)
+	if err != nil {
+		return nil, err
+	}
+	earned, scored, err := s.purchasePoints(ctx, purchase)
+	if err != nil {
+		return nil, err
+	}
+
+	ledger, err := s.returnLedger(ctx, purchase, receipt)
+	if err != nil {
+		return nil, err
+	}
+	return s.takeBack(earned, scored, ledger, receipt)
+}
+
+// GetReturnsPoints replays the returns on one ledger, so that the points of all the returns
+// of a purchase are computed with one pass instead of one replay per return.
+func (s *ReceiptServiceImpl) GetReturnsPoints(ctx context.Context, purchase *models.Receipt, returns []*models.Receipt) ([]int, error) {
+	if purchase == nil {
+		return nil, ErrReceiptIsNil
+	}
+	earned, scored, err := s.purchasePoints(ctx, purchase)
+	if err != nil {
+		return nil, err
+	}
+	ledger, err := newReturnLedger(purchase)
+	if err != nil {
+		return nil, err
+	}
+
+	points := make([]int, len(returns))
+	for i, r := range returns {
+		breakdown, err := s.takeBack(earned, scored, ledger, r)
+		if err != nil {
+			return nil, err
+		}
+		points[i] = breakdown.Total
+	}
+	return points, nil
+}
+
+// purchasePoints gives the points the purchase earned and the receipt they were scored on.
+func (s *ReceiptServiceImpl) purchasePoints(ctx context.Context, purchase *models.Receipt) (*models.PointsBreakdown, *models.Receipt, error) {
+	earned, err := s.GetReceiptPointsBreakdown(ctx, purchase)
+	if err != nil {
+		return nil, nil, err
+	}
+	scored, err := s.scoredReceipt(purchase)
+	if err != nil {
+		return nil, nil, err
+	}
+	return earned, scored, nil
+}
+
+// takeBack records the return on the ledger of its purchase, which holds the returns recorded
+// before it, and breaks down the points it takes back.
+func (s *ReceiptServiceImpl) takeBack(earned *models.PointsBreakdown, scored *models.Receipt, ledger *returnLedger, receipt *models.Receipt) (*models.PointsBreakdown, error) {
+	purchase := ledger.purchase
+	before := append([]int64(nil), ledger.returned...)
+	if _, err := ledger.record(receipt); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidReturn, err)
+	}
+
+	descriptionPoints := 0
+	var itemsTotal, returnedBefore, returnedNow int64
+	for j := range ledger.prices {
+		itemsTotal += ledger.prices[j]
+		returnedBefore += before[j]
+		returnedNow += ledger.returned[j] - before[j]
+		if ledger.returned[j] == before[j] {
+			continue
+		}
+		linePoints, err := getItemDescriptionPoints(&scored.Items[j], s.ruleSet.QuantityAware)
+		if err != nil {
+			return nil, err
+		}
+		descriptionPoints += reversedPoints(linePoints, ledger.prices[j], before[j], ledger.returned[j]-before[j])
+	}
+
+	otherPoints := 0
+	for _, rule := range earned.Rules {
+		if rule.Rule != "item_descriptions" && rule.Rule != "signature_policy" {
+			otherPoints += rule.Points
+		}
+	}
+
+	breakdown := &models.PointsBreakdown{
+		Rules: []models.PointsRule{
+			{Rule: "returned_item_descriptions", Description: "The description points of the returned items", Points: -descriptionPoints},
+			{Rule: "returned_share", Description: "The share of the other points of the original receipt for the amount returned", Points: -reversedPoints(otherPoints, itemsTotal, returnedBefore, returnedNow)},
+		},
+	}
+	for _, rule := range breakdown.Rules {
+		breakdown.Total += rule.Points
+	}
+
+	// The purchase only earned a share of its points when it was unsigned, and so does the
+	// return take back only that share.
+	if adjusted := applySignaturePolicy(purchase, breakdown.Total); adjusted != breakdown.Total {
+		breakdown.Rules = append(breakdown.Rules, models.PointsRule{
+			Rule:        "signature_policy",
+			Description: "The original receipt was unsigned and earned a share of the points",
+			Points:      adjusted - breakdown.Total,
+		})
+		breakdown.Total = adjusted
+	}
+
+	return breakdown, nil
+}
+
+// reversedPoints is the part of the points taken back by returning an amount of a total, when
+// an earlier amount was already returned.
+func reversedPoints(points int, total, earlier, amount int64) int {
+	if total <= 0 {
+		return 0
+	}
+	return int(int64(points)*(earlier+amount)/total - int64(points)*earlier/total)
+}
+
+// sameProduct tells whether the returned item is the line of the purchase: the same
+// description, and the same SKU when the return gives one.
+func sameProduct(line *models.ReceiptItem, item *models.ReceiptItem) bool {
+	if item.SKU != "" && item.SKU != line.SKU {
+		return false
+	}
+	return strings.EqualFold(strings.TrimSpace(line.ShortDescription), strings.TrimSpace(item.ShortDescription))
+}
+
+func thousandths(quantity float64) int64 {
+	return int64(math.Round(quantity * 1000))
+}
@@ -0,0 +1,174 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/retailer/repository"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/retailer/service"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/dto"
+	"github.com/CarlosMtz98/receipt-processor-challenge/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"net/http"
+)
+
+type RetailerHandler interface {
+	Create(c *gin.Context)
+	List(c *gin.Context)
+	Get(c *gin.Context)
+	Update(c *gin.Context)
+	Delete(c *gin.Context)
+}
+
+type RetailerHandlerImpl struct {
+	retailerSvc service.RetailerService
+}
+
+func NewRetailerHandler(retailerService service.RetailerService) RetailerHandler {
+	return &RetailerHandlerImpl{
+		retailerSvc: retailerService,
+	}
+}
+
+func (h RetailerHandlerImpl) Create(c *gin.Context) {
+	request, ok := bindRetailerRequest(c)
+	if !ok {
+		return
+	}
+
+	retailer, err := h.retailerSvc.CreateRetailer(c, request.Name, request.Aliases, request.Patterns)
+	if handleRetailerError(c, err, "Could not create the retailer") {
+		return
+	}
+
+	c.JSON(http.StatusCreated, newRetailerResponse(retailer))
+}
+
+func (h RetailerHandlerImpl) List(c *gin.Context) {
+	retailers, err := h.retailerSvc.ListRetailers(c)
+	if err != nil {
+		utils.HandleInternalError(c, "Could not list the retailers", err)
+		return
+	}
+
+	response := dto.ListRetailersResponse{
+		Retailers: make([]dto.RetailerResponse, len(retailers)),
+	}
+	for i, retailer := range retailers {
+		response.Retailers[i] = newRetailerResponse(retailer)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func (h RetailerHandlerImpl) Get(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.HandleBadRequest(c, "Invalid ID format", err)
+		return
+	}
+
+	retailer, err := h.retailerSvc.GetRetailer(c, id)
+	if errors.Is(err, repository.ErrRetailerNotFound) {
+		utils.HandleNotFound(c, fmt.Sprintf("Could not find the retailer with ID %s", id))
+		return
+	}
+	if err != nil {
+		utils.HandleInternalError(c, "Could not get the retailer", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newRetailerResponse(retailer))
+}
+
+func (h RetailerHandlerImpl) Update(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.HandleBadRequest(c, "Invalid ID format", err)
+		return
+	}
+	request, ok := bindRetailerRequest(c)
+	if !ok {
+		return
+	}
+
+	retailer, err := h.retailerSvc.UpdateRetailer(c, id, request.Name, request.Aliases, request.Patterns)
+	if errors.Is(err, repository.ErrRetailerNotFound) {
+		utils.HandleNotFound(c, fmt.Sprintf("Could not find the retailer with ID %s", id))
+		return
+	}
+	if handleRetailerError(c, err, "Could not update the retailer") {
+		return
+	}
+
+	c.JSON(http.StatusOK, newRetailerResponse(retailer))
+}
+
+func (h RetailerHandlerImpl) Delete(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.HandleBadRequest(c, "Invalid ID format", err)
+		return
+	}
+
+	err = h.retailerSvc.DeleteRetailer(c, id)
+	if errors.Is(err, repository.ErrRetailerNotFound) {
+		utils.HandleNotFound(c, fmt.Sprintf("Could not find the retailer with ID %s", id))
+		return
+	}
+	if err != nil {
+		utils.HandleInternalError(c, "Could not delete the retailer", err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func bindRetailerRequest(c *gin.Context) (dto.RetailerRequest, bool) {
+	request := dto.RetailerRequest{}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.HandleBadRequest(c, "Could not parse the request body", err)
+		return request, false
+	}
+
+	if err := utils.ValidateStruct(c, request); err != nil {
+		utils.HandleBadRequest(c, "The retailer params are not valid", err)
+		return request, false
+	}
+	return request, true
+}
+
+// handleRetailerError answers the errors of creating or updating a retailer, and tells
+// whether there was one.
+func handleRetailerError(c *gin.Context, err error, message string) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, service.ErrInvalidRetailer):
+		utils.HandleBadRequest(c, "The retailer params are not valid", err)
+	case errors.Is(err, service.ErrRetailerNameTaken):
+		utils.HandleConflict(c, err.Error())
+	default:
+		utils.HandleInternalError(c, message, err)
+	}
+	return true
+}
+
+func newRetailerResponse(retailer *models.Retailer) dto.RetailerResponse {
+	response := dto.RetailerResponse{
+		ID:        retailer.ID.String(),
+		Name:      retailer.Name,
+		Aliases:   retailer.Aliases,
+		Patterns:  retailer.Patterns,
+		CreatedAt: retailer.CreatedAt,
+		UpdatedAt: retailer.UpdatedAt,
+	}
+	// The lists are answered empty rather than null.
+	if response.Aliases == nil {
+		response.Aliases = []string{}
+	}
+	if response.Patterns == nil {
+		response.Patterns = []string{}
+	}
+	return response
+}
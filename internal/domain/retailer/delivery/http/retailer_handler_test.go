@@ -0,0 +1,147 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/retailer/mock"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/retailer/repository"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/retailer/service"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/dto"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRetailerHandlerImpl_Create(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRetailerService := mock.NewMockRetailerService(ctrl)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/admin/retailers", NewRetailerHandler(mockRetailerService).Create)
+
+	send := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/admin/retailers", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	t.Run("Created", func(t *testing.T) {
+		retailer := &models.Retailer{
+			ID:        uuid.New(),
+			Name:      "Target",
+			Aliases:   []string{"target.com"},
+			CreatedAt: time.Now(),
+		}
+		mockRetailerService.EXPECT().
+			CreateRetailer(gomock.Any(), "Target", []string{"target.com"}, nil).
+			Return(retailer, nil)
+
+		resp := send(`{"name": "Target", "aliases": ["target.com"]}`)
+		assert.Equal(t, http.StatusCreated, resp.Code)
+
+		var response dto.RetailerResponse
+		if err := json.Unmarshal(resp.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, retailer.ID.String(), response.ID)
+		assert.Equal(t, []string{"target.com"}, response.Aliases)
+		assert.Equal(t, []string{}, response.Patterns)
+	})
+
+	t.Run("Missing name", func(t *testing.T) {
+		resp := send(`{"aliases": ["target.com"]}`)
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+	})
+
+	t.Run("Invalid pattern", func(t *testing.T) {
+		mockRetailerService.EXPECT().
+			CreateRetailer(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(nil, service.ErrInvalidRetailer)
+
+		resp := send(`{"name": "Target", "patterns": ["(target"]}`)
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+	})
+
+	t.Run("Name taken", func(t *testing.T) {
+		mockRetailerService.EXPECT().
+			CreateRetailer(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(nil, fmt.Errorf("%w: target", service.ErrRetailerNameTaken))
+
+		resp := send(`{"name": "Target"}`)
+		assert.Equal(t, http.StatusConflict, resp.Code)
+	})
+}
+
+func TestRetailerHandlerImpl_Update(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRetailerService := mock.NewMockRetailerService(ctrl)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.PUT("/admin/retailers/:id", NewRetailerHandler(mockRetailerService).Update)
+
+	send := func(id string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("PUT", "/admin/retailers/"+id, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	t.Run("Updated", func(t *testing.T) {
+		retailer := &models.Retailer{ID: uuid.New(), Name: "Target", Patterns: []string{`(?i)^target #\d+$`}}
+		mockRetailerService.EXPECT().
+			UpdateRetailer(gomock.Any(), retailer.ID, "Target", nil, []string{`(?i)^target #\d+$`}).
+			Return(retailer, nil)
+
+		resp := send(retailer.ID.String(), `{"name": "Target", "patterns": ["(?i)^target #\\d+$"]}`)
+		assert.Equal(t, http.StatusOK, resp.Code)
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		mockRetailerService.EXPECT().
+			UpdateRetailer(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(nil, repository.ErrRetailerNotFound)
+
+		resp := send(uuid.NewString(), `{"name": "Target"}`)
+		assert.Equal(t, http.StatusNotFound, resp.Code)
+	})
+
+	t.Run("Invalid ID", func(t *testing.T) {
+		resp := send("not-a-uuid", `{"name": "Target"}`)
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+	})
+}
+
+func TestRetailerHandlerImpl_Delete(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRetailerService := mock.NewMockRetailerService(ctrl)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.DELETE("/admin/retailers/:id", NewRetailerHandler(mockRetailerService).Delete)
+
+	id := uuid.New()
+	mockRetailerService.EXPECT().DeleteRetailer(gomock.Any(), id).Return(nil)
+	mockRetailerService.EXPECT().DeleteRetailer(gomock.Any(), id).Return(repository.ErrRetailerNotFound)
+
+	for _, expected := range []int{http.StatusNoContent, http.StatusNotFound} {
+		req := httptest.NewRequest("DELETE", "/admin/retailers/"+id.String(), nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		assert.Equal(t, expected, resp.Code)
+	}
+}
@@ -0,0 +1,18 @@
+package http
+
+import (
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// ScopeRetailersAdmin allows managing the retailer registry.
+const ScopeRetailersAdmin = "retailers:admin"
+
+func MapRetailerRoutes(routesGroup *gin.RouterGroup, handler RetailerHandler, guard middleware.ScopeGuard) {
+	routesGroup.Use(guard.Require(ScopeRetailersAdmin))
+	routesGroup.POST("", handler.Create)
+	routesGroup.GET("", handler.List)
+	routesGroup.GET("/:id", handler.Get)
+	routesGroup.PUT("/:id", handler.Update)
+	routesGroup.DELETE("/:id", handler.Delete)
+}
@@ -0,0 +1,126 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/domain/retailer/service/retailer_service.go
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	reflect "reflect"
+
+	models "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
+	gomock "github.com/golang/mock/gomock"
+	uuid "github.com/google/uuid"
+)
+
+// MockRetailerService is a mock of RetailerService interface.
+type MockRetailerService struct {
+	ctrl     *gomock.Controller
+	recorder *MockRetailerServiceMockRecorder
+}
+
+// MockRetailerServiceMockRecorder is the mock recorder for MockRetailerService.
+type MockRetailerServiceMockRecorder struct {
+	mock *MockRetailerService
+}
+
+// NewMockRetailerService creates a new mock instance.
+func NewMockRetailerService(ctrl *gomock.Controller) *MockRetailerService {
+	mock := &MockRetailerService{ctrl: ctrl}
+	mock.recorder = &MockRetailerServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRetailerService) EXPECT() *MockRetailerServiceMockRecorder {
+	return m.recorder
+}
+
+// CreateRetailer mocks base method.
+func (m *MockRetailerService) CreateRetailer(ctx context.Context, name string, aliases, patterns []string) (*models.Retailer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateRetailer", ctx, name, aliases, patterns)
+	ret0, _ := ret[0].(*models.Retailer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateRetailer indicates an expected call of CreateRetailer.
+func (mr *MockRetailerServiceMockRecorder) CreateRetailer(ctx, name, aliases, patterns interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateRetailer", reflect.TypeOf((*MockRetailerService)(nil).CreateRetailer), ctx, name, aliases, patterns)
+}
+
+// DeleteRetailer mocks base method.
+func (m *MockRetailerService) DeleteRetailer(ctx context.Context, id uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteRetailer", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteRetailer indicates an expected call of DeleteRetailer.
+func (mr *MockRetailerServiceMockRecorder) DeleteRetailer(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteRetailer", reflect.TypeOf((*MockRetailerService)(nil).DeleteRetailer), ctx, id)
+}
+
+// GetRetailer mocks base method.
+func (m *MockRetailerService) GetRetailer(ctx context.Context, id uuid.UUID) (*models.Retailer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRetailer", ctx, id)
+	ret0, _ := ret[0].(*models.Retailer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRetailer indicates an expected call of GetRetailer.
+func (mr *MockRetailerServiceMockRecorder) GetRetailer(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRetailer", reflect.TypeOf((*MockRetailerService)(nil).GetRetailer), ctx, id)
+}
+
+// ListRetailers mocks base method.
+func (m *MockRetailerService) ListRetailers(ctx context.Context) ([]*models.Retailer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListRetailers", ctx)
+	ret0, _ := ret[0].([]*models.Retailer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListRetailers indicates an expected call of ListRetailers.
+func (mr *MockRetailerServiceMockRecorder) ListRetailers(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListRetailers", reflect.TypeOf((*MockRetailerService)(nil).ListRetailers), ctx)
+}
+
+// Resolve mocks base method.
+func (m *MockRetailerService) Resolve(ctx context.Context, name string) (*models.Retailer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Resolve", ctx, name)
+	ret0, _ := ret[0].(*models.Retailer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Resolve indicates an expected call of Resolve.
+func (mr *MockRetailerServiceMockRecorder) Resolve(ctx, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Resolve", reflect.TypeOf((*MockRetailerService)(nil).Resolve), ctx, name)
+}
+
+// UpdateRetailer mocks base method.
+func (m *MockRetailerService) UpdateRetailer(ctx context.Context, id uuid.UUID, name string, aliases, patterns []string) (*models.Retailer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateRetailer", ctx, id, name, aliases, patterns)
+	ret0, _ := ret[0].(*models.Retailer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateRetailer indicates an expected call of UpdateRetailer.
+func (mr *MockRetailerServiceMockRecorder) UpdateRetailer(ctx, id, name, aliases, patterns interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateRetailer", reflect.TypeOf((*MockRetailerService)(nil).UpdateRetailer), ctx, id, name, aliases, patterns)
+}
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
+	"github.com/google/uuid"
+	"sort"
+	"sync"
+)
+
+var (
+	// ErrRetailerNotFound is returned when a retailer is not in the registry.
+	ErrRetailerNotFound = errors.New("the retailer was not found")
+)
+
+type RetailerRepository interface {
+	Create(ctx context.Context, retailer *models.Retailer) error
+	GetByID(ctx context.Context, id uuid.UUID) (*models.Retailer, error)
+	List(ctx context.Context) ([]*models.Retailer, error)
+	Update(ctx context.Context, retailer *models.Retailer) error
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+type InMemoryRetailerRepository struct {
+	mu        sync.RWMutex
+	retailers map[uuid.UUID]*models.Retailer
+}
+
+func InitRetailerRepository() RetailerRepository {
+	return &InMemoryRetailerRepository{
+		retailers: make(map[uuid.UUID]*models.Retailer),
+	}
+}
+
+func (memoryRepo *InMemoryRetailerRepository) Create(ctx context.Context, retailer *models.Retailer) error {
+	memoryRepo.mu.Lock()
+	defer memoryRepo.mu.Unlock()
+
+	memoryRepo.retailers[retailer.ID] = copyRetailer(retailer)
+	return nil
+}
+
+func (memoryRepo *InMemoryRetailerRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Retailer, error) {
+	memoryRepo.mu.RLock()
+	defer memoryRepo.mu.RUnlock()
+
+	if retailer, ok := memoryRepo.retailers[id]; ok {
+		return copyRetailer(retailer), nil
+	}
+	return nil, ErrRetailerNotFound
+}
+
+// List returns the retailers in the order they were registered.
+func (memoryRepo *InMemoryRetailerRepository) List(ctx context.Context) ([]*models.Retailer, error) {
+	memoryRepo.mu.RLock()
+	defer memoryRepo.mu.RUnlock()
+
+	retailers := make([]*models.Retailer, 0, len(memoryRepo.retailers))
+	for _, retailer := range memoryRepo.retailers {
+		retailers = append(retailers, copyRetailer(retailer))
+	}
+	sort.Slice(retailers, func(i, j int) bool {
+		return retailers[i].CreatedAt.Before(retailers[j].CreatedAt)
+	})
+	return retailers, nil
+}
+
+func (memoryRepo *InMemoryRetailerRepository) Update(ctx context.Context, retailer *models.Retailer) error {
+	memoryRepo.mu.Lock()
+	defer memoryRepo.mu.Unlock()
+
+	if _, ok := memoryRepo.retailers[retailer.ID]; !ok {
+		return ErrRetailerNotFound
+	}
+	memoryRepo.retailers[retailer.ID] = copyRetailer(retailer)
+	return nil
+}
+
+func (memoryRepo *InMemoryRetailerRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	memoryRepo.mu.Lock()
+	defer memoryRepo.mu.Unlock()
+
+	if _, ok := memoryRepo.retailers[id]; !ok {
+		return ErrRetailerNotFound
+	}
+	delete(memoryRepo.retailers, id)
+	return nil
+}
+
+func copyRetailer(retailer *models.Retailer) *models.Retailer {
+	copied := *retailer
+	copied.Aliases = append([]string(nil), retailer.Aliases...)
+	copied.Patterns = append([]string(nil), retailer.Patterns...)
+	return &copied
+}
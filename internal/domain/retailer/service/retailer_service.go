@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/retailer/repository"
+	"github.com/google/uuid"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrInvalidRetailer is returned when the retailer has no name, an empty alias or pattern, or a pattern that does not compile.
+	ErrInvalidRetailer = errors.New("the retailer params are not valid")
+	// ErrRetailerNameTaken is returned when the name or an alias of the retailer is already one of another retailer.
+	ErrRetailerNameTaken = errors.New("the name is already used by another retailer")
+)
+
+type RetailerService interface {
+	CreateRetailer(ctx context.Context, name string, aliases []string, patterns []string) (*models.Retailer, error)
+	GetRetailer(ctx context.Context, id uuid.UUID) (*models.Retailer, error)
+	ListRetailers(ctx context.Context) ([]*models.Retailer, error)
+	UpdateRetailer(ctx context.Context, id uuid.UUID, name string, aliases []string, patterns []string) (*models.Retailer, error)
+	DeleteRetailer(ctx context.Context, id uuid.UUID) error
+	// Resolve finds the retailer a name printed on a receipt belongs to, nil when it matches
+	// none of the registry.
+	Resolve(ctx context.Context, name string) (*models.Retailer, error)
+}
+
+type RetailerServiceImpl struct {
+	retailerRepository repository.RetailerRepository
+	now                func() time.Time
+	// mu serializes the changes to the registry, so that two retailers cannot take the same
+	// name at once.
+	mu sync.Mutex
+	// compiled caches the patterns of the registry, keyed by their source.
+	compiled   map[string]*regexp.Regexp
+	compiledMu sync.Mutex
+}
+
+func NewRetailerService(retailerRepository repository.RetailerRepository) RetailerService {
+	return &RetailerServiceImpl{
+		retailerRepository: retailerRepository,
+		now:                time.Now,
+		compiled:           make(map[string]*regexp.Regexp),
+	}
+}
+
+func (s *RetailerServiceImpl) CreateRetailer(ctx context.Context, name string, aliases []string, patterns []string) (*models.Retailer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	retailer := &models.Retailer{
+		ID:        uuid.New(),
+		Name:      strings.TrimSpace(name),
+		Aliases:   trimAll(aliases),
+		Patterns:  patterns,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.validate(ctx, retailer); err != nil {
+		return nil, err
+	}
+	if err := s.retailerRepository.Create(ctx, retailer); err != nil {
+		return nil, err
+	}
+	return retailer, nil
+}
+
+func (s *RetailerServiceImpl) GetRetailer(ctx context.Context, id uuid.UUID) (*models.Retailer, error) {
+	return s.retailerRepository.GetByID(ctx, id)
+}
+
+func (s *RetailerServiceImpl) ListRetailers(ctx context.Context) ([]*models.Retailer, error) {
+	return s.retailerRepository.List(ctx)
+}
+
+// UpdateRetailer replaces the name, aliases and patterns of the retailer. Receipts already
+// created keep the retailer they were matched with.
+func (s *RetailerServiceImpl) UpdateRetailer(ctx context.Context, id uuid.UUID, name string, aliases []string, patterns []string) (*models.Retailer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	retailer, err := s.retailerRepository.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	retailer.Name = strings.TrimSpace(name)
+	retailer.Aliases = trimAll(aliases)
+	retailer.Patterns = patterns
+	retailer.UpdatedAt = s.now()
+	if err := s.validate(ctx, retailer); err != nil {
+		return nil, err
+	}
+	if err := s.retailerRepository.Update(ctx, retailer); err != nil {
+		return nil, err
+	}
+	return retailer, nil
+}
+
+func (s *RetailerServiceImpl) DeleteRetailer(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.retailerRepository.Delete(ctx, id)
+}
+
+// Resolve matches the name with the names and aliases of every retailer first, and only then
+// with their patterns, in the order the retailers were registered.
+func (s *RetailerServiceImpl) Resolve(ctx context.Context, name string) (*models.Retailer, error) {
+	retailers, err := s.retailerRepository.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	normalized := models.NormalizeRetailerName(name)
+	for _, retailer := range retailers {
+		for _, known := range retailer.Names() {
+			if known == normalized {
+				return retailer, nil
+			}
+		}
+	}
+
+	raw := strings.TrimSpace(name)
+	for _, retailer := range retailers {
+		for _, pattern := range retailer.Patterns {
+			matcher, err := s.compile(pattern)
+			if err != nil {
+				return nil, err
+			}
+			if matcher.MatchString(raw) {
+				return retailer, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// validate checks the retailer and that none of its names is one of another retailer.
+func (s *RetailerServiceImpl) validate(ctx context.Context, retailer *models.Retailer) error {
+	if retailer.Name == "" {
+		return fmt.Errorf("%w: the name is required", ErrInvalidRetailer)
+	}
+	for _, alias := range retailer.Aliases {
+		if alias == "" {
+			return fmt.Errorf("%w: the aliases can't be empty", ErrInvalidRetailer)
+		}
+	}
+	for _, pattern := range retailer.Patterns {
+		// An empty pattern would match every receipt.
+		if pattern == "" {
+			return fmt.Errorf("%w: the patterns can't be empty", ErrInvalidRetailer)
+		}
+		if _, err := s.compile(pattern); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidRetailer, err)
+		}
+	}
+
+	retailers, err := s.retailerRepository.List(ctx)
+	if err != nil {
+		return err
+	}
+	taken := make(map[string]bool)
+	for _, other := range retailers {
+		if other.ID == retailer.ID {
+			continue
+		}
+		for _, name := range other.Names() {
+			taken[name] = true
+		}
+	}
+	for _, name := range retailer.Names() {
+		if taken[name] {
+			return fmt.Errorf("%w: %s", ErrRetailerNameTaken, name)
+		}
+	}
+	return nil
+}
+
+func (s *RetailerServiceImpl) compile(pattern string) (*regexp.Regexp, error) {
+	s.compiledMu.Lock()
+	defer s.compiledMu.Unlock()
+
+	if matcher, ok := s.compiled[pattern]; ok {
+		return matcher, nil
+	}
+	matcher, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	s.compiled[pattern] = matcher
+	return matcher, nil
+}
+
+func trimAll(values []string) []string {
+	trimmed := make([]string, len(values))
+	for i, value := range values {
+		trimmed[i] = strings.TrimSpace(value)
+	}
+	return trimmed
+}
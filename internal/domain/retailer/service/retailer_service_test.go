@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/retailer/repository"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func newTestService() RetailerService {
+	clock := time.Date(2022, time.January, 1, 0, 0, 0, 0, time.UTC)
+	svc := NewRetailerService(repository.InitRetailerRepository()).(*RetailerServiceImpl)
+	// Every retailer is created a second after the previous one, so that they list in order.
+	svc.now = func() time.Time {
+		clock = clock.Add(time.Second)
+		return clock
+	}
+	return svc
+}
+
+func TestRetailerServiceImpl_Resolve(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService()
+
+	target, err := svc.CreateRetailer(ctx, "Target", []string{" target.com "}, []string{`(?i)^target\s*#\d+$`})
+	require.NoError(t, err)
+	catchAll, err := svc.CreateRetailer(ctx, "Target Optical", nil, []string{`(?i)^target`})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		expected *uuid.UUID
+	}{
+		{name: "Target", expected: &target.ID},
+		{name: "  TARGET ", expected: &target.ID},
+		{name: "target.com", expected: &target.ID},
+		{name: "TARGET #1234", expected: &target.ID},
+		// Names and aliases are matched before any pattern.
+		{name: "target  optical", expected: &catchAll.ID},
+		{name: "Target Express", expected: &catchAll.ID},
+		{name: "Walgreens"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			retailer, err := svc.Resolve(ctx, tt.name)
+			assert.NoError(t, err)
+			if tt.expected == nil {
+				assert.Nil(t, retailer)
+				return
+			}
+			require.NotNil(t, retailer)
+			assert.Equal(t, *tt.expected, retailer.ID)
+		})
+	}
+}
+
+func TestRetailerServiceImpl_CreateRetailer(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService()
+
+	created, err := svc.CreateRetailer(ctx, " Target ", []string{"target.com"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Target", created.Name)
+
+	tests := []struct {
+		name     string
+		retailer string
+		aliases  []string
+		patterns []string
+		err      error
+	}{
+		{name: "Missing name", retailer: "  ", err: ErrInvalidRetailer},
+		{name: "Empty alias", retailer: "Walgreens", aliases: []string{""}, err: ErrInvalidRetailer},
+		{name: "Empty pattern", retailer: "Walgreens", patterns: []string{""}, err: ErrInvalidRetailer},
+		{name: "Invalid pattern", retailer: "Walgreens", patterns: []string{"(walgreens"}, err: ErrInvalidRetailer},
+		{name: "Name taken", retailer: "TARGET", err: ErrRetailerNameTaken},
+		{name: "Alias taken", retailer: "Target Online", aliases: []string{"Target.com"}, err: ErrRetailerNameTaken},
+		{name: "Alias of another name", retailer: "Target Online", aliases: []string{"target"}, err: ErrRetailerNameTaken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.CreateRetailer(ctx, tt.retailer, tt.aliases, tt.patterns)
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+}
+
+func TestRetailerServiceImpl_UpdateRetailer(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService()
+
+	target, err := svc.CreateRetailer(ctx, "Target", []string{"target.com"}, nil)
+	require.NoError(t, err)
+	walgreens, err := svc.CreateRetailer(ctx, "Walgreens", nil, nil)
+	require.NoError(t, err)
+
+	t.Run("Updated", func(t *testing.T) {
+		// A retailer keeps its own names.
+		updated, err := svc.UpdateRetailer(ctx, target.ID, "Target", []string{"target.com", "TARGET STORES"}, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"target.com", "TARGET STORES"}, updated.Aliases)
+		assert.True(t, updated.UpdatedAt.After(updated.CreatedAt))
+
+		resolved, err := svc.Resolve(ctx, "Target Stores")
+		assert.NoError(t, err)
+		assert.Equal(t, target.ID, resolved.ID)
+	})
+
+	t.Run("Name taken", func(t *testing.T) {
+		_, err := svc.UpdateRetailer(ctx, walgreens.ID, "Walgreens", []string{"target.com"}, nil)
+		assert.ErrorIs(t, err, ErrRetailerNameTaken)
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		_, err := svc.UpdateRetailer(ctx, uuid.New(), "Walmart", nil, nil)
+		assert.ErrorIs(t, err, repository.ErrRetailerNotFound)
+	})
+
+	t.Run("Deleted", func(t *testing.T) {
+		assert.NoError(t, svc.DeleteRetailer(ctx, walgreens.ID))
+		assert.ErrorIs(t, svc.DeleteRetailer(ctx, walgreens.ID), repository.ErrRetailerNotFound)
+
+		resolved, err := svc.Resolve(ctx, "Walgreens")
+		assert.NoError(t, err)
+		assert.Nil(t, resolved)
+
+		retailers, err := svc.ListRetailers(ctx)
+		assert.NoError(t, err)
+		assert.Len(t, retailers, 1)
+	})
+}
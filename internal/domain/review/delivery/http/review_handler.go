@@ -0,0 +1,152 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/repository"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/review/service"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/dto"
+	"github.com/CarlosMtz98/receipt-processor-challenge/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"net/http"
+)
+
+type ReviewHandler interface {
+	List(c *gin.Context)
+	Approve(c *gin.Context)
+	Reject(c *gin.Context)
+	ListDecisions(c *gin.Context)
+}
+
+type ReviewHandlerImpl struct {
+	reviewSvc service.ReviewService
+}
+
+func NewReviewHandler(reviewService service.ReviewService) ReviewHandler {
+	return &ReviewHandlerImpl{
+		reviewSvc: reviewService,
+	}
+}
+
+func (h ReviewHandlerImpl) List(c *gin.Context) {
+	receipts, err := h.reviewSvc.ListPending(c)
+	if err != nil {
+		utils.HandleInternalError(c, "Could not list the receipts pending review", err)
+		return
+	}
+
+	response := dto.ListReviewsResponse{
+		Reviews: make([]dto.ReviewResponse, len(receipts)),
+	}
+	for i, receipt := range receipts {
+		response.Reviews[i] = newReviewResponse(receipt)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h ReviewHandlerImpl) Approve(c *gin.Context) {
+	h.decide(c, h.reviewSvc.Approve)
+}
+
+func (h ReviewHandlerImpl) Reject(c *gin.Context) {
+	h.decide(c, h.reviewSvc.Reject)
+}
+
+func (h ReviewHandlerImpl) ListDecisions(c *gin.Context) {
+	receiptId, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.HandleBadRequest(c, "Invalid ID format", err)
+		return
+	}
+
+	decisions, err := h.reviewSvc.ListDecisions(c, receiptId)
+	if errors.Is(err, repository.ErrReceiptNotFound) {
+		utils.HandleNotFound(c, fmt.Sprintf("Could not find the receipt with ID %s", receiptId))
+		return
+	}
+	if err != nil {
+		utils.HandleInternalError(c, "Could not list the review decisions", err)
+		return
+	}
+
+	response := dto.ListReviewDecisionsResponse{
+		Decisions: make([]dto.ReviewDecisionResponse, len(decisions)),
+	}
+	for i, decision := range decisions {
+		response.Decisions[i] = newReviewDecisionResponse(decision)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h ReviewHandlerImpl) decide(c *gin.Context, decide func(ctx context.Context, receiptID uuid.UUID, reason string) (*models.ReviewDecision, error)) {
+	receiptId, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.HandleBadRequest(c, "Invalid ID format", err)
+		return
+	}
+
+	request := dto.ReviewDecisionRequest{}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			utils.HandleBadRequest(c, "Could not parse the request body", err)
+			return
+		}
+	}
+
+	decision, err := decide(c, receiptId, request.Reason)
+	switch {
+	case errors.Is(err, repository.ErrReceiptNotFound):
+		utils.HandleNotFound(c, fmt.Sprintf("Could not find the receipt with ID %s", receiptId))
+		return
+	case errors.Is(err, service.ErrReceiptNotHeld):
+		utils.HandleConflict(c, fmt.Sprintf("The receipt with ID %s is not pending review", receiptId))
+		return
+	case errors.Is(err, service.ErrMissingReason):
+		utils.HandleBadRequest(c, "The decision is not valid", err)
+		return
+	case err != nil:
+		utils.HandleInternalError(c, "Could not record the review decision", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newReviewDecisionResponse(decision))
+}
+
+func newReviewResponse(receipt *models.Receipt) dto.ReviewResponse {
+	review := dto.ReviewResponse{
+		ID:           receipt.ID.String(),
+		Retailer:     receipt.Retailer,
+		PurchaseDate: receipt.PurchaseDate,
+		PurchaseTime: receipt.PurchaseTime,
+		Total:        receipt.Total,
+		Status:       string(receipt.Status),
+		SubmittedAt:  receipt.CreatedAt,
+		Flags:        []models.RiskFlag{},
+	}
+	if receipt.Risk != nil {
+		review.RiskScore = receipt.Risk.Score
+		review.Flags = receipt.Risk.Flags
+	}
+	return review
+}
+
+func newReviewDecisionResponse(decision *models.ReviewDecision) dto.ReviewDecisionResponse {
+	response := dto.ReviewDecisionResponse{
+		ID:        decision.ID.String(),
+		ReceiptID: decision.ReceiptID.String(),
+		Outcome:   string(decision.Outcome),
+		Reason:    decision.Reason,
+		Reviewer:  decision.Reviewer,
+		DecidedAt: decision.DecidedAt,
+	}
+	if decision.Outcome == models.ReviewApproved {
+		points := decision.Points
+		response.Points = &points
+	}
+	return response
+}
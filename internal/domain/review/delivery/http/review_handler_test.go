@@ -0,0 +1,128 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/repository"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/review/mock"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/review/service"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/dto"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestReviewHandlerImpl_List(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	heldReceipt := &models.Receipt{
+		ID:           uuid.New(),
+		Retailer:     "Target",
+		PurchaseDate: "2030-01-01",
+		PurchaseTime: "13:01",
+		Total:        "12.98",
+		Status:       models.ReceiptStatusHeld,
+		Risk: &models.RiskAssessment{
+			Score: 70,
+			Flags: []models.RiskFlag{{Check: "purchase_datetime", Score: 70, Reason: "the purchase datetime is in the future"}},
+			Held:  true,
+		},
+	}
+
+	mockReviewService := mock.NewMockReviewService(ctrl)
+	mockReviewService.EXPECT().ListPending(gomock.Any()).Return([]*models.Receipt{heldReceipt}, nil)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/admin/reviews", NewReviewHandler(mockReviewService).List)
+
+	req := httptest.NewRequest("GET", "/admin/reviews", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var response dto.ListReviewsResponse
+	if err := json.Unmarshal(resp.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, response.Reviews, 1)
+	assert.Equal(t, heldReceipt.ID.String(), response.Reviews[0].ID)
+	assert.Equal(t, 70, response.Reviews[0].RiskScore)
+	assert.Equal(t, "purchase_datetime", response.Reviews[0].Flags[0].Check)
+}
+
+func TestReviewHandlerImpl_Decisions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockReviewService := mock.NewMockReviewService(ctrl)
+	handler := NewReviewHandler(mockReviewService)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/admin/reviews/:id/approve", handler.Approve)
+	router.POST("/admin/reviews/:id/reject", handler.Reject)
+
+	send := func(path string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	t.Run("Approve", func(t *testing.T) {
+		receiptID := uuid.New()
+		mockReviewService.EXPECT().Approve(gomock.Any(), receiptID, "").Return(&models.ReviewDecision{
+			ID:        uuid.New(),
+			ReceiptID: receiptID,
+			Outcome:   models.ReviewApproved,
+			Reviewer:  "reviewer@example.com",
+			Points:    28,
+		}, nil)
+
+		resp := send("/admin/reviews/"+receiptID.String()+"/approve", "")
+		assert.Equal(t, http.StatusOK, resp.Code)
+
+		var response dto.ReviewDecisionResponse
+		if err := json.Unmarshal(resp.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, "approved", response.Outcome)
+		assert.Equal(t, 28, *response.Points)
+	})
+
+	t.Run("Reject", func(t *testing.T) {
+		receiptID := uuid.New()
+		mockReviewService.EXPECT().Reject(gomock.Any(), receiptID, "fabricated").Return(&models.ReviewDecision{
+			ID:        uuid.New(),
+			ReceiptID: receiptID,
+			Outcome:   models.ReviewRejected,
+			Reason:    "fabricated",
+		}, nil)
+
+		resp := send("/admin/reviews/"+receiptID.String()+"/reject", `{"reason": "fabricated"}`)
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.NotContains(t, resp.Body.String(), "points")
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		receiptID := uuid.New()
+		mockReviewService.EXPECT().Reject(gomock.Any(), receiptID, "").Return(nil, service.ErrMissingReason)
+		assert.Equal(t, http.StatusBadRequest, send("/admin/reviews/"+receiptID.String()+"/reject", `{}`).Code)
+
+		mockReviewService.EXPECT().Approve(gomock.Any(), receiptID, "").Return(nil, service.ErrReceiptNotHeld)
+		assert.Equal(t, http.StatusConflict, send("/admin/reviews/"+receiptID.String()+"/approve", "").Code)
+
+		mockReviewService.EXPECT().Approve(gomock.Any(), receiptID, "").Return(nil, repository.ErrReceiptNotFound)
+		assert.Equal(t, http.StatusNotFound, send("/admin/reviews/"+receiptID.String()+"/approve", "").Code)
+
+		assert.Equal(t, http.StatusBadRequest, send("/admin/reviews/not-an-id/approve", "").Code)
+	})
+}
@@ -0,0 +1,16 @@
+package http
+
+import (
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// ScopeReceiptsReview allows listing and deciding on held receipts.
+const ScopeReceiptsReview = "receipts:review"
+
+func MapReviewRoutes(routesGroup *gin.RouterGroup, handler ReviewHandler, guard middleware.ScopeGuard) {
+	routesGroup.GET("", guard.Require(ScopeReceiptsReview), handler.List)
+	routesGroup.POST("/:id/approve", guard.Require(ScopeReceiptsReview), handler.Approve)
+	routesGroup.POST("/:id/reject", guard.Require(ScopeReceiptsReview), handler.Reject)
+	routesGroup.GET("/:id/decisions", guard.Require(ScopeReceiptsReview), handler.ListDecisions)
+}
@@ -0,0 +1,97 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/domain/review/service/review_service.go
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	reflect "reflect"
+
+	models "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
+	gomock "github.com/golang/mock/gomock"
+	uuid "github.com/google/uuid"
+)
+
+// MockReviewService is a mock of ReviewService interface.
+type MockReviewService struct {
+	ctrl     *gomock.Controller
+	recorder *MockReviewServiceMockRecorder
+}
+
+// MockReviewServiceMockRecorder is the mock recorder for MockReviewService.
+type MockReviewServiceMockRecorder struct {
+	mock *MockReviewService
+}
+
+// NewMockReviewService creates a new mock instance.
+func NewMockReviewService(ctrl *gomock.Controller) *MockReviewService {
+	mock := &MockReviewService{ctrl: ctrl}
+	mock.recorder = &MockReviewServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockReviewService) EXPECT() *MockReviewServiceMockRecorder {
+	return m.recorder
+}
+
+// Approve mocks base method.
+func (m *MockReviewService) Approve(ctx context.Context, receiptID uuid.UUID, reason string) (*models.ReviewDecision, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Approve", ctx, receiptID, reason)
+	ret0, _ := ret[0].(*models.ReviewDecision)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Approve indicates an expected call of Approve.
+func (mr *MockReviewServiceMockRecorder) Approve(ctx, receiptID, reason interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Approve", reflect.TypeOf((*MockReviewService)(nil).Approve), ctx, receiptID, reason)
+}
+
+// ListDecisions mocks base method.
+func (m *MockReviewService) ListDecisions(ctx context.Context, receiptID uuid.UUID) ([]*models.ReviewDecision, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListDecisions", ctx, receiptID)
+	ret0, _ := ret[0].([]*models.ReviewDecision)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListDecisions indicates an expected call of ListDecisions.
+func (mr *MockReviewServiceMockRecorder) ListDecisions(ctx, receiptID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListDecisions", reflect.TypeOf((*MockReviewService)(nil).ListDecisions), ctx, receiptID)
+}
+
+// ListPending mocks base method.
+func (m *MockReviewService) ListPending(ctx context.Context) ([]*models.Receipt, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListPending", ctx)
+	ret0, _ := ret[0].([]*models.Receipt)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListPending indicates an expected call of ListPending.
+func (mr *MockReviewServiceMockRecorder) ListPending(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListPending", reflect.TypeOf((*MockReviewService)(nil).ListPending), ctx)
+}
+
+// Reject mocks base method.
+func (m *MockReviewService) Reject(ctx context.Context, receiptID uuid.UUID, reason string) (*models.ReviewDecision, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Reject", ctx, receiptID, reason)
+	ret0, _ := ret[0].(*models.ReviewDecision)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Reject indicates an expected call of Reject.
+func (mr *MockReviewServiceMockRecorder) Reject(ctx, receiptID, reason interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reject", reflect.TypeOf((*MockReviewService)(nil).Reject), ctx, receiptID, reason)
+}
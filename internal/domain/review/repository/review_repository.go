@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
+	"github.com/google/uuid"
+	"sync"
+)
+
+// ReviewRepository is the append-only audit log of review decisions.
+type ReviewRepository interface {
+	Create(ctx context.Context, decision *models.ReviewDecision) error
+	ListByReceiptID(ctx context.Context, receiptID uuid.UUID) ([]*models.ReviewDecision, error)
+}
+
+type InMemoryReviewRepository struct {
+	mu        sync.RWMutex
+	decisions []*models.ReviewDecision
+}
+
+func InitReviewRepository() ReviewRepository {
+	return &InMemoryReviewRepository{}
+}
+
+func (memoryRepo *InMemoryReviewRepository) Create(ctx context.Context, decision *models.ReviewDecision) error {
+	memoryRepo.mu.Lock()
+	defer memoryRepo.mu.Unlock()
+
+	memoryRepo.decisions = append(memoryRepo.decisions, decision)
+	return nil
+}
+
+func (memoryRepo *InMemoryReviewRepository) ListByReceiptID(ctx context.Context, receiptID uuid.UUID) ([]*models.ReviewDecision, error) {
+	memoryRepo.mu.RLock()
+	defer memoryRepo.mu.RUnlock()
+
+	decisions := make([]*models.ReviewDecision, 0)
+	for _, decision := range memoryRepo.decisions {
+		if decision.ReceiptID == receiptID {
+			decisions = append(decisions, decision)
+		}
+	}
+	return decisions, nil
+}
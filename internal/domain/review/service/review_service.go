@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/auth"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/repository"
+	receiptService "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/service"
+	reviewRepository "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/review/repository"
+	"github.com/google/uuid"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrReceiptNotHeld is returned when a decision is taken on a receipt that is not waiting for review.
+	ErrReceiptNotHeld = errors.New("the receipt is not pending review")
+	// ErrMissingReason is returned when a receipt is rejected without a reason.
+	ErrMissingReason = errors.New("a reason is required to reject a receipt")
+)
+
+// anonymousReviewer is recorded when authentication is disabled.
+const anonymousReviewer = "anonymous"
+
+type ReviewService interface {
+	ListPending(ctx context.Context) ([]*models.Receipt, error)
+	Approve(ctx context.Context, receiptID uuid.UUID, reason string) (*models.ReviewDecision, error)
+	Reject(ctx context.Context, receiptID uuid.UUID, reason string) (*models.ReviewDecision, error)
+	ListDecisions(ctx context.Context, receiptID uuid.UUID) ([]*models.ReviewDecision, error)
+}
+
+type ReviewServiceImpl struct {
+	receiptRepository repository.ReceiptRepository
+	reviewRepository  reviewRepository.ReviewRepository
+	receiptSvc        receiptService.ReceiptService
+	now               func() time.Time
+	// mu serializes decisions so that a receipt cannot be approved and rejected concurrently.
+	mu sync.Mutex
+}
+
+func NewReviewService(receiptRepository repository.ReceiptRepository, reviewRepository reviewRepository.ReviewRepository, receiptSvc receiptService.ReceiptService) ReviewService {
+	return &ReviewServiceImpl{
+		receiptRepository: receiptRepository,
+		reviewRepository:  reviewRepository,
+		receiptSvc:        receiptSvc,
+		now:               time.Now,
+	}
+}
+
+// ListPending returns the receipts held by the fraud checks, oldest first.
+func (s *ReviewServiceImpl) ListPending(ctx context.Context) ([]*models.Receipt, error) {
+	return s.receiptRepository.ListByStatus(ctx, models.ReceiptStatusHeld)
+}
+
+// Approve releases a held receipt so that its points are computed and awarded.
+func (s *ReviewServiceImpl) Approve(ctx context.Context, receiptID uuid.UUID, reason string) (*models.ReviewDecision, error) {
+	return s.decide(ctx, receiptID, models.ReviewApproved, reason)
+}
+
+// Reject marks a held receipt as rejected; it will never earn points.
+func (s *ReviewServiceImpl) Reject(ctx context.Context, receiptID uuid.UUID, reason string) (*models.ReviewDecision, error) {
+	if strings.TrimSpace(reason) == "" {
+		return nil, ErrMissingReason
+	}
+	return s.decide(ctx, receiptID, models.ReviewRejected, reason)
+}
+
+func (s *ReviewServiceImpl) ListDecisions(ctx context.Context, receiptID uuid.UUID) ([]*models.ReviewDecision, error) {
+	if _, err := s.receiptRepository.GetByID(ctx, receiptID); err != nil {
+		return nil, err
+	}
+	return s.reviewRepository.ListByReceiptID(ctx, receiptID)
+}
+
+func (s *ReviewServiceImpl) decide(ctx context.Context, receiptID uuid.UUID, outcome models.ReviewOutcome, reason string) (*models.ReviewDecision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, err := s.receiptRepository.GetByID(ctx, receiptID)
+	if err != nil {
+		return nil, err
+	}
+	if stored.Status != models.ReceiptStatusHeld {
+		return nil, ErrReceiptNotHeld
+	}
+
+	decision := &models.ReviewDecision{
+		ID:        uuid.New(),
+		ReceiptID: receiptID,
+		Outcome:   outcome,
+		Reason:    strings.TrimSpace(reason),
+		Reviewer:  reviewerFromContext(ctx),
+		DecidedAt: s.now(),
+	}
+
+	receipt := *stored
+	receipt.Review = decision
+	if outcome == models.ReviewApproved {
+		receipt.Status = models.ReceiptStatusProcessed
+		points, err := s.receiptSvc.GetReceiptPoints(ctx, &receipt)
+		if err != nil {
+			return nil, err
+		}
+		decision.Points = points
+	} else {
+		receipt.Status = models.ReceiptStatusRejected
+	}
+
+	if err := s.receiptRepository.UpdateWithEvents(ctx, &receipt, []*models.ReceiptEvent{decisionEvent(&receipt, decision)}); err != nil {
+		return nil, err
+	}
+	if err := s.reviewRepository.Create(ctx, decision); err != nil {
+		return nil, err
+	}
+
+	return decision, nil
+}
+
+// decisionEvent announces the outcome of a review: the points of an approved receipt or the
+// reason it was rejected.
+func decisionEvent(receipt *models.Receipt, decision *models.ReviewDecision) *models.ReceiptEvent {
+	if decision.Outcome == models.ReviewApproved {
+		event := models.NewReceiptEvent(models.ReceiptScored, receipt, decision.DecidedAt)
+		points := decision.Points
+		event.Points = &points
+		return event
+	}
+
+	event := models.NewReceiptEvent(models.ReceiptRejected, receipt, decision.DecidedAt)
+	event.Reason = decision.Reason
+	return event
+}
+
+func reviewerFromContext(ctx context.Context) string {
+	if principal, ok := auth.PrincipalFromContext(ctx); ok && principal.Subject != "" {
+		return principal.Subject
+	}
+	return anonymousReviewer
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/auth"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/repository"
+	receiptService "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/service"
+	reviewRepository "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/review/repository"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestReviewServiceImpl(t *testing.T) {
+	receiptRepo := repository.InitReceiptRepository()
+	reviewRepo := reviewRepository.InitReviewRepository()
+	reviewSvc := NewReviewService(receiptRepo, reviewRepo, receiptService.NewReceiptService(receiptRepo))
+
+	lastEvent := func() *models.ReceiptEvent {
+		events, err := receiptRepo.ListPendingEvents(context.Background(), 0)
+		assert.NoError(t, err)
+		return events[len(events)-1]
+	}
+
+	newHeldReceipt := func() *models.Receipt {
+		receipt := &models.Receipt{
+			ID:           uuid.New(),
+			Retailer:     "M&M Corner Market",
+			PurchaseDate: "2022-03-20",
+			PurchaseTime: "14:33",
+			Total:        "9.00",
+			Items: []models.ReceiptItem{
+				{ShortDescription: "Gatorade", Price: "2.25"},
+				{ShortDescription: "Gatorade", Price: "2.25"},
+				{ShortDescription: "Gatorade", Price: "2.25"},
+				{ShortDescription: "Gatorade", Price: "2.25"},
+			},
+			Status: models.ReceiptStatusHeld,
+		}
+		assert.NoError(t, receiptRepo.Create(context.Background(), receipt))
+		return receipt
+	}
+	reviewerCtx := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "reviewer@example.com"})
+
+	t.Run("List pending", func(t *testing.T) {
+		receipt := newHeldReceipt()
+		pending, err := reviewSvc.ListPending(context.Background())
+		assert.NoError(t, err)
+		assert.Contains(t, pending, receipt)
+	})
+
+	t.Run("Approve", func(t *testing.T) {
+		receipt := newHeldReceipt()
+		decision, err := reviewSvc.Approve(reviewerCtx, receipt.ID, "")
+		assert.NoError(t, err)
+		assert.Equal(t, models.ReviewApproved, decision.Outcome)
+		assert.Equal(t, "reviewer@example.com", decision.Reviewer)
+		assert.Equal(t, 109, decision.Points)
+
+		stored, err := receiptRepo.GetByID(context.Background(), receipt.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, models.ReceiptStatusProcessed, stored.Status)
+		assert.Equal(t, models.ReceiptScored, lastEvent().Type)
+		assert.Equal(t, 109, *lastEvent().Points)
+
+		_, err = reviewSvc.Reject(reviewerCtx, receipt.ID, "too late")
+		assert.Equal(t, ErrReceiptNotHeld, err)
+	})
+
+	t.Run("Reject", func(t *testing.T) {
+		receipt := newHeldReceipt()
+		_, err := reviewSvc.Reject(reviewerCtx, receipt.ID, " ")
+		assert.Equal(t, ErrMissingReason, err)
+
+		decision, err := reviewSvc.Reject(context.Background(), receipt.ID, "fabricated receipt")
+		assert.NoError(t, err)
+		assert.Equal(t, anonymousReviewer, decision.Reviewer)
+
+		stored, err := receiptRepo.GetByID(context.Background(), receipt.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, models.ReceiptStatusRejected, stored.Status)
+		assert.Equal(t, "fabricated receipt", stored.Review.Reason)
+		assert.Equal(t, models.ReceiptRejected, lastEvent().Type)
+		assert.Equal(t, "fabricated receipt", lastEvent().Reason)
+
+		decisions, err := reviewSvc.ListDecisions(context.Background(), receipt.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, []*models.ReviewDecision{decision}, decisions)
+	})
+
+	t.Run("Unknown receipt", func(t *testing.T) {
+		_, err := reviewSvc.Approve(reviewerCtx, uuid.New(), "")
+		assert.Equal(t, repository.ErrReceiptNotFound, err)
+	})
+}
@@ -0,0 +1,51 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/domain/signature/service/signature_service.go
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	reflect "reflect"
+
+	models "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockSignatureService is a mock of SignatureService interface.
+type MockSignatureService struct {
+	ctrl     *gomock.Controller
+	recorder *MockSignatureServiceMockRecorder
+}
+
+// MockSignatureServiceMockRecorder is the mock recorder for MockSignatureService.
+type MockSignatureServiceMockRecorder struct {
+	mock *MockSignatureService
+}
+
+// NewMockSignatureService creates a new mock instance.
+func NewMockSignatureService(ctrl *gomock.Controller) *MockSignatureService {
+	mock := &MockSignatureService{ctrl: ctrl}
+	mock.recorder = &MockSignatureServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSignatureService) EXPECT() *MockSignatureServiceMockRecorder {
+	return m.recorder
+}
+
+// Verify mocks base method.
+func (m *MockSignatureService) Verify(ctx context.Context, receipt *models.Receipt) (*models.SignatureVerification, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Verify", ctx, receipt)
+	ret0, _ := ret[0].(*models.SignatureVerification)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Verify indicates an expected call of Verify.
+func (mr *MockSignatureServiceMockRecorder) Verify(ctx, receipt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Verify", reflect.TypeOf((*MockSignatureService)(nil).Verify), ctx, receipt)
+}
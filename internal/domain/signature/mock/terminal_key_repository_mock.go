@@ -0,0 +1,66 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/domain/signature/repository/terminal_key_repository.go
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	reflect "reflect"
+
+	models "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockTerminalKeyRepository is a mock of TerminalKeyRepository interface.
+type MockTerminalKeyRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockTerminalKeyRepositoryMockRecorder
+}
+
+// MockTerminalKeyRepositoryMockRecorder is the mock recorder for MockTerminalKeyRepository.
+type MockTerminalKeyRepositoryMockRecorder struct {
+	mock *MockTerminalKeyRepository
+}
+
+// NewMockTerminalKeyRepository creates a new mock instance.
+func NewMockTerminalKeyRepository(ctrl *gomock.Controller) *MockTerminalKeyRepository {
+	mock := &MockTerminalKeyRepository{ctrl: ctrl}
+	mock.recorder = &MockTerminalKeyRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTerminalKeyRepository) EXPECT() *MockTerminalKeyRepositoryMockRecorder {
+	return m.recorder
+}
+
+// GetByID mocks base method.
+func (m *MockTerminalKeyRepository) GetByID(ctx context.Context, id string) (*models.TerminalKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByID", ctx, id)
+	ret0, _ := ret[0].(*models.TerminalKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByID indicates an expected call of GetByID.
+func (mr *MockTerminalKeyRepositoryMockRecorder) GetByID(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByID", reflect.TypeOf((*MockTerminalKeyRepository)(nil).GetByID), ctx, id)
+}
+
+// GetPolicy mocks base method.
+func (m *MockTerminalKeyRepository) GetPolicy(ctx context.Context, retailer string) (models.RetailerSignaturePolicy, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPolicy", ctx, retailer)
+	ret0, _ := ret[0].(models.RetailerSignaturePolicy)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPolicy indicates an expected call of GetPolicy.
+func (mr *MockTerminalKeyRepositoryMockRecorder) GetPolicy(ctx, retailer interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPolicy", reflect.TypeOf((*MockTerminalKeyRepository)(nil).GetPolicy), ctx, retailer)
+}
@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
+	"os"
+	"strings"
+	"sync"
+)
+
+var (
+	// ErrTerminalKeyNotFound is returned when no trusted key exists for a terminal id.
+	ErrTerminalKeyNotFound = errors.New("the terminal key was not found in the registry")
+)
+
+type TerminalKeyRepository interface {
+	GetByID(ctx context.Context, id string) (*models.TerminalKey, error)
+	GetPolicy(ctx context.Context, retailer string) (models.RetailerSignaturePolicy, error)
+}
+
+type InMemoryTerminalKeyRepository struct {
+	mu            sync.RWMutex
+	keys          map[string]*models.TerminalKey
+	policies      map[string]models.RetailerSignaturePolicy
+	defaultPolicy models.RetailerSignaturePolicy
+}
+
+// registryFile is the on-disk format of the trusted terminal registry.
+type registryFile struct {
+	DefaultPolicy         models.SignaturePolicy `json:"defaultPolicy"`
+	UnsignedPointsPercent *int                   `json:"unsignedPointsPercent"`
+	Retailers             map[string]struct {
+		Policy                models.SignaturePolicy `json:"policy"`
+		UnsignedPointsPercent *int                   `json:"unsignedPointsPercent"`
+	} `json:"retailers"`
+	Terminals []struct {
+		ID        string                    `json:"id"`
+		Retailer  string                    `json:"retailer"`
+		Algorithm models.SignatureAlgorithm `json:"algorithm"`
+		Key       string                    `json:"key"`
+	} `json:"terminals"`
+}
+
+func InitTerminalKeyRepository(defaultPolicy models.RetailerSignaturePolicy) *InMemoryTerminalKeyRepository {
+	return &InMemoryTerminalKeyRepository{
+		keys:          make(map[string]*models.TerminalKey),
+		policies:      make(map[string]models.RetailerSignaturePolicy),
+		defaultPolicy: defaultPolicy,
+	}
+}
+
+// LoadTerminalKeyRepository reads the terminal registry and retailer policies from a JSON file.
+func LoadTerminalKeyRepository(path string) (*InMemoryTerminalKeyRepository, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read terminal registry: %v", err)
+	}
+
+	var file registryFile
+	if err := json.Unmarshal(raw, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse terminal registry: %v", err)
+	}
+
+	defaultPercent := 50
+	if file.UnsignedPointsPercent != nil {
+		defaultPercent = *file.UnsignedPointsPercent
+	}
+	if file.DefaultPolicy == "" {
+		file.DefaultPolicy = models.SignaturePolicyIgnore
+	}
+
+	repo := InitTerminalKeyRepository(models.RetailerSignaturePolicy{
+		Policy:                file.DefaultPolicy,
+		UnsignedPointsPercent: defaultPercent,
+	})
+	if err := validatePolicy(repo.defaultPolicy); err != nil {
+		return nil, err
+	}
+
+	for retailer, entry := range file.Retailers {
+		policy := models.RetailerSignaturePolicy{
+			Policy:                entry.Policy,
+			UnsignedPointsPercent: defaultPercent,
+		}
+		if entry.UnsignedPointsPercent != nil {
+			policy.UnsignedPointsPercent = *entry.UnsignedPointsPercent
+		}
+		if err := validatePolicy(policy); err != nil {
+			return nil, fmt.Errorf("retailer %q: %v", retailer, err)
+		}
+		repo.SetPolicy(retailer, policy)
+	}
+
+	for _, terminal := range file.Terminals {
+		key, err := base64.StdEncoding.DecodeString(terminal.Key)
+		if err != nil {
+			return nil, fmt.Errorf("terminal %q: invalid key encoding: %v", terminal.ID, err)
+		}
+		if err := repo.AddKey(&models.TerminalKey{
+			ID:        terminal.ID,
+			Retailer:  terminal.Retailer,
+			Algorithm: terminal.Algorithm,
+			Key:       key,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	return repo, nil
+}
+
+func (repo *InMemoryTerminalKeyRepository) AddKey(key *models.TerminalKey) error {
+	if key.ID == "" || key.Retailer == "" {
+		return errors.New("terminal keys require an id and a retailer")
+	}
+	if key.Algorithm != models.SignatureHMACSHA256 && key.Algorithm != models.SignatureEd25519 {
+		return fmt.Errorf("terminal %q: unsupported algorithm %q", key.ID, key.Algorithm)
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	repo.keys[key.ID] = key
+	return nil
+}
+
+func (repo *InMemoryTerminalKeyRepository) SetPolicy(retailer string, policy models.RetailerSignaturePolicy) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	repo.policies[normalizeRetailer(retailer)] = policy
+}
+
+func (repo *InMemoryTerminalKeyRepository) GetByID(ctx context.Context, id string) (*models.TerminalKey, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	if key, ok := repo.keys[id]; ok {
+		return key, nil
+	}
+	return nil, ErrTerminalKeyNotFound
+}
+
+func (repo *InMemoryTerminalKeyRepository) GetPolicy(ctx context.Context, retailer string) (models.RetailerSignaturePolicy, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	if policy, ok := repo.policies[normalizeRetailer(retailer)]; ok {
+		return policy, nil
+	}
+	return repo.defaultPolicy, nil
+}
+
+func validatePolicy(policy models.RetailerSignaturePolicy) error {
+	switch policy.Policy {
+	case models.SignaturePolicyRequire, models.SignaturePolicyPrefer, models.SignaturePolicyIgnore:
+	default:
+		return fmt.Errorf("unknown signature policy %q", policy.Policy)
+	}
+	if policy.UnsignedPointsPercent < 0 || policy.UnsignedPointsPercent > 100 {
+		return fmt.Errorf("unsigned points percent must be between 0 and 100")
+	}
+	return nil
+}
+
+func normalizeRetailer(retailer string) string {
+	return strings.ToLower(strings.TrimSpace(retailer))
+}
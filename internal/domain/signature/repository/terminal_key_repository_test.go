@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadTerminalKeyRepository(t *testing.T) {
+	t.Run("Valid registry", func(t *testing.T) {
+		path := writeRegistry(t, `{
+			"defaultPolicy": "prefer",
+			"unsignedPointsPercent": 40,
+			"retailers": {
+				"Target": {"policy": "require"},
+				"Walgreens": {"policy": "prefer", "unsignedPointsPercent": 10}
+			},
+			"terminals": [
+				{"id": "target-pos-1", "retailer": "Target", "algorithm": "hmac-sha256", "key": "c2VjcmV0"}
+			]
+		}`)
+
+		repo, err := LoadTerminalKeyRepository(path)
+		assert.NoError(t, err)
+
+		key, err := repo.GetByID(context.Background(), "target-pos-1")
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("secret"), key.Key)
+		assert.True(t, key.MatchesRetailer(" target "))
+
+		policy, err := repo.GetPolicy(context.Background(), "TARGET")
+		assert.NoError(t, err)
+		assert.Equal(t, models.SignaturePolicyRequire, policy.Policy)
+
+		policy, err = repo.GetPolicy(context.Background(), "Walgreens")
+		assert.NoError(t, err)
+		assert.Equal(t, 10, policy.UnsignedPointsPercent)
+
+		policy, err = repo.GetPolicy(context.Background(), "Corner Shop")
+		assert.NoError(t, err)
+		assert.Equal(t, models.RetailerSignaturePolicy{Policy: models.SignaturePolicyPrefer, UnsignedPointsPercent: 40}, policy)
+
+		_, err = repo.GetByID(context.Background(), "missing")
+		assert.Equal(t, ErrTerminalKeyNotFound, err)
+	})
+
+	t.Run("Unknown policy", func(t *testing.T) {
+		_, err := LoadTerminalKeyRepository(writeRegistry(t, `{"retailers": {"Target": {"policy": "sometimes"}}}`))
+		assert.Error(t, err)
+	})
+
+	t.Run("Unsupported algorithm", func(t *testing.T) {
+		_, err := LoadTerminalKeyRepository(writeRegistry(t, `{"terminals": [{"id": "pos", "retailer": "Target", "algorithm": "rsa", "key": "c2VjcmV0"}]}`))
+		assert.Error(t, err)
+	})
+}
+
+func writeRegistry(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "terminals.json")
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
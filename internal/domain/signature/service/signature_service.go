@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/signature/repository"
+)
+
+var (
+	// ErrSignatureRequired is returned when the retailer policy requires a signature and none was sent.
+	ErrSignatureRequired = errors.New("the retailer requires signed receipts")
+	// ErrUntrustedTerminal is returned when the signing key is unknown or belongs to another retailer.
+	ErrUntrustedTerminal = errors.New("the receipt was signed by an untrusted terminal")
+	// ErrInvalidSignature is returned when the signature does not match the receipt payload.
+	ErrInvalidSignature = errors.New("the receipt signature is invalid")
+)
+
+type SignatureService interface {
+	Verify(ctx context.Context, receipt *models.Receipt) (*models.SignatureVerification, error)
+}
+
+type SignatureServiceImpl struct {
+	terminalKeyRepository repository.TerminalKeyRepository
+}
+
+func NewSignatureService(terminalKeyRepository repository.TerminalKeyRepository) SignatureService {
+	return &SignatureServiceImpl{
+		terminalKeyRepository: terminalKeyRepository,
+	}
+}
+
+// Verify applies the retailer signature policy to the receipt. A signature that is
+// present but invalid is always rejected unless the policy ignores signatures. The policy is
+// the stricter of the ones of the printed and the canonical retailer names, so that matching a
+// receipt to the registry never loosens the policy it had.
+func (s *SignatureServiceImpl) Verify(ctx context.Context, receipt *models.Receipt) (*models.SignatureVerification, error) {
+	policy, err := s.terminalKeyRepository.GetPolicy(ctx, receipt.Retailer)
+	if err != nil {
+		return nil, err
+	}
+	if receipt.GetRetailerName() != receipt.Retailer {
+		canonical, err := s.terminalKeyRepository.GetPolicy(ctx, receipt.GetRetailerName())
+		if err != nil {
+			return nil, err
+		}
+		policy = stricterPolicy(policy, canonical)
+	}
+
+	verification := &models.SignatureVerification{
+		Policy:        policy.Policy,
+		PointsPercent: 100,
+	}
+
+	if policy.Policy == models.SignaturePolicyIgnore {
+		verification.Status = models.SignatureSkipped
+		return verification, nil
+	}
+
+	if receipt.Signature == nil {
+		if policy.Policy == models.SignaturePolicyRequire {
+			return nil, ErrSignatureRequired
+		}
+		verification.Status = models.SignatureUnsigned
+		verification.PointsPercent = policy.UnsignedPointsPercent
+		return verification, nil
+	}
+
+	key, err := s.terminalKeyRepository.GetByID(ctx, receipt.Signature.KeyID)
+	if errors.Is(err, repository.ErrTerminalKeyNotFound) {
+		return nil, ErrUntrustedTerminal
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Terminals can be registered under the name they print or the canonical one.
+	matches := key.MatchesRetailer(receipt.Retailer) || key.MatchesRetailer(receipt.GetRetailerName())
+	if !matches || key.Algorithm != receipt.Signature.Algorithm {
+		return nil, ErrUntrustedTerminal
+	}
+
+	payload, err := receipt.CanonicalJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	if !verifySignature(key, payload, receipt.Signature.Value) {
+		return nil, ErrInvalidSignature
+	}
+
+	verification.Status = models.SignatureVerified
+	verification.TerminalID = key.ID
+	return verification, nil
+}
+
+// stricterPolicy returns the policy that accepts fewer unsigned receipts, or awards them fewer
+// points.
+func stricterPolicy(a, b models.RetailerSignaturePolicy) models.RetailerSignaturePolicy {
+	strictness := map[models.SignaturePolicy]int{
+		models.SignaturePolicyIgnore:  0,
+		models.SignaturePolicyPrefer:  1,
+		models.SignaturePolicyRequire: 2,
+	}
+	switch {
+	case strictness[a.Policy] != strictness[b.Policy]:
+		if strictness[a.Policy] > strictness[b.Policy] {
+			return a
+		}
+		return b
+	case a.UnsignedPointsPercent <= b.UnsignedPointsPercent:
+		return a
+	default:
+		return b
+	}
+}
+
+func verifySignature(key *models.TerminalKey, payload, signature []byte) bool {
+	switch key.Algorithm {
+	case models.SignatureHMACSHA256:
+		mac := hmac.New(sha256.New, key.Key)
+		mac.Write(payload)
+		return hmac.Equal(mac.Sum(nil), signature)
+	case models.SignatureEd25519:
+		if len(key.Key) != ed25519.PublicKeySize {
+			return false
+		}
+		return ed25519.Verify(key.Key, payload, signature)
+	default:
+		return false
+	}
+}
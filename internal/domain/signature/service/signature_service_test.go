@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/signature/mock"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/signature/repository"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSignatureServiceImpl_Verify(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	secret := []byte("terminal-shared-secret")
+
+	repo := repository.InitTerminalKeyRepository(models.RetailerSignaturePolicy{Policy: models.SignaturePolicyIgnore})
+	repo.SetPolicy("Target", models.RetailerSignaturePolicy{Policy: models.SignaturePolicyRequire})
+	repo.SetPolicy("Walgreens", models.RetailerSignaturePolicy{Policy: models.SignaturePolicyPrefer, UnsignedPointsPercent: 25})
+	assert.NoError(t, repo.AddKey(&models.TerminalKey{ID: "target-pos-1", Retailer: "Target", Algorithm: models.SignatureEd25519, Key: publicKey}))
+	assert.NoError(t, repo.AddKey(&models.TerminalKey{ID: "walgreens-pos-1", Retailer: "Walgreens", Algorithm: models.SignatureHMACSHA256, Key: secret}))
+
+	signatureSvc := NewSignatureService(repo)
+
+	newReceipt := func(retailer string) *models.Receipt {
+		return &models.Receipt{
+			Retailer:     retailer,
+			PurchaseDate: "2022-01-02",
+			PurchaseTime: "13:13",
+			Total:        "1.25",
+			Items:        []models.ReceiptItem{{ShortDescription: "Pepsi - 12-oz", Price: "1.25"}},
+		}
+	}
+	signEd25519 := func(receipt *models.Receipt) {
+		payload, err := receipt.CanonicalJSON()
+		assert.NoError(t, err)
+		receipt.Signature = &models.ReceiptSignature{KeyID: "target-pos-1", Algorithm: models.SignatureEd25519, Value: ed25519.Sign(privateKey, payload)}
+	}
+	signHMAC := func(receipt *models.Receipt) {
+		payload, err := receipt.CanonicalJSON()
+		assert.NoError(t, err)
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(payload)
+		receipt.Signature = &models.ReceiptSignature{KeyID: "walgreens-pos-1", Algorithm: models.SignatureHMACSHA256, Value: mac.Sum(nil)}
+	}
+
+	t.Run("Valid Ed25519 signature", func(t *testing.T) {
+		receipt := newReceipt("Target")
+		signEd25519(receipt)
+		verification, err := signatureSvc.Verify(context.Background(), receipt)
+		assert.NoError(t, err)
+		assert.Equal(t, models.SignatureVerified, verification.Status)
+		assert.Equal(t, "target-pos-1", verification.TerminalID)
+		assert.Equal(t, 100, verification.PointsPercent)
+	})
+
+	t.Run("Valid HMAC signature", func(t *testing.T) {
+		receipt := newReceipt("Walgreens")
+		signHMAC(receipt)
+		verification, err := signatureSvc.Verify(context.Background(), receipt)
+		assert.NoError(t, err)
+		assert.Equal(t, models.SignatureVerified, verification.Status)
+	})
+
+	t.Run("Tampered payload", func(t *testing.T) {
+		receipt := newReceipt("Target")
+		signEd25519(receipt)
+		receipt.Total = "100.00"
+		_, err := signatureSvc.Verify(context.Background(), receipt)
+		assert.Equal(t, ErrInvalidSignature, err)
+	})
+
+	t.Run("Required signature missing", func(t *testing.T) {
+		_, err := signatureSvc.Verify(context.Background(), newReceipt("Target"))
+		assert.Equal(t, ErrSignatureRequired, err)
+	})
+
+	t.Run("Preferred signature missing", func(t *testing.T) {
+		verification, err := signatureSvc.Verify(context.Background(), newReceipt("Walgreens"))
+		assert.NoError(t, err)
+		assert.Equal(t, models.SignatureUnsigned, verification.Status)
+		assert.Equal(t, 25, verification.PointsPercent)
+	})
+
+	t.Run("Key from another retailer", func(t *testing.T) {
+		receipt := newReceipt("Walgreens")
+		signEd25519(receipt)
+		_, err := signatureSvc.Verify(context.Background(), receipt)
+		assert.Equal(t, ErrUntrustedTerminal, err)
+	})
+
+	t.Run("Policy of the printed name", func(t *testing.T) {
+		repo.SetPolicy("TARGET #1234", models.RetailerSignaturePolicy{Policy: models.SignaturePolicyRequire})
+		receipt := newReceipt("TARGET #1234")
+		receipt.RetailerName = "Corner Shop"
+		_, err := signatureSvc.Verify(context.Background(), receipt)
+		assert.Equal(t, ErrSignatureRequired, err)
+
+		// The stricter policy holds whichever name it is set on.
+		receipt = newReceipt("Walgreens #42")
+		receipt.RetailerName = "Walgreens"
+		verification, err := signatureSvc.Verify(context.Background(), receipt)
+		assert.NoError(t, err)
+		assert.Equal(t, 25, verification.PointsPercent)
+	})
+
+	t.Run("Ignored policy", func(t *testing.T) {
+		verification, err := signatureSvc.Verify(context.Background(), newReceipt("Corner Shop"))
+		assert.NoError(t, err)
+		assert.Equal(t, models.SignatureSkipped, verification.Status)
+	})
+}
+
+func TestSignatureServiceImpl_UnknownTerminal(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := mock.NewMockTerminalKeyRepository(ctrl)
+	repo.EXPECT().GetPolicy(gomock.Any(), "Target").Return(models.RetailerSignaturePolicy{Policy: models.SignaturePolicyPrefer}, nil)
+	repo.EXPECT().GetByID(gomock.Any(), "unknown").Return(nil, repository.ErrTerminalKeyNotFound)
+
+	receipt := &models.Receipt{
+		Retailer:  "Target",
+		Signature: &models.ReceiptSignature{KeyID: "unknown", Algorithm: models.SignatureEd25519, Value: []byte("sig")},
+	}
+	_, err := NewSignatureService(repo).Verify(context.Background(), receipt)
+	assert.Equal(t, ErrUntrustedTerminal, err)
+}
@@ -0,0 +1,18 @@
+package http
+
+import (
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// ScopeWebhooksAdmin allows managing webhook subscriptions and deliveries.
+const ScopeWebhooksAdmin = "webhooks:admin"
+
+func MapWebhookRoutes(routesGroup *gin.RouterGroup, handler WebhookHandler, guard middleware.ScopeGuard) {
+	routesGroup.Use(guard.Require(ScopeWebhooksAdmin))
+	routesGroup.POST("", handler.Create)
+	routesGroup.GET("", handler.List)
+	routesGroup.DELETE("/:id", handler.Delete)
+	routesGroup.GET("/dead-letters", handler.ListDeadLetters)
+	routesGroup.POST("/deliveries/:id/redeliver", handler.Redeliver)
+}
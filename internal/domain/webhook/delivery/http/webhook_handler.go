@@ -0,0 +1,170 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/webhook/repository"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/webhook/service"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/dto"
+	"github.com/CarlosMtz98/receipt-processor-challenge/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"net/http"
+)
+
+type WebhookHandler interface {
+	Create(c *gin.Context)
+	List(c *gin.Context)
+	Delete(c *gin.Context)
+	ListDeadLetters(c *gin.Context)
+	Redeliver(c *gin.Context)
+}
+
+type WebhookHandlerImpl struct {
+	webhookSvc service.WebhookService
+}
+
+func NewWebhookHandler(webhookService service.WebhookService) WebhookHandler {
+	return &WebhookHandlerImpl{
+		webhookSvc: webhookService,
+	}
+}
+
+func (h WebhookHandlerImpl) Create(c *gin.Context) {
+	request := dto.CreateWebhookRequest{}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.HandleBadRequest(c, "Could not parse the request body", err)
+		return
+	}
+
+	if err := utils.ValidateStruct(c, request); err != nil {
+		utils.HandleBadRequest(c, "The webhook params are not valid", err)
+		return
+	}
+
+	events := make([]models.ReceiptEventType, len(request.Events))
+	for i, event := range request.Events {
+		events[i] = models.ReceiptEventType(event)
+	}
+
+	subscription, err := h.webhookSvc.CreateSubscription(c, request.URL, events, request.Secret)
+	if errors.Is(err, service.ErrInvalidWebhookURL) || errors.Is(err, service.ErrForbiddenWebhookAddress) || errors.Is(err, service.ErrInvalidEventType) {
+		utils.HandleBadRequest(c, "The webhook params are not valid", err)
+		return
+	}
+	if err != nil {
+		utils.HandleInternalError(c, "Could not create the webhook subscription", err)
+		return
+	}
+
+	// The secret is only disclosed when the subscription is created.
+	response := newSubscriptionResponse(subscription)
+	response.Secret = subscription.Secret
+	c.JSON(http.StatusCreated, response)
+}
+
+func (h WebhookHandlerImpl) List(c *gin.Context) {
+	subscriptions, err := h.webhookSvc.ListSubscriptions(c)
+	if err != nil {
+		utils.HandleInternalError(c, "Could not list the webhook subscriptions", err)
+		return
+	}
+
+	response := dto.ListWebhooksResponse{
+		Subscriptions: make([]dto.WebhookSubscriptionResponse, len(subscriptions)),
+	}
+	for i, subscription := range subscriptions {
+		response.Subscriptions[i] = newSubscriptionResponse(subscription)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func (h WebhookHandlerImpl) Delete(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.HandleBadRequest(c, "Invalid ID format", err)
+		return
+	}
+
+	err = h.webhookSvc.DeleteSubscription(c, id)
+	if errors.Is(err, repository.ErrSubscriptionNotFound) {
+		utils.HandleNotFound(c, fmt.Sprintf("Could not find the webhook subscription with ID %s", id))
+		return
+	}
+	if err != nil {
+		utils.HandleInternalError(c, "Could not delete the webhook subscription", err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h WebhookHandlerImpl) ListDeadLetters(c *gin.Context) {
+	deliveries, err := h.webhookSvc.ListDeadLetters(c)
+	if err != nil {
+		utils.HandleInternalError(c, "Could not list the dead letters", err)
+		return
+	}
+
+	response := dto.ListWebhookDeliveriesResponse{
+		Deliveries: make([]dto.WebhookDeliveryResponse, len(deliveries)),
+	}
+	for i, delivery := range deliveries {
+		response.Deliveries[i] = newDeliveryResponse(delivery)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func (h WebhookHandlerImpl) Redeliver(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.HandleBadRequest(c, "Invalid ID format", err)
+		return
+	}
+
+	delivery, err := h.webhookSvc.Redeliver(c, id)
+	if errors.Is(err, repository.ErrDeliveryNotFound) {
+		utils.HandleNotFound(c, fmt.Sprintf("Could not find the webhook delivery with ID %s", id))
+		return
+	}
+	if errors.Is(err, service.ErrDeliveryPending) {
+		utils.HandleConflict(c, fmt.Sprintf("The webhook delivery with ID %s is still pending", id))
+		return
+	}
+	if err != nil {
+		utils.HandleInternalError(c, "Could not redeliver the webhook", err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, newDeliveryResponse(delivery))
+}
+
+func newSubscriptionResponse(subscription *models.WebhookSubscription) dto.WebhookSubscriptionResponse {
+	events := make([]string, len(subscription.Events))
+	for i, event := range subscription.Events {
+		events[i] = string(event)
+	}
+
+	return dto.WebhookSubscriptionResponse{
+		ID:        subscription.ID.String(),
+		URL:       subscription.URL,
+		Events:    events,
+		CreatedAt: subscription.CreatedAt,
+	}
+}
+
+func newDeliveryResponse(delivery *models.WebhookDelivery) dto.WebhookDeliveryResponse {
+	return dto.WebhookDeliveryResponse{
+		ID:             delivery.ID.String(),
+		SubscriptionID: delivery.SubscriptionID.String(),
+		EventID:        delivery.EventID.String(),
+		EventType:      string(delivery.EventType),
+		Status:         string(delivery.Status),
+		Attempts:       delivery.Attempts,
+		LastError:      delivery.LastError,
+		NextAttemptAt:  delivery.NextAttemptAt,
+		CreatedAt:      delivery.CreatedAt,
+		DeliveredAt:    delivery.DeliveredAt,
+	}
+}
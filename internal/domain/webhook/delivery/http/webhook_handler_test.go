@@ -0,0 +1,193 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/webhook/mock"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/webhook/repository"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/webhook/service"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/dto"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWebhookHandlerImpl_Create(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockWebhookService := mock.NewMockWebhookService(ctrl)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/admin/webhooks", NewWebhookHandler(mockWebhookService).Create)
+
+	send := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/admin/webhooks", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	t.Run("Created", func(t *testing.T) {
+		subscription := &models.WebhookSubscription{
+			ID:        uuid.New(),
+			URL:       "https://example.com/hooks",
+			Events:    []models.ReceiptEventType{models.ReceiptScored},
+			Secret:    "generated",
+			CreatedAt: time.Now(),
+		}
+		mockWebhookService.EXPECT().
+			CreateSubscription(gomock.Any(), "https://example.com/hooks", []models.ReceiptEventType{models.ReceiptScored}, "").
+			Return(subscription, nil)
+
+		resp := send(`{"url": "https://example.com/hooks", "events": ["receipt.scored"]}`)
+		assert.Equal(t, http.StatusCreated, resp.Code)
+
+		var response dto.WebhookSubscriptionResponse
+		if err := json.Unmarshal(resp.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, subscription.ID.String(), response.ID)
+		assert.Equal(t, "generated", response.Secret)
+	})
+
+	t.Run("Missing events", func(t *testing.T) {
+		resp := send(`{"url": "https://example.com/hooks"}`)
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+	})
+
+	t.Run("Unknown event", func(t *testing.T) {
+		mockWebhookService.EXPECT().
+			CreateSubscription(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(nil, service.ErrInvalidEventType)
+
+		resp := send(`{"url": "https://example.com/hooks", "events": ["receipt.deleted"]}`)
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+	})
+}
+
+func TestWebhookHandlerImpl_List(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	subscription := &models.WebhookSubscription{
+		ID:     uuid.New(),
+		URL:    "https://example.com/hooks",
+		Events: []models.ReceiptEventType{models.ReceiptCreated},
+		Secret: "secret",
+	}
+	mockWebhookService := mock.NewMockWebhookService(ctrl)
+	mockWebhookService.EXPECT().ListSubscriptions(gomock.Any()).Return([]*models.WebhookSubscription{subscription}, nil)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/admin/webhooks", NewWebhookHandler(mockWebhookService).List)
+
+	req := httptest.NewRequest("GET", "/admin/webhooks", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var response dto.ListWebhooksResponse
+	if err := json.Unmarshal(resp.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, response.Subscriptions, 1)
+	assert.Equal(t, []string{"receipt.created"}, response.Subscriptions[0].Events)
+	assert.Empty(t, response.Subscriptions[0].Secret)
+}
+
+func TestWebhookHandlerImpl_Delete(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockWebhookService := mock.NewMockWebhookService(ctrl)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.DELETE("/admin/webhooks/:id", NewWebhookHandler(mockWebhookService).Delete)
+
+	send := func(id string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("DELETE", "/admin/webhooks/"+id, nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	existingID := uuid.New()
+	mockWebhookService.EXPECT().DeleteSubscription(gomock.Any(), existingID).Return(nil)
+	assert.Equal(t, http.StatusNoContent, send(existingID.String()).Code)
+
+	missingID := uuid.New()
+	mockWebhookService.EXPECT().DeleteSubscription(gomock.Any(), missingID).Return(repository.ErrSubscriptionNotFound)
+	assert.Equal(t, http.StatusNotFound, send(missingID.String()).Code)
+
+	assert.Equal(t, http.StatusBadRequest, send("not-a-uuid").Code)
+}
+
+func TestWebhookHandlerImpl_DeadLetters(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockWebhookService := mock.NewMockWebhookService(ctrl)
+	handler := NewWebhookHandler(mockWebhookService)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/admin/webhooks/dead-letters", handler.ListDeadLetters)
+	router.POST("/admin/webhooks/deliveries/:id/redeliver", handler.Redeliver)
+
+	delivery := &models.WebhookDelivery{
+		ID:             uuid.New(),
+		SubscriptionID: uuid.New(),
+		EventID:        uuid.New(),
+		EventType:      models.ReceiptScored,
+		Status:         models.DeliveryDead,
+		Attempts:       8,
+		LastError:      "the receiver answered with status 503",
+	}
+
+	t.Run("List", func(t *testing.T) {
+		mockWebhookService.EXPECT().ListDeadLetters(gomock.Any()).Return([]*models.WebhookDelivery{delivery}, nil)
+
+		req := httptest.NewRequest("GET", "/admin/webhooks/dead-letters", nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusOK, resp.Code)
+
+		var response dto.ListWebhookDeliveriesResponse
+		if err := json.Unmarshal(resp.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+		assert.Len(t, response.Deliveries, 1)
+		assert.Equal(t, "dead", response.Deliveries[0].Status)
+		assert.Equal(t, 8, response.Deliveries[0].Attempts)
+	})
+
+	t.Run("Redeliver", func(t *testing.T) {
+		redelivered := *delivery
+		redelivered.Status = models.DeliveryPending
+		redelivered.Attempts = 0
+		mockWebhookService.EXPECT().Redeliver(gomock.Any(), delivery.ID).Return(&redelivered, nil)
+
+		req := httptest.NewRequest("POST", "/admin/webhooks/deliveries/"+delivery.ID.String()+"/redeliver", nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusAccepted, resp.Code)
+	})
+
+	t.Run("Redeliver pending", func(t *testing.T) {
+		mockWebhookService.EXPECT().Redeliver(gomock.Any(), delivery.ID).Return(nil, service.ErrDeliveryPending)
+
+		req := httptest.NewRequest("POST", "/admin/webhooks/deliveries/"+delivery.ID.String()+"/redeliver", nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusConflict, resp.Code)
+	})
+}
@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
+	"github.com/google/uuid"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrSubscriptionNotFound is returned when a webhook subscription does not exist.
+	ErrSubscriptionNotFound = errors.New("the webhook subscription was not found")
+	// ErrDeliveryNotFound is returned when a webhook delivery does not exist.
+	ErrDeliveryNotFound = errors.New("the webhook delivery was not found")
+	// ErrDuplicateDelivery is returned when the event was already queued for the subscription.
+	ErrDuplicateDelivery = errors.New("the event was already queued for the subscription")
+)
+
+type WebhookRepository interface {
+	CreateSubscription(ctx context.Context, subscription *models.WebhookSubscription) error
+	GetSubscription(ctx context.Context, id uuid.UUID) (*models.WebhookSubscription, error)
+	ListSubscriptions(ctx context.Context) ([]*models.WebhookSubscription, error)
+	DeleteSubscription(ctx context.Context, id uuid.UUID) error
+
+	CreateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+	GetDelivery(ctx context.Context, id uuid.UUID) (*models.WebhookDelivery, error)
+	UpdateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+	ListDueDeliveries(ctx context.Context, now time.Time, limit int) ([]*models.WebhookDelivery, error)
+	ListDeliveriesByStatus(ctx context.Context, status models.DeliveryStatus) ([]*models.WebhookDelivery, error)
+}
+
+type InMemoryWebhookRepository struct {
+	mu            sync.RWMutex
+	subscriptions map[uuid.UUID]*models.WebhookSubscription
+	deliveries    map[uuid.UUID]*models.WebhookDelivery
+	// queued indexes the deliveries by subscription and event to reject duplicates.
+	queued map[deliveryKey]uuid.UUID
+}
+
+type deliveryKey struct {
+	subscriptionID uuid.UUID
+	eventID        uuid.UUID
+}
+
+func InitWebhookRepository() WebhookRepository {
+	return &InMemoryWebhookRepository{
+		subscriptions: make(map[uuid.UUID]*models.WebhookSubscription),
+		deliveries:    make(map[uuid.UUID]*models.WebhookDelivery),
+		queued:        make(map[deliveryKey]uuid.UUID),
+	}
+}
+
+func (memoryRepo *InMemoryWebhookRepository) CreateSubscription(ctx context.Context, subscription *models.WebhookSubscription) error {
+	memoryRepo.mu.Lock()
+	defer memoryRepo.mu.Unlock()
+
+	memoryRepo.subscriptions[subscription.ID] = subscription
+	return nil
+}
+
+func (memoryRepo *InMemoryWebhookRepository) GetSubscription(ctx context.Context, id uuid.UUID) (*models.WebhookSubscription, error) {
+	memoryRepo.mu.RLock()
+	defer memoryRepo.mu.RUnlock()
+
+	if subscription, ok := memoryRepo.subscriptions[id]; ok {
+		return subscription, nil
+	}
+	return nil, ErrSubscriptionNotFound
+}
+
+func (memoryRepo *InMemoryWebhookRepository) ListSubscriptions(ctx context.Context) ([]*models.WebhookSubscription, error) {
+	memoryRepo.mu.RLock()
+	defer memoryRepo.mu.RUnlock()
+
+	subscriptions := make([]*models.WebhookSubscription, 0, len(memoryRepo.subscriptions))
+	for _, subscription := range memoryRepo.subscriptions {
+		subscriptions = append(subscriptions, subscription)
+	}
+	sort.Slice(subscriptions, func(i, j int) bool {
+		return subscriptions[i].CreatedAt.Before(subscriptions[j].CreatedAt)
+	})
+	return subscriptions, nil
+}
+
+func (memoryRepo *InMemoryWebhookRepository) DeleteSubscription(ctx context.Context, id uuid.UUID) error {
+	memoryRepo.mu.Lock()
+	defer memoryRepo.mu.Unlock()
+
+	if _, ok := memoryRepo.subscriptions[id]; !ok {
+		return ErrSubscriptionNotFound
+	}
+	delete(memoryRepo.subscriptions, id)
+	return nil
+}
+
+func (memoryRepo *InMemoryWebhookRepository) CreateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	memoryRepo.mu.Lock()
+	defer memoryRepo.mu.Unlock()
+
+	key := deliveryKey{subscriptionID: delivery.SubscriptionID, eventID: delivery.EventID}
+	if _, ok := memoryRepo.queued[key]; ok {
+		return ErrDuplicateDelivery
+	}
+
+	copied := *delivery
+	memoryRepo.deliveries[delivery.ID] = &copied
+	memoryRepo.queued[key] = delivery.ID
+	return nil
+}
+
+func (memoryRepo *InMemoryWebhookRepository) GetDelivery(ctx context.Context, id uuid.UUID) (*models.WebhookDelivery, error) {
+	memoryRepo.mu.RLock()
+	defer memoryRepo.mu.RUnlock()
+
+	if delivery, ok := memoryRepo.deliveries[id]; ok {
+		copied := *delivery
+		return &copied, nil
+	}
+	return nil, ErrDeliveryNotFound
+}
+
+func (memoryRepo *InMemoryWebhookRepository) UpdateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	memoryRepo.mu.Lock()
+	defer memoryRepo.mu.Unlock()
+
+	if _, ok := memoryRepo.deliveries[delivery.ID]; !ok {
+		return ErrDeliveryNotFound
+	}
+	copied := *delivery
+	memoryRepo.deliveries[delivery.ID] = &copied
+	return nil
+}
+
+// ListDueDeliveries returns pending deliveries whose next attempt is due, oldest first.
+func (memoryRepo *InMemoryWebhookRepository) ListDueDeliveries(ctx context.Context, now time.Time, limit int) ([]*models.WebhookDelivery, error) {
+	memoryRepo.mu.RLock()
+	defer memoryRepo.mu.RUnlock()
+
+	due := make([]*models.WebhookDelivery, 0)
+	for _, delivery := range memoryRepo.deliveries {
+		if delivery.Status == models.DeliveryPending && !delivery.NextAttemptAt.After(now) {
+			copied := *delivery
+			due = append(due, &copied)
+		}
+	}
+
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].NextAttemptAt.Before(due[j].NextAttemptAt)
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	return due, nil
+}
+
+func (memoryRepo *InMemoryWebhookRepository) ListDeliveriesByStatus(ctx context.Context, status models.DeliveryStatus) ([]*models.WebhookDelivery, error) {
+	memoryRepo.mu.RLock()
+	defer memoryRepo.mu.RUnlock()
+
+	deliveries := make([]*models.WebhookDelivery, 0)
+	for _, delivery := range memoryRepo.deliveries {
+		if delivery.Status == status {
+			copied := *delivery
+			deliveries = append(deliveries, &copied)
+		}
+	}
+
+	sort.Slice(deliveries, func(i, j int) bool {
+		return deliveries[i].CreatedAt.Before(deliveries[j].CreatedAt)
+	})
+	return deliveries, nil
+}
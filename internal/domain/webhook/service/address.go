@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// isPublicAddress tells whether webhooks can be delivered to the address: loopback, link-local,
+// private and unspecified addresses reach the host of the service or its network, e.g. the
+// cloud metadata endpoint at 169.254.169.254.
+func isPublicAddress(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+		!ip.IsPrivate() && !ip.IsUnspecified() && !ip.IsMulticast()
+}
+
+// checkHost rejects the webhook hosts that are, or resolve to, an address that isn't public.
+// Hosts that don't resolve yet are left to the check of the dialer.
+func checkHost(ctx context.Context, lookup func(context.Context, string) ([]net.IPAddr, error), host string) error {
+	if ip := net.ParseIP(host); ip != nil {
+		if !isPublicAddress(ip) {
+			return fmt.Errorf("%w: %s", ErrForbiddenWebhookAddress, host)
+		}
+		return nil
+	}
+
+	addresses, err := lookup(ctx, host)
+	if err != nil {
+		return nil
+	}
+	for _, address := range addresses {
+		if !isPublicAddress(address.IP) {
+			return fmt.Errorf("%w: %s resolves to %s", ErrForbiddenWebhookAddress, host, address.IP)
+		}
+	}
+	return nil
+}
+
+// NewWebhookClient returns the client the deliveries are posted with. Unless private networks
+// are allowed, its dialer refuses the addresses that aren't public once the host is resolved, so
+// that a host can't be pointed at the internal network after it was subscribed.
+func NewWebhookClient(timeout time.Duration, allowPrivateNetworks bool) *http.Client {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	if !allowPrivateNetworks {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !isPublicAddress(ip) {
+				return fmt.Errorf("%w: %s", ErrForbiddenWebhookAddress, host)
+			}
+			return nil
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// Proxies would dial the webhook on our behalf, out of reach of the check.
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
@@ -0,0 +1,182 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/webhook/repository"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// SignatureHeader carries "t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<body>">".
+	SignatureHeader = "X-Webhook-Signature"
+	EventHeader     = "X-Webhook-Event"
+	DeliveryHeader  = "X-Webhook-Delivery"
+)
+
+// DispatcherOptions tunes the delivery loop and its retry policy.
+type DispatcherOptions struct {
+	MaxAttempts  int
+	BaseBackoff  time.Duration
+	MaxBackoff   time.Duration
+	PollInterval time.Duration
+	BatchSize    int
+	// AllowPrivateNetworks accepts webhooks in loopback, link-local and private networks.
+	AllowPrivateNetworks bool
+}
+
+func DefaultDispatcherOptions() DispatcherOptions {
+	return DispatcherOptions{
+		MaxAttempts:  8,
+		BaseBackoff:  time.Second,
+		MaxBackoff:   10 * time.Minute,
+		PollInterval: time.Second,
+		BatchSize:    50,
+	}
+}
+
+// Dispatcher delivers pending webhook deliveries in the background, retrying failures
+// with exponential backoff until they succeed or are moved to the dead-letter list.
+type Dispatcher struct {
+	webhookRepository repository.WebhookRepository
+	client            *http.Client
+	options           DispatcherOptions
+	wake              chan struct{}
+	now               func() time.Time
+}
+
+func NewDispatcher(webhookRepository repository.WebhookRepository, client *http.Client, options DispatcherOptions) *Dispatcher {
+	if client == nil {
+		client = NewWebhookClient(10*time.Second, options.AllowPrivateNetworks)
+	}
+
+	return &Dispatcher{
+		webhookRepository: webhookRepository,
+		client:            client,
+		options:           options,
+		wake:              make(chan struct{}, 1),
+		now:               time.Now,
+	}
+}
+
+// Start runs the delivery loop until the context is cancelled.
+func (d *Dispatcher) Start(ctx context.Context) {
+	ticker := time.NewTicker(d.options.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+
+		if err := d.DispatchDue(ctx); err != nil {
+			log.Printf("Error dispatching webhooks: %v", err)
+		}
+	}
+}
+
+// Notify wakes the delivery loop without waiting for the next poll.
+func (d *Dispatcher) Notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// DispatchDue attempts every delivery whose next attempt is due.
+func (d *Dispatcher) DispatchDue(ctx context.Context) error {
+	deliveries, err := d.webhookRepository.ListDueDeliveries(ctx, d.now(), d.options.BatchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, delivery := range deliveries {
+		if err := d.attempt(ctx, delivery); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *Dispatcher) attempt(ctx context.Context, delivery *models.WebhookDelivery) error {
+	subscription, err := d.webhookRepository.GetSubscription(ctx, delivery.SubscriptionID)
+	if err != nil {
+		delivery.Status = models.DeliveryDead
+		delivery.LastError = err.Error()
+		return d.webhookRepository.UpdateDelivery(ctx, delivery)
+	}
+
+	delivery.Attempts++
+	sendErr := d.send(ctx, subscription, delivery)
+	now := d.now()
+
+	switch {
+	case sendErr == nil:
+		delivery.Status = models.DeliveryDelivered
+		delivery.DeliveredAt = &now
+		delivery.LastError = ""
+	case delivery.Attempts >= d.options.MaxAttempts:
+		delivery.Status = models.DeliveryDead
+		delivery.LastError = sendErr.Error()
+		log.Printf("Webhook delivery %s moved to dead letters after %d attempts: %v", delivery.ID, delivery.Attempts, sendErr)
+	default:
+		delivery.LastError = sendErr.Error()
+		delivery.NextAttemptAt = now.Add(d.backoff(delivery.Attempts))
+	}
+
+	return d.webhookRepository.UpdateDelivery(ctx, delivery)
+}
+
+func (d *Dispatcher) send(ctx context.Context, subscription *models.WebhookSubscription, delivery *models.WebhookDelivery) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return err
+	}
+
+	timestamp := strconv.FormatInt(d.now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, string(delivery.EventType))
+	req.Header.Set(DeliveryHeader, delivery.ID.String())
+	req.Header.Set(SignatureHeader, fmt.Sprintf("t=%s,v1=%s", timestamp, Sign(subscription.Secret, timestamp, delivery.Payload)))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("the receiver answered with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	backoff := d.options.BaseBackoff << (attempts - 1)
+	if backoff <= 0 || backoff > d.options.MaxBackoff {
+		return d.options.MaxBackoff
+	}
+	return backoff
+}
+
+// Sign computes the hex HMAC-SHA256 receivers use to authenticate a payload.
+func Sign(secret, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
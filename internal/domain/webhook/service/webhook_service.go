@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/webhook/repository"
+	"github.com/google/uuid"
+	"net"
+	"net/url"
+	"time"
+)
+
+var (
+	// ErrInvalidWebhookURL is returned when the subscription URL is not an absolute http(s) URL.
+	ErrInvalidWebhookURL = errors.New("the webhook url must be an absolute http or https url")
+	// ErrForbiddenWebhookAddress is returned when the subscription URL points to a loopback,
+	// link-local or private address.
+	ErrForbiddenWebhookAddress = errors.New("the webhook url must not point to a loopback, link-local or private address")
+	// ErrInvalidEventType is returned when subscribing to an unknown event.
+	ErrInvalidEventType = errors.New("the event type is not supported")
+	// ErrDeliveryPending is returned when redelivering a delivery that is still being retried.
+	ErrDeliveryPending = errors.New("the delivery is still pending")
+)
+
+type WebhookService interface {
+	CreateSubscription(ctx context.Context, rawURL string, events []models.ReceiptEventType, secret string) (*models.WebhookSubscription, error)
+	ListSubscriptions(ctx context.Context) ([]*models.WebhookSubscription, error)
+	DeleteSubscription(ctx context.Context, id uuid.UUID) error
+	Publish(ctx context.Context, event *models.ReceiptEvent) error
+	ListDeadLetters(ctx context.Context) ([]*models.WebhookDelivery, error)
+	Redeliver(ctx context.Context, deliveryID uuid.UUID) (*models.WebhookDelivery, error)
+}
+
+type WebhookServiceImpl struct {
+	webhookRepository repository.WebhookRepository
+	dispatcher        *Dispatcher
+	// allowPrivateNetworks follows the dispatcher, which is the one delivering to them.
+	allowPrivateNetworks bool
+	lookupIP             func(ctx context.Context, host string) ([]net.IPAddr, error)
+	now                  func() time.Time
+}
+
+func NewWebhookService(webhookRepository repository.WebhookRepository, dispatcher *Dispatcher) WebhookService {
+	return &WebhookServiceImpl{
+		webhookRepository:    webhookRepository,
+		dispatcher:           dispatcher,
+		allowPrivateNetworks: dispatcher != nil && dispatcher.options.AllowPrivateNetworks,
+		lookupIP:             net.DefaultResolver.LookupIPAddr,
+		now:                  time.Now,
+	}
+}
+
+// CreateSubscription registers an endpoint. A random secret is generated when none is given.
+// Endpoints in private networks are refused unless the dispatcher allows them.
+func (s *WebhookServiceImpl) CreateSubscription(ctx context.Context, rawURL string, events []models.ReceiptEventType, secret string) (*models.WebhookSubscription, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Hostname() == "" {
+		return nil, ErrInvalidWebhookURL
+	}
+	if !s.allowPrivateNetworks {
+		if err := checkHost(ctx, s.lookupIP, parsed.Hostname()); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(events) == 0 {
+		return nil, ErrInvalidEventType
+	}
+	for _, event := range events {
+		if !models.IsValidReceiptEventType(event) {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidEventType, event)
+		}
+	}
+
+	if secret == "" {
+		secret, err = generateSecret()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	subscription := &models.WebhookSubscription{
+		ID:        uuid.New(),
+		URL:       parsed.String(),
+		Events:    events,
+		Secret:    secret,
+		CreatedAt: s.now(),
+	}
+	if err := s.webhookRepository.CreateSubscription(ctx, subscription); err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+func (s *WebhookServiceImpl) ListSubscriptions(ctx context.Context) ([]*models.WebhookSubscription, error) {
+	return s.webhookRepository.ListSubscriptions(ctx)
+}
+
+func (s *WebhookServiceImpl) DeleteSubscription(ctx context.Context, id uuid.UUID) error {
+	return s.webhookRepository.DeleteSubscription(ctx, id)
+}
+
+// Publish queues one delivery per subscription interested in the event. Publishing the same
+// event again does not queue it twice.
+func (s *WebhookServiceImpl) Publish(ctx context.Context, event *models.ReceiptEvent) error {
+	subscriptions, err := s.webhookRepository.ListSubscriptions(ctx)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	queued := false
+	for _, subscription := range subscriptions {
+		if !subscription.Subscribes(event.Type) {
+			continue
+		}
+
+		now := s.now()
+		delivery := &models.WebhookDelivery{
+			ID:             uuid.New(),
+			SubscriptionID: subscription.ID,
+			EventID:        event.ID,
+			EventType:      event.Type,
+			Payload:        payload,
+			Status:         models.DeliveryPending,
+			NextAttemptAt:  now,
+			CreatedAt:      now,
+		}
+		err := s.webhookRepository.CreateDelivery(ctx, delivery)
+		if errors.Is(err, repository.ErrDuplicateDelivery) {
+			// The event bus delivers at least once; the event is already queued.
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		queued = true
+	}
+
+	if queued && s.dispatcher != nil {
+		s.dispatcher.Notify()
+	}
+	return nil
+}
+
+func (s *WebhookServiceImpl) ListDeadLetters(ctx context.Context) ([]*models.WebhookDelivery, error) {
+	return s.webhookRepository.ListDeliveriesByStatus(ctx, models.DeliveryDead)
+}
+
+// Redeliver queues a dead or delivered delivery again with a fresh retry budget.
+func (s *WebhookServiceImpl) Redeliver(ctx context.Context, deliveryID uuid.UUID) (*models.WebhookDelivery, error) {
+	delivery, err := s.webhookRepository.GetDelivery(ctx, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	if delivery.Status == models.DeliveryPending {
+		return nil, ErrDeliveryPending
+	}
+
+	delivery.Status = models.DeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = s.now()
+	delivery.DeliveredAt = nil
+	if err := s.webhookRepository.UpdateDelivery(ctx, delivery); err != nil {
+		return nil, err
+	}
+
+	if s.dispatcher != nil {
+		s.dispatcher.Notify()
+	}
+	return delivery, nil
+}
+
+func generateSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(secret), nil
+}
@@ -0,0 +1,217 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/json"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/webhook/repository"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type receiver struct {
+	mu       sync.Mutex
+	secret   string
+	failures int
+	events   []models.ReceiptEvent
+	verified []bool
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	body, _ := io.ReadAll(req.Body)
+	if r.failures > 0 {
+		r.failures--
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	var timestamp, signature string
+	for _, part := range strings.Split(req.Header.Get(SignatureHeader), ",") {
+		key, value, _ := strings.Cut(part, "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signature = value
+		}
+	}
+
+	var event models.ReceiptEvent
+	_ = json.Unmarshal(body, &event)
+	r.events = append(r.events, event)
+	r.verified = append(r.verified, hmac.Equal([]byte(signature), []byte(Sign(r.secret, timestamp, body))))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func newTestService(repo repository.WebhookRepository, clock *time.Time) (WebhookService, *Dispatcher) {
+	dispatcher := NewDispatcher(repo, nil, DispatcherOptions{
+		MaxAttempts:  3,
+		BaseBackoff:  time.Second,
+		MaxBackoff:   time.Minute,
+		PollInterval: time.Second,
+		BatchSize:    10,
+		// The receivers listen on the loopback interface.
+		AllowPrivateNetworks: true,
+	})
+	dispatcher.now = func() time.Time { return *clock }
+
+	webhookSvc := &WebhookServiceImpl{
+		webhookRepository:    repo,
+		dispatcher:           dispatcher,
+		allowPrivateNetworks: true,
+		now:                  dispatcher.now,
+	}
+	return webhookSvc, dispatcher
+}
+
+func TestWebhookServiceImpl_CreateSubscription(t *testing.T) {
+	webhookSvc := NewWebhookService(repository.InitWebhookRepository(), nil)
+	webhookSvc.(*WebhookServiceImpl).lookupIP = func(ctx context.Context, host string) ([]net.IPAddr, error) {
+		switch host {
+		case "example.com":
+			return []net.IPAddr{{IP: net.ParseIP("93.184.216.34")}}, nil
+		case "metadata.internal":
+			return []net.IPAddr{{IP: net.ParseIP("169.254.169.254")}}, nil
+		}
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	ctx := context.Background()
+
+	subscription, err := webhookSvc.CreateSubscription(ctx, "https://example.com/hooks", []models.ReceiptEventType{models.ReceiptScored}, "")
+	assert.NoError(t, err)
+	assert.Len(t, subscription.Secret, 64)
+
+	_, err = webhookSvc.CreateSubscription(ctx, "ftp://example.com/hooks", []models.ReceiptEventType{models.ReceiptScored}, "")
+	assert.ErrorIs(t, err, ErrInvalidWebhookURL)
+
+	_, err = webhookSvc.CreateSubscription(ctx, "https://example.com/hooks", []models.ReceiptEventType{"receipt.deleted"}, "")
+	assert.ErrorIs(t, err, ErrInvalidEventType)
+
+	for _, rawURL := range []string{
+		"http://169.254.169.254/latest/meta-data",
+		"http://127.0.0.1:8080/hooks",
+		"http://[::1]/hooks",
+		"http://10.0.0.5/hooks",
+		"http://metadata.internal/hooks",
+	} {
+		_, err = webhookSvc.CreateSubscription(ctx, rawURL, []models.ReceiptEventType{models.ReceiptScored}, "")
+		assert.ErrorIs(t, err, ErrForbiddenWebhookAddress, rawURL)
+	}
+}
+
+func TestNewWebhookClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	// The check runs on the resolved address, whatever the URL was when it was subscribed.
+	_, err := NewWebhookClient(time.Second, false).Get(server.URL)
+	assert.ErrorIs(t, err, ErrForbiddenWebhookAddress)
+
+	resp, err := NewWebhookClient(time.Second, true).Get(server.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	_ = resp.Body.Close()
+}
+
+func TestWebhookServiceImpl_Delivery(t *testing.T) {
+	ctx := context.Background()
+	clock := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("Signed delivery to interested subscriptions", func(t *testing.T) {
+		repo := repository.InitWebhookRepository()
+		webhookSvc, dispatcher := newTestService(repo, &clock)
+
+		scored := &receiver{secret: "scored-secret"}
+		scoredServer := httptest.NewServer(scored)
+		defer scoredServer.Close()
+		rejected := &receiver{secret: "rejected-secret"}
+		rejectedServer := httptest.NewServer(rejected)
+		defer rejectedServer.Close()
+
+		_, err := webhookSvc.CreateSubscription(ctx, scoredServer.URL, []models.ReceiptEventType{models.ReceiptScored}, scored.secret)
+		assert.NoError(t, err)
+		_, err = webhookSvc.CreateSubscription(ctx, rejectedServer.URL, []models.ReceiptEventType{models.ReceiptRejected}, rejected.secret)
+		assert.NoError(t, err)
+
+		points := 28
+		originalID := uuid.New()
+		event := models.NewReceiptEvent(models.ReceiptScored, &models.Receipt{ID: uuid.New(), Retailer: "Target", Total: "-3500", Currency: "JPY", Type: models.ReceiptTypeReturn, OriginalID: &originalID}, clock)
+		event.Points = &points
+		assert.NoError(t, webhookSvc.Publish(ctx, event))
+		// Events relayed again are not delivered twice.
+		assert.NoError(t, webhookSvc.Publish(ctx, event))
+		assert.NoError(t, dispatcher.DispatchDue(ctx))
+
+		assert.Len(t, scored.events, 1)
+		assert.Equal(t, event.ID, scored.events[0].ID)
+		assert.Equal(t, 28, *scored.events[0].Points)
+		assert.Equal(t, "JPY", scored.events[0].Currency)
+		assert.Equal(t, models.ReceiptTypeReturn, scored.events[0].ReceiptType)
+		assert.Equal(t, originalID, *scored.events[0].OriginalID)
+		assert.True(t, scored.verified[0])
+		assert.Empty(t, rejected.events)
+	})
+
+	t.Run("Retries with backoff, dead letters and redelivery", func(t *testing.T) {
+		repo := repository.InitWebhookRepository()
+		webhookSvc, dispatcher := newTestService(repo, &clock)
+
+		failing := &receiver{secret: "secret", failures: 3}
+		server := httptest.NewServer(failing)
+		defer server.Close()
+
+		_, err := webhookSvc.CreateSubscription(ctx, server.URL, []models.ReceiptEventType{models.ReceiptCreated}, failing.secret)
+		assert.NoError(t, err)
+		event := models.NewReceiptEvent(models.ReceiptCreated, &models.Receipt{ID: uuid.New()}, clock)
+		assert.NoError(t, webhookSvc.Publish(ctx, event))
+
+		assert.NoError(t, dispatcher.DispatchDue(ctx))
+		pending, _ := repo.ListDeliveriesByStatus(ctx, models.DeliveryPending)
+		assert.Len(t, pending, 1)
+		assert.Equal(t, 1, pending[0].Attempts)
+		assert.Equal(t, clock.Add(time.Second), pending[0].NextAttemptAt)
+
+		// Nothing is due until the backoff has elapsed.
+		assert.NoError(t, dispatcher.DispatchDue(ctx))
+		pending, _ = repo.ListDeliveriesByStatus(ctx, models.DeliveryPending)
+		assert.Equal(t, 1, pending[0].Attempts)
+
+		clock = clock.Add(time.Second)
+		assert.NoError(t, dispatcher.DispatchDue(ctx))
+		pending, _ = repo.ListDeliveriesByStatus(ctx, models.DeliveryPending)
+		assert.Equal(t, clock.Add(2*time.Second), pending[0].NextAttemptAt)
+
+		clock = clock.Add(2 * time.Second)
+		assert.NoError(t, dispatcher.DispatchDue(ctx))
+		deadLetters, err := webhookSvc.ListDeadLetters(ctx)
+		assert.NoError(t, err)
+		assert.Len(t, deadLetters, 1)
+		assert.Equal(t, 3, deadLetters[0].Attempts)
+		assert.Contains(t, deadLetters[0].LastError, "503")
+
+		redelivered, err := webhookSvc.Redeliver(ctx, deadLetters[0].ID)
+		assert.NoError(t, err)
+		assert.Equal(t, models.DeliveryPending, redelivered.Status)
+		_, err = webhookSvc.Redeliver(ctx, deadLetters[0].ID)
+		assert.ErrorIs(t, err, ErrDeliveryPending)
+
+		assert.NoError(t, dispatcher.DispatchDue(ctx))
+		delivered, _ := repo.ListDeliveriesByStatus(ctx, models.DeliveryDelivered)
+		assert.Len(t, delivered, 1)
+		assert.True(t, failing.verified[0])
+	})
+}
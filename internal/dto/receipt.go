@@ -0,0 +1,54 @@
+package dto
+
+import (
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
+)
+
+// ReceiptRequest is the body of POST /receipts/process.
+type ReceiptRequest struct {
+	Retailer     string               `json:"retailer"`
+	PurchaseDate string               `json:"purchaseDate"`
+	PurchaseTime string               `json:"purchaseTime"`
+	Items        []models.ReceiptItem `json:"items"`
+	Total        string               `json:"total"`
+}
+
+func NewReceiptRequest(receipt *models.Receipt) ReceiptRequest {
+	return ReceiptRequest{
+		Retailer:     receipt.Retailer,
+		PurchaseDate: receipt.PurchaseDate,
+		PurchaseTime: receipt.PurchaseTime,
+		Items:        receipt.Items,
+		Total:        receipt.Total,
+	}
+}
+
+// ParseReceiptResponse is the draft read from a receipt text. Valid tells whether the draft
+// would be accepted by POST /receipts/process as it is.
+type ParseReceiptResponse struct {
+	Receipt    ReceiptRequest `json:"receipt"`
+	Valid      bool           `json:"valid"`
+	Confidence float64        `json:"confidence"`
+	Warnings   []string       `json:"warnings"`
+	Unparsed   []UnparsedLine `json:"unparsed"`
+}
+
+type UnparsedLine struct {
+	Line   int    `json:"line"`
+	Text   string `json:"text"`
+	Reason string `json:"reason"`
+}
+
+// EmailReceiptResponse is the receipt created from an email and the parts of the message that
+// were not used.
+type EmailReceiptResponse struct {
+	ID      string        `json:"id"`
+	Sender  string        `json:"sender"`
+	Source  string        `json:"source"`
+	Skipped []SkippedPart `json:"skipped"`
+}
+
+type SkippedPart struct {
+	Source string `json:"source"`
+	Reason string `json:"reason"`
+}
@@ -4,8 +4,11 @@ type CreateReceiptResponse struct {
 	ID string `json:"id"`
 }
 
+// GetPointsResponse has the points of the receipt, negative for returns. NetPoints are the
+// points of a purchase minus what its returns took back, and are left out for returns.
 type GetPointsResponse struct {
-	Points int `json:"points"`
+	Points    int  `json:"points"`
+	NetPoints *int `json:"netPoints,omitempty"`
 }
 
 type ResponseErrorModel struct {
@@ -13,3 +16,36 @@ type ResponseErrorModel struct {
 	Message string `json:"message"`
 	Details string `json:"details"`
 }
+
+// PointsStatusResponse is returned instead of the points while a receipt cannot be scored.
+type PointsStatusResponse struct {
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// ReceiptStreamEvent is the data of the events sent by the receipts stream.
+type ReceiptStreamEvent struct {
+	ID         string `json:"id"`
+	Type       string `json:"type"`
+	OriginalID string `json:"originalReceiptId,omitempty"`
+	Retailer   string `json:"retailer"`
+	Total      string `json:"total"`
+	Currency   string `json:"currency"`
+	Points     *int   `json:"points,omitempty"`
+}
+
+// ProblemDetails is an RFC 7807 problem, served as application/problem+json.
+type ProblemDetails struct {
+	Type       string      `json:"type"`
+	Title      string      `json:"title"`
+	Status     int         `json:"status"`
+	Detail     string      `json:"detail,omitempty"`
+	Violations []Violation `json:"violations,omitempty"`
+}
+
+// Violation is a single mismatch between a message and the OpenAPI specification.
+type Violation struct {
+	In      string `json:"in"`
+	Pointer string `json:"pointer,omitempty"`
+	Message string `json:"message"`
+}
@@ -0,0 +1,24 @@
+package dto
+
+import (
+	"time"
+)
+
+type RetailerRequest struct {
+	Name     string   `json:"name" validate:"required"`
+	Aliases  []string `json:"aliases"`
+	Patterns []string `json:"patterns"`
+}
+
+type RetailerResponse struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Aliases   []string  `json:"aliases"`
+	Patterns  []string  `json:"patterns"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type ListRetailersResponse struct {
+	Retailers []RetailerResponse `json:"retailers"`
+}
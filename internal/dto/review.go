@@ -0,0 +1,40 @@
+package dto
+
+import (
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
+	"time"
+)
+
+type ReviewResponse struct {
+	ID           string            `json:"id"`
+	Retailer     string            `json:"retailer"`
+	PurchaseDate string            `json:"purchaseDate"`
+	PurchaseTime string            `json:"purchaseTime"`
+	Total        string            `json:"total"`
+	Status       string            `json:"status"`
+	RiskScore    int               `json:"riskScore"`
+	Flags        []models.RiskFlag `json:"flags"`
+	SubmittedAt  time.Time         `json:"submittedAt"`
+}
+
+type ListReviewsResponse struct {
+	Reviews []ReviewResponse `json:"reviews"`
+}
+
+type ReviewDecisionRequest struct {
+	Reason string `json:"reason"`
+}
+
+type ReviewDecisionResponse struct {
+	ID        string    `json:"id"`
+	ReceiptID string    `json:"receiptId"`
+	Outcome   string    `json:"outcome"`
+	Reason    string    `json:"reason,omitempty"`
+	Reviewer  string    `json:"reviewer"`
+	Points    *int      `json:"points,omitempty"`
+	DecidedAt time.Time `json:"decidedAt"`
+}
+
+type ListReviewDecisionsResponse struct {
+	Decisions []ReviewDecisionResponse `json:"decisions"`
+}
@@ -0,0 +1,169 @@
+package eventbus
+
+import (
+	"context"
+	"expvar"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// deadLetters counts the events every subscriber gave up on, keyed by subscriber.
+var deadLetters = expvar.NewMap("eventbus_dead_letters")
+
+// Handler reacts to a receipt event. Events are delivered at least once, so handlers must
+// tolerate seeing the same event ID more than once.
+type Handler func(ctx context.Context, event *models.ReceiptEvent) error
+
+type subscriber struct {
+	name    string
+	handler Handler
+}
+
+// BusOptions tunes how the events a subscriber fails on are retried.
+type BusOptions struct {
+	MaxAttempts   int
+	BaseBackoff   time.Duration
+	MaxBackoff    time.Duration
+	RetryInterval time.Duration
+}
+
+func DefaultBusOptions() BusOptions {
+	return BusOptions{
+		MaxAttempts:   8,
+		BaseBackoff:   time.Second,
+		MaxBackoff:    5 * time.Minute,
+		RetryInterval: time.Second,
+	}
+}
+
+// Delivery is an event a subscriber failed on, waiting for its next attempt or, once it ran
+// out of attempts, in the dead letters.
+type Delivery struct {
+	Subscriber    string
+	Event         *models.ReceiptEvent
+	Attempts      int
+	LastError     string
+	NextAttemptAt time.Time
+}
+
+// Bus is an in-process publish/subscribe bus for receipt events. Every subscriber is retried
+// on its own, so that a failing one neither holds back nor duplicates the events of the others.
+type Bus struct {
+	mu          sync.Mutex
+	subscribers []subscriber
+	retries     []*Delivery
+	deadLetters []*Delivery
+	options     BusOptions
+	now         func() time.Time
+}
+
+func NewBus(options BusOptions) *Bus {
+	return &Bus{
+		options: options,
+		now:     time.Now,
+	}
+}
+
+// Subscribe registers a handler under a name used to report its failures.
+func (b *Bus) Subscribe(name string, handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.subscribers = append(b.subscribers, subscriber{name: name, handler: handler})
+}
+
+// Publish hands the event to every subscriber, in subscription order. The subscribers that
+// fail on it are retried with exponential backoff until they accept it or run out of
+// attempts; the bus owns the event from then on.
+func (b *Bus) Publish(ctx context.Context, event *models.ReceiptEvent) {
+	b.mu.Lock()
+	subscribers := b.subscribers
+	b.mu.Unlock()
+
+	for _, s := range subscribers {
+		if err := s.handler(ctx, event); err != nil {
+			b.failed(&Delivery{Subscriber: s.name, Event: event}, err)
+		}
+	}
+}
+
+// Start retries the failed deliveries until the context is cancelled.
+func (b *Bus) Start(ctx context.Context) {
+	ticker := time.NewTicker(b.options.RetryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		b.RetryDue(ctx)
+	}
+}
+
+// RetryDue attempts the failed deliveries whose backoff has elapsed, in sequence order. A
+// retried event can reach its subscriber after the events that followed it.
+func (b *Bus) RetryDue(ctx context.Context) {
+	now := b.now()
+	handlers := make(map[string]Handler)
+
+	b.mu.Lock()
+	for _, s := range b.subscribers {
+		handlers[s.name] = s.handler
+	}
+	var due, waiting []*Delivery
+	for _, delivery := range b.retries {
+		if delivery.NextAttemptAt.After(now) {
+			waiting = append(waiting, delivery)
+		} else {
+			due = append(due, delivery)
+		}
+	}
+	b.retries = waiting
+	b.mu.Unlock()
+
+	sort.SliceStable(due, func(i, j int) bool { return due[i].Event.Sequence < due[j].Event.Sequence })
+	for _, delivery := range due {
+		if err := handlers[delivery.Subscriber](ctx, delivery.Event); err != nil {
+			b.failed(delivery, err)
+		}
+	}
+}
+
+// DeadLetters returns the deliveries that ran out of attempts, oldest first.
+func (b *Bus) DeadLetters() []*Delivery {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return append([]*Delivery(nil), b.deadLetters...)
+}
+
+func (b *Bus) failed(delivery *Delivery, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delivery.Attempts++
+	delivery.LastError = err.Error()
+	if delivery.Attempts >= b.options.MaxAttempts {
+		b.deadLetters = append(b.deadLetters, delivery)
+		deadLetters.Add(delivery.Subscriber, 1)
+		log.Printf("Event %s moved to the dead letters of %s after %d attempts: %v", delivery.Event.ID, delivery.Subscriber, delivery.Attempts, err)
+		return
+	}
+
+	delivery.NextAttemptAt = b.now().Add(b.backoff(delivery.Attempts))
+	b.retries = append(b.retries, delivery)
+}
+
+func (b *Bus) backoff(attempts int) time.Duration {
+	backoff := b.options.BaseBackoff << (attempts - 1)
+	if backoff <= 0 || backoff > b.options.MaxBackoff {
+		return b.options.MaxBackoff
+	}
+	return backoff
+}
@@ -0,0 +1,80 @@
+package eventbus
+
+import (
+	"context"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
+	"github.com/google/uuid"
+	"log"
+	"time"
+)
+
+// Outbox is the store events are written to in the same transaction as the receipts.
+type Outbox interface {
+	ListPendingEvents(ctx context.Context, limit int) ([]*models.ReceiptEvent, error)
+	MarkEventPublished(ctx context.Context, id uuid.UUID) error
+}
+
+// RelayOptions tunes how often the outbox is polled and how many events are relayed at once.
+type RelayOptions struct {
+	PollInterval time.Duration
+	BatchSize    int
+}
+
+func DefaultRelayOptions() RelayOptions {
+	return RelayOptions{
+		PollInterval: 250 * time.Millisecond,
+		BatchSize:    100,
+	}
+}
+
+// Relay publishes the outbox events to the bus and marks them as published once the bus has
+// handed them to every subscriber. The bus retries the subscribers that failed on them, so an
+// event is never lost between being committed and being published, and a failing subscriber
+// doesn't hold back the outbox.
+type Relay struct {
+	outbox  Outbox
+	bus     *Bus
+	options RelayOptions
+}
+
+func NewRelay(outbox Outbox, bus *Bus, options RelayOptions) *Relay {
+	return &Relay{
+		outbox:  outbox,
+		bus:     bus,
+		options: options,
+	}
+}
+
+// Start relays events until the context is cancelled.
+func (r *Relay) Start(ctx context.Context) {
+	ticker := time.NewTicker(r.options.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := r.RelayPending(ctx); err != nil {
+			log.Printf("Error relaying outbox events: %v", err)
+		}
+	}
+}
+
+// RelayPending publishes the pending events in sequence order.
+func (r *Relay) RelayPending(ctx context.Context) error {
+	events, err := r.outbox.ListPendingEvents(ctx, r.options.BatchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, event := range events {
+		r.bus.Publish(ctx, event)
+		if err := r.outbox.MarkEventPublished(ctx, event.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
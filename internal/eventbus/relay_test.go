@@ -0,0 +1,114 @@
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/repository"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestBus_Publish(t *testing.T) {
+	ctx := context.Background()
+	clock := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	bus := NewBus(BusOptions{MaxAttempts: 3, BaseBackoff: time.Second, MaxBackoff: time.Minute})
+	bus.now = func() time.Time { return clock }
+
+	failures := 1
+	var ledger, analytics []int64
+	bus.Subscribe("ledger", func(ctx context.Context, event *models.ReceiptEvent) error {
+		if failures > 0 {
+			failures--
+			return errors.New("ledger unavailable")
+		}
+		ledger = append(ledger, event.Sequence)
+		return nil
+	})
+	bus.Subscribe("analytics", func(ctx context.Context, event *models.ReceiptEvent) error {
+		analytics = append(analytics, event.Sequence)
+		return nil
+	})
+
+	t.Run("A failing subscriber is retried on its own", func(t *testing.T) {
+		bus.Publish(ctx, &models.ReceiptEvent{ID: uuid.New(), Sequence: 1})
+		bus.Publish(ctx, &models.ReceiptEvent{ID: uuid.New(), Sequence: 2})
+		assert.Equal(t, []int64{2}, ledger)
+		assert.Equal(t, []int64{1, 2}, analytics)
+
+		// Nothing is retried before the backoff elapses.
+		bus.RetryDue(ctx)
+		assert.Equal(t, []int64{2}, ledger)
+
+		clock = clock.Add(time.Second)
+		bus.RetryDue(ctx)
+		assert.Equal(t, []int64{2, 1}, ledger)
+		assert.Equal(t, []int64{1, 2}, analytics)
+	})
+
+	t.Run("Dead letters", func(t *testing.T) {
+		failures = 3
+		event := &models.ReceiptEvent{ID: uuid.New(), Sequence: 3}
+		bus.Publish(ctx, event)
+		for i := 0; i < 3; i++ {
+			clock = clock.Add(time.Minute)
+			bus.RetryDue(ctx)
+		}
+
+		deadLetters := bus.DeadLetters()
+		assert.Len(t, deadLetters, 1)
+		assert.Equal(t, "ledger", deadLetters[0].Subscriber)
+		assert.Equal(t, event, deadLetters[0].Event)
+		assert.Equal(t, 3, deadLetters[0].Attempts)
+		assert.Equal(t, "ledger unavailable", deadLetters[0].LastError)
+		assert.Equal(t, []int64{1, 2, 3}, analytics)
+	})
+}
+
+func TestRelay_RelayPending(t *testing.T) {
+	ctx := context.Background()
+	receiptRepo := repository.InitReceiptRepository()
+	bus := NewBus(BusOptions{MaxAttempts: 3, BaseBackoff: 0, MaxBackoff: 0})
+	relay := NewRelay(receiptRepo, bus, DefaultRelayOptions())
+
+	failing := true
+	var ledger, analytics []*models.ReceiptEvent
+	bus.Subscribe("ledger", func(ctx context.Context, event *models.ReceiptEvent) error {
+		if failing {
+			return errors.New("ledger unavailable")
+		}
+		ledger = append(ledger, event)
+		return nil
+	})
+	bus.Subscribe("analytics", func(ctx context.Context, event *models.ReceiptEvent) error {
+		analytics = append(analytics, event)
+		return nil
+	})
+
+	receipt := &models.Receipt{ID: uuid.New(), Retailer: "Target"}
+	created := models.NewReceiptEvent(models.ReceiptCreated, receipt, time.Now())
+	scored := models.NewReceiptEvent(models.ReceiptScored, receipt, time.Now())
+	assert.NoError(t, receiptRepo.CreateWithEvents(ctx, receipt, []*models.ReceiptEvent{created, scored}))
+
+	// A failing subscriber neither holds back the outbox nor the other subscribers.
+	assert.NoError(t, relay.RelayPending(ctx))
+	pending, err := receiptRepo.ListPendingEvents(ctx, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, pending)
+	assert.Equal(t, []*models.ReceiptEvent{created, scored}, analytics)
+	assert.Equal(t, int64(1), analytics[0].Sequence)
+	assert.Equal(t, int64(2), analytics[1].Sequence)
+
+	// Only the failing subscriber gets the events again.
+	failing = false
+	bus.RetryDue(ctx)
+	assert.Equal(t, []*models.ReceiptEvent{created, scored}, ledger)
+	assert.Len(t, analytics, 2)
+
+	assert.NoError(t, relay.RelayPending(ctx))
+	bus.RetryDue(ctx)
+	assert.Len(t, ledger, 2)
+	assert.Len(t, analytics, 2)
+}
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyContextKey is the key under which the name of a verified API key is stored.
+const APIKeyContextKey = "apiKeyName"
+
+// APIKeys maps the API keys issued to integrations to the names they are known by.
+type APIKeys map[string]string
+
+// Name returns the name of the integration the key was issued to, comparing every key in
+// constant time.
+func (k APIKeys) Name(key string) (string, bool) {
+	if key == "" {
+		return "", false
+	}
+	name, found := "", false
+	for issued, issuedTo := range k {
+		if subtle.ConstantTimeCompare([]byte(issued), []byte(key)) == 1 {
+			name, found = issuedTo, true
+		}
+	}
+	return name, found
+}
+
+// Handler stores the name of the integration when the X-API-Key header is one of the keys.
+// Unknown keys are ignored, so that they cannot be rotated to get a fresh rate limit; those
+// clients are identified by their IP.
+func (k APIKeys) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if name, ok := k.Name(c.GetHeader(APIKeyHeader)); ok {
+			c.Set(APIKeyContextKey, name)
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"fmt"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/auth"
+	"github.com/CarlosMtz98/receipt-processor-challenge/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"strings"
+)
+
+// ScopeGuard builds the handlers that routes use to declare their required scopes.
+type ScopeGuard interface {
+	Authenticate() gin.HandlerFunc
+	Require(scopes ...string) gin.HandlerFunc
+}
+
+// JWTAuthenticator validates bearer tokens and enforces route scopes.
+type JWTAuthenticator struct {
+	verifier *auth.Verifier
+}
+
+func NewJWTAuthenticator(verifier *auth.Verifier) ScopeGuard {
+	return &JWTAuthenticator{
+		verifier: verifier,
+	}
+}
+
+// Authenticate verifies the bearer token and stores the principal in the request context.
+func (a *JWTAuthenticator) Authenticate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		scheme, token, found := strings.Cut(header, " ")
+		if !found || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+			utils.HandleUnauthorized(c, "A bearer token is required", nil)
+			return
+		}
+
+		principal, err := a.verifier.Verify(c.Request.Context(), strings.TrimSpace(token))
+		if err != nil {
+			utils.HandleUnauthorized(c, "The bearer token is not valid", err)
+			return
+		}
+
+		c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), principal))
+		c.Next()
+	}
+}
+
+// Require rejects requests whose principal lacks any of the given scopes.
+func (a *JWTAuthenticator) Require(scopes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := auth.PrincipalFromContext(c.Request.Context())
+		if !ok {
+			utils.HandleUnauthorized(c, "A bearer token is required", nil)
+			return
+		}
+
+		if !principal.HasScopes(scopes...) {
+			utils.HandleForbidden(c, fmt.Sprintf("The token requires the scopes: %s", strings.Join(scopes, " ")))
+			return
+		}
+		c.Next()
+	}
+}
+
+// AllowAll is the ScopeGuard used when authentication is disabled.
+type AllowAll struct{}
+
+func (AllowAll) Authenticate() gin.HandlerFunc {
+	return func(c *gin.Context) { c.Next() }
+}
+
+func (AllowAll) Require(...string) gin.HandlerFunc {
+	return func(c *gin.Context) { c.Next() }
+}
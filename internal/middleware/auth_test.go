@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/auth"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type staticKeySource struct {
+	key *auth.PublicKey
+}
+
+func (s staticKeySource) Key(ctx context.Context, kid string) (*auth.PublicKey, error) {
+	if kid != s.key.ID {
+		return nil, auth.ErrUnknownKey
+	}
+	return s.key, nil
+}
+
+func TestJWTAuthenticator(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	verifier := auth.NewVerifier(staticKeySource{key: &auth.PublicKey{
+		ID:        "test",
+		Algorithm: auth.AlgRS256,
+		Key:       &privateKey.PublicKey,
+	}}, auth.VerifierOptions{Audience: "receipt-processor"})
+	guard := NewJWTAuthenticator(verifier)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	group := router.Group("/receipts", guard.Authenticate())
+	group.GET("/protected", guard.Require("receipts:read"), func(c *gin.Context) {
+		principal, ok := auth.PrincipalFromContext(c.Request.Context())
+		assert.True(t, ok)
+		c.String(http.StatusOK, principal.Subject)
+	})
+
+	sign := func(scope string) string {
+		claims := map[string]interface{}{
+			"sub":   "partner-app",
+			"aud":   "receipt-processor",
+			"exp":   time.Now().Add(time.Hour).Unix(),
+			"scope": scope,
+		}
+		header, _ := json.Marshal(map[string]string{"alg": auth.AlgRS256, "kid": "test"})
+		payload, _ := json.Marshal(claims)
+		input := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+		digest := sha256.Sum256([]byte(input))
+		signature, err := rsa.SignPKCS1v15(rand.Reader, privateKey, crypto.SHA256, digest[:])
+		assert.NoError(t, err)
+		return input + "." + base64.RawURLEncoding.EncodeToString(signature)
+	}
+
+	tests := []struct {
+		name          string
+		authorization string
+		expectedCode  int
+	}{
+		{name: "Missing token", authorization: "", expectedCode: http.StatusUnauthorized},
+		{name: "Wrong scheme", authorization: "Basic dXNlcjpwYXNz", expectedCode: http.StatusUnauthorized},
+		{name: "Invalid token", authorization: "Bearer abc.def.ghi", expectedCode: http.StatusUnauthorized},
+		{name: "Missing scope", authorization: "Bearer " + sign("receipts:write"), expectedCode: http.StatusForbidden},
+		{name: "Authorized", authorization: "Bearer " + sign("receipts:read receipts:write"), expectedCode: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/receipts/protected", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.expectedCode, resp.Code)
+			if tt.expectedCode == http.StatusOK {
+				assert.Equal(t, "partner-app", resp.Body.String())
+			} else {
+				assert.NotEmpty(t, resp.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"strings"
+)
+
+// GRPCAuthInterceptor authenticates unary calls with the bearer token of the "authorization"
+// metadata and enforces the scopes declared for each full method name. Methods without
+// declared scopes only require a valid token.
+func GRPCAuthInterceptor(verifier *auth.Verifier, methodScopes map[string][]string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		values := md.Get("authorization")
+		if len(values) == 0 {
+			return nil, status.Error(codes.Unauthenticated, "a bearer token is required")
+		}
+
+		scheme, token, found := strings.Cut(values[0], " ")
+		if !found || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+			return nil, status.Error(codes.Unauthenticated, "a bearer token is required")
+		}
+
+		principal, err := verifier.Verify(ctx, strings.TrimSpace(token))
+		if err != nil {
+			return nil, status.Errorf(codes.Unauthenticated, "the bearer token is not valid: %v", err)
+		}
+
+		if scopes := methodScopes[info.FullMethod]; !principal.HasScopes(scopes...) {
+			return nil, status.Error(codes.PermissionDenied, fmt.Sprintf("the token requires the scopes: %s", strings.Join(scopes, " ")))
+		}
+
+		return handler(auth.WithPrincipal(ctx, principal), req)
+	}
+}
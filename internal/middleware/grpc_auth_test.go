@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/auth"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"testing"
+	"time"
+)
+
+func TestGRPCAuthInterceptor(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	verifier := auth.NewVerifier(staticKeySource{key: &auth.PublicKey{
+		ID:        "test",
+		Algorithm: auth.AlgRS256,
+		Key:       &privateKey.PublicKey,
+	}}, auth.VerifierOptions{})
+	interceptor := GRPCAuthInterceptor(verifier, map[string][]string{
+		"/receipt.v1.ReceiptService/GetPoints": {"receipts:read"},
+	})
+
+	sign := func(scope string) string {
+		header, _ := json.Marshal(map[string]string{"alg": auth.AlgRS256, "kid": "test"})
+		payload, _ := json.Marshal(map[string]interface{}{
+			"sub":   "partner-app",
+			"exp":   time.Now().Add(time.Hour).Unix(),
+			"scope": scope,
+		})
+		input := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+		digest := sha256.Sum256([]byte(input))
+		signature, err := rsa.SignPKCS1v15(rand.Reader, privateKey, crypto.SHA256, digest[:])
+		assert.NoError(t, err)
+		return input + "." + base64.RawURLEncoding.EncodeToString(signature)
+	}
+
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		principal, ok := auth.PrincipalFromContext(ctx)
+		assert.True(t, ok)
+		return principal.Subject, nil
+	}
+	info := &grpc.UnaryServerInfo{FullMethod: "/receipt.v1.ReceiptService/GetPoints"}
+
+	tests := []struct {
+		name          string
+		authorization string
+		expectedCode  codes.Code
+	}{
+		{name: "Missing token", expectedCode: codes.Unauthenticated},
+		{name: "Wrong scheme", authorization: "Basic dXNlcjpwYXNz", expectedCode: codes.Unauthenticated},
+		{name: "Invalid token", authorization: "Bearer abc.def.ghi", expectedCode: codes.Unauthenticated},
+		{name: "Missing scope", authorization: "Bearer " + sign("receipts:write"), expectedCode: codes.PermissionDenied},
+		{name: "Authorized", authorization: "Bearer " + sign("receipts:read"), expectedCode: codes.OK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.authorization != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", tt.authorization))
+			}
+
+			response, err := interceptor(ctx, nil, info, handler)
+			assert.Equal(t, tt.expectedCode, status.Code(err))
+			if tt.expectedCode == codes.OK {
+				assert.Equal(t, "partner-app", response)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"context"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"net"
+	"strings"
+)
+
+// apiKeyContextKey is the key under which GRPCInterceptor stores the name of a verified API key.
+type apiKeyContextKey struct{}
+
+// GRPCInterceptor stores the name of the integration when the "x-api-key" metadata is one of
+// the keys. Like Handler, unknown keys are ignored.
+func (k APIKeys) GRPCInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		if values := md.Get(strings.ToLower(APIKeyHeader)); len(values) > 0 {
+			if name, ok := k.Name(values[0]); ok {
+				ctx = context.WithValue(ctx, apiKeyContextKey{}, name)
+			}
+		}
+		return handler(ctx, req)
+	}
+}
+
+// GRPCInterceptor applies the limits and the daily quota to unary calls. methodRoutes maps
+// full method names to the HTTP route they share their limits with, so that a client gets
+// the same budget on both APIs; other methods are limited under their full method name.
+// It must be chained after the authentication and API key interceptors.
+func (r *RateLimiter) GRPCInterceptor(methodRoutes map[string]string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		route, ok := methodRoutes[info.FullMethod]
+		if !ok {
+			route = info.FullMethod
+		}
+		client := GRPCClientKey(ctx)
+
+		if limit, ok := r.config.LimitFor(route); ok {
+			decision := r.limiter.Allow(client+"|"+route, limit)
+			if !decision.Allowed {
+				rejectedRequests.Add("rate_limit "+route, 1)
+				_ = grpc.SetHeader(ctx, metadata.Pairs("retry-after", formatSeconds(decision.RetryAfter)))
+				return nil, status.Error(codes.ResourceExhausted, "Too many requests, slow down")
+			}
+		}
+
+		if r.config.HasQuota(route) {
+			decision := r.ConsumeQuota(client, route)
+			if !decision.Allowed {
+				_ = grpc.SetHeader(ctx, metadata.Pairs("retry-after", formatSeconds(decision.Reset)))
+				return nil, status.Error(codes.ResourceExhausted, "The daily quota of receipt submissions has been reached")
+			}
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// GRPCClientKey identifies the caller like ClientKey: by token subject, then by the name of
+// its verified API key, then by peer address.
+func GRPCClientKey(ctx context.Context) string {
+	if principal, ok := auth.PrincipalFromContext(ctx); ok && principal.Subject != "" {
+		return "sub:" + principal.Subject
+	}
+	if name, _ := ctx.Value(apiKeyContextKey{}).(string); name != "" {
+		return "key:" + name
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		host, _, err := net.SplitHostPort(p.Addr.String())
+		if err != nil {
+			host = p.Addr.String()
+		}
+		return "ip:" + host
+	}
+	return ""
+}
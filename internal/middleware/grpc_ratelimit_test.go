@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"context"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"net"
+	"testing"
+)
+
+func TestRateLimiter_GRPCInterceptor(t *testing.T) {
+	rateLimiter := NewRateLimiter(ratelimit.Config{
+		Default: ratelimit.Limit{Rate: 0.001, Burst: 5},
+		Routes: map[string]ratelimit.Limit{
+			"/receipt.v1.ReceiptService/GetPoints": {Rate: 0.001, Burst: 2},
+		},
+		DailyQuota:  2,
+		QuotaRoutes: []string{"POST /receipts/process"},
+	})
+	apiKeys := APIKeys{"key-a": "integration-a", "key-b": "integration-b"}
+	rateLimit := rateLimiter.GRPCInterceptor(map[string]string{
+		"/receipt.v1.ReceiptService/ProcessReceipt": "POST /receipts/process",
+	})
+	chained := func(ctx context.Context, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return apiKeys.GRPCInterceptor()(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return rateLimit(ctx, req, info, handler)
+		})
+	}
+
+	call := func(method, apiKey, ip string) (string, codes.Code) {
+		ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(ip), Port: 50051}})
+		if apiKey != "" {
+			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("x-api-key", apiKey))
+		}
+		client, err := chained(ctx, &grpc.UnaryServerInfo{FullMethod: method}, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return GRPCClientKey(ctx), nil
+		})
+		if err != nil {
+			return "", status.Code(err)
+		}
+		return client.(string), codes.OK
+	}
+
+	t.Run("Client key", func(t *testing.T) {
+		client, code := call("/receipt.v1.ReceiptService/ListReceipts", "key-b", "10.0.0.1")
+		assert.Equal(t, codes.OK, code)
+		assert.Equal(t, "key:integration-b", client)
+
+		client, code = call("/receipt.v1.ReceiptService/ListReceipts", "unknown", "10.0.0.1")
+		assert.Equal(t, codes.OK, code)
+		assert.Equal(t, "ip:10.0.0.1", client)
+	})
+
+	t.Run("Method limit", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			_, code := call("/receipt.v1.ReceiptService/GetPoints", "key-a", "10.0.0.2")
+			assert.Equal(t, codes.OK, code)
+		}
+		_, code := call("/receipt.v1.ReceiptService/GetPoints", "key-a", "10.0.0.2")
+		assert.Equal(t, codes.ResourceExhausted, code)
+
+		// Other clients keep their own budget.
+		_, code = call("/receipt.v1.ReceiptService/GetPoints", "", "10.0.0.2")
+		assert.Equal(t, codes.OK, code)
+	})
+
+	t.Run("Daily quota", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			_, code := call("/receipt.v1.ReceiptService/ProcessReceipt", "key-a", "10.0.0.3")
+			assert.Equal(t, codes.OK, code)
+		}
+		_, code := call("/receipt.v1.ReceiptService/ProcessReceipt", "key-a", "10.0.0.3")
+		assert.Equal(t, codes.ResourceExhausted, code)
+
+		// Reads don't count against the quota of submissions.
+		_, code = call("/receipt.v1.ReceiptService/ListReceipts", "key-a", "10.0.0.3")
+		assert.Equal(t, codes.OK, code)
+	})
+}
@@ -0,0 +1,259 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/dto"
+	"github.com/CarlosMtz98/receipt-processor-challenge/pkg/utils"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/gin-gonic/gin"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+)
+
+const (
+	// ProblemInvalidRequest is the problem type of requests that do not match the specification.
+	ProblemInvalidRequest = "/problems/invalid-request"
+	// ProblemInvalidResponse is the problem type served instead of a response that does not
+	// match the specification.
+	ProblemInvalidResponse = "/problems/invalid-response"
+)
+
+// XML bodies, like the UBL invoices of POST /receipts/process, and emails are parsed by their
+// handler, and the NDJSON exports are written by theirs: the specification only describes them
+// as strings.
+func init() {
+	for _, contentType := range []string{"application/xml", "text/xml", "message/rfc822", "application/x-ndjson"} {
+		openapi3filter.RegisterBodyDecoder(contentType, stringBodyDecoder)
+	}
+}
+
+func stringBodyDecoder(body io.Reader, _ http.Header, _ *openapi3.SchemaRef, _ openapi3filter.EncodingFn) (interface{}, error) {
+	document, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	return string(document), nil
+}
+
+// OpenAPIValidator checks the requests, and optionally the responses, of the operations
+// documented in an OpenAPI specification. Routes that are not documented pass through.
+type OpenAPIValidator struct {
+	router            routers.Router
+	validateResponses bool
+	options           *openapi3filter.Options
+}
+
+// LoadOpenAPISpec reads and validates the specification file.
+func LoadOpenAPISpec(path string) (*openapi3.T, error) {
+	loader := openapi3.NewLoader()
+	spec, err := loader.LoadFromFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the OpenAPI spec %s: %w", path, err)
+	}
+
+	if err := spec.Validate(loader.Context); err != nil {
+		return nil, fmt.Errorf("the OpenAPI spec %s is not valid: %w", path, err)
+	}
+	return spec, nil
+}
+
+// ParseOpenAPISpec reads and validates a specification held in memory, e.g. the one embedded
+// in the binary.
+func ParseOpenAPISpec(data []byte) (*openapi3.T, error) {
+	loader := openapi3.NewLoader()
+	spec, err := loader.LoadFromData(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the OpenAPI spec: %w", err)
+	}
+
+	if err := spec.Validate(loader.Context); err != nil {
+		return nil, fmt.Errorf("the OpenAPI spec is not valid: %w", err)
+	}
+	return spec, nil
+}
+
+// NewOpenAPIValidator validates the responses too when validateResponses is set, which
+// buffers them; it is meant for development and tests.
+func NewOpenAPIValidator(spec *openapi3.T, validateResponses bool) (*OpenAPIValidator, error) {
+	router, err := gorillamux.NewRouter(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OpenAPIValidator{
+		router:            router,
+		validateResponses: validateResponses,
+		options:           &openapi3filter.Options{MultiError: true},
+	}, nil
+}
+
+// Handler must be the last API middleware so that only the route handlers run between the
+// request and the response validation.
+func (v *OpenAPIValidator) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route, pathParams, err := v.router.FindRoute(c.Request)
+		if err != nil {
+			c.Next()
+			return
+		}
+
+		input := &openapi3filter.RequestValidationInput{
+			Request:    c.Request,
+			PathParams: pathParams,
+			Route:      route,
+			Options:    v.options,
+		}
+		if err := openapi3filter.ValidateRequest(c.Request.Context(), input); err != nil {
+			utils.HandleProblem(c, dto.ProblemDetails{
+				Type:       ProblemInvalidRequest,
+				Title:      "The request does not match the API specification",
+				Status:     http.StatusBadRequest,
+				Violations: requestViolations(err),
+			})
+			return
+		}
+
+		if !v.validateResponses {
+			c.Next()
+			return
+		}
+
+		writer := &bufferedResponseWriter{ResponseWriter: c.Writer, status: http.StatusOK}
+		c.Writer = writer
+		// Restored on panics too, so that the recovery middleware can answer.
+		defer func() { c.Writer = writer.ResponseWriter }()
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		if err := v.validateResponse(c.Request.Context(), input, writer); err != nil {
+			log.Printf("Response of %s %s does not match the API specification: %v", c.Request.Method, c.Request.URL.Path, err)
+			writer.Header().Del("Content-Type")
+			utils.HandleProblem(c, dto.ProblemDetails{
+				Type:       ProblemInvalidResponse,
+				Title:      "The response does not match the API specification",
+				Status:     http.StatusInternalServerError,
+				Violations: schemaViolations("response", err),
+			})
+			return
+		}
+
+		c.Writer.WriteHeader(writer.status)
+		_, _ = c.Writer.Write(writer.body.Bytes())
+	}
+}
+
+func (v *OpenAPIValidator) validateResponse(ctx context.Context, input *openapi3filter.RequestValidationInput, writer *bufferedResponseWriter) error {
+	err := openapi3filter.ValidateResponse(ctx, &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: input,
+		Status:                 writer.status,
+		Header:                 writer.Header(),
+		Body:                   io.NopCloser(bytes.NewReader(writer.body.Bytes())),
+		Options:                v.options,
+	})
+
+	var responseErr *openapi3filter.ResponseError
+	if errors.As(err, &responseErr) && responseErr.Err != nil {
+		return responseErr.Err
+	}
+	return err
+}
+
+// requestViolations flattens the errors of a request validation into one entry per mismatch.
+func requestViolations(err error) []dto.Violation {
+	switch e := err.(type) {
+	case openapi3.MultiError:
+		var violations []dto.Violation
+		for _, inner := range e {
+			violations = append(violations, requestViolations(inner)...)
+		}
+		return violations
+	case *openapi3filter.RequestError:
+		in := "body"
+		if e.Parameter != nil {
+			in = e.Parameter.In
+		}
+		if e.Err == nil {
+			return []dto.Violation{{In: in, Pointer: parameterPointer(e), Message: e.Reason}}
+		}
+
+		violations := schemaViolations(in, e.Err)
+		for i := range violations {
+			if violations[i].Pointer == "" {
+				violations[i].Pointer = parameterPointer(e)
+			}
+		}
+		return violations
+	default:
+		return []dto.Violation{{In: "request", Message: err.Error()}}
+	}
+}
+
+func schemaViolations(in string, err error) []dto.Violation {
+	switch e := err.(type) {
+	case openapi3.MultiError:
+		var violations []dto.Violation
+		for _, inner := range e {
+			violations = append(violations, schemaViolations(in, inner)...)
+		}
+		return violations
+	case *openapi3.SchemaError:
+		violation := dto.Violation{In: in, Message: e.Reason}
+		if pointer := e.JSONPointer(); len(pointer) > 0 {
+			violation.Pointer = "/" + strings.Join(pointer, "/")
+		}
+		return []dto.Violation{violation}
+	default:
+		return []dto.Violation{{In: in, Message: err.Error()}}
+	}
+}
+
+func parameterPointer(err *openapi3filter.RequestError) string {
+	if err.Parameter == nil {
+		return ""
+	}
+	return "/" + err.Parameter.Name
+}
+
+// bufferedResponseWriter holds the response back until it has been validated.
+type bufferedResponseWriter struct {
+	gin.ResponseWriter
+	body   bytes.Buffer
+	status int
+}
+
+func (w *bufferedResponseWriter) WriteHeader(code int) {
+	w.status = code
+}
+
+func (w *bufferedResponseWriter) WriteHeaderNow() {}
+
+func (w *bufferedResponseWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+func (w *bufferedResponseWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+func (w *bufferedResponseWriter) Status() int {
+	return w.status
+}
+
+func (w *bufferedResponseWriter) Size() int {
+	return w.body.Len()
+}
+
+func (w *bufferedResponseWriter) Written() bool {
+	return w.body.Len() > 0
+}
+
+// Flush is a no-op: streamed responses, like the exports, are sent once they are validated.
+func (w *bufferedResponseWriter) Flush() {}
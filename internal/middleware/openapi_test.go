@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"encoding/json"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/dto"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const testSpec = `
+openapi: 3.0.3
+info:
+  title: Test
+  version: 1.0.0
+paths:
+  /items/{id}:
+    get:
+      parameters:
+        - name: id
+          in: path
+          required: true
+          schema:
+            type: integer
+      responses:
+        200:
+          description: The item
+          content:
+            application/json:
+              schema:
+                type: object
+                required: [name]
+                properties:
+                  name:
+                    type: string
+`
+
+func newValidatedRouter(t *testing.T, validateResponses bool, item gin.H) *gin.Engine {
+	spec, err := openapi3.NewLoader().LoadFromData([]byte(testSpec))
+	require.NoError(t, err)
+	validator, err := NewOpenAPIValidator(spec, validateResponses)
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(validator.Handler())
+	router.GET("/items/:id", func(c *gin.Context) {
+		c.JSON(http.StatusOK, item)
+	})
+	router.GET("/undocumented", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"anything": true})
+	})
+	return router
+}
+
+func serve(router *gin.Engine, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	return w
+}
+
+func TestOpenAPIValidator(t *testing.T) {
+	t.Run("Valid request and response", func(t *testing.T) {
+		w := serve(newValidatedRouter(t, true, gin.H{"name": "pepsi"}), "/items/1")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"name":"pepsi"}`, w.Body.String())
+	})
+
+	t.Run("Invalid path parameter", func(t *testing.T) {
+		w := serve(newValidatedRouter(t, true, gin.H{"name": "pepsi"}), "/items/abc")
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		var problem dto.ProblemDetails
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+		assert.Equal(t, ProblemInvalidRequest, problem.Type)
+		require.Len(t, problem.Violations, 1)
+		assert.Equal(t, "path", problem.Violations[0].In)
+		assert.Equal(t, "/id", problem.Violations[0].Pointer)
+	})
+
+	t.Run("Invalid response", func(t *testing.T) {
+		w := serve(newValidatedRouter(t, true, gin.H{"title": "pepsi"}), "/items/1")
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		var problem dto.ProblemDetails
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+		assert.Equal(t, ProblemInvalidResponse, problem.Type)
+		assert.Equal(t, "response", problem.Violations[0].In)
+	})
+
+	t.Run("Responses are not validated unless enabled", func(t *testing.T) {
+		w := serve(newValidatedRouter(t, false, gin.H{"title": "pepsi"}), "/items/1")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("Undocumented routes pass through", func(t *testing.T) {
+		w := serve(newValidatedRouter(t, true, nil), "/undocumented")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+}
@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"expvar"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/auth"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/ratelimit"
+	"github.com/CarlosMtz98/receipt-processor-challenge/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"math"
+	"strconv"
+	"time"
+)
+
+// APIKeyHeader identifies the calling integration when no token subject is available.
+const APIKeyHeader = "X-API-Key"
+
+// rejectedRequests counts requests rejected by the rate limiter, keyed by reason and route.
+var rejectedRequests = expvar.NewMap("ratelimit_rejected_requests")
+
+// RateLimiter applies per-client token bucket limits and daily quotas.
+type RateLimiter struct {
+	config  ratelimit.Config
+	limiter *ratelimit.Limiter
+	quota   *ratelimit.DailyQuota
+}
+
+func NewRateLimiter(config ratelimit.Config) *RateLimiter {
+	return &RateLimiter{
+		config:  config,
+		limiter: ratelimit.NewLimiter(),
+		quota:   ratelimit.NewDailyQuota(config.DailyQuota),
+	}
+}
+
+// Handler must run after authentication and APIKeys so that clients can be keyed by token
+// subject or API key.
+func (r *RateLimiter) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.Request.Method + " " + c.FullPath()
+		client := ClientKey(c)
+
+		if limit, ok := r.config.LimitFor(route); ok {
+			decision := r.limiter.Allow(client+"|"+route, limit)
+			c.Header("RateLimit-Limit", strconv.Itoa(decision.Limit))
+			c.Header("RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+			c.Header("RateLimit-Reset", formatSeconds(decision.Reset))
+
+			if !decision.Allowed {
+				rejectedRequests.Add("rate_limit "+route, 1)
+				c.Header("Retry-After", formatSeconds(decision.RetryAfter))
+				utils.HandleTooManyRequests(c, "Too many requests, slow down")
+				return
+			}
+		}
+
+		if r.config.HasQuota(route) {
+			decision := r.ConsumeQuota(client, route)
+			if !decision.Allowed {
+				c.Header("Retry-After", formatSeconds(decision.Reset))
+				utils.HandleTooManyRequests(c, "The daily quota of receipt submissions has been reached")
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// ConsumeQuota counts one submission of the client against its daily quota, for the operations
+// that submit receipts without a route of their own, like GraphQL mutations. Rejections are
+// counted under the given route. Every submission is allowed when the quota is disabled.
+func (r *RateLimiter) ConsumeQuota(client, route string) ratelimit.QuotaDecision {
+	if r.config.DailyQuota <= 0 {
+		return ratelimit.QuotaDecision{Allowed: true}
+	}
+
+	decision := r.quota.Consume(client)
+	if !decision.Allowed {
+		rejectedRequests.Add("daily_quota "+route, 1)
+	}
+	return decision
+}
+
+// ClientKey identifies the caller by token subject, then by the name of its API key when
+// APIKeys verified it, then by client IP.
+func ClientKey(c *gin.Context) string {
+	if principal, ok := auth.PrincipalFromContext(c.Request.Context()); ok && principal.Subject != "" {
+		return "sub:" + principal.Subject
+	}
+	if name := c.GetString(APIKeyContextKey); name != "" {
+		return "key:" + name
+	}
+	return "ip:" + c.ClientIP()
+}
+
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
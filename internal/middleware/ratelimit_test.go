@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/ratelimit"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRateLimiter_Handler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rateLimiter := NewRateLimiter(ratelimit.Config{
+		Default: ratelimit.Limit{Rate: 0.001, Burst: 5},
+		Routes: map[string]ratelimit.Limit{
+			"POST /receipts/process": {Rate: 0.001, Burst: 2},
+		},
+		DailyQuota:  3,
+		QuotaRoutes: []string{"GET /receipts/:id/points"},
+	})
+
+	apiKeys := APIKeys{"key-a": "integration-a", "key-b": "integration-b", "key-c": "integration-c"}
+	router := gin.New()
+	group := router.Group("/receipts", apiKeys.Handler(), rateLimiter.Handler())
+	group.POST("/process", func(c *gin.Context) { c.Status(http.StatusOK) })
+	group.GET("/:id/points", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	send := func(method, path, apiKey string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if apiKey != "" {
+			req.Header.Set(APIKeyHeader, apiKey)
+		}
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	t.Run("Route limit", func(t *testing.T) {
+		resp := send("POST", "/receipts/process", "key-a")
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.Equal(t, "2", resp.Header().Get("RateLimit-Limit"))
+		assert.Equal(t, "1", resp.Header().Get("RateLimit-Remaining"))
+
+		assert.Equal(t, http.StatusOK, send("POST", "/receipts/process", "key-a").Code)
+
+		resp = send("POST", "/receipts/process", "key-a")
+		assert.Equal(t, http.StatusTooManyRequests, resp.Code)
+		assert.NotEmpty(t, resp.Header().Get("Retry-After"))
+		assert.Equal(t, "0", resp.Header().Get("RateLimit-Remaining"))
+
+		// A different API key is limited independently.
+		assert.Equal(t, http.StatusOK, send("POST", "/receipts/process", "key-b").Code)
+	})
+
+	t.Run("Unknown API keys", func(t *testing.T) {
+		// Keys that were not issued are ignored: rotating them doesn't refill the bucket of
+		// the client IP.
+		assert.Equal(t, http.StatusOK, send("POST", "/receipts/process", "random-1").Code)
+		assert.Equal(t, http.StatusOK, send("POST", "/receipts/process", "random-2").Code)
+		assert.Equal(t, http.StatusTooManyRequests, send("POST", "/receipts/process", "random-3").Code)
+	})
+
+	t.Run("Daily quota", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			resp := send("GET", "/receipts/abc/points", "key-c")
+			assert.Equal(t, http.StatusOK, resp.Code)
+			assert.Equal(t, "5", resp.Header().Get("RateLimit-Limit"))
+		}
+
+		resp := send("GET", "/receipts/abc/points", "key-c")
+		assert.Equal(t, http.StatusTooManyRequests, resp.Code)
+		assert.NotEmpty(t, resp.Header().Get("Retry-After"))
+		assert.Equal(t, "1", rejectedRequests.Get("daily_quota GET /receipts/:id/points").String())
+	})
+}
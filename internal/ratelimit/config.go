@@ -0,0 +1,33 @@
+package ratelimit
+
+// Config describes the limits applied by the rate limiting middleware.
+type Config struct {
+	// Default applies to routes without an entry in Routes. A zero rate disables it.
+	Default Limit
+	// Routes maps "METHOD /route/:pattern" to its own limit.
+	Routes map[string]Limit
+	// DailyQuota caps the requests a client can make per day to QuotaRoutes. Zero disables it.
+	DailyQuota  int
+	QuotaRoutes []string
+}
+
+// LimitFor returns the limit configured for the route and whether one applies.
+func (c Config) LimitFor(route string) (Limit, bool) {
+	if limit, ok := c.Routes[route]; ok {
+		return limit, limit.Rate > 0
+	}
+	return c.Default, c.Default.Rate > 0
+}
+
+// HasQuota reports whether the daily quota applies to the route.
+func (c Config) HasQuota(route string) bool {
+	if c.DailyQuota <= 0 {
+		return false
+	}
+	for _, r := range c.QuotaRoutes {
+		if r == route {
+			return true
+		}
+	}
+	return false
+}
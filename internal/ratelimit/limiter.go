@@ -0,0 +1,99 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// idleBucketTTL is how long an untouched bucket is kept before it is evicted.
+const idleBucketTTL = 10 * time.Minute
+
+// Limit is a token bucket refilled at Rate tokens per second holding at most Burst tokens.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Decision is the outcome of a rate limit check.
+type Decision struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limiter keeps one token bucket per key.
+type Limiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	now       func() time.Time
+	lastSweep time.Time
+}
+
+func NewLimiter() *Limiter {
+	return &Limiter{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// Allow takes one token from the bucket identified by key.
+func (l *Limiter) Allow(key string, limit Limit) Decision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), last: now}
+		l.buckets[key] = b
+	}
+
+	elapsed := now.Sub(b.last).Seconds()
+	b.tokens = math.Min(float64(limit.Burst), b.tokens+elapsed*limit.Rate)
+	b.last = now
+
+	decision := Decision{Limit: limit.Burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		decision.Allowed = true
+	} else {
+		decision.RetryAfter = durationForTokens(1-b.tokens, limit.Rate)
+	}
+
+	decision.Remaining = int(math.Floor(b.tokens))
+	decision.Reset = durationForTokens(float64(limit.Burst)-b.tokens, limit.Rate)
+	return decision
+}
+
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < idleBucketTTL {
+		return
+	}
+	l.lastSweep = now
+
+	for key, b := range l.buckets {
+		if now.Sub(b.last) > idleBucketTTL {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+func durationForTokens(tokens, rate float64) time.Duration {
+	if tokens <= 0 {
+		return 0
+	}
+	if rate <= 0 {
+		return math.MaxInt64
+	}
+	return time.Duration(tokens / rate * float64(time.Second))
+}
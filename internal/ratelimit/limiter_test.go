@@ -0,0 +1,64 @@
+package ratelimit
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestLimiter_Allow(t *testing.T) {
+	limiter := NewLimiter()
+	clock := time.Date(2023, time.October, 8, 12, 0, 0, 0, time.UTC)
+	limiter.now = func() time.Time { return clock }
+	limit := Limit{Rate: 1, Burst: 2}
+
+	first := limiter.Allow("client", limit)
+	assert.True(t, first.Allowed)
+	assert.Equal(t, 2, first.Limit)
+	assert.Equal(t, 1, first.Remaining)
+
+	second := limiter.Allow("client", limit)
+	assert.True(t, second.Allowed)
+	assert.Equal(t, 0, second.Remaining)
+	assert.Equal(t, 2*time.Second, second.Reset)
+
+	rejected := limiter.Allow("client", limit)
+	assert.False(t, rejected.Allowed)
+	assert.Equal(t, time.Second, rejected.RetryAfter)
+
+	// Other clients have their own bucket.
+	assert.True(t, limiter.Allow("other", limit).Allowed)
+
+	clock = clock.Add(time.Second)
+	assert.True(t, limiter.Allow("client", limit).Allowed)
+}
+
+func TestLimiter_EvictsIdleBuckets(t *testing.T) {
+	limiter := NewLimiter()
+	clock := time.Now()
+	limiter.now = func() time.Time { return clock }
+
+	limiter.Allow("client", Limit{Rate: 1, Burst: 1})
+	clock = clock.Add(2 * idleBucketTTL)
+	limiter.Allow("other", Limit{Rate: 1, Burst: 1})
+
+	assert.Len(t, limiter.buckets, 1)
+}
+
+func TestDailyQuota_Consume(t *testing.T) {
+	quota := NewDailyQuota(2)
+	clock := time.Date(2023, time.October, 8, 22, 0, 0, 0, time.UTC)
+	quota.now = func() time.Time { return clock }
+
+	assert.True(t, quota.Consume("client").Allowed)
+	decision := quota.Consume("client")
+	assert.True(t, decision.Allowed)
+	assert.Equal(t, 0, decision.Remaining)
+
+	decision = quota.Consume("client")
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, 2*time.Hour, decision.Reset)
+
+	clock = clock.Add(2 * time.Hour)
+	assert.True(t, quota.Consume("client").Allowed)
+}
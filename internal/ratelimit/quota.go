@@ -0,0 +1,60 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// QuotaDecision is the outcome of a daily quota check.
+type QuotaDecision struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	Reset     time.Duration
+}
+
+// DailyQuota counts requests per key and resets the counters at midnight UTC.
+type DailyQuota struct {
+	limit int
+	now   func() time.Time
+
+	mu     sync.Mutex
+	day    time.Time
+	counts map[string]int
+}
+
+func NewDailyQuota(limit int) *DailyQuota {
+	return &DailyQuota{
+		limit:  limit,
+		now:    time.Now,
+		counts: make(map[string]int),
+	}
+}
+
+// Consume records one request for key unless its quota for the day is used up.
+func (q *DailyQuota) Consume(key string) QuotaDecision {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if !today.Equal(q.day) {
+		q.day = today
+		q.counts = make(map[string]int)
+	}
+
+	decision := QuotaDecision{
+		Limit: q.limit,
+		Reset: today.AddDate(0, 0, 1).Sub(now),
+	}
+
+	used := q.counts[key]
+	if used < q.limit {
+		used++
+		q.counts[key] = used
+		decision.Allowed = true
+	}
+
+	decision.Remaining = q.limit - used
+	return decision
+}
@@ -0,0 +1,94 @@
+package receiptctl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/service"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/dto"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/receiptio"
+	"io"
+	"os"
+)
+
+// invoiceAuto detects the format of every invoice.
+const invoiceAuto = "auto"
+
+// runConvert converts XML invoices to receipts, one JSON receipt per line, which score and import
+// read as they are. Submitting the XML to the server instead keeps the duplicate invoice checks.
+func runConvert(args []string, streams IO) int {
+	flags := newFlagSet("convert", streams)
+	from := flags.String("from", invoiceAuto, "format of the invoices: auto, ubl or cfdi")
+	output := flags.String("o", "-", "file the receipts are written to; - is stdout")
+	flags.Usage = func() {
+		fmt.Fprintln(streams.Stderr, "Usage: receiptctl convert [-from auto|ubl|cfdi] [-o file] [file ...]")
+		fmt.Fprintln(streams.Stderr, "Reads stdin when no file is given or the file is -.")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return ExitUsage
+	}
+	if *from != invoiceAuto && *from != string(models.InvoiceFormatUBL) && *from != string(models.InvoiceFormatCFDI) {
+		fmt.Fprintf(streams.Stderr, "receiptctl convert: unknown invoice format %q\n", *from)
+		return ExitUsage
+	}
+
+	sources := flags.Args()
+	if len(sources) == 0 {
+		sources = []string{"-"}
+	}
+
+	out := streams.Stdout
+	if *output != "-" {
+		file, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintf(streams.Stderr, "receiptctl convert: %v\n", err)
+			return ExitFailure
+		}
+		defer file.Close()
+		out = file
+	}
+
+	code := ExitOK
+	encoder := json.NewEncoder(out)
+	for _, source := range sources {
+		receipt, err := convertInvoice(source, streams.Stdin, *from)
+		if err == nil {
+			err = service.ValidateReceipt(context.Background(), receipt, 0)
+		}
+		if err != nil {
+			fmt.Fprintf(streams.Stderr, "receiptctl convert: %s: %v\n", source, err)
+			code = ExitFailure
+			continue
+		}
+
+		if err := encoder.Encode(dto.NewReceiptRequest(receipt)); err != nil {
+			fmt.Fprintf(streams.Stderr, "receiptctl convert: %v\n", err)
+			return ExitFailure
+		}
+	}
+	return code
+}
+
+func convertInvoice(source string, stdin io.Reader, from string) (*models.Receipt, error) {
+	reader := stdin
+	if source != "-" {
+		file, err := os.Open(source)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		reader = file
+	}
+	document, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	format := models.InvoiceFormat(from)
+	if from == invoiceAuto {
+		format = ""
+	}
+	return receiptio.ParseInvoice(document, format)
+}
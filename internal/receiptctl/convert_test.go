@@ -0,0 +1,64 @@
+package receiptctl
+
+import (
+	"encoding/json"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/dto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConvert(t *testing.T) {
+	t.Run("UBL and CFDI invoices", func(t *testing.T) {
+		code, stdout, stderr := runCommand(t, "", "convert", "../../examples/ubl-invoice.xml", "../../examples/cfdi-invoice.xml")
+
+		require.Equal(t, ExitOK, code, stderr)
+		lines := strings.Split(strings.TrimSpace(stdout), "\n")
+		require.Len(t, lines, 2)
+		var receipt dto.ReceiptRequest
+		require.NoError(t, json.Unmarshal([]byte(lines[0]), &receipt))
+		assert.Equal(t, "De Hoek Supermarkt", receipt.Retailer)
+		assert.Equal(t, "23.81", receipt.Total)
+		assert.NotContains(t, lines[0], `"ID"`)
+		assert.Contains(t, lines[1], `"retailer":"TIENDA LA ESPERANZA"`)
+
+		code, stdout, _ = runCommand(t, stdout, "score", "-format", "json")
+
+		assert.Equal(t, ExitOK, code)
+		var results []scoreResult
+		require.NoError(t, json.Unmarshal([]byte(stdout), &results))
+		require.Len(t, results, 2)
+		assert.Equal(t, 32, *results[1].Points)
+	})
+
+	t.Run("Unsupported constructs are reported per file", func(t *testing.T) {
+		example, err := os.ReadFile("../../examples/ubl-invoice.xml")
+		require.NoError(t, err)
+		creditNote := filepath.Join(t.TempDir(), "credit-note.xml")
+		document := strings.NewReplacer("<Invoice ", "<CreditNote ", "</Invoice>", "</CreditNote>", "xsd:Invoice-2", "xsd:CreditNote-2").Replace(string(example))
+		require.NoError(t, os.WriteFile(creditNote, []byte(document), 0o600))
+
+		code, stdout, stderr := runCommand(t, "", "convert", "-o", filepath.Join(t.TempDir(), "receipts.ndjson"), creditNote, "../../examples/ubl-invoice.xml")
+
+		assert.Equal(t, ExitFailure, code)
+		assert.Empty(t, stdout)
+		assert.Contains(t, stderr, "credit-note.xml: the document is not a valid UBL 2.1 invoice: not supported: CreditNote documents")
+	})
+
+	t.Run("Unknown documents", func(t *testing.T) {
+		code, _, stderr := runCommand(t, `<Order xmlns="urn:example:orders"/>`, "convert")
+
+		assert.Equal(t, ExitFailure, code)
+		assert.Contains(t, stderr, "unknown invoice format")
+	})
+
+	t.Run("Forced format", func(t *testing.T) {
+		code, _, stderr := runCommand(t, "", "convert", "-from", "cfdi", "../../examples/ubl-invoice.xml")
+
+		assert.Equal(t, ExitFailure, code)
+		assert.Contains(t, stderr, "not a valid CFDI")
+	})
+}
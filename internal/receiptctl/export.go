@@ -0,0 +1,91 @@
+package receiptctl
+
+import (
+	"fmt"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/receiptio"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+)
+
+// runExport downloads the receipts of a server through GET /receipts/export.
+func runExport(args []string, streams IO) int {
+	flags := newFlagSet("export", streams)
+	target := flags.String("target", "", "base URL of the server (required)")
+	format := flags.String("format", string(receiptio.FormatNDJSON), "format of the export: ndjson or csv")
+	layout := flags.String("layout", string(receiptio.LayoutReceipt), "CSV rows: receipt or item")
+	retailer := flags.String("retailer", "", "only export the receipts of this retailer")
+	status := flags.String("status", "", "only export the receipts with this status: processed, held or rejected")
+	output := flags.String("o", "-", "file the export is written to; - is stdout")
+	headers := headerFlags{}
+	flags.Var(headers, "header", "header sent with the request, as Name: value; can be repeated")
+	flags.Usage = func() {
+		fmt.Fprintln(streams.Stderr, "Usage: receiptctl export -target URL [-format ndjson|csv] [-layout receipt|item] [-retailer R] [-status S] [-header H] [-o file]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return ExitUsage
+	}
+	if *target == "" || flags.NArg() > 0 {
+		flags.Usage()
+		return ExitUsage
+	}
+	if _, err := receiptio.ParseFormat(*format); err != nil {
+		fmt.Fprintf(streams.Stderr, "receiptctl export: %v\n", err)
+		return ExitUsage
+	}
+	if _, err := receiptio.ParseLayout(*layout); err != nil {
+		fmt.Fprintf(streams.Stderr, "receiptctl export: %v\n", err)
+		return ExitUsage
+	}
+
+	// Exports are streamed, so they are not bound by a timeout.
+	client, baseURL, err := newTarget(*target, 0)
+	if err != nil {
+		fmt.Fprintf(streams.Stderr, "receiptctl export: %v\n", err)
+		return ExitUsage
+	}
+
+	query := url.Values{"format": {*format}, "layout": {*layout}}
+	if *retailer != "" {
+		query.Set("retailer", *retailer)
+	}
+	if *status != "" {
+		query.Set("status", *status)
+	}
+	req, err := http.NewRequest(http.MethodGet, baseURL+"/receipts/export?"+query.Encode(), nil)
+	if err != nil {
+		fmt.Fprintf(streams.Stderr, "receiptctl export: %v\n", err)
+		return ExitUsage
+	}
+	headers.apply(req)
+
+	res, err := client.Do(req)
+	if err != nil {
+		fmt.Fprintf(streams.Stderr, "receiptctl export: %v\n", err)
+		return ExitFailure
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(res.Body)
+		fmt.Fprintf(streams.Stderr, "receiptctl export: %v\n", responseError(res.StatusCode, body))
+		return ExitFailure
+	}
+
+	out := streams.Stdout
+	if *output != "-" {
+		file, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintf(streams.Stderr, "receiptctl export: %v\n", err)
+			return ExitFailure
+		}
+		defer file.Close()
+		out = file
+	}
+	if _, err := io.Copy(out, res.Body); err != nil {
+		fmt.Fprintf(streams.Stderr, "receiptctl export: %v\n", err)
+		return ExitFailure
+	}
+	return ExitOK
+}
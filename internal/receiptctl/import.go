@@ -0,0 +1,220 @@
+package receiptctl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/service"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/dto"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/receiptio"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+type importResult struct {
+	Line     int    `json:"line"`
+	Retailer string `json:"retailer,omitempty"`
+	Total    string `json:"total,omitempty"`
+	ID       string `json:"id,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+type importReport struct {
+	Imported int            `json:"imported"`
+	Failed   int            `json:"failed"`
+	Results  []importResult `json:"results"`
+}
+
+// runImport submits the receipts of a CSV or NDJSON file to POST /receipts/process and reports
+// the receipts that could not be imported with the line they start on.
+func runImport(args []string, streams IO) int {
+	flags := newFlagSet("import", streams)
+	target := flags.String("target", "", "base URL of the server; an in-process server is used when empty, which only checks the file")
+	input := flags.String("input", "", "format of the file: csv or ndjson; guessed from the file extension when empty")
+	mappingFile := flags.String("mapping", "", "JSON file mapping the CSV columns to the receipt fields")
+	timeout := flags.Duration("timeout", 10*time.Second, "timeout of every request")
+	format := flags.String("format", formatTable, "output format: table or json")
+	headers := headerFlags{}
+	flags.Var(headers, "header", "header sent with every request, as Name: value; can be repeated")
+	flags.Usage = func() {
+		fmt.Fprintln(streams.Stderr, "Usage: receiptctl import [-target URL] [-input csv|ndjson] [-mapping file] [-header H] [-format table|json] [file]")
+		fmt.Fprintln(streams.Stderr, "Reads NDJSON from stdin when no file is given or the file is -.")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return ExitUsage
+	}
+	if *format != formatTable && *format != formatJSON {
+		fmt.Fprintf(streams.Stderr, "receiptctl import: unknown format %q\n", *format)
+		return ExitUsage
+	}
+	if flags.NArg() > 1 {
+		flags.Usage()
+		return ExitUsage
+	}
+
+	source := "-"
+	if flags.NArg() == 1 {
+		source = flags.Arg(0)
+	}
+	fileFormat, err := importFormat(*input, source)
+	if err != nil {
+		fmt.Fprintf(streams.Stderr, "receiptctl import: %v\n", err)
+		return ExitUsage
+	}
+	var mapping receiptio.Mapping
+	if *mappingFile != "" {
+		if mapping, err = receiptio.LoadMapping(*mappingFile); err != nil {
+			fmt.Fprintf(streams.Stderr, "receiptctl import: %v\n", err)
+			return ExitUsage
+		}
+	}
+
+	client, baseURL, err := newTarget(*target, *timeout)
+	if err != nil {
+		fmt.Fprintf(streams.Stderr, "receiptctl import: %v\n", err)
+		return ExitUsage
+	}
+
+	reader := streams.Stdin
+	if source != "-" {
+		file, err := os.Open(source)
+		if err != nil {
+			fmt.Fprintf(streams.Stderr, "receiptctl import: %v\n", err)
+			return ExitFailure
+		}
+		defer file.Close()
+		reader = file
+	}
+	receipts, err := receiptio.NewReader(reader, fileFormat, mapping)
+	if err != nil {
+		fmt.Fprintf(streams.Stderr, "receiptctl import: %v\n", err)
+		return ExitFailure
+	}
+
+	report := importReport{Results: make([]importResult, 0)}
+	for {
+		entry, err := receipts.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			fmt.Fprintf(streams.Stderr, "receiptctl import: %v\n", err)
+			return ExitFailure
+		}
+
+		result := importReceipt(client, baseURL, headers, entry)
+		if result.Error != "" {
+			report.Failed++
+		} else {
+			report.Imported++
+		}
+		report.Results = append(report.Results, result)
+	}
+
+	if *format == formatJSON {
+		encoder := json.NewEncoder(streams.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			fmt.Fprintf(streams.Stderr, "receiptctl import: %v\n", err)
+			return ExitFailure
+		}
+	} else {
+		writeImportReport(streams.Stdout, report)
+	}
+
+	if report.Failed > 0 {
+		return ExitFailure
+	}
+	return ExitOK
+}
+
+func importFormat(input, source string) (receiptio.Format, error) {
+	if input != "" {
+		return receiptio.ParseFormat(input)
+	}
+	if strings.EqualFold(filepath.Ext(source), ".csv") {
+		return receiptio.FormatCSV, nil
+	}
+	return receiptio.FormatNDJSON, nil
+}
+
+// importReceipt checks the receipt before sending it, which gives the same errors as the API
+// without a round trip.
+func importReceipt(client *http.Client, baseURL string, headers headerFlags, entry receiptio.Entry) importResult {
+	result := importResult{Line: entry.Line}
+	if entry.Err != nil {
+		result.Error = entry.Err.Error()
+		return result
+	}
+
+	receipt := entry.Receipt
+	result.Retailer, result.Total = receipt.Retailer, receipt.Total
+	if err := service.ValidateReceipt(context.Background(), receipt, 0); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	id, err := submit(client, baseURL, headers, receipt)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.ID = id
+	return result
+}
+
+func submit(client *http.Client, baseURL string, headers headerFlags, receipt *models.Receipt) (string, error) {
+	body, err := json.Marshal(receipt)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest(http.MethodPost, baseURL+"/receipts/process", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	headers.apply(req)
+
+	res, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	responseBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return "", err
+	}
+	if res.StatusCode != http.StatusOK {
+		return "", responseError(res.StatusCode, responseBody)
+	}
+
+	var created dto.CreateReceiptResponse
+	if err := json.Unmarshal(responseBody, &created); err != nil {
+		return "", fmt.Errorf("could not parse the response: %w", err)
+	}
+	return created.ID, nil
+}
+
+// writeImportReport only lists the receipts that failed, as imports can hold many receipts.
+func writeImportReport(w io.Writer, report importReport) {
+	if report.Failed > 0 {
+		table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(table, "LINE\tRETAILER\tTOTAL\tERROR")
+		for _, result := range report.Results {
+			if result.Error != "" {
+				message := strings.ReplaceAll(result.Error, "\n", "; ")
+				fmt.Fprintf(table, "%d\t%s\t%s\t%s\n", result.Line, result.Retailer, result.Total, message)
+			}
+		}
+		table.Flush()
+	}
+	fmt.Fprintf(w, "Imported %d receipts, %d failed\n", report.Imported, report.Failed)
+}
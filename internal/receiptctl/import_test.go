@@ -0,0 +1,88 @@
+package receiptctl
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const historicalReceipts = `Ticket,Store,Date,Time,Amount,Item,Price
+1,Target,01/02/2022,1:13 PM,2.50,Pepsi - 12-oz,1.25
+1,Target,01/02/2022,1:13 PM,2.50,Dasani,1.25
+2,Walgreens,01/03/2022,8:13 AM,9.99,Pepsi - 12-oz,1.25
+3,Walgreens,01/03/2022,8:13 AM,1.25,Pepsi - 12-oz,1.25
+`
+
+const historicalMapping = `{
+	"columns": {"receipt": "Ticket", "retailer": "Store", "purchaseDate": "Date", "purchaseTime": "Time", "total": "Amount", "shortDescription": "Item", "price": "Price"},
+	"dateFormat": "01/02/2006",
+	"timeFormat": "3:04 PM"
+}`
+
+func TestImportExport(t *testing.T) {
+	router, err := newInProcessRouter()
+	require.NoError(t, err)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	dir := t.TempDir()
+	csvPath := filepath.Join(dir, "receipts.csv")
+	mappingPath := filepath.Join(dir, "mapping.json")
+	require.NoError(t, os.WriteFile(csvPath, []byte(historicalReceipts), 0o600))
+	require.NoError(t, os.WriteFile(mappingPath, []byte(historicalMapping), 0o600))
+
+	t.Run("Import a mapped CSV file", func(t *testing.T) {
+		code, stdout, stderr := runCommand(t, "", "import", "-target", server.URL, "-mapping", mappingPath, "-header", "X-Request-Source: import", "-format", "json", csvPath)
+
+		assert.Equal(t, ExitFailure, code, stderr)
+		var report importReport
+		require.NoError(t, json.Unmarshal([]byte(stdout), &report))
+		assert.Equal(t, 2, report.Imported)
+		assert.Equal(t, 1, report.Failed)
+		require.Len(t, report.Results, 3)
+		assert.NotEmpty(t, report.Results[0].ID)
+		assert.Equal(t, 4, report.Results[1].Line)
+		assert.Contains(t, report.Results[1].Error, "total")
+	})
+
+	t.Run("Export as CSV", func(t *testing.T) {
+		output := filepath.Join(dir, "export.csv")
+		code, _, stderr := runCommand(t, "", "export", "-target", server.URL, "-format", "csv", "-layout", "item", "-retailer", "Target", "-o", output)
+
+		assert.Equal(t, ExitOK, code, stderr)
+		exported, err := os.ReadFile(output)
+		require.NoError(t, err)
+		lines := strings.Split(strings.TrimSpace(string(exported)), "\n")
+		require.Len(t, lines, 3)
+		assert.Contains(t, lines[1], "Target,2022-01-02,13:13,2.50,processed")
+	})
+
+	t.Run("An NDJSON export can be imported again", func(t *testing.T) {
+		code, exported, stderr := runCommand(t, "", "export", "-target", server.URL)
+		require.Equal(t, ExitOK, code, stderr)
+
+		code, stdout, _ := runCommand(t, exported, "import")
+
+		assert.Equal(t, ExitOK, code)
+		assert.Equal(t, "Imported 2 receipts, 0 failed\n", stdout)
+	})
+
+	t.Run("Errors of the server are reported", func(t *testing.T) {
+		code, _, stderr := runCommand(t, "", "export", "-target", server.URL, "-status", "pending")
+
+		assert.Equal(t, ExitFailure, code)
+		// The router validates the requests against the OpenAPI spec, like the server.
+		assert.Contains(t, stderr, "400 The request does not match the API specification: query /status: value is not one of the allowed values")
+	})
+
+	t.Run("Export requires a target", func(t *testing.T) {
+		code, _, _ := runCommand(t, "", "export")
+
+		assert.Equal(t, ExitUsage, code)
+	})
+}
@@ -0,0 +1,331 @@
+package receiptctl
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
+	"io"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const defaultRetailers = "Target,Walgreens,M&M Corner Market,Costco,Whole Foods"
+
+var itemDescriptions = []string{
+	"Mountain Dew 12PK", "Emils Cheese Pizza", "Knorr Creamy Chicken", "Doritos Nacho Cheese",
+	"Klarbrunn 12-PK 12 FL OZ", "Pepsi - 12-oz", "Dasani", "Gatorade", "Paper Towels", "Bananas",
+}
+
+// invalidations make a valid receipt invalid, each in a way POST /receipts/process rejects.
+var invalidations = []struct {
+	name  string
+	apply func(receipt *models.Receipt)
+}{
+	{"total-mismatch", func(receipt *models.Receipt) { receipt.Total = "0.01" }},
+	{"bad-date", func(receipt *models.Receipt) { receipt.PurchaseDate = "2022-13-40" }},
+	{"bad-time", func(receipt *models.Receipt) { receipt.PurchaseTime = "25:61" }},
+	{"no-items", func(receipt *models.Receipt) { receipt.Items = nil }},
+	{"bad-price", func(receipt *models.Receipt) { receipt.Items[0].Price = "1.2.3" }},
+	{"no-retailer", func(receipt *models.Receipt) { receipt.Retailer = "" }},
+}
+
+type loadtestConfig struct {
+	duration    time.Duration
+	rate        float64
+	concurrency int
+	invalid     float64
+	minItems    int
+	maxItems    int
+	retailers   []string
+	seed        int64
+}
+
+type loadtestReport struct {
+	Target      string         `json:"target"`
+	Duration    float64        `json:"durationSeconds"`
+	Rate        float64        `json:"targetRequestsPerSecond,omitempty"`
+	Concurrency int            `json:"concurrency"`
+	Requests    int            `json:"requests"`
+	Valid       int64          `json:"valid"`
+	Invalid     int64          `json:"invalid"`
+	Dropped     int64          `json:"dropped"`
+	Throughput  float64        `json:"requestsPerSecond"`
+	Latency     latencySummary `json:"latencyMs"`
+	Statuses    map[string]int `json:"statuses"`
+	// Unexpected counts the valid receipts that were not accepted and the invalid ones that were.
+	Unexpected map[string]int `json:"unexpected,omitempty"`
+	Errors     map[string]int `json:"errors,omitempty"`
+}
+
+// runLoadtest submits randomized receipts to POST /receipts/process for a duration, either at a
+// fixed rate or as fast as the workers can.
+func runLoadtest(args []string, streams IO) int {
+	flags := newFlagSet("loadtest", streams)
+	target := flags.String("target", "", "base URL of the server; an in-process server is used when empty")
+	duration := flags.Duration("duration", 10*time.Second, "how long to send requests for")
+	rate := flags.Float64("rate", 0, "requests per second; 0 keeps every worker busy")
+	concurrency := flags.Int("concurrency", 10, "maximum requests in flight")
+	invalid := flags.Float64("invalid", 0.1, "fraction of the receipts that are invalid, between 0 and 1")
+	minItems := flags.Int("min-items", 1, "minimum items per receipt")
+	maxItems := flags.Int("max-items", 5, "maximum items per receipt")
+	retailers := flags.String("retailers", defaultRetailers, "comma separated retailer names to pick from")
+	seed := flags.Int64("seed", 0, "seed of the receipt generator; 0 uses the current time")
+	timeout := flags.Duration("timeout", 10*time.Second, "timeout of every request")
+	format := flags.String("format", formatTable, "output format: table or json")
+	flags.Usage = func() {
+		fmt.Fprintln(streams.Stderr, "Usage: receiptctl loadtest [-target URL] [-duration D] [-rate R] [-concurrency N] [flags]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return ExitUsage
+	}
+	if *format != formatTable && *format != formatJSON {
+		fmt.Fprintf(streams.Stderr, "receiptctl loadtest: unknown format %q\n", *format)
+		return ExitUsage
+	}
+
+	config := loadtestConfig{
+		duration:    *duration,
+		rate:        *rate,
+		concurrency: *concurrency,
+		invalid:     *invalid,
+		minItems:    *minItems,
+		maxItems:    *maxItems,
+		seed:        *seed,
+	}
+	for _, retailer := range strings.Split(*retailers, ",") {
+		if retailer = strings.TrimSpace(retailer); retailer != "" {
+			config.retailers = append(config.retailers, retailer)
+		}
+	}
+	if err := config.validate(); err != nil {
+		fmt.Fprintf(streams.Stderr, "receiptctl loadtest: %v\n", err)
+		return ExitUsage
+	}
+	if config.seed == 0 {
+		config.seed = time.Now().UnixNano()
+	}
+
+	client, baseURL, err := newTarget(*target, *timeout)
+	if err != nil {
+		fmt.Fprintf(streams.Stderr, "receiptctl loadtest: %v\n", err)
+		return ExitUsage
+	}
+
+	report := loadtest(client, baseURL, config)
+	if *target == "" {
+		report.Target = "in-process"
+	}
+
+	if *format == formatJSON {
+		encoder := json.NewEncoder(streams.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			fmt.Fprintf(streams.Stderr, "receiptctl loadtest: %v\n", err)
+			return ExitFailure
+		}
+	} else {
+		writeLoadtestReport(streams.Stdout, report)
+	}
+
+	if report.Requests == 0 || len(report.Errors) > 0 || len(report.Unexpected) > 0 {
+		return ExitFailure
+	}
+	return ExitOK
+}
+
+func (c loadtestConfig) validate() error {
+	switch {
+	case c.duration <= 0:
+		return fmt.Errorf("the duration must be positive")
+	case c.rate < 0:
+		return fmt.Errorf("the rate can not be negative")
+	case c.concurrency < 1:
+		return fmt.Errorf("the concurrency must be at least 1")
+	case c.invalid < 0 || c.invalid > 1:
+		return fmt.Errorf("the invalid fraction must be between 0 and 1")
+	case c.minItems < 1 || c.maxItems < c.minItems:
+		return fmt.Errorf("the item counts must satisfy 1 <= min-items <= max-items")
+	case len(c.retailers) == 0:
+		return fmt.Errorf("at least one retailer is required")
+	}
+	return nil
+}
+
+func loadtest(client *http.Client, baseURL string, config loadtestConfig) loadtestReport {
+	results := newRecorder()
+	var valid, invalid, dropped atomic.Int64
+	var unexpectedMu sync.Mutex
+	unexpected := make(map[string]int)
+
+	// With a rate, a ticker hands out the requests and the ticks that find every worker busy are
+	// dropped instead of queued, so that the latencies are not hidden by the queue.
+	var tokens chan struct{}
+	if config.rate > 0 {
+		tokens = make(chan struct{})
+	}
+
+	deadline := time.Now().Add(config.duration)
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < config.concurrency; i++ {
+		wg.Add(1)
+		go func(worker int64) {
+			defer wg.Done()
+			generator := rand.New(rand.NewSource(config.seed + worker))
+			for {
+				if tokens != nil {
+					if _, ok := <-tokens; !ok {
+						return
+					}
+				} else if time.Now().After(deadline) {
+					return
+				}
+
+				receipt, invalidation := generateReceipt(generator, config)
+				status, err := submitReceipt(client, baseURL, receipt, results)
+				if invalidation == "" {
+					valid.Add(1)
+				} else {
+					invalid.Add(1)
+				}
+				if err != nil {
+					continue
+				}
+				if reason := unexpectedOutcome(status, invalidation); reason != "" {
+					unexpectedMu.Lock()
+					unexpected[reason]++
+					unexpectedMu.Unlock()
+				}
+			}
+		}(int64(i))
+	}
+
+	if tokens != nil {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / config.rate))
+		for now := range ticker.C {
+			if now.After(deadline) {
+				break
+			}
+			select {
+			case tokens <- struct{}{}:
+			default:
+				dropped.Add(1)
+			}
+		}
+		ticker.Stop()
+		close(tokens)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	report := loadtestReport{
+		Target:      baseURL,
+		Duration:    elapsed.Seconds(),
+		Rate:        config.rate,
+		Concurrency: config.concurrency,
+		Requests:    results.Count(),
+		Valid:       valid.Load(),
+		Invalid:     invalid.Load(),
+		Dropped:     dropped.Load(),
+		Latency:     results.Latency(),
+		Statuses:    results.Statuses(),
+		Errors:      results.Errors(),
+	}
+	if len(unexpected) > 0 {
+		report.Unexpected = unexpected
+	}
+	if elapsed > 0 {
+		report.Throughput = float64(report.Requests) / elapsed.Seconds()
+	}
+	return report
+}
+
+// generateReceipt returns a random receipt and the name of the invalidation applied to it, if any.
+func generateReceipt(generator *rand.Rand, config loadtestConfig) (*models.Receipt, string) {
+	purchased := time.Date(2022, time.January, 1, 0, 0, 0, 0, time.UTC).
+		Add(time.Duration(generator.Int63n(int64(3 * 365 * 24 * time.Hour))))
+	receipt := &models.Receipt{
+		Retailer:     config.retailers[generator.Intn(len(config.retailers))],
+		PurchaseDate: purchased.Format("2006-01-02"),
+		PurchaseTime: purchased.Format("15:04"),
+	}
+
+	var totalCents int
+	count := config.minItems + generator.Intn(config.maxItems-config.minItems+1)
+	for i := 0; i < count; i++ {
+		cents := 1 + generator.Intn(5000)
+		totalCents += cents
+		receipt.Items = append(receipt.Items, models.ReceiptItem{
+			ShortDescription: itemDescriptions[generator.Intn(len(itemDescriptions))],
+			Price:            formatCents(cents),
+		})
+	}
+	receipt.Total = formatCents(totalCents)
+
+	if generator.Float64() >= config.invalid {
+		return receipt, ""
+	}
+	invalidation := invalidations[generator.Intn(len(invalidations))]
+	invalidation.apply(receipt)
+	return receipt, invalidation.name
+}
+
+func formatCents(cents int) string {
+	return fmt.Sprintf("%d.%02d", cents/100, cents%100)
+}
+
+func submitReceipt(client *http.Client, baseURL string, receipt *models.Receipt, results *recorder) (int, error) {
+	body, err := json.Marshal(receipt)
+	if err != nil {
+		return 0, err
+	}
+
+	start := time.Now()
+	res, err := client.Post(baseURL+"/receipts/process", "application/json", bytes.NewReader(body))
+	if err != nil {
+		results.Record(time.Since(start), 0, err)
+		return 0, err
+	}
+	_, err = io.Copy(io.Discard, res.Body)
+	res.Body.Close()
+	results.Record(time.Since(start), res.StatusCode, err)
+	return res.StatusCode, err
+}
+
+// unexpectedOutcome tells why a status is not the one expected for the receipt. Rate limiting
+// and quota responses are not a wrong outcome: they are reported with the other status codes.
+func unexpectedOutcome(status int, invalidation string) string {
+	switch {
+	case status == http.StatusTooManyRequests:
+		return ""
+	case invalidation == "" && status >= 300:
+		return fmt.Sprintf("valid receipt rejected with %d", status)
+	case invalidation != "" && status != http.StatusBadRequest:
+		return fmt.Sprintf("%s receipt answered with %d", invalidation, status)
+	}
+	return ""
+}
+
+func writeLoadtestReport(w io.Writer, report loadtestReport) {
+	fmt.Fprintf(w, "Target:        %s\n", report.Target)
+	if report.Rate > 0 {
+		fmt.Fprintf(w, "Load:          %.1f req/s, up to %d in flight\n", report.Rate, report.Concurrency)
+	} else {
+		fmt.Fprintf(w, "Load:          %d workers\n", report.Concurrency)
+	}
+	fmt.Fprintf(w, "Requests:      %d in %.3fs (%.1f req/s)\n", report.Requests, report.Duration, report.Throughput)
+	fmt.Fprintf(w, "Receipts:      %d valid, %d invalid\n", report.Valid, report.Invalid)
+	if report.Dropped > 0 {
+		fmt.Fprintf(w, "Dropped:       %d (every worker was busy)\n", report.Dropped)
+	}
+	writeLatency(w, report.Latency)
+	writeCounts(w, "Status codes:", report.Statuses)
+	writeCounts(w, "Unexpected:", report.Unexpected)
+	writeCounts(w, "Errors:", report.Errors)
+}
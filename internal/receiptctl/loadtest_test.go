@@ -0,0 +1,79 @@
+package receiptctl
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLoadtest(t *testing.T) {
+	t.Run("Closed loop against the in-process server", func(t *testing.T) {
+		code, stdout, stderr := runCommand(t, "", "loadtest", "-duration", "200ms", "-concurrency", "2", "-invalid", "0.3", "-seed", "7", "-format", "json")
+
+		assert.Equal(t, ExitOK, code, stderr)
+		var report loadtestReport
+		require.NoError(t, json.Unmarshal([]byte(stdout), &report))
+		assert.Equal(t, "in-process", report.Target)
+		assert.Positive(t, report.Requests)
+		assert.EqualValues(t, report.Requests, report.Valid+report.Invalid)
+		assert.EqualValues(t, report.Valid, report.Statuses["200"])
+		assert.EqualValues(t, report.Invalid, report.Statuses["400"])
+		assert.Empty(t, report.Unexpected)
+	})
+
+	t.Run("Fixed rate", func(t *testing.T) {
+		code, stdout, _ := runCommand(t, "", "loadtest", "-duration", "500ms", "-rate", "20", "-concurrency", "1", "-format", "json")
+
+		assert.Equal(t, ExitOK, code)
+		var report loadtestReport
+		require.NoError(t, json.Unmarshal([]byte(stdout), &report))
+		assert.InDelta(t, 10, report.Requests, 2)
+	})
+
+	t.Run("Server errors are reported", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/receipts/process", r.URL.Path)
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer server.Close()
+
+		code, stdout, _ := runCommand(t, "", "loadtest", "-target", server.URL, "-duration", "100ms", "-concurrency", "1", "-invalid", "0")
+
+		assert.Equal(t, ExitFailure, code)
+		assert.Contains(t, stdout, "valid receipt rejected with 500")
+	})
+
+	t.Run("Invalid flags", func(t *testing.T) {
+		code, _, stderr := runCommand(t, "", "loadtest", "-min-items", "3", "-max-items", "2")
+
+		assert.Equal(t, ExitUsage, code)
+		assert.Contains(t, stderr, "min-items")
+	})
+}
+
+func TestGenerateReceipt(t *testing.T) {
+	generator := rand.New(rand.NewSource(1))
+	config := loadtestConfig{minItems: 2, maxItems: 4, retailers: []string{"Target"}}
+
+	for i := 0; i < 100; i++ {
+		receipt, invalidation := generateReceipt(generator, config)
+		assert.Empty(t, invalidation)
+		assert.Equal(t, "Target", receipt.Retailer)
+		assert.GreaterOrEqual(t, len(receipt.Items), 2)
+		assert.LessOrEqual(t, len(receipt.Items), 4)
+		assert.NoError(t, service.ValidateReceipt(context.Background(), receipt, 0))
+	}
+
+	config.invalid = 1
+	for i := 0; i < 100; i++ {
+		receipt, invalidation := generateReceipt(generator, config)
+		assert.NotEmpty(t, invalidation)
+		assert.Error(t, service.ValidateReceipt(context.Background(), receipt, 0), invalidation)
+	}
+}
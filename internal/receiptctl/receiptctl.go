@@ -0,0 +1,77 @@
+// Package receiptctl implements the receiptctl command line tool.
+package receiptctl
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+)
+
+const (
+	// ExitOK is returned when the command succeeded.
+	ExitOK = 0
+	// ExitFailure is returned when the command ran but some of its inputs were rejected.
+	ExitFailure = 1
+	// ExitUsage is returned when the command line is not valid.
+	ExitUsage = 2
+)
+
+// IO holds the streams a command reads from and writes to.
+type IO struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+type command struct {
+	summary string
+	run     func(args []string, streams IO) int
+}
+
+var commands = map[string]command{
+	"convert":  {summary: "Convert UBL and CFDI XML invoices to JSON receipts", run: runConvert},
+	"export":   {summary: "Download the receipts of a server as NDJSON or CSV", run: runExport},
+	"import":   {summary: "Submit the receipts of a CSV or NDJSON file to a server", run: runImport},
+	"loadtest": {summary: "Benchmark POST /receipts/process with generated receipts", run: runLoadtest},
+	"replay":   {summary: "Replay a JSONL file of recorded requests against a server", run: runReplay},
+	"score":    {summary: "Score receipt files without running the server", run: runScore},
+}
+
+// Run executes the command named by the first argument and returns the exit code.
+func Run(args []string, streams IO) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "-help" || args[0] == "--help" {
+		usage(streams.Stderr)
+		return ExitUsage
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(streams.Stderr, "receiptctl: unknown command %q\n", args[0])
+		usage(streams.Stderr)
+		return ExitUsage
+	}
+	return cmd.run(args[1:], streams)
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: receiptctl <command> [flags] [args]")
+	fmt.Fprintln(w, "\nCommands:")
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-10s %s\n", name, commands[name].summary)
+	}
+	fmt.Fprintln(w, "\nRun receiptctl <command> -h for the flags of a command.")
+}
+
+// newFlagSet reports flag errors on stderr instead of exiting the process.
+func newFlagSet(name string, streams IO) *flag.FlagSet {
+	flags := flag.NewFlagSet("receiptctl "+name, flag.ContinueOnError)
+	flags.SetOutput(streams.Stderr)
+	return flags
+}
@@ -0,0 +1,441 @@
+package receiptctl
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// recordedRequest is a line of a replay file.
+type recordedRequest struct {
+	Method  string            `json:"method"`
+	Path    string            `json:"path"`
+	Headers map[string]string `json:"headers,omitempty"`
+	// Body is sent as is when it is a JSON string, and encoded as JSON otherwise.
+	Body   json.RawMessage `json:"body,omitempty"`
+	Expect *expectation    `json:"expect,omitempty"`
+	// Capture stores fields of the JSON response, by dotted path, as variables that the
+	// following lines can reference as {{name}}.
+	Capture map[string]string `json:"capture,omitempty"`
+
+	line int
+}
+
+type expectation struct {
+	Status int `json:"status,omitempty"`
+	// Body must be contained in the response: objects may have more fields than expected.
+	Body json.RawMessage `json:"body,omitempty"`
+}
+
+type replayMismatch struct {
+	Line   int    `json:"line"`
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+type skippedLine struct {
+	Line   int    `json:"line"`
+	Reason string `json:"reason"`
+}
+
+type replaySummary struct {
+	Requests   int              `json:"requests"`
+	Skipped    []skippedLine    `json:"skipped,omitempty"`
+	Duration   float64          `json:"durationSeconds"`
+	Throughput float64          `json:"requestsPerSecond"`
+	Statuses   map[string]int   `json:"statuses"`
+	Errors     map[string]int   `json:"errors,omitempty"`
+	Latency    latencySummary   `json:"latencyMs"`
+	Mismatches []replayMismatch `json:"mismatches,omitempty"`
+}
+
+var variablePattern = regexp.MustCompile(`{{\s*([A-Za-z0-9_.-]+)\s*}}`)
+
+// runReplay sends the requests of a JSONL file to a server and checks the responses against
+// the expectations recorded with them.
+func runReplay(args []string, streams IO) int {
+	flags := newFlagSet("replay", streams)
+	target := flags.String("target", "", "base URL of the server; an in-process server is used when empty")
+	concurrency := flags.Int("concurrency", 1, "requests in flight at the same time")
+	rate := flags.Float64("rate", 0, "requests per second; 0 sends them as fast as possible")
+	timeout := flags.Duration("timeout", 10*time.Second, "timeout of every request")
+	format := flags.String("format", formatTable, "output format: table or json")
+	flags.Usage = func() {
+		fmt.Fprintln(streams.Stderr, "Usage: receiptctl replay [-target URL] [-concurrency N] [-rate R] [-timeout D] [-format table|json] [file]")
+		fmt.Fprintln(streams.Stderr, "Reads stdin when no file is given or the file is -. Captured variables are")
+		fmt.Fprintln(streams.Stderr, "only guaranteed to be set for the following lines with -concurrency 1.")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return ExitUsage
+	}
+	if *format != formatTable && *format != formatJSON {
+		fmt.Fprintf(streams.Stderr, "receiptctl replay: unknown format %q\n", *format)
+		return ExitUsage
+	}
+	if *concurrency < 1 || *rate < 0 || flags.NArg() > 1 {
+		flags.Usage()
+		return ExitUsage
+	}
+
+	source := "-"
+	if flags.NArg() == 1 {
+		source = flags.Arg(0)
+	}
+	requests, skipped, err := readRecordedRequests(source, streams.Stdin)
+	if err != nil {
+		fmt.Fprintf(streams.Stderr, "receiptctl replay: %v\n", err)
+		return ExitFailure
+	}
+
+	client, baseURL, err := newTarget(*target, *timeout)
+	if err != nil {
+		fmt.Fprintf(streams.Stderr, "receiptctl replay: %v\n", err)
+		return ExitUsage
+	}
+
+	replayer := &replayer{
+		client:    client,
+		baseURL:   baseURL,
+		recorder:  newRecorder(),
+		variables: make(map[string]string),
+	}
+	start := time.Now()
+	replayer.replay(requests, *concurrency, *rate)
+	elapsed := time.Since(start)
+
+	summary := replaySummary{
+		Requests:   replayer.recorder.Count(),
+		Skipped:    skipped,
+		Duration:   elapsed.Seconds(),
+		Statuses:   replayer.recorder.Statuses(),
+		Errors:     replayer.recorder.Errors(),
+		Latency:    replayer.recorder.Latency(),
+		Mismatches: replayer.mismatches,
+	}
+	if elapsed > 0 {
+		summary.Throughput = float64(summary.Requests) / elapsed.Seconds()
+	}
+	sort.Slice(summary.Mismatches, func(i, j int) bool { return summary.Mismatches[i].Line < summary.Mismatches[j].Line })
+
+	if *format == formatJSON {
+		encoder := json.NewEncoder(streams.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(summary); err != nil {
+			fmt.Fprintf(streams.Stderr, "receiptctl replay: %v\n", err)
+			return ExitFailure
+		}
+	} else {
+		writeReplaySummary(streams.Stdout, summary)
+	}
+
+	if summary.Requests == 0 || len(summary.Mismatches) > 0 || len(summary.Errors) > 0 {
+		return ExitFailure
+	}
+	return ExitOK
+}
+
+// readRecordedRequests parses a JSONL file. Blank lines are ignored and the lines that are not
+// a request are returned as skipped.
+func readRecordedRequests(source string, stdin io.Reader) ([]recordedRequest, []skippedLine, error) {
+	reader := stdin
+	if source != "-" {
+		file, err := os.Open(source)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer file.Close()
+		reader = file
+	}
+
+	var requests []recordedRequest
+	var skipped []skippedLine
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		var request recordedRequest
+		if err := json.Unmarshal([]byte(text), &request); err != nil {
+			skipped = append(skipped, skippedLine{Line: line, Reason: err.Error()})
+			continue
+		}
+		if request.Method == "" || request.Path == "" {
+			skipped = append(skipped, skippedLine{Line: line, Reason: "the method and path are required"})
+			continue
+		}
+		request.Method = strings.ToUpper(request.Method)
+		request.line = line
+		requests = append(requests, request)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	return requests, skipped, nil
+}
+
+type replayer struct {
+	client   *http.Client
+	baseURL  string
+	recorder *recorder
+
+	mu         sync.Mutex
+	variables  map[string]string
+	mismatches []replayMismatch
+}
+
+func (r *replayer) replay(requests []recordedRequest, concurrency int, rate float64) {
+	queue := make(chan recordedRequest)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for request := range queue {
+				r.send(request)
+			}
+		}()
+	}
+
+	var ticker *time.Ticker
+	if rate > 0 {
+		ticker = time.NewTicker(time.Duration(float64(time.Second) / rate))
+		defer ticker.Stop()
+	}
+	for i, request := range requests {
+		if ticker != nil && i > 0 {
+			<-ticker.C
+		}
+		queue <- request
+	}
+	close(queue)
+	wg.Wait()
+}
+
+func (r *replayer) send(recorded recordedRequest) {
+	path := r.expand(recorded.Path)
+	mismatch := func(format string, args ...interface{}) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.mismatches = append(r.mismatches, replayMismatch{
+			Line:   recorded.line,
+			Method: recorded.Method,
+			Path:   path,
+			Reason: fmt.Sprintf(format, args...),
+		})
+	}
+
+	body, err := requestBody(recorded.Body)
+	if err != nil {
+		mismatch("could not read the body: %v", err)
+		return
+	}
+	req, err := http.NewRequest(recorded.Method, r.baseURL+path, strings.NewReader(r.expand(body)))
+	if err != nil {
+		mismatch("could not build the request: %v", err)
+		return
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for name, value := range recorded.Headers {
+		req.Header.Set(name, r.expand(value))
+	}
+
+	start := time.Now()
+	res, err := r.client.Do(req)
+	if err != nil {
+		r.recorder.Record(time.Since(start), 0, err)
+		return
+	}
+	responseBody, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	r.recorder.Record(time.Since(start), res.StatusCode, err)
+	if err != nil {
+		return
+	}
+
+	var actual interface{}
+	decodeErr := json.Unmarshal(responseBody, &actual)
+
+	if expect := recorded.Expect; expect != nil {
+		if expect.Status != 0 && expect.Status != res.StatusCode {
+			mismatch("expected status %d, got %d", expect.Status, res.StatusCode)
+		}
+		if len(expect.Body) > 0 {
+			var expected interface{}
+			if err := json.Unmarshal([]byte(r.expand(string(expect.Body))), &expected); err != nil {
+				mismatch("the expected body is not valid JSON: %v", err)
+			} else if decodeErr != nil {
+				mismatch("the response body is not JSON: %q", truncate(string(responseBody), 80))
+			} else if reason := matchSubset("", expected, actual); reason != "" {
+				mismatch("%s", reason)
+			}
+		}
+	}
+
+	for name, field := range recorded.Capture {
+		value, ok := lookup(actual, field)
+		if decodeErr != nil || !ok {
+			mismatch("could not capture %s: the response has no %q field", name, field)
+			continue
+		}
+		r.mu.Lock()
+		r.variables[name] = value
+		r.mu.Unlock()
+	}
+}
+
+// expand replaces the {{name}} references with the captured variables. Unknown variables are
+// left untouched so that the mismatch shows which one was missing.
+func (r *replayer) expand(s string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return variablePattern.ReplaceAllStringFunc(s, func(reference string) string {
+		name := variablePattern.FindStringSubmatch(reference)[1]
+		if value, ok := r.variables[name]; ok {
+			return value
+		}
+		return reference
+	})
+}
+
+func requestBody(raw json.RawMessage) (string, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || string(raw) == "null" {
+		return "", nil
+	}
+	if raw[0] == '"' {
+		var body string
+		err := json.Unmarshal(raw, &body)
+		return body, err
+	}
+	return string(raw), nil
+}
+
+// matchSubset returns why actual does not contain expected, or an empty string when it does.
+func matchSubset(pointer string, expected, actual interface{}) string {
+	switch expected := expected.(type) {
+	case map[string]interface{}:
+		object, ok := actual.(map[string]interface{})
+		if !ok {
+			return fmt.Sprintf("%s: expected an object, got %s", pointerOrRoot(pointer), describe(actual))
+		}
+		keys := make([]string, 0, len(expected))
+		for key := range expected {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			value, ok := object[key]
+			if !ok {
+				return fmt.Sprintf("%s/%s: missing", pointer, key)
+			}
+			if reason := matchSubset(pointer+"/"+key, expected[key], value); reason != "" {
+				return reason
+			}
+		}
+		return ""
+	case []interface{}:
+		elements, ok := actual.([]interface{})
+		if !ok {
+			return fmt.Sprintf("%s: expected an array, got %s", pointerOrRoot(pointer), describe(actual))
+		}
+		if len(expected) != len(elements) {
+			return fmt.Sprintf("%s: expected %d elements, got %d", pointerOrRoot(pointer), len(expected), len(elements))
+		}
+		for i := range expected {
+			if reason := matchSubset(pointer+"/"+strconv.Itoa(i), expected[i], elements[i]); reason != "" {
+				return reason
+			}
+		}
+		return ""
+	default:
+		if !reflect.DeepEqual(expected, actual) {
+			return fmt.Sprintf("%s: expected %s, got %s", pointerOrRoot(pointer), describe(expected), describe(actual))
+		}
+		return ""
+	}
+}
+
+// lookup returns the field of a JSON document at a dotted path, formatted as a string.
+func lookup(document interface{}, path string) (string, bool) {
+	value := document
+	for _, key := range strings.Split(path, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return "", false
+		}
+		if value, ok = object[key]; !ok {
+			return "", false
+		}
+	}
+
+	switch value := value.(type) {
+	case string:
+		return value, true
+	case nil, map[string]interface{}, []interface{}:
+		return "", false
+	default:
+		return fmt.Sprint(value), true
+	}
+}
+
+func pointerOrRoot(pointer string) string {
+	if pointer == "" {
+		return "/"
+	}
+	return pointer
+}
+
+func describe(value interface{}) string {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return truncate(string(encoded), 80)
+}
+
+func truncate(s string, length int) string {
+	if len(s) <= length {
+		return s
+	}
+	return s[:length] + "..."
+}
+
+func writeReplaySummary(w io.Writer, summary replaySummary) {
+	fmt.Fprintf(w, "Requests:      %d in %.3fs (%.1f req/s)\n", summary.Requests, summary.Duration, summary.Throughput)
+	fmt.Fprintf(w, "Skipped lines: %d\n", len(summary.Skipped))
+	fmt.Fprintf(w, "Mismatches:    %d\n", len(summary.Mismatches))
+	writeLatency(w, summary.Latency)
+	writeCounts(w, "Status codes:", summary.Statuses)
+	writeCounts(w, "Errors:", summary.Errors)
+
+	if len(summary.Skipped) > 0 {
+		fmt.Fprintln(w, "Skipped:")
+		for _, skipped := range summary.Skipped {
+			fmt.Fprintf(w, "  line %d: %s\n", skipped.Line, skipped.Reason)
+		}
+	}
+	if len(summary.Mismatches) > 0 {
+		fmt.Fprintln(w, "Mismatches:")
+		for _, mismatch := range summary.Mismatches {
+			fmt.Fprintf(w, "  line %d %s %s: %s\n", mismatch.Line, mismatch.Method, mismatch.Path, mismatch.Reason)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package receiptctl
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestReplay(t *testing.T) {
+	t.Run("Example file against the in-process server", func(t *testing.T) {
+		code, stdout, _ := runCommand(t, "", "replay", "-format", "json", "../../examples/replay.jsonl")
+
+		assert.Equal(t, ExitOK, code)
+		var summary replaySummary
+		require.NoError(t, json.Unmarshal([]byte(stdout), &summary))
+		assert.Equal(t, 4, summary.Requests)
+		assert.Empty(t, summary.Mismatches)
+		assert.Equal(t, map[string]int{"200": 2, "400": 1, "404": 1}, summary.Statuses)
+	})
+
+	t.Run("Mismatches and skipped lines are reported", func(t *testing.T) {
+		input := strings.Join([]string{
+			`{"method":"GET","path":"/health","expect":{"status":200,"body":{"status":"down"}}}`,
+			`not json`,
+			``,
+			`{"path":"/health"}`,
+			`{"method":"GET","path":"/receipts/{{missing}}/points","expect":{"status":200}}`,
+		}, "\n")
+
+		code, stdout, _ := runCommand(t, input, "replay", "-format", "json")
+
+		assert.Equal(t, ExitFailure, code)
+		var summary replaySummary
+		require.NoError(t, json.Unmarshal([]byte(stdout), &summary))
+		assert.Equal(t, 2, summary.Requests)
+		require.Len(t, summary.Skipped, 2)
+		assert.Equal(t, 2, summary.Skipped[0].Line)
+		assert.Equal(t, 4, summary.Skipped[1].Line)
+		require.Len(t, summary.Mismatches, 2)
+		assert.Equal(t, 1, summary.Mismatches[0].Line)
+		assert.Contains(t, summary.Mismatches[0].Reason, "/status")
+		assert.Equal(t, 5, summary.Mismatches[1].Line)
+		assert.Contains(t, summary.Mismatches[1].Path, "{{missing}}")
+	})
+
+	t.Run("Remote target with concurrency and rate", func(t *testing.T) {
+		var requests atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests.Add(1)
+			assert.Equal(t, "secret", r.Header.Get("X-Api-Key"))
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"points":10,"extra":true}`))
+		}))
+		defer server.Close()
+
+		line := `{"method":"GET","path":"/points","headers":{"X-Api-Key":"secret"},"expect":{"status":200,"body":{"points":10}}}`
+		input := strings.Repeat(line+"\n", 5)
+
+		start := time.Now()
+		code, stdout, stderr := runCommand(t, input, "replay", "-target", server.URL, "-concurrency", "2", "-rate", "50")
+
+		assert.Equal(t, ExitOK, code, stderr)
+		assert.EqualValues(t, 5, requests.Load())
+		assert.GreaterOrEqual(t, time.Since(start), 80*time.Millisecond)
+		assert.Contains(t, stdout, "Requests:      5")
+	})
+
+	t.Run("Invalid target", func(t *testing.T) {
+		code, _, stderr := runCommand(t, `{"method":"GET","path":"/health"}`, "replay", "-target", "localhost:7070")
+
+		assert.Equal(t, ExitUsage, code)
+		assert.Contains(t, stderr, "http or https")
+	})
+}
+
+func TestMatchSubset(t *testing.T) {
+	decode := func(s string) interface{} {
+		var v interface{}
+		require.NoError(t, json.Unmarshal([]byte(s), &v))
+		return v
+	}
+
+	assert.Empty(t, matchSubset("", decode(`{"a":{"b":[1,2]}}`), decode(`{"a":{"b":[1,2],"c":3},"d":4}`)))
+	assert.Equal(t, "/a/b/1: expected 2, got 3", matchSubset("", decode(`{"a":{"b":[1,2]}}`), decode(`{"a":{"b":[1,3]}}`)))
+	assert.Equal(t, "/a: missing", matchSubset("", decode(`{"a":1}`), decode(`{}`)))
+	assert.Equal(t, "/: expected an object, got []", matchSubset("", decode(`{}`), decode(`[]`)))
+}
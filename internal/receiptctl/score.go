@@ -0,0 +1,241 @@
+package receiptctl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/repository"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/service"
+	retailerRepository "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/retailer/repository"
+	retailerService "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/retailer/service"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/dto"
+	"github.com/CarlosMtz98/receipt-processor-challenge/pkg/currency"
+	"github.com/CarlosMtz98/receipt-processor-challenge/pkg/timezone"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+)
+
+type scoreResult struct {
+	Source    string       `json:"source"`
+	Retailer  string       `json:"retailer,omitempty"`
+	Total     string       `json:"total,omitempty"`
+	Points    *int         `json:"points,omitempty"`
+	Breakdown []ruleResult `json:"breakdown,omitempty"`
+	Error     string       `json:"error,omitempty"`
+}
+
+type ruleResult struct {
+	Rule        string `json:"rule"`
+	Description string `json:"description"`
+	Points      int    `json:"points"`
+}
+
+// runScore validates and scores receipts like POST /receipts/process and GET /receipts/{id}/points
+// would, without storing them. Every file, or stdin, can hold one or more JSON receipts.
+func runScore(args []string, streams IO) int {
+	flags := newFlagSet("score", streams)
+	format := flags.String("format", formatTable, "output format: table or json")
+	breakdown := flags.Bool("breakdown", false, "show the points awarded by every rule")
+	quantityAware := flags.Bool("quantity-aware", false, "count the quantity of the items like RULES_QUANTITY_AWARE")
+	preTax := flags.Bool("pre-tax", false, "score the total rules on the amount before taxes and tip like RULES_AMOUNT_BASIS=pre-tax")
+	tolerance := flags.Float64("tolerance", 0, "largest difference accepted between the total and the other amounts, like RECEIPT_TOTAL_TOLERANCE")
+	ratesFile := flags.String("rates", "", "exchange-rate file the receipts in other currencies are converted with, like EXCHANGE_RATES_FILE")
+	base := flags.String("base", currency.DefaultCurrency, "currency the receipts are scored in, like BASE_CURRENCY")
+	timeZones := flags.String("time-zones", "", "time zones of the retailers as retailer=zone,..., like RETAILER_TIME_ZONES")
+	retailersFile := flags.String("retailers", "", "JSON array of retailers, in the body of POST /admin/retailers, the receipts are matched with")
+	canonicalRetailer := flags.Bool("canonical-retailer", false, "score the retailer name rule on the registered name like RULES_CANONICAL_RETAILER")
+	flags.Usage = func() {
+		fmt.Fprintln(streams.Stderr, "Usage: receiptctl score [-format table|json] [-breakdown] [-quantity-aware] [-pre-tax] [-tolerance amount] [-rates file] [-base currency] [-time-zones retailer=zone,...] [-retailers file] [-canonical-retailer] [file ...]")
+		fmt.Fprintln(streams.Stderr, "Reads stdin when no file is given or the file is -.")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return ExitUsage
+	}
+	if *format != formatTable && *format != formatJSON {
+		fmt.Fprintf(streams.Stderr, "receiptctl score: unknown format %q\n", *format)
+		return ExitUsage
+	}
+	if *tolerance < 0 {
+		fmt.Fprintln(streams.Stderr, "receiptctl score: the tolerance cannot be negative")
+		return ExitUsage
+	}
+
+	retailerZones, err := timezone.ParseRetailerZones(*timeZones)
+	if err != nil {
+		fmt.Fprintf(streams.Stderr, "receiptctl score: %v\n", err)
+		return ExitUsage
+	}
+
+	sources := flags.Args()
+	if len(sources) == 0 {
+		sources = []string{"-"}
+	}
+
+	rates := currency.NewRates(strings.ToUpper(*base))
+	if *ratesFile != "" {
+		loaded, err := currency.LoadRates(*ratesFile, strings.ToUpper(*base))
+		if err != nil {
+			fmt.Fprintf(streams.Stderr, "receiptctl score: %v\n", err)
+			return ExitUsage
+		}
+		rates = loaded
+	}
+
+	ctx := context.Background()
+	options := []service.Option{
+		service.WithRuleSet(service.RuleSet{QuantityAware: *quantityAware, PreTax: *preTax, CanonicalRetailer: *canonicalRetailer}),
+		service.WithTotalTolerance(*tolerance),
+		service.WithExchangeRates(rates),
+		service.WithRetailerTimeZones(retailerZones),
+	}
+	if *retailersFile != "" {
+		registry, err := loadRetailers(ctx, *retailersFile)
+		if err != nil {
+			fmt.Fprintf(streams.Stderr, "receiptctl score: %v\n", err)
+			return ExitUsage
+		}
+		options = append(options, service.WithRetailerRegistry(registry))
+	}
+	receiptSvc := service.NewReceiptService(repository.InitReceiptRepository(), options...)
+
+	var results []scoreResult
+	for _, source := range sources {
+		receipts, err := readReceipts(source, streams.Stdin)
+		for i, receipt := range receipts {
+			name := source
+			if len(receipts) > 1 || err != nil {
+				name = source + "#" + strconv.Itoa(i+1)
+			}
+			results = append(results, scoreReceipt(ctx, receiptSvc, name, receipt, *breakdown))
+		}
+		if err != nil {
+			results = append(results, scoreResult{Source: source, Error: err.Error()})
+		}
+	}
+
+	if *format == formatJSON {
+		encoder := json.NewEncoder(streams.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(results); err != nil {
+			fmt.Fprintf(streams.Stderr, "receiptctl score: %v\n", err)
+			return ExitFailure
+		}
+	} else {
+		writeScoreTable(streams.Stdout, results)
+	}
+
+	for _, result := range results {
+		if result.Error != "" {
+			return ExitFailure
+		}
+	}
+	return ExitOK
+}
+
+// loadRetailers registers the retailers of the file in an in-memory registry, in their order.
+func loadRetailers(ctx context.Context, path string) (retailerService.RetailerService, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the retailers: %v", err)
+	}
+	var requests []dto.RetailerRequest
+	if err := json.Unmarshal(data, &requests); err != nil {
+		return nil, fmt.Errorf("failed to parse the retailers: %v", err)
+	}
+
+	registry := retailerService.NewRetailerService(retailerRepository.InitRetailerRepository())
+	for _, request := range requests {
+		if _, err := registry.CreateRetailer(ctx, request.Name, request.Aliases, request.Patterns); err != nil {
+			return nil, fmt.Errorf("failed to register the retailer %q: %v", request.Name, err)
+		}
+	}
+	return registry, nil
+}
+
+func scoreReceipt(ctx context.Context, receiptSvc service.ReceiptService, source string, receipt *models.Receipt, withBreakdown bool) scoreResult {
+	result := scoreResult{Source: source, Retailer: receipt.Retailer, Total: receipt.Total}
+	if err := receiptSvc.ValidateReceipt(ctx, receipt); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	if !withBreakdown {
+		points, err := receiptSvc.GetReceiptPoints(ctx, receipt)
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		result.Points = &points
+		return result
+	}
+
+	breakdown, err := receiptSvc.GetReceiptPointsBreakdown(ctx, receipt)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Points = &breakdown.Total
+	for _, rule := range breakdown.Rules {
+		result.Breakdown = append(result.Breakdown, ruleResult(rule))
+	}
+	return result
+}
+
+// readReceipts decodes the concatenated JSON receipts of a file. The receipts decoded before
+// a syntax error are returned along with it.
+func readReceipts(source string, stdin io.Reader) ([]*models.Receipt, error) {
+	reader := stdin
+	if source != "-" {
+		file, err := os.Open(source)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		reader = file
+	}
+
+	var receipts []*models.Receipt
+	decoder := json.NewDecoder(reader)
+	for {
+		receipt := &models.Receipt{}
+		if err := decoder.Decode(receipt); err == io.EOF {
+			break
+		} else if err != nil {
+			return receipts, fmt.Errorf("could not parse the receipt: %w", err)
+		}
+		receipts = append(receipts, receipt)
+	}
+
+	if len(receipts) == 0 {
+		return nil, fmt.Errorf("no receipt found")
+	}
+	return receipts, nil
+}
+
+func writeScoreTable(w io.Writer, results []scoreResult) {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "SOURCE\tRETAILER\tTOTAL\tPOINTS")
+	for _, result := range results {
+		if result.Error != "" {
+			message := strings.ReplaceAll(result.Error, "\n", "; ")
+			fmt.Fprintf(table, "%s\t%s\t%s\tinvalid: %s\n", result.Source, result.Retailer, result.Total, message)
+			continue
+		}
+
+		fmt.Fprintf(table, "%s\t%s\t%s\t%d\n", result.Source, result.Retailer, result.Total, *result.Points)
+		for _, rule := range result.Breakdown {
+			fmt.Fprintf(table, "  %s\t\t\t%d\n", rule.Rule, rule.Points)
+		}
+	}
+	table.Flush()
+}
@@ -0,0 +1,188 @@
+package receiptctl
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const invalidTotalReceipt = `{"retailer":"Target","purchaseDate":"2022-01-01","purchaseTime":"13:01","total":"9.99","items":[{"shortDescription":"Pepsi","price":"1.25"}]}`
+
+func runCommand(t *testing.T, stdin string, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := Run(args, IO{Stdin: strings.NewReader(stdin), Stdout: &stdout, Stderr: &stderr})
+	return code, stdout.String(), stderr.String()
+}
+
+func TestScore(t *testing.T) {
+	t.Run("Example receipts as a table", func(t *testing.T) {
+		code, stdout, _ := runCommand(t, "", "score", "../../examples/morning-receipt.json", "../../examples/simple-receipt.json")
+
+		assert.Equal(t, ExitOK, code)
+		lines := strings.Split(strings.TrimSpace(stdout), "\n")
+		require.Len(t, lines, 3)
+		assert.Equal(t, []string{"../../examples/morning-receipt.json", "Walgreens", "2.65", "15"}, strings.Fields(lines[1]))
+		assert.Equal(t, []string{"../../examples/simple-receipt.json", "Target", "1.25", "31"}, strings.Fields(lines[2]))
+	})
+
+	t.Run("Breakdown as JSON", func(t *testing.T) {
+		morning, err := os.ReadFile("../../examples/morning-receipt.json")
+		require.NoError(t, err)
+
+		code, stdout, _ := runCommand(t, string(morning), "score", "-format", "json", "-breakdown")
+
+		assert.Equal(t, ExitOK, code)
+		var results []scoreResult
+		require.NoError(t, json.Unmarshal([]byte(stdout), &results))
+		require.Len(t, results, 1)
+		assert.Equal(t, "-", results[0].Source)
+		assert.Equal(t, 15, *results[0].Points)
+
+		total := 0
+		for _, rule := range results[0].Breakdown {
+			total += rule.Points
+		}
+		assert.Equal(t, 15, total)
+	})
+
+	t.Run("Several receipts in one file", func(t *testing.T) {
+		simple, err := os.ReadFile("../../examples/simple-receipt.json")
+		require.NoError(t, err)
+		path := filepath.Join(t.TempDir(), "receipts.json")
+		require.NoError(t, os.WriteFile(path, append(simple, []byte(invalidTotalReceipt)...), 0o600))
+
+		code, stdout, _ := runCommand(t, "", "score", "-format", "json", path)
+
+		assert.Equal(t, ExitFailure, code)
+		var results []scoreResult
+		require.NoError(t, json.Unmarshal([]byte(stdout), &results))
+		require.Len(t, results, 2)
+		assert.Equal(t, path+"#1", results[0].Source)
+		assert.Equal(t, 31, *results[0].Points)
+		assert.Equal(t, path+"#2", results[1].Source)
+		assert.Nil(t, results[1].Points)
+		assert.Equal(t, "the receipt total must match with the items total", results[1].Error)
+	})
+
+	t.Run("Quantity-aware rules", func(t *testing.T) {
+		receipt := `{"retailer":"Target","purchaseDate":"2022-01-01","purchaseTime":"13:01","total":"3.75","items":[{"shortDescription":"Pepsi","price":"3.75","quantity":"3","unitPrice":"1.25"}]}`
+
+		_, stdout, _ := runCommand(t, receipt, "score")
+		assert.Equal(t, []string{"-", "Target", "3.75", "37"}, strings.Fields(strings.Split(strings.TrimSpace(stdout), "\n")[1]))
+
+		// The three units make one pair of items, worth 5 points.
+		_, stdout, _ = runCommand(t, receipt, "score", "-quantity-aware")
+		assert.Equal(t, []string{"-", "Target", "3.75", "42"}, strings.Fields(strings.Split(strings.TrimSpace(stdout), "\n")[1]))
+	})
+
+	t.Run("Pre-tax rules and total tolerance", func(t *testing.T) {
+		receipt := `{"retailer":"Target","purchaseDate":"2022-01-01","purchaseTime":"13:01","total":"15.16","items":[{"shortDescription":"Pizza","price":"12.25"},{"shortDescription":"Soda","price":"2.75"}],"discounts":[{"description":"Coupon","amount":"1.00"}],"taxes":[{"description":"Sales tax","amount":"1.16"}]}`
+
+		_, stdout, _ := runCommand(t, receipt, "score")
+		assert.Equal(t, []string{"-", "Target", "15.16", "17"}, strings.Fields(strings.Split(strings.TrimSpace(stdout), "\n")[1]))
+
+		// The 14.00 paid before taxes is a round amount and a multiple of 0.25.
+		_, stdout, _ = runCommand(t, receipt, "score", "-pre-tax")
+		assert.Equal(t, []string{"-", "Target", "15.16", "92"}, strings.Fields(strings.Split(strings.TrimSpace(stdout), "\n")[1]))
+
+		offByOneCent := strings.Replace(receipt, `"total":"15.16"`, `"total":"15.15"`, 1)
+		code, stdout, _ := runCommand(t, offByOneCent, "score")
+		assert.Equal(t, ExitFailure, code)
+		assert.Contains(t, stdout, "the subtotal minus the discounts plus the taxes and the tip is 15.16")
+
+		code, _, _ = runCommand(t, offByOneCent, "score", "-tolerance", "0.01")
+		assert.Equal(t, ExitOK, code)
+
+		code, _, stderr := runCommand(t, receipt, "score", "-tolerance", "-1")
+		assert.Equal(t, ExitUsage, code)
+		assert.Contains(t, stderr, "tolerance")
+	})
+
+	t.Run("Other currencies", func(t *testing.T) {
+		receipt := `{"retailer":"Lawson","purchaseDate":"2022-03-20","purchaseTime":"13:01","total":"150","currency":"JPY","items":[{"shortDescription":"Tea","price":"150"}]}`
+
+		code, stdout, _ := runCommand(t, receipt, "score")
+		assert.Equal(t, ExitFailure, code)
+		assert.Contains(t, stdout, "no exchange rate from JPY to USD on 2022-03-20")
+
+		// 150 yen are 1.26 dollars: 6 points for the retailer and ceil(1.26 * 0.2) for the tea.
+		code, stdout, _ = runCommand(t, receipt, "score", "-rates", "../../examples/exchange-rates.json")
+		assert.Equal(t, ExitOK, code)
+		assert.Equal(t, []string{"-", "Lawson", "150", "7"}, strings.Fields(strings.Split(strings.TrimSpace(stdout), "\n")[1]))
+
+		// Scored in yen, 150 is a round amount and the tea earns ceil(150 * 0.2) points.
+		_, stdout, _ = runCommand(t, receipt, "score", "-base", "jpy")
+		assert.Equal(t, []string{"-", "Lawson", "150", "111"}, strings.Fields(strings.Split(strings.TrimSpace(stdout), "\n")[1]))
+
+		code, _, _ = runCommand(t, receipt, "score", "-rates", "missing.json")
+		assert.Equal(t, ExitUsage, code)
+	})
+
+	t.Run("Time zones", func(t *testing.T) {
+		receipt := `{"retailer":"Target","purchasedAt":"2022-01-01T20:30:00Z","total":"1.25","items":[{"shortDescription":"Pepsi","price":"1.25"}]}`
+
+		// 6 points for the retailer, 25 for the total and 6 for the odd day. At 20:30 UTC the
+		// purchase misses the afternoon points it earns at 14:30 in Chicago.
+		code, stdout, _ := runCommand(t, receipt, "score")
+		assert.Equal(t, ExitOK, code)
+		assert.Equal(t, []string{"-", "Target", "1.25", "37"}, strings.Fields(strings.Split(strings.TrimSpace(stdout), "\n")[1]))
+
+		_, stdout, _ = runCommand(t, receipt, "score", "-time-zones", "Target=America/Chicago")
+		assert.Equal(t, []string{"-", "Target", "1.25", "47"}, strings.Fields(strings.Split(strings.TrimSpace(stdout), "\n")[1]))
+
+		code, _, _ = runCommand(t, receipt, "score", "-time-zones", "Target")
+		assert.Equal(t, ExitUsage, code)
+	})
+
+	t.Run("Registered retailers", func(t *testing.T) {
+		receipt := `{"retailer":"target.com","purchaseDate":"2022-01-01","purchaseTime":"13:01","total":"1.25","items":[{"shortDescription":"Pepsi","price":"1.25"}]}`
+		retailers := filepath.Join(t.TempDir(), "retailers.json")
+		require.NoError(t, os.WriteFile(retailers, []byte(`[{"name":"Target","aliases":["target.com"]}]`), 0o600))
+
+		// 9 points for the printed name, 25 for the total and 6 for the odd day.
+		_, stdout, _ := runCommand(t, receipt, "score", "-retailers", retailers)
+		assert.Equal(t, []string{"-", "target.com", "1.25", "40"}, strings.Fields(strings.Split(strings.TrimSpace(stdout), "\n")[1]))
+
+		// The registered name only has 6.
+		_, stdout, _ = runCommand(t, receipt, "score", "-retailers", retailers, "-canonical-retailer")
+		assert.Equal(t, []string{"-", "target.com", "1.25", "37"}, strings.Fields(strings.Split(strings.TrimSpace(stdout), "\n")[1]))
+
+		code, _, _ := runCommand(t, receipt, "score", "-retailers", "missing.json")
+		assert.Equal(t, ExitUsage, code)
+	})
+
+	t.Run("Malformed JSON", func(t *testing.T) {
+		code, stdout, _ := runCommand(t, "{not json", "score")
+
+		assert.Equal(t, ExitFailure, code)
+		assert.Contains(t, stdout, "invalid: could not parse the receipt")
+	})
+
+	t.Run("Missing file", func(t *testing.T) {
+		code, stdout, _ := runCommand(t, "", "score", filepath.Join(t.TempDir(), "missing.json"))
+
+		assert.Equal(t, ExitFailure, code)
+		assert.Contains(t, stdout, "no such file")
+	})
+
+	t.Run("Unknown format", func(t *testing.T) {
+		code, _, stderr := runCommand(t, "", "score", "-format", "yaml")
+
+		assert.Equal(t, ExitUsage, code)
+		assert.Contains(t, stderr, "unknown format")
+	})
+}
+
+func TestRun(t *testing.T) {
+	code, _, stderr := runCommand(t, "", "frobnicate")
+
+	assert.Equal(t, ExitUsage, code)
+	assert.Contains(t, stderr, `unknown command "frobnicate"`)
+	assert.Contains(t, stderr, "score")
+}
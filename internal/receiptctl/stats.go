@@ -0,0 +1,135 @@
+package receiptctl
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// latencySummary reports latencies in milliseconds.
+type latencySummary struct {
+	Min  float64 `json:"min"`
+	Mean float64 `json:"mean"`
+	P50  float64 `json:"p50"`
+	P90  float64 `json:"p90"`
+	P99  float64 `json:"p99"`
+	Max  float64 `json:"max"`
+}
+
+// recorder collects the outcome of the requests sent by a command. It is safe for concurrent use.
+type recorder struct {
+	mu        sync.Mutex
+	latencies []time.Duration
+	statuses  map[int]int
+	errors    map[string]int
+}
+
+func newRecorder() *recorder {
+	return &recorder{
+		statuses: make(map[int]int),
+		errors:   make(map[string]int),
+	}
+}
+
+// Record counts a response, or the transport error that prevented getting one.
+func (r *recorder) Record(latency time.Duration, status int, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.latencies = append(r.latencies, latency)
+	if err != nil {
+		r.errors[err.Error()]++
+		return
+	}
+	r.statuses[status]++
+}
+
+func (r *recorder) Count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.latencies)
+}
+
+// Statuses returns the number of responses by status code, keyed by the code as a string
+// so that it serializes as a JSON object.
+func (r *recorder) Statuses() map[string]int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	statuses := make(map[string]int, len(r.statuses))
+	for status, count := range r.statuses {
+		statuses[strconv.Itoa(status)] = count
+	}
+	return statuses
+}
+
+func (r *recorder) Errors() map[string]int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	errors := make(map[string]int, len(r.errors))
+	for message, count := range r.errors {
+		errors[message] = count
+	}
+	return errors
+}
+
+func (r *recorder) Latency() latencySummary {
+	r.mu.Lock()
+	sorted := append([]time.Duration(nil), r.latencies...)
+	r.mu.Unlock()
+
+	if len(sorted) == 0 {
+		return latencySummary{}
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var sum time.Duration
+	for _, latency := range sorted {
+		sum += latency
+	}
+	return latencySummary{
+		Min:  milliseconds(sorted[0]),
+		Mean: milliseconds(sum / time.Duration(len(sorted))),
+		P50:  milliseconds(percentile(sorted, 50)),
+		P90:  milliseconds(percentile(sorted, 90)),
+		P99:  milliseconds(percentile(sorted, 99)),
+		Max:  milliseconds(sorted[len(sorted)-1]),
+	}
+}
+
+// percentile uses the nearest-rank method on sorted latencies.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[max(rank, 1)-1]
+}
+
+func milliseconds(d time.Duration) float64 {
+	return math.Round(float64(d)/float64(time.Millisecond)*1000) / 1000
+}
+
+func writeLatency(w io.Writer, latency latencySummary) {
+	fmt.Fprintf(w, "Latency (ms):  min %.3f  mean %.3f  p50 %.3f  p90 %.3f  p99 %.3f  max %.3f\n",
+		latency.Min, latency.Mean, latency.P50, latency.P90, latency.P99, latency.Max)
+}
+
+func writeCounts(w io.Writer, title string, counts map[string]int) {
+	if len(counts) == 0 {
+		return
+	}
+
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	fmt.Fprintln(w, title)
+	for _, key := range keys {
+		fmt.Fprintf(w, "  %-6d %s\n", counts[key], key)
+	}
+}
@@ -0,0 +1,119 @@
+package receiptctl
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/config"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/dto"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/server"
+	"github.com/gin-gonic/gin"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"time"
+)
+
+// inProcessURL is the base URL of the requests served by the in-process router.
+const inProcessURL = "http://in-process"
+
+// newTarget returns the client and base URL of the server the requests are sent to. An empty
+// target serves them with an in-process router backed by in-memory repositories.
+func newTarget(target string, timeout time.Duration) (*http.Client, string, error) {
+	if target != "" {
+		if !strings.HasPrefix(target, "http://") && !strings.HasPrefix(target, "https://") {
+			return nil, "", fmt.Errorf("the target %q must be an http or https URL", target)
+		}
+		return &http.Client{Timeout: timeout}, strings.TrimSuffix(target, "/"), nil
+	}
+
+	router, err := newInProcessRouter()
+	if err != nil {
+		return nil, "", err
+	}
+	return &http.Client{Timeout: timeout, Transport: routerTransport{router: router}}, inProcessURL, nil
+}
+
+// newInProcessRouter builds the router of the server from the environment, like the server
+// does, without starting its workers.
+func newInProcessRouter() (http.Handler, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	// The access log and the setup log of the server would be mixed with the output of the command.
+	gin.SetMode(gin.ReleaseMode)
+	gin.DefaultWriter = io.Discard
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+	app, err := server.NewApp(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return app.Router, nil
+}
+
+// routerTransport answers the requests with the handler instead of going through the network.
+type routerTransport struct {
+	router http.Handler
+}
+
+func (t routerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	recorder := httptest.NewRecorder()
+	t.router.ServeHTTP(recorder, req)
+	return recorder.Result(), nil
+}
+
+// headerFlags collects the repeatable -header "Name: value" flag, e.g. to send a bearer token.
+type headerFlags http.Header
+
+func (h headerFlags) String() string {
+	names := make([]string, 0, len(h))
+	for name := range h {
+		names = append(names, name)
+	}
+	return strings.Join(names, ", ")
+}
+
+func (h headerFlags) Set(value string) error {
+	name, headerValue, ok := strings.Cut(value, ":")
+	if !ok || strings.TrimSpace(name) == "" {
+		return fmt.Errorf("the header %q must be written as Name: value", value)
+	}
+	http.Header(h).Add(strings.TrimSpace(name), strings.TrimSpace(headerValue))
+	return nil
+}
+
+func (h headerFlags) apply(req *http.Request) {
+	for name, values := range h {
+		req.Header[name] = values
+	}
+}
+
+// responseError describes an error response of the API with the message of its body.
+func responseError(status int, body []byte) error {
+	var apiError struct {
+		dto.ResponseErrorModel
+		dto.ProblemDetails
+	}
+	if err := json.Unmarshal(body, &apiError); err == nil {
+		switch {
+		case apiError.Message != "" && apiError.ResponseErrorModel.Details != "":
+			return fmt.Errorf("%d %s: %s", status, apiError.Message, apiError.ResponseErrorModel.Details)
+		case apiError.Message != "":
+			return fmt.Errorf("%d %s", status, apiError.Message)
+		case apiError.Title != "" && apiError.Detail == "" && len(apiError.Violations) > 0:
+			violations := make([]string, len(apiError.Violations))
+			for i, violation := range apiError.Violations {
+				violations[i] = strings.TrimSpace(violation.In + " " + violation.Pointer + ": " + violation.Message)
+			}
+			return fmt.Errorf("%d %s: %s", status, apiError.Title, strings.Join(violations, "; "))
+		case apiError.Title != "":
+			return fmt.Errorf("%d %s: %s", status, apiError.Title, apiError.Detail)
+		}
+	}
+	return fmt.Errorf("%d %s", status, strings.TrimSpace(truncate(string(body), 200)))
+}
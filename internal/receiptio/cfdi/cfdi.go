@@ -0,0 +1,237 @@
+// Package cfdi parses Mexican CFDI 4.0 electronic invoices into receipts. The type and field
+// names follow the SAT schema (Anexo 20) so that they can be looked up in its documentation.
+package cfdi
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
+	"github.com/CarlosMtz98/receipt-processor-challenge/pkg/currency"
+	"github.com/google/uuid"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Namespace is the namespace of the CFDI 4.0 elements.
+	Namespace = "http://www.sat.gob.mx/cfd/4"
+	// TimbreNamespace is the namespace of the stamp the SAT adds to the Complemento.
+	TimbreNamespace = "http://www.sat.gob.mx/TimbreFiscalDigital"
+
+	// TipoIngreso is the type of the invoices issued for a sale.
+	TipoIngreso = "I"
+
+	fechaLayout = "2006-01-02T15:04:05"
+)
+
+var (
+	// ErrInvalidCFDI is returned when the document is not a valid CFDI 4.0 sale invoice
+	ErrInvalidCFDI = errors.New("the document is not a valid CFDI 4.0 invoice")
+
+	amountPattern = regexp.MustCompile(`^\d+(\.\d{1,6})?$`)
+	rfcPattern    = regexp.MustCompile(`^[A-ZÑ&]{3,4}\d{6}[A-Z\d]{3}$`)
+	monedaPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+)
+
+// Comprobante is the root element of a CFDI.
+type Comprobante struct {
+	XMLName           xml.Name    `xml:"Comprobante"`
+	Version           string      `xml:"Version,attr"`
+	Serie             string      `xml:"Serie,attr"`
+	Folio             string      `xml:"Folio,attr"`
+	Fecha             string      `xml:"Fecha,attr"`
+	SubTotal          string      `xml:"SubTotal,attr"`
+	Descuento         string      `xml:"Descuento,attr"`
+	Moneda            string      `xml:"Moneda,attr"`
+	Total             string      `xml:"Total,attr"`
+	TipoDeComprobante string      `xml:"TipoDeComprobante,attr"`
+	Emisor            Emisor      `xml:"Emisor"`
+	Conceptos         []Concepto  `xml:"Conceptos>Concepto"`
+	Complemento       Complemento `xml:"Complemento"`
+}
+
+// Emisor is the retailer that issued the invoice.
+type Emisor struct {
+	Rfc    string `xml:"Rfc,attr"`
+	Nombre string `xml:"Nombre,attr"`
+}
+
+// Concepto is a line of the invoice. Importe is Cantidad times ValorUnitario, before the
+// discount and the taxes of the line.
+type Concepto struct {
+	Cantidad      string     `xml:"Cantidad,attr"`
+	Descripcion   string     `xml:"Descripcion,attr"`
+	ValorUnitario string     `xml:"ValorUnitario,attr"`
+	Importe       string     `xml:"Importe,attr"`
+	Descuento     string     `xml:"Descuento,attr"`
+	Traslados     []Impuesto `xml:"Impuestos>Traslados>Traslado"`
+	Retenciones   []Impuesto `xml:"Impuestos>Retenciones>Retencion"`
+}
+
+// Impuesto is a tax transferred to (Traslado) or withheld from (Retencion) a line. Exempt
+// taxes have no Importe.
+type Impuesto struct {
+	Impuesto   string `xml:"Impuesto,attr"`
+	TipoFactor string `xml:"TipoFactor,attr"`
+	Importe    string `xml:"Importe,attr"`
+}
+
+type Complemento struct {
+	Timbres []TimbreFiscalDigital `xml:"TimbreFiscalDigital"`
+}
+
+// TimbreFiscalDigital is the stamp of the SAT certifying the invoice.
+type TimbreFiscalDigital struct {
+	XMLName       xml.Name `xml:"TimbreFiscalDigital"`
+	Version       string   `xml:"Version,attr"`
+	UUID          string   `xml:"UUID,attr"`
+	FechaTimbrado string   `xml:"FechaTimbrado,attr"`
+}
+
+// Parse decodes and validates a CFDI document.
+func Parse(r io.Reader) (*Comprobante, error) {
+	var comprobante Comprobante
+	if err := xml.NewDecoder(r).Decode(&comprobante); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCFDI, err)
+	}
+	if err := comprobante.Validate(); err != nil {
+		return nil, err
+	}
+	return &comprobante, nil
+}
+
+// Validate checks the structure of the invoice and its stamp. The seals are not verified: that
+// requires the certificates of the issuer and the SAT.
+func (c *Comprobante) Validate() error {
+	var problems []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Errorf(format, args...))
+		}
+	}
+
+	if c.XMLName.Space != Namespace {
+		return fmt.Errorf("%w: the root element must be a Comprobante of the %s namespace", ErrInvalidCFDI, Namespace)
+	}
+	check(c.Version == "4.0", "Version must be 4.0, got %q", c.Version)
+	check(c.TipoDeComprobante == TipoIngreso, "TipoDeComprobante must be %s, got %q", TipoIngreso, c.TipoDeComprobante)
+	_, err := time.Parse(fechaLayout, c.Fecha)
+	check(err == nil, "Fecha must be written as AAAA-MM-DDThh:mm:ss, got %q", c.Fecha)
+	check(amountPattern.MatchString(c.SubTotal), "SubTotal %q is not an amount", c.SubTotal)
+	check(amountPattern.MatchString(c.Total), "Total %q is not an amount", c.Total)
+	check(c.Descuento == "" || amountPattern.MatchString(c.Descuento), "Descuento %q is not an amount", c.Descuento)
+	check(monedaPattern.MatchString(c.Moneda) && c.Moneda != "XXX", "Moneda must be the ISO 4217 code of the currency, got %q", c.Moneda)
+	check(currency.MinorUnits(c.Moneda) <= 2, "Moneda %s has more than two decimals, which is not supported", c.Moneda)
+
+	check(rfcPattern.MatchString(c.Emisor.Rfc), "the Rfc of the Emisor %q is not valid", c.Emisor.Rfc)
+	check(strings.TrimSpace(c.Emisor.Nombre) != "", "the Emisor has no Nombre")
+
+	check(len(c.Conceptos) > 0, "the invoice has no Concepto")
+	var importes float64
+	for i, concepto := range c.Conceptos {
+		line := i + 1
+		check(strings.TrimSpace(concepto.Descripcion) != "", "Concepto %d has no Descripcion", line)
+		check(amountPattern.MatchString(concepto.Importe), "the Importe %q of Concepto %d is not an amount", concepto.Importe, line)
+		check(concepto.Descuento == "" || amountPattern.MatchString(concepto.Descuento), "the Descuento %q of Concepto %d is not an amount", concepto.Descuento, line)
+		for _, impuesto := range append(append([]Impuesto{}, concepto.Traslados...), concepto.Retenciones...) {
+			check(impuesto.Importe == "" || amountPattern.MatchString(impuesto.Importe), "a tax Importe %q of Concepto %d is not an amount", impuesto.Importe, line)
+		}
+		importes += parseAmount(concepto.Importe)
+	}
+	if amountPattern.MatchString(c.SubTotal) {
+		check(toCents(importes) == toCents(parseAmount(c.SubTotal)), "SubTotal %s is not the sum of the Importe of the Conceptos", c.SubTotal)
+	}
+
+	switch len(c.Complemento.Timbres) {
+	case 0:
+		check(false, "the invoice has no TimbreFiscalDigital")
+	case 1:
+		timbre := c.Complemento.Timbres[0]
+		check(timbre.XMLName.Space == TimbreNamespace, "the TimbreFiscalDigital must be in the %s namespace", TimbreNamespace)
+		check(timbre.Version == "1.1", "the TimbreFiscalDigital Version must be 1.1, got %q", timbre.Version)
+		_, err := uuid.Parse(timbre.UUID)
+		check(err == nil && len(timbre.UUID) == 36, "the TimbreFiscalDigital UUID %q is not valid", timbre.UUID)
+	default:
+		check(false, "the invoice has more than one TimbreFiscalDigital")
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %w", ErrInvalidCFDI, errors.Join(problems...))
+	}
+	return nil
+}
+
+// Receipt maps a validated invoice to a receipt. The price of every item is what was paid
+// for the line: its Importe minus its discount plus its taxes, so that the items add up to the
+// Total. The cents lost rounding the lines are added to the last item.
+func (c *Comprobante) Receipt() *models.Receipt {
+	fecha, _ := time.Parse(fechaLayout, c.Fecha)
+	receipt := &models.Receipt{
+		Retailer:     strings.TrimSpace(c.Emisor.Nombre),
+		PurchaseDate: fecha.Format("2006-01-02"),
+		PurchaseTime: fecha.Format("15:04"),
+		Total:        formatAmount(toCents(parseAmount(c.Total)), c.Moneda),
+		Currency:     c.Moneda,
+		Invoice: &models.InvoiceReference{
+			Format:   models.InvoiceFormatCFDI,
+			Issuer:   c.Emisor.Rfc,
+			Series:   c.Serie,
+			Number:   c.Folio,
+			UUID:     strings.ToUpper(c.Complemento.Timbres[0].UUID),
+			Currency: c.Moneda,
+		},
+	}
+
+	prices := make([]int64, len(c.Conceptos))
+	var sum int64
+	for i, concepto := range c.Conceptos {
+		paid := parseAmount(concepto.Importe) - parseAmount(concepto.Descuento)
+		for _, traslado := range concepto.Traslados {
+			paid += parseAmount(traslado.Importe)
+		}
+		for _, retencion := range concepto.Retenciones {
+			paid -= parseAmount(retencion.Importe)
+		}
+		prices[i] = toCents(paid)
+		sum += prices[i]
+	}
+	if remainder := toCents(parseAmount(c.Total)) - sum; abs(remainder) <= int64(len(prices)) {
+		prices[len(prices)-1] += remainder
+	}
+
+	for i, concepto := range c.Conceptos {
+		receipt.Items = append(receipt.Items, models.ReceiptItem{
+			ShortDescription: strings.TrimSpace(concepto.Descripcion),
+			Price:            formatAmount(prices[i], c.Moneda),
+		})
+	}
+	return receipt
+}
+
+// parseAmount returns 0 for the missing optional amounts; the required ones are validated first.
+func parseAmount(amount string) float64 {
+	value, _ := strconv.ParseFloat(amount, 64)
+	return value
+}
+
+func toCents(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+// formatAmount writes an amount computed in cents with the decimals of the currency, e.g.
+// without the cents for yen.
+func formatAmount(cents int64, code string) string {
+	return currency.FormatAmount(currency.ToMinor(float64(cents)/100, code), code)
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,98 @@
+package cfdi
+
+import (
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"strings"
+	"testing"
+)
+
+func readExample(t *testing.T) string {
+	t.Helper()
+	content, err := os.ReadFile("../../../examples/cfdi-invoice.xml")
+	require.NoError(t, err)
+	return string(content)
+}
+
+func TestParse(t *testing.T) {
+	t.Run("Example invoice", func(t *testing.T) {
+		comprobante, err := Parse(strings.NewReader(readExample(t)))
+		require.NoError(t, err)
+
+		receipt := comprobante.Receipt()
+
+		assert.Equal(t, "TIENDA LA ESPERANZA", receipt.Retailer)
+		assert.Equal(t, "2022-03-20", receipt.PurchaseDate)
+		assert.Equal(t, "14:33", receipt.PurchaseTime)
+		assert.Equal(t, "77.99", receipt.Total)
+		assert.Equal(t, "MXN", receipt.Currency)
+		assert.Equal(t, []models.ReceiptItem{
+			{ShortDescription: "Refresco de cola 600 ml", Price: "35.99"},
+			{ShortDescription: "Papas fritas 45 g", Price: "20.00"},
+			{ShortDescription: "Tortillas de maiz", Price: "22.00"},
+		}, receipt.Items)
+		assert.Equal(t, &models.InvoiceReference{
+			Format:   models.InvoiceFormatCFDI,
+			Issuer:   "EKU9003173C9",
+			Series:   "A",
+			Number:   "1024",
+			UUID:     "5FB2822E-396D-4725-8521-CDC4BDD20CCF",
+			Currency: "MXN",
+		}, receipt.Invoice)
+		valid, err := receipt.IsValid(0)
+		assert.NoError(t, err)
+		assert.True(t, valid)
+	})
+
+	t.Run("Discounts, withholdings and rounding", func(t *testing.T) {
+		document := strings.NewReplacer(
+			`SubTotal="70.27"`, `SubTotal="70.27" Descuento="5.00"`,
+			`Total="77.99"`, `Total="72.00"`,
+			`ValorUnitario="22.00" Importe="22.00"`, `ValorUnitario="22.00" Importe="22.00" Descuento="5.00"`,
+			`TasaOCuota="0.000000" Importe="0.00"/>
+        </cfdi:Traslados>`, `TasaOCuota="0.000000" Importe="0.00"/>
+        </cfdi:Traslados>
+        <cfdi:Retenciones>
+          <cfdi:Retencion Base="22.00" Impuesto="001" TipoFactor="Tasa" TasaOCuota="0.012500" Importe="0.98"/>
+        </cfdi:Retenciones>`,
+		).Replace(readExample(t))
+
+		comprobante, err := Parse(strings.NewReader(document))
+		require.NoError(t, err)
+		receipt := comprobante.Receipt()
+
+		// 35.99 + 20.00 + (22.00 - 5.00 - 0.98) = 72.01: the cent is taken from the last line.
+		assert.Equal(t, "72.00", receipt.Total)
+		assert.Equal(t, "16.01", receipt.Items[2].Price)
+	})
+
+	t.Run("Invalid documents", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			replacer *strings.Replacer
+			problem  string
+		}{
+			{"CFDI 3.3", strings.NewReplacer("http://www.sat.gob.mx/cfd/4", "http://www.sat.gob.mx/cfd/3"), "namespace"},
+			{"Credit note", strings.NewReplacer(`TipoDeComprobante="I"`, `TipoDeComprobante="E"`), "TipoDeComprobante must be I"},
+			{"Date", strings.NewReplacer(`Fecha="2022-03-20T14:33:00"`, `Fecha="20/03/2022"`), "Fecha"},
+			{"Issuer", strings.NewReplacer(`Rfc="EKU9003173C9"`, `Rfc="123"`), "Rfc of the Emisor"},
+			{"Subtotal", strings.NewReplacer(`SubTotal="70.27"`, `SubTotal="80.27"`), "SubTotal 80.27"},
+			{"Amount", strings.NewReplacer(`Importe="17.24"`, `Importe="17,24"`), "Importe \"17,24\" of Concepto 2"},
+			{"No currency", strings.NewReplacer(`Moneda="MXN"`, `Moneda="XXX"`), "Moneda must be the ISO 4217 code"},
+			{"Currency with three decimals", strings.NewReplacer(`Moneda="MXN"`, `Moneda="KWD"`), "Moneda KWD has more than two decimals"},
+			{"Stamp UUID", strings.NewReplacer(`UUID="5FB2822E-396D-4725-8521-CDC4BDD20CCF"`, `UUID="5FB2822E"`), "UUID"},
+			{"Missing stamp", strings.NewReplacer("tfd:TimbreFiscalDigital", "tfd:Otro"), "no TimbreFiscalDigital"},
+			{"Not XML", strings.NewReplacer("<cfdi:Comprobante", "{"), "not a valid CFDI"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				_, err := Parse(strings.NewReader(tt.replacer.Replace(readExample(t))))
+
+				assert.ErrorIs(t, err, ErrInvalidCFDI)
+				assert.ErrorContains(t, err, tt.problem)
+			})
+		}
+	})
+}
@@ -0,0 +1,195 @@
+// Package email reads the receipts forwarded by email. It walks the MIME tree of an RFC 5322
+// message, including forwarded messages, and decodes the content of every leaf part.
+package email
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"golang.org/x/net/html"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"regexp"
+	"strings"
+)
+
+// maxDepth bounds the nesting of multipart and forwarded messages.
+const maxDepth = 10
+
+var (
+	// ErrInvalidMessage is returned when the message or one of its parts cannot be read
+	ErrInvalidMessage = errors.New("the message is not a valid email")
+
+	whitespacePattern = regexp.MustCompile(`\s+`)
+)
+
+// Message is an email with its leaf parts in the order they appear.
+type Message struct {
+	// From is the address of the sender, without the display name.
+	From    string
+	Subject string
+	Parts   []Part
+}
+
+// Part is a leaf of the MIME tree, decoded from its transfer encoding and, for text parts,
+// converted to UTF-8.
+type Part struct {
+	MediaType string
+	Filename  string
+	// Attachment is set for the parts with an attachment disposition or a file name.
+	Attachment bool
+	Content    []byte
+}
+
+// ReadMessage reads and decodes the message. A message without a valid From address is rejected.
+func ReadMessage(r io.Reader) (*Message, error) {
+	raw, err := mail.ReadMessage(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+	}
+	from, err := raw.Header.AddressList("From")
+	if err != nil || len(from) == 0 {
+		return nil, fmt.Errorf("%w: the From header has no valid address", ErrInvalidMessage)
+	}
+
+	message := &Message{
+		From:    strings.ToLower(from[0].Address),
+		Subject: decodeHeader(raw.Header.Get("Subject")),
+	}
+	if err := message.walk(textproto.MIMEHeader(raw.Header), raw.Body, 0); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+	}
+	return message, nil
+}
+
+func (m *Message) walk(header textproto.MIMEHeader, body io.Reader, depth int) error {
+	if depth > maxDepth {
+		return fmt.Errorf("the parts are nested more than %d levels", maxDepth)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType, params = "text/plain", map[string]string{}
+	}
+
+	switch {
+	case strings.HasPrefix(mediaType, "multipart/"):
+		parts := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := parts.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := m.walk(part.Header, part, depth+1); err != nil {
+				return err
+			}
+		}
+	case mediaType == "message/rfc822":
+		content, err := decodeTransfer(header, body)
+		if err != nil {
+			return err
+		}
+		forwarded, err := mail.ReadMessage(bytes.NewReader(content))
+		if err != nil {
+			return fmt.Errorf("the forwarded message is not valid: %v", err)
+		}
+		return m.walk(textproto.MIMEHeader(forwarded.Header), forwarded.Body, depth+1)
+	}
+
+	content, err := decodeTransfer(header, body)
+	if err != nil {
+		return err
+	}
+	if strings.HasPrefix(mediaType, "text/") {
+		content = toUTF8(content, params["charset"])
+	}
+
+	disposition, dispositionParams, _ := mime.ParseMediaType(header.Get("Content-Disposition"))
+	filename := decodeHeader(dispositionParams["filename"])
+	if filename == "" {
+		filename = decodeHeader(params["name"])
+	}
+	m.Parts = append(m.Parts, Part{
+		MediaType:  mediaType,
+		Filename:   filename,
+		Attachment: disposition == "attachment" || filename != "",
+		Content:    content,
+	})
+	return nil
+}
+
+func decodeTransfer(header textproto.MIMEHeader, body io.Reader) ([]byte, error) {
+	switch strings.ToLower(strings.TrimSpace(header.Get("Content-Transfer-Encoding"))) {
+	case "base64":
+		// Line breaks are ignored by the decoder.
+		return io.ReadAll(base64.NewDecoder(base64.StdEncoding, body))
+	case "quoted-printable":
+		return io.ReadAll(quotedprintable.NewReader(body))
+	default:
+		return io.ReadAll(body)
+	}
+}
+
+// toUTF8 converts the Latin-1 texts; the other charsets are kept as they are, which is right
+// for UTF-8 and ASCII.
+func toUTF8(content []byte, charset string) []byte {
+	switch strings.ToLower(charset) {
+	case "iso-8859-1", "latin1", "windows-1252":
+		converted := make([]rune, len(content))
+		for i, b := range content {
+			converted[i] = rune(b)
+		}
+		return []byte(string(converted))
+	}
+	return content
+}
+
+func decodeHeader(value string) string {
+	decoded, err := new(mime.WordDecoder).DecodeHeader(value)
+	if err != nil {
+		return value
+	}
+	return decoded
+}
+
+// HTMLText returns the text of an HTML body with a line per block element and the cells of a
+// table row separated by spaces, so that item rows keep their price at the end.
+func HTMLText(content []byte) string {
+	var text strings.Builder
+	tokenizer := html.NewTokenizer(bytes.NewReader(content))
+	skip := 0
+	for {
+		tokenType := tokenizer.Next()
+		switch tokenType {
+		case html.ErrorToken:
+			return strings.TrimSpace(text.String())
+		case html.TextToken:
+			if skip == 0 {
+				// The spaces around inline elements are kept: "Total: <b>$2.65</b>".
+				text.WriteString(whitespacePattern.ReplaceAllString(string(tokenizer.Text()), " "))
+			}
+		case html.StartTagToken, html.EndTagToken, html.SelfClosingTagToken:
+			name, _ := tokenizer.TagName()
+			switch string(name) {
+			case "script", "style", "head", "title":
+				if tokenType == html.StartTagToken {
+					skip++
+				} else if tokenType == html.EndTagToken && skip > 0 {
+					skip--
+				}
+			case "br", "p", "div", "tr", "li", "h1", "h2", "h3", "h4", "h5", "h6", "table", "section", "header", "footer":
+				text.WriteString("\n")
+			case "td", "th":
+				text.WriteString(" ")
+			}
+		}
+	}
+}
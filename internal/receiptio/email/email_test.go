@@ -0,0 +1,116 @@
+package email
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestReadMessage(t *testing.T) {
+	t.Run("Alternative bodies", func(t *testing.T) {
+		file, err := os.Open("../../../examples/email-receipt.eml")
+		require.NoError(t, err)
+		defer file.Close()
+
+		message, err := ReadMessage(file)
+
+		require.NoError(t, err)
+		assert.Equal(t, "receipts@cornermarket.example", message.From)
+		assert.Equal(t, "Your receipt from Corner Market", message.Subject)
+		require.Len(t, message.Parts, 2)
+		assert.Equal(t, "text/plain", message.Parts[0].MediaType)
+		assert.False(t, message.Parts[0].Attachment)
+		assert.Contains(t, string(message.Parts[0].Content), "Emils Cheese Pizza        12.25")
+		assert.Equal(t, "text/html", message.Parts[1].MediaType)
+		// The quoted-printable soft line break and =3D are decoded.
+		assert.Contains(t, string(message.Parts[1].Content), `</head><body>`)
+		assert.Contains(t, string(message.Parts[1].Content), `<table style="width: 100%">`)
+	})
+
+	t.Run("Forwarded message with a base64 attachment", func(t *testing.T) {
+		raw := strings.Join([]string{
+			`From: "Doe, Jane" <Jane.Doe@Example.com>`,
+			`Subject: =?UTF-8?Q?Fwd:_Tu_factura_electr=C3=B3nica?=`,
+			`Content-Type: multipart/mixed; boundary=outer`,
+			``,
+			`--outer`,
+			`Content-Type: text/plain`,
+			``,
+			`See below.`,
+			`--outer`,
+			`Content-Type: message/rfc822`,
+			``,
+			`From: facturas@tienda.example`,
+			`Content-Type: multipart/mixed; boundary=inner`,
+			``,
+			`--inner`,
+			`Content-Type: application/octet-stream; name="=?UTF-8?Q?factura_n=C2=BA1.json?="`,
+			`Content-Transfer-Encoding: base64`,
+			``,
+			`eyJyZXRhaWxlciI6IlRh`,
+			`cmdldCJ9`,
+			`--inner--`,
+			`--outer--`,
+			``,
+		}, "\r\n")
+
+		message, err := ReadMessage(strings.NewReader(raw))
+
+		require.NoError(t, err)
+		assert.Equal(t, "jane.doe@example.com", message.From)
+		assert.Equal(t, "Fwd: Tu factura electrónica", message.Subject)
+		require.Len(t, message.Parts, 2)
+		assert.Equal(t, "See below.", string(message.Parts[0].Content))
+		assert.Equal(t, Part{
+			MediaType:  "application/octet-stream",
+			Filename:   "factura nº1.json",
+			Attachment: true,
+			Content:    []byte(`{"retailer":"Target"}`),
+		}, message.Parts[1])
+	})
+
+	t.Run("Latin-1 text", func(t *testing.T) {
+		raw := "From: a@example.com\nContent-Type: text/plain; charset=ISO-8859-1\n\nCaf\xe9 2.50\n"
+
+		message, err := ReadMessage(strings.NewReader(raw))
+
+		require.NoError(t, err)
+		assert.Equal(t, "Café 2.50\n", string(message.Parts[0].Content))
+	})
+
+	t.Run("Invalid messages", func(t *testing.T) {
+		for name, raw := range map[string]string{
+			"no header":         "just some text",
+			"no From":           "Subject: receipt\n\nTOTAL 1.00\n",
+			"invalid From":      "From: not an address\n\nTOTAL 1.00\n",
+			"truncated":         "From: a@example.com\nContent-Type: multipart/mixed; boundary=b\n\n--b\nContent-Type: text/plain\n\nTOTAL",
+			"too deeply nested": "From: a@example.com\n" + strings.Repeat("Content-Type: message/rfc822\n\n", 12) + "TOTAL 1.00\n",
+		} {
+			t.Run(name, func(t *testing.T) {
+				_, err := ReadMessage(strings.NewReader(raw))
+
+				assert.True(t, errors.Is(err, ErrInvalidMessage), "got %v", err)
+			})
+		}
+	})
+}
+
+func TestHTMLText(t *testing.T) {
+	text := HTMLText([]byte(`<html><head><title>Receipt</title><style>p { color: red }</style></head>
+<body><script>var total = "9.99";</script>
+<div>CORNER   MARKET</div>
+<table><tr><td>Mountain Dew</td><td>6.49</td></tr><tr><th>Total:</th><td><b>6.49</b></td></tr></table>
+<p>Thanks<br>See you soon</p></body></html>`))
+
+	lines := strings.Split(text, "\n")
+	var nonEmpty []string
+	for _, line := range lines {
+		if line = strings.TrimSpace(line); line != "" {
+			nonEmpty = append(nonEmpty, strings.Join(strings.Fields(line), " "))
+		}
+	}
+	assert.Equal(t, []string{"CORNER MARKET", "Mountain Dew 6.49", "Total: 6.49", "Thanks", "See you soon"}, nonEmpty)
+}
@@ -0,0 +1,66 @@
+package receiptio
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/receiptio/cfdi"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/receiptio/ubl"
+	"strings"
+)
+
+// ublNamespacePrefix is shared by the namespaces of every UBL document type.
+const ublNamespacePrefix = "urn:oasis:names:specification:ubl:schema:xsd:"
+
+// ErrUnknownInvoice is returned for XML documents that are neither CFDI nor UBL
+var ErrUnknownInvoice = errors.New("unknown invoice format")
+
+// DetectInvoice recognizes the format of an XML invoice by the namespace of its root element.
+func DetectInvoice(document []byte) (models.InvoiceFormat, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(document))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return "", fmt.Errorf("could not find the root element: %w", err)
+		}
+		root, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch {
+		case root.Name.Space == cfdi.Namespace:
+			return models.InvoiceFormatCFDI, nil
+		case strings.HasPrefix(root.Name.Space, ublNamespacePrefix):
+			return models.InvoiceFormatUBL, nil
+		}
+		return "", fmt.Errorf("%w: the root element is %s in the %q namespace", ErrUnknownInvoice, root.Name.Local, root.Name.Space)
+	}
+}
+
+// ParseInvoice maps an XML invoice to a receipt. The format is detected when it is empty.
+func ParseInvoice(document []byte, format models.InvoiceFormat) (*models.Receipt, error) {
+	if format == "" {
+		var err error
+		if format, err = DetectInvoice(document); err != nil {
+			return nil, err
+		}
+	}
+
+	switch format {
+	case models.InvoiceFormatCFDI:
+		comprobante, err := cfdi.Parse(bytes.NewReader(document))
+		if err != nil {
+			return nil, err
+		}
+		return comprobante.Receipt(), nil
+	case models.InvoiceFormatUBL:
+		invoice, err := ubl.Parse(bytes.NewReader(document))
+		if err != nil {
+			return nil, err
+		}
+		return invoice.Receipt(), nil
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnknownInvoice, format)
+}
@@ -0,0 +1,280 @@
+// Package plaintext reads receipts from the text printed by thermal printers or pasted in email
+// bodies. The result is a draft: every line that was not understood is reported, and the
+// confidence tells how complete the receipt is.
+package plaintext
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Reasons a line was not used in the draft.
+const (
+	ReasonNotRecognized = "not recognized"
+	ReasonAfterTotal    = "after the total"
+	ReasonNegative      = "negative amounts are not supported"
+)
+
+// Draft is a receipt read from text.
+type Draft struct {
+	Receipt *models.Receipt
+	// Confidence goes from 0, nothing was found, to 1, every field was found, the items add
+	// up to the total and no line between the items was skipped.
+	Confidence float64
+	Warnings   []string
+	Unparsed   []Line
+}
+
+// Line is a line of the text that is not part of the draft.
+type Line struct {
+	Number int
+	Text   string
+	Reason string
+}
+
+// The weights of the parts of the receipt in the confidence.
+const (
+	weightRetailer   = 0.2
+	weightDate       = 0.2
+	weightTime       = 0.1
+	weightItems      = 0.2
+	weightTotal      = 0.2
+	weightReconciled = 0.1
+	// penaltySkipped is taken for every line skipped among the items, which may be a missed item.
+	penaltySkipped = 0.05
+)
+
+var (
+	separatorPattern = regexp.MustCompile(`^[\s\-=*_#~.]*$`)
+	// A trailing price, with an optional currency sign, minus sign and tax flag (e.g. "T", "N").
+	pricePattern = regexp.MustCompile(`^(.*?[\p{L}\d].*?)[\s.:]+(-)?[$€£]?\s?(\d{1,3}(?:[,.]\d{3})+[.,]\d{2}|\d+[.,]\d{2})(-)?(?:\s+[A-Z]{1,2})?$`)
+	totalPattern = regexp.MustCompile(`(?i)^(grand\s+)?total\b|^(amount|balance|total)\s+due\b`)
+	// summaryPattern recognizes the amounts around the total that are not items.
+	summaryPattern = regexp.MustCompile(`(?i)^(sub[\s-]?total|(sales\s+)?tax|vat|gst|hst|change|cash|tender|visa|master\s?card|amex|discover|debit|credit|card|tip|gratuity|you saved|savings|rounding)\b`)
+	letterPattern  = regexp.MustCompile(`\p{L}`)
+	welcomePattern = regexp.MustCompile(`(?i)^welcome\s+to\s+`)
+
+	isoDatePattern     = regexp.MustCompile(`\b(\d{4})-(\d{2})-(\d{2})\b`)
+	numericDatePattern = regexp.MustCompile(`\b(\d{1,2})[/.-](\d{1,2})[/.-](\d{4}|\d{2})\b`)
+	monthFirstPattern  = regexp.MustCompile(`(?i)\b(jan|feb|mar|apr|may|jun|jul|aug|sep|oct|nov|dec)[a-z]*\.?\s+(\d{1,2}),?\s+(\d{4})\b`)
+	dayFirstPattern    = regexp.MustCompile(`(?i)\b(\d{1,2})\s+(jan|feb|mar|apr|may|jun|jul|aug|sep|oct|nov|dec)[a-z]*\.?,?\s+(\d{4})\b`)
+	twelveHourPattern  = regexp.MustCompile(`(?i)\b(\d{1,2}):(\d{2})(?::\d{2})?\s*([ap])\.?\s?m\b\.?`)
+	clockPattern       = regexp.MustCompile(`\b([01]?\d|2[0-3]):([0-5]\d)(?::[0-5]\d)?\b`)
+	// labelPattern is what is left of a line that only held the date and the time.
+	labelPattern = regexp.MustCompile(`(?i)^(date|time|fecha|hora|on|at|[\s:,@|/-])*$`)
+
+	months = map[string]time.Month{
+		"jan": time.January, "feb": time.February, "mar": time.March, "apr": time.April,
+		"may": time.May, "jun": time.June, "jul": time.July, "aug": time.August,
+		"sep": time.September, "oct": time.October, "nov": time.November, "dec": time.December,
+	}
+)
+
+type section int
+
+const (
+	sectionHeader section = iota
+	sectionItems
+	sectionFooter
+)
+
+// Parse reads the receipt text. It only fails when the text cannot be read.
+func Parse(r io.Reader) (*Draft, error) {
+	draft := &Draft{Receipt: &models.Receipt{}}
+	receipt := draft.Receipt
+	current := sectionHeader
+	var skippedAmongItems []Line
+
+	scanner := bufio.NewScanner(r)
+	number := 0
+	for scanner.Scan() {
+		number++
+		text := strings.Join(strings.Fields(strings.TrimPrefix(scanner.Text(), "\ufeff")), " ")
+		if separatorPattern.MatchString(text) {
+			continue
+		}
+		unparsed := func(reason string) {
+			line := Line{Number: number, Text: text, Reason: reason}
+			draft.Unparsed = append(draft.Unparsed, line)
+			if current == sectionItems {
+				skippedAmongItems = append(skippedAmongItems, line)
+			}
+		}
+
+		rest := text
+		if receipt.PurchaseDate == "" {
+			rest = draft.findDate(rest, number)
+		}
+		if receipt.PurchaseTime == "" {
+			rest = draft.findTime(rest)
+		}
+		if rest != text && labelPattern.MatchString(rest) {
+			continue
+		}
+
+		match := pricePattern.FindStringSubmatch(rest)
+		if match == nil {
+			if current == sectionHeader && receipt.Retailer == "" && letterPattern.MatchString(rest) {
+				receipt.Retailer = welcomePattern.ReplaceAllString(strings.Trim(rest, " :"), "")
+				continue
+			}
+			if rest != text {
+				// The line held the date or the time, among other things.
+				continue
+			}
+			unparsed(ReasonNotRecognized)
+			continue
+		}
+
+		description := strings.TrimRight(match[1], " :")
+		price := normalizePrice(match[3])
+		negative := match[2] != "" || match[4] != ""
+		switch {
+		case current != sectionFooter && totalPattern.MatchString(description):
+			receipt.Total = price
+			current = sectionFooter
+		case summaryPattern.MatchString(description):
+		case current == sectionFooter:
+			unparsed(ReasonAfterTotal)
+		case negative:
+			unparsed(ReasonNegative)
+		default:
+			receipt.Items = append(receipt.Items, models.ReceiptItem{ShortDescription: description, Price: price})
+			current = sectionItems
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	draft.score(skippedAmongItems)
+	return draft, nil
+}
+
+// findDate stores the first date of the line and returns the line without it. Numeric dates
+// are read month first, unless the first number cannot be a month.
+func (d *Draft) findDate(text string, number int) string {
+	var date time.Time
+	var location []int
+	if match := isoDatePattern.FindStringSubmatchIndex(text); match != nil {
+		date, location = buildDate(atoi(text[match[2]:match[3]]), time.Month(atoi(text[match[4]:match[5]])), atoi(text[match[6]:match[7]])), match
+	} else if match := monthFirstPattern.FindStringSubmatchIndex(text); match != nil {
+		month := months[strings.ToLower(text[match[2]:match[3]])]
+		date, location = buildDate(atoi(text[match[6]:match[7]]), month, atoi(text[match[4]:match[5]])), match
+	} else if match := dayFirstPattern.FindStringSubmatchIndex(text); match != nil {
+		month := months[strings.ToLower(text[match[4]:match[5]])]
+		date, location = buildDate(atoi(text[match[6]:match[7]]), month, atoi(text[match[2]:match[3]])), match
+	} else if match := numericDatePattern.FindStringSubmatchIndex(text); match != nil {
+		first, second, year := atoi(text[match[2]:match[3]]), atoi(text[match[4]:match[5]]), atoi(text[match[6]:match[7]])
+		if year < 100 {
+			year += 2000
+		}
+		month, day := first, second
+		if first > 12 {
+			month, day = second, first
+		} else if second <= 12 && first != second {
+			d.Warnings = append(d.Warnings, fmt.Sprintf("line %d: %s was read as month/day/year", number, text[match[0]:match[1]]))
+		}
+		date, location = buildDate(year, time.Month(month), day), match
+	}
+	if location == nil || date.IsZero() {
+		return text
+	}
+
+	d.Receipt.PurchaseDate = date.Format("2006-01-02")
+	return strings.TrimSpace(text[:location[0]] + " " + text[location[1]:])
+}
+
+// findTime stores the first time of the line and returns the line without it.
+func (d *Draft) findTime(text string) string {
+	var hour, minute int
+	var location []int
+	if match := twelveHourPattern.FindStringSubmatchIndex(text); match != nil {
+		hour, minute, location = atoi(text[match[2]:match[3]]), atoi(text[match[4]:match[5]]), match
+		if hour < 1 || hour > 12 || minute > 59 {
+			return text
+		}
+		hour %= 12
+		if strings.EqualFold(text[match[6]:match[7]], "p") {
+			hour += 12
+		}
+	} else if match := clockPattern.FindStringSubmatchIndex(text); match != nil {
+		hour, minute, location = atoi(text[match[2]:match[3]]), atoi(text[match[4]:match[5]]), match
+	}
+	if location == nil {
+		return text
+	}
+
+	d.Receipt.PurchaseTime = fmt.Sprintf("%02d:%02d", hour, minute)
+	return strings.TrimSpace(text[:location[0]] + " " + text[location[1]:])
+}
+
+func (d *Draft) score(skippedAmongItems []Line) {
+	receipt := d.Receipt
+	confidence := 0.0
+	found := func(ok bool, weight float64, warning string) {
+		if ok {
+			confidence += weight
+		} else {
+			d.Warnings = append(d.Warnings, warning)
+		}
+	}
+
+	found(receipt.Retailer != "", weightRetailer, "no retailer found")
+	found(receipt.PurchaseDate != "", weightDate, "no purchase date found")
+	found(receipt.PurchaseTime != "", weightTime, "no purchase time found")
+	found(len(receipt.Items) > 0, weightItems, "no item found")
+	found(receipt.Total != "", weightTotal, "no total found")
+	if len(receipt.Items) > 0 && receipt.Total != "" {
+		var sum int64
+		for _, item := range receipt.Items {
+			sum += toCents(item.Price)
+		}
+		found(sum == toCents(receipt.Total), weightReconciled, fmt.Sprintf("the items add up to %s, not the total %s", formatCents(sum), receipt.Total))
+	}
+	for _, line := range skippedAmongItems {
+		d.Warnings = append(d.Warnings, fmt.Sprintf("line %d among the items was skipped: %s", line.Number, line.Reason))
+	}
+
+	confidence -= penaltySkipped * float64(len(skippedAmongItems))
+	d.Confidence = math.Max(0, math.Round(confidence*100)/100)
+}
+
+// normalizePrice writes the price with a dot before the cents and no thousands separator.
+func normalizePrice(price string) string {
+	digits := strings.NewReplacer(",", "", ".", "").Replace(price)
+	return digits[:len(digits)-2] + "." + digits[len(digits)-2:]
+}
+
+func buildDate(year int, month time.Month, day int) time.Time {
+	if month < time.January || month > time.December || day < 1 || day > 31 {
+		return time.Time{}
+	}
+	date := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	if date.Day() != day {
+		// e.g. February 30th
+		return time.Time{}
+	}
+	return date
+}
+
+func atoi(digits string) int {
+	value, _ := strconv.Atoi(digits)
+	return value
+}
+
+func toCents(price string) int64 {
+	value, _ := strconv.ParseFloat(price, 64)
+	return int64(math.Round(value * 100))
+}
+
+func formatCents(cents int64) string {
+	return fmt.Sprintf("%d.%02d", cents/100, cents%100)
+}
@@ -0,0 +1,121 @@
+package plaintext
+
+import (
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+const thermalReceipt = `
+        WELCOME TO CORNER MARKET
+          123 Main St, Springfield
+            (555) 010-4477
+------------------------------------
+03/15/2022               2:33 PM
+------------------------------------
+Mountain Dew 12PK          6.49 T
+Emils Cheese Pizza        12.25
+Knorr Creamy Chicken   $   1.26
+COUPON                     1.00-
+Doritos Nacho Cheese       3.35
+------------------------------------
+SUBTOTAL                  23.35
+TAX                        0.00
+TOTAL                     23.35
+VISA TEND                 23.35
+CHANGE DUE                 0.00
+Thank you for shopping!
+`
+
+func TestParse(t *testing.T) {
+	t.Run("Thermal printer receipt", func(t *testing.T) {
+		draft, err := Parse(strings.NewReader(thermalReceipt))
+		require.NoError(t, err)
+
+		assert.Equal(t, "CORNER MARKET", draft.Receipt.Retailer)
+		assert.Equal(t, "2022-03-15", draft.Receipt.PurchaseDate)
+		assert.Equal(t, "14:33", draft.Receipt.PurchaseTime)
+		assert.Equal(t, "23.35", draft.Receipt.Total)
+		assert.Equal(t, []models.ReceiptItem{
+			{ShortDescription: "Mountain Dew 12PK", Price: "6.49"},
+			{ShortDescription: "Emils Cheese Pizza", Price: "12.25"},
+			{ShortDescription: "Knorr Creamy Chicken", Price: "1.26"},
+			{ShortDescription: "Doritos Nacho Cheese", Price: "3.35"},
+		}, draft.Receipt.Items)
+		assert.Equal(t, []Line{
+			{Number: 3, Text: "123 Main St, Springfield", Reason: ReasonNotRecognized},
+			{Number: 4, Text: "(555) 010-4477", Reason: ReasonNotRecognized},
+			{Number: 11, Text: "COUPON 1.00-", Reason: ReasonNegative},
+			{Number: 19, Text: "Thank you for shopping!", Reason: ReasonNotRecognized},
+		}, draft.Unparsed)
+		assert.Equal(t, 0.95, draft.Confidence)
+		assert.Equal(t, []string{"line 11 among the items was skipped: negative amounts are not supported"}, draft.Warnings)
+	})
+
+	t.Run("Email body", func(t *testing.T) {
+		body := "Your order at Target\nPlaced on Jan 2, 2022 at 13:13\n\nPepsi - 12-oz: $1.25\nDasani: $1.40\nTotal: $2.65\n"
+
+		draft, err := Parse(strings.NewReader(body))
+		require.NoError(t, err)
+
+		assert.Equal(t, "Your order at Target", draft.Receipt.Retailer)
+		assert.Equal(t, "2022-01-02", draft.Receipt.PurchaseDate)
+		assert.Equal(t, "13:13", draft.Receipt.PurchaseTime)
+		assert.Len(t, draft.Receipt.Items, 2)
+		assert.Equal(t, "2.65", draft.Receipt.Total)
+		assert.Equal(t, 1.0, draft.Confidence)
+		assert.Empty(t, draft.Warnings)
+	})
+
+	t.Run("Dates", func(t *testing.T) {
+		tests := []struct {
+			line string
+			date string
+			time string
+		}{
+			{"2022-03-20 14:33:00", "2022-03-20", "14:33"},
+			{"Date: 20/03/22 Time: 9:05 am", "2022-03-20", "09:05"},
+			{"12 March 2022 12:00 a.m.", "2022-03-12", "00:00"},
+			{"02.03.2022 18:45", "2022-02-03", "18:45"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.line, func(t *testing.T) {
+				draft, err := Parse(strings.NewReader("Store\n" + tt.line + "\nItem 1.00\nTotal 1.00\n"))
+				require.NoError(t, err)
+
+				assert.Equal(t, tt.date, draft.Receipt.PurchaseDate)
+				assert.Equal(t, tt.time, draft.Receipt.PurchaseTime)
+				assert.Empty(t, draft.Unparsed)
+			})
+		}
+	})
+
+	t.Run("Ambiguous dates are reported", func(t *testing.T) {
+		draft, err := Parse(strings.NewReader("Store\n03/04/2022\nItem 1.00\nTotal 1.00\n"))
+		require.NoError(t, err)
+
+		assert.Equal(t, "2022-03-04", draft.Receipt.PurchaseDate)
+		assert.Contains(t, draft.Warnings, "line 2: 03/04/2022 was read as month/day/year")
+	})
+
+	t.Run("Taxes are not reconciled", func(t *testing.T) {
+		draft, err := Parse(strings.NewReader("Store\n2022-01-01 10:00\nItem 1,234.50\nTax 98.76\nTotal 1,333.26\n"))
+		require.NoError(t, err)
+
+		assert.Equal(t, "1234.50", draft.Receipt.Items[0].Price)
+		assert.Equal(t, "1333.26", draft.Receipt.Total)
+		assert.Equal(t, 0.9, draft.Confidence)
+		assert.Equal(t, []string{"the items add up to 1234.50, not the total 1333.26"}, draft.Warnings)
+	})
+
+	t.Run("Not a receipt", func(t *testing.T) {
+		draft, err := Parse(strings.NewReader("Hello\nHow are you?\n"))
+		require.NoError(t, err)
+
+		assert.Equal(t, 0.2, draft.Confidence)
+		assert.Len(t, draft.Unparsed, 1)
+		assert.Len(t, draft.Warnings, 4)
+	})
+}
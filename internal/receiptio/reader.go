@@ -0,0 +1,295 @@
+package receiptio
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
+	"github.com/google/uuid"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// Fields of models.Receipt that CSV columns can be mapped to. FieldReceipt is not a receipt
+// field: consecutive rows with the same value in its column are the items of one receipt.
+const (
+	FieldReceipt          = "receipt"
+	FieldRetailer         = "retailer"
+	FieldPurchaseDate     = "purchaseDate"
+	FieldPurchaseTime     = "purchaseTime"
+	FieldTotal            = "total"
+	FieldShortDescription = "shortDescription"
+	FieldPrice            = "price"
+)
+
+var requiredFields = []string{FieldRetailer, FieldPurchaseDate, FieldPurchaseTime, FieldTotal, FieldShortDescription, FieldPrice}
+
+// ErrMissingColumn is returned when the header of a CSV file lacks a mapped column
+var ErrMissingColumn = errors.New("the CSV header is missing a column")
+
+// Mapping tells which CSV column holds every receipt field, and how dates and times are
+// written when they are not in the format of the API.
+type Mapping struct {
+	// Columns maps receipt fields to column names. Unmapped fields use the column of the
+	// same name, and the receipt key uses the id column of the exports.
+	Columns map[string]string `json:"columns"`
+	// DateFormat is a Go time layout, e.g. 01/02/2006, converted to 2006-01-02.
+	DateFormat string `json:"dateFormat,omitempty"`
+	// TimeFormat is a Go time layout, e.g. 3:04 PM, converted to 15:04.
+	TimeFormat string `json:"timeFormat,omitempty"`
+}
+
+// LoadMapping reads a JSON mapping file.
+func LoadMapping(path string) (Mapping, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return Mapping{}, err
+	}
+
+	var mapping Mapping
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&mapping); err != nil {
+		return Mapping{}, fmt.Errorf("could not parse the mapping file: %w", err)
+	}
+	for field := range mapping.Columns {
+		if field != FieldReceipt && !contains(requiredFields, field) {
+			return Mapping{}, fmt.Errorf("the mapping file maps the unknown field %q", field)
+		}
+	}
+	return mapping, nil
+}
+
+func (m Mapping) column(field string) string {
+	if column, ok := m.Columns[field]; ok {
+		return column
+	}
+	if field == FieldReceipt {
+		return ColumnID
+	}
+	return field
+}
+
+// Entry is a receipt read from a file. Err is set instead of Receipt when the file content
+// could not be turned into a receipt. Line is the line of the file the receipt starts on.
+type Entry struct {
+	Receipt *models.Receipt
+	Line    int
+	Err     error
+}
+
+// Reader reads the receipts of a file one at a time. Next returns io.EOF after the last one,
+// and any other error means that the file can not be read further.
+type Reader interface {
+	Next() (Entry, error)
+}
+
+// NewReader returns a reader for the format. The mapping only applies to CSV.
+func NewReader(r io.Reader, format Format, mapping Mapping) (Reader, error) {
+	switch format {
+	case FormatNDJSON:
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+		return &ndjsonReader{scanner: scanner}, nil
+	case FormatCSV:
+		return newCSVReader(r, mapping)
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
+
+type ndjsonReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func (r *ndjsonReader) Next() (Entry, error) {
+	for r.scanner.Scan() {
+		r.line++
+		text := bytes.TrimSpace(r.scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+
+		receipt := &models.Receipt{}
+		if err := json.Unmarshal(text, receipt); err != nil {
+			return Entry{Line: r.line, Err: fmt.Errorf("could not parse the receipt: %w", err)}, nil
+		}
+		// The id of exported receipts would otherwise be decoded, and the service assigns it.
+		receipt.ID = uuid.Nil
+		return Entry{Receipt: receipt, Line: r.line}, nil
+	}
+	if err := r.scanner.Err(); err != nil {
+		return Entry{}, err
+	}
+	return Entry{}, io.EOF
+}
+
+type csvReader struct {
+	reader  *csv.Reader
+	mapping Mapping
+	indexes map[string]int
+	// pending is the receipt being assembled from consecutive rows.
+	pending *csvReceipt
+	// queued is a malformed row found while a receipt was pending, returned after it.
+	queued *Entry
+	done   bool
+}
+
+type csvReceipt struct {
+	key     string
+	columns []string
+	entry   Entry
+}
+
+func newCSVReader(r io.Reader, mapping Mapping) (*csvReader, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("%w: the file is empty", ErrMissingColumn)
+	}
+	if err != nil {
+		return nil, err
+	}
+	positions := make(map[string]int, len(header))
+	for i, column := range header {
+		positions[strings.TrimSpace(strings.TrimPrefix(column, "\ufeff"))] = i
+	}
+
+	indexes := make(map[string]int)
+	for _, field := range append([]string{FieldReceipt}, requiredFields...) {
+		index, ok := positions[mapping.column(field)]
+		if !ok {
+			if field == FieldReceipt {
+				continue
+			}
+			return nil, fmt.Errorf("%w: %q, mapped to %s", ErrMissingColumn, mapping.column(field), field)
+		}
+		indexes[field] = index
+	}
+	return &csvReader{reader: reader, mapping: mapping, indexes: indexes}, nil
+}
+
+func (r *csvReader) Next() (Entry, error) {
+	if r.queued != nil {
+		entry := *r.queued
+		r.queued = nil
+		return entry, nil
+	}
+
+	for !r.done {
+		row, err := r.reader.Read()
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			// The row is reported and skipped; the reader resumes on the next line.
+			malformed := Entry{Line: parseErr.StartLine, Err: parseErr}
+			if entry, ok := r.flush(); ok {
+				r.queued = &malformed
+				return entry, nil
+			}
+			return malformed, nil
+		}
+		if err == io.EOF {
+			r.done = true
+			break
+		}
+		if err != nil {
+			return Entry{}, err
+		}
+
+		line, _ := r.reader.FieldPos(0)
+		if entry, ok := r.add(row, line); ok {
+			return entry, nil
+		}
+	}
+
+	if entry, ok := r.flush(); ok {
+		return entry, nil
+	}
+	return Entry{}, io.EOF
+}
+
+// add appends the item of the row to the pending receipt. When the row starts a new receipt,
+// the previous one is complete and returned.
+func (r *csvReader) add(row []string, line int) (Entry, bool) {
+	value := func(field string) string {
+		index, ok := r.indexes[field]
+		if !ok || index >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[index])
+	}
+
+	columns := []string{value(FieldRetailer), value(FieldPurchaseDate), value(FieldPurchaseTime), value(FieldTotal)}
+	key := value(FieldReceipt)
+	if _, ok := r.indexes[FieldReceipt]; !ok {
+		key = strings.Join(columns, "\x00")
+	}
+
+	var complete Entry
+	var ok bool
+	if r.pending == nil || r.pending.key != key {
+		complete, ok = r.flush()
+		r.pending = &csvReceipt{key: key, columns: columns, entry: Entry{Line: line, Receipt: &models.Receipt{}}}
+		r.pending.entry.Err = r.setReceiptColumns(r.pending.entry.Receipt, columns, line)
+	} else if r.pending.entry.Err == nil && strings.Join(columns, "\x00") != strings.Join(r.pending.columns, "\x00") {
+		r.pending.entry.Err = fmt.Errorf("line %d: the receipt columns differ from line %d", line, r.pending.entry.Line)
+	}
+
+	if receipt := r.pending.entry.Receipt; receipt != nil {
+		receipt.Items = append(receipt.Items, models.ReceiptItem{
+			ShortDescription: value(FieldShortDescription),
+			Price:            value(FieldPrice),
+		})
+	}
+	return complete, ok
+}
+
+func (r *csvReader) setReceiptColumns(receipt *models.Receipt, columns []string, line int) error {
+	receipt.Retailer, receipt.PurchaseDate, receipt.PurchaseTime, receipt.Total = columns[0], columns[1], columns[2], columns[3]
+
+	if r.mapping.DateFormat != "" {
+		date, err := time.Parse(r.mapping.DateFormat, receipt.PurchaseDate)
+		if err != nil {
+			return fmt.Errorf("line %d: the purchase date %q does not match %q", line, receipt.PurchaseDate, r.mapping.DateFormat)
+		}
+		receipt.PurchaseDate = date.Format("2006-01-02")
+	}
+	if r.mapping.TimeFormat != "" {
+		purchaseTime, err := time.Parse(r.mapping.TimeFormat, receipt.PurchaseTime)
+		if err != nil {
+			return fmt.Errorf("line %d: the purchase time %q does not match %q", line, receipt.PurchaseTime, r.mapping.TimeFormat)
+		}
+		receipt.PurchaseTime = purchaseTime.Format("15:04")
+	}
+	return nil
+}
+
+func (r *csvReader) flush() (Entry, bool) {
+	if r.pending == nil {
+		return Entry{}, false
+	}
+	entry := r.pending.entry
+	r.pending = nil
+	if entry.Err != nil {
+		entry.Receipt = nil
+	}
+	return entry, true
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
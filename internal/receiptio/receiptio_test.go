@@ -0,0 +1,246 @@
+package receiptio
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testRecords() []Record {
+	points := 28
+	return []Record{
+		{
+			Receipt: &models.Receipt{
+				ID:           uuid.MustParse("7fb1377b-b223-49d9-a31a-5a02701dd310"),
+				Retailer:     "Target",
+				PurchaseDate: "2022-01-01",
+				PurchaseTime: "13:01",
+				Total:        "6.49",
+				Items: []models.ReceiptItem{
+					{ShortDescription: "Mountain Dew 12PK", Price: "6.49"},
+				},
+				Status:    models.ReceiptStatusProcessed,
+				CreatedAt: time.Date(2024, time.May, 1, 10, 0, 0, 0, time.UTC),
+			},
+			Points: &points,
+		},
+		{
+			Receipt: &models.Receipt{
+				ID:           uuid.MustParse("2c5cbd0b-1c9b-4a0b-9f64-0c7f5b2f8f55"),
+				Retailer:     "M&M Corner Market",
+				PurchaseDate: "2022-03-20",
+				PurchaseTime: "14:33",
+				Total:        "9.00",
+				Items: []models.ReceiptItem{
+					{ShortDescription: "Gatorade", Price: "2.25"},
+					{ShortDescription: "Gatorade, Cool Blue", Price: "6.75"},
+				},
+				Status:    models.ReceiptStatusHeld,
+				CreatedAt: time.Date(2024, time.May, 1, 11, 0, 0, 0, time.UTC),
+			},
+		},
+	}
+}
+
+func writeRecords(t *testing.T, format Format, layout Layout) string {
+	t.Helper()
+	var buf bytes.Buffer
+	writer, err := NewWriter(&buf, format, layout)
+	require.NoError(t, err)
+	for _, record := range testRecords() {
+		require.NoError(t, writer.Write(record))
+	}
+	require.NoError(t, writer.Flush())
+	return buf.String()
+}
+
+func readAll(t *testing.T, reader Reader) []Entry {
+	t.Helper()
+	var entries []Entry
+	for {
+		entry, err := reader.Next()
+		if err == io.EOF {
+			return entries
+		}
+		require.NoError(t, err)
+		entries = append(entries, entry)
+	}
+}
+
+func TestWriter(t *testing.T) {
+	t.Run("NDJSON", func(t *testing.T) {
+		lines := strings.Split(strings.TrimSpace(writeRecords(t, FormatNDJSON, "")), "\n")
+
+		require.Len(t, lines, 2)
+		assert.JSONEq(t, `{"id":"7fb1377b-b223-49d9-a31a-5a02701dd310","retailer":"Target","purchaseDate":"2022-01-01","purchaseTime":"13:01","total":"6.49","items":[{"shortDescription":"Mountain Dew 12PK","price":"6.49"}],"status":"processed","createdAt":"2024-05-01T10:00:00Z","points":28}`, lines[0])
+		var held map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(lines[1]), &held))
+		assert.NotContains(t, held, "points")
+	})
+
+	t.Run("NDJSON return", func(t *testing.T) {
+		originalID := uuid.MustParse("7fb1377b-b223-49d9-a31a-5a02701dd310")
+		points := -28
+		var buf bytes.Buffer
+		writer, err := NewWriter(&buf, FormatNDJSON, "")
+		require.NoError(t, err)
+		require.NoError(t, writer.Write(Record{Receipt: &models.Receipt{
+			ID:           uuid.MustParse("0b9c3e47-7b5a-4f6e-9d55-6bdb2a1f4f0e"),
+			Retailer:     "Target",
+			PurchaseDate: "2022-01-05",
+			PurchaseTime: "10:00",
+			Total:        "6.49",
+			Items:        []models.ReceiptItem{{ShortDescription: "Mountain Dew 12PK", Price: "6.49"}},
+			Type:         models.ReceiptTypeReturn,
+			OriginalID:   &originalID,
+			Status:       models.ReceiptStatusProcessed,
+			CreatedAt:    time.Date(2024, time.May, 2, 10, 0, 0, 0, time.UTC),
+		}, Points: &points}))
+		require.NoError(t, writer.Flush())
+
+		assert.JSONEq(t, `{"id":"0b9c3e47-7b5a-4f6e-9d55-6bdb2a1f4f0e","retailer":"Target","purchaseDate":"2022-01-05","purchaseTime":"10:00","total":"6.49","items":[{"shortDescription":"Mountain Dew 12PK","price":"6.49"}],"type":"return","originalReceiptId":"7fb1377b-b223-49d9-a31a-5a02701dd310","status":"processed","createdAt":"2024-05-02T10:00:00Z","points":-28}`, buf.String())
+	})
+
+	t.Run("CSV with a row per receipt", func(t *testing.T) {
+		expected := "id,retailer,purchaseDate,purchaseTime,total,status,createdAt,points,itemCount\n" +
+			"7fb1377b-b223-49d9-a31a-5a02701dd310,Target,2022-01-01,13:01,6.49,processed,2024-05-01T10:00:00Z,28,1\n" +
+			"2c5cbd0b-1c9b-4a0b-9f64-0c7f5b2f8f55,M&M Corner Market,2022-03-20,14:33,9.00,held,2024-05-01T11:00:00Z,,2\n"
+
+		assert.Equal(t, expected, writeRecords(t, FormatCSV, LayoutReceipt))
+	})
+
+	t.Run("CSV with a row per item", func(t *testing.T) {
+		lines := strings.Split(strings.TrimSpace(writeRecords(t, FormatCSV, LayoutItem)), "\n")
+
+		require.Len(t, lines, 4)
+		assert.Equal(t, "id,retailer,purchaseDate,purchaseTime,total,status,createdAt,points,shortDescription,price", lines[0])
+		assert.Equal(t, `2c5cbd0b-1c9b-4a0b-9f64-0c7f5b2f8f55,M&M Corner Market,2022-03-20,14:33,9.00,held,2024-05-01T11:00:00Z,,"Gatorade, Cool Blue",6.75`, lines[3])
+	})
+
+	t.Run("Unknown format and layout", func(t *testing.T) {
+		_, err := NewWriter(io.Discard, "xml", "")
+		assert.ErrorIs(t, err, ErrUnknownFormat)
+		_, err = NewWriter(io.Discard, FormatCSV, "")
+		assert.ErrorIs(t, err, ErrUnknownLayout)
+	})
+}
+
+func TestReader(t *testing.T) {
+	t.Run("Exports can be imported back", func(t *testing.T) {
+		for _, format := range []Format{FormatNDJSON, FormatCSV} {
+			reader, err := NewReader(strings.NewReader(writeRecords(t, format, LayoutItem)), format, Mapping{})
+			require.NoError(t, err)
+
+			entries := readAll(t, reader)
+
+			require.Len(t, entries, 2, format)
+			for i, record := range testRecords() {
+				require.NoError(t, entries[i].Err)
+				assert.Equal(t, uuid.Nil, entries[i].Receipt.ID)
+				assert.Equal(t, record.Receipt.Retailer, entries[i].Receipt.Retailer)
+				assert.Equal(t, record.Receipt.Total, entries[i].Receipt.Total)
+				assert.Equal(t, record.Receipt.Items, entries[i].Receipt.Items)
+			}
+		}
+	})
+
+	t.Run("Mapped columns and formats", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "mapping.json")
+		require.NoError(t, os.WriteFile(path, []byte(`{
+			"columns": {"retailer": "Store", "purchaseDate": "Date", "purchaseTime": "Time", "total": "Amount", "shortDescription": "Item", "price": "Price", "receipt": "Ticket"},
+			"dateFormat": "01/02/2006",
+			"timeFormat": "3:04 PM"
+		}`), 0o600))
+		mapping, err := LoadMapping(path)
+		require.NoError(t, err)
+
+		input := "Ticket,Store,Date,Time,Amount,Item,Price,Cashier\n" +
+			"1,Target,01/02/2022,1:13 PM,2.50,Pepsi,1.25,Ann\n" +
+			"1,Target,01/02/2022,1:13 PM,2.50,Dasani,1.25,Ann\n" +
+			"2,Target,13/40/2022,1:13 PM,1.25,Pepsi,1.25,Bob\n" +
+			"3,Walgreens,01/03/2022,8:13 AM,1.25,Pepsi,1.25,Ann\n" +
+			"3,Walgreens,01/03/2022,8:13 AM,9.99,Dasani,1.25,Ann\n"
+		reader, err := NewReader(strings.NewReader(input), FormatCSV, mapping)
+		require.NoError(t, err)
+
+		entries := readAll(t, reader)
+
+		require.Len(t, entries, 3)
+		assert.Equal(t, &models.Receipt{
+			Retailer:     "Target",
+			PurchaseDate: "2022-01-02",
+			PurchaseTime: "13:13",
+			Total:        "2.50",
+			Items:        []models.ReceiptItem{{ShortDescription: "Pepsi", Price: "1.25"}, {ShortDescription: "Dasani", Price: "1.25"}},
+		}, entries[0].Receipt)
+		assert.Equal(t, 4, entries[1].Line)
+		assert.EqualError(t, entries[1].Err, `line 4: the purchase date "13/40/2022" does not match "01/02/2006"`)
+		assert.Equal(t, 5, entries[2].Line)
+		assert.EqualError(t, entries[2].Err, "line 6: the receipt columns differ from line 5")
+	})
+
+	t.Run("Rows without a receipt column are grouped by their receipt fields", func(t *testing.T) {
+		input := "retailer,purchaseDate,purchaseTime,total,shortDescription,price\n" +
+			"Target,2022-01-02,13:13,2.50,Pepsi,1.25\n" +
+			"Target,2022-01-02,13:13,2.50,Dasani,1.25\n" +
+			"Target,2022-01-02,13:14,2.50,Pepsi,1.25\n"
+		reader, err := NewReader(strings.NewReader(input), FormatCSV, Mapping{})
+		require.NoError(t, err)
+
+		entries := readAll(t, reader)
+
+		require.Len(t, entries, 2)
+		assert.Len(t, entries[0].Receipt.Items, 2)
+		assert.Len(t, entries[1].Receipt.Items, 1)
+	})
+
+	t.Run("Malformed rows are reported", func(t *testing.T) {
+		input := "retailer,purchaseDate,purchaseTime,total,shortDescription,price\n" +
+			"Target,2022-01-02,13:13,1.25,Pepsi,1.25\n" +
+			"Target,2022-01-02,13:14,1.25,\"Pepsi,1.25\n"
+		reader, err := NewReader(strings.NewReader(input), FormatCSV, Mapping{})
+		require.NoError(t, err)
+
+		entries := readAll(t, reader)
+
+		require.Len(t, entries, 2)
+		assert.NoError(t, entries[0].Err)
+		assert.Error(t, entries[1].Err)
+		assert.Equal(t, 3, entries[1].Line)
+	})
+
+	t.Run("Missing column", func(t *testing.T) {
+		_, err := NewReader(strings.NewReader("retailer,total\n"), FormatCSV, Mapping{})
+
+		assert.ErrorIs(t, err, ErrMissingColumn)
+	})
+
+	t.Run("Unknown field in the mapping", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "mapping.json")
+		require.NoError(t, os.WriteFile(path, []byte(`{"columns": {"cashier": "Cashier"}}`), 0o600))
+
+		_, err := LoadMapping(path)
+
+		assert.ErrorContains(t, err, "cashier")
+	})
+
+	t.Run("Invalid NDJSON line", func(t *testing.T) {
+		reader, err := NewReader(strings.NewReader("\n{\"retailer\":\n"), FormatNDJSON, Mapping{})
+		require.NoError(t, err)
+
+		entries := readAll(t, reader)
+
+		require.Len(t, entries, 1)
+		assert.Equal(t, 2, entries[0].Line)
+		assert.Error(t, entries[0].Err)
+	})
+}
@@ -0,0 +1,329 @@
+// Package ubl parses OASIS UBL 2.1 invoices, as exchanged over Peppol, into receipts. The type
+// and field names follow the UBL element names.
+package ubl
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
+	"github.com/CarlosMtz98/receipt-processor-challenge/pkg/currency"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// InvoiceNamespace is the namespace of the Invoice root element.
+	InvoiceNamespace = "urn:oasis:names:specification:ubl:schema:xsd:Invoice-2"
+	// CreditNoteNamespace is the namespace of the CreditNote root element, which is recognized
+	// only to reject it with a clear error.
+	CreditNoteNamespace = "urn:oasis:names:specification:ubl:schema:xsd:CreditNote-2"
+
+	// TypeCommercialInvoice is the InvoiceTypeCode (UNTDID 1001) of a sale.
+	TypeCommercialInvoice = "380"
+)
+
+var (
+	// ErrInvalidUBL is returned when the document is not a valid UBL 2.1 invoice
+	ErrInvalidUBL = errors.New("the document is not a valid UBL 2.1 invoice")
+	// ErrUnsupported is wrapped by the problems of valid invoices that cannot be mapped to a receipt
+	ErrUnsupported = errors.New("not supported")
+
+	amountPattern   = regexp.MustCompile(`^\d+(\.\d+)?$`)
+	currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+	// IssueTime may carry fractional seconds and a UTC offset; the receipt keeps the local time.
+	issueTimePattern = regexp.MustCompile(`^(\d{2}:\d{2}):\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})?$`)
+)
+
+// Invoice is the root element of a UBL invoice.
+type Invoice struct {
+	XMLName                 xml.Name
+	UBLVersionID            string            `xml:"UBLVersionID"`
+	ID                      string            `xml:"ID"`
+	UUID                    string            `xml:"UUID"`
+	IssueDate               string            `xml:"IssueDate"`
+	IssueTime               string            `xml:"IssueTime"`
+	InvoiceTypeCode         string            `xml:"InvoiceTypeCode"`
+	DocumentCurrencyCode    string            `xml:"DocumentCurrencyCode"`
+	AccountingSupplierParty Party             `xml:"AccountingSupplierParty>Party"`
+	AllowanceCharges        []AllowanceCharge `xml:"AllowanceCharge"`
+	LegalMonetaryTotal      MonetaryTotal     `xml:"LegalMonetaryTotal"`
+	InvoiceLines            []InvoiceLine     `xml:"InvoiceLine"`
+}
+
+// Party is the supplier that issued the invoice.
+type Party struct {
+	EndpointID       string           `xml:"EndpointID"`
+	PartyNames       []string         `xml:"PartyName>Name"`
+	PartyTaxSchemes  []PartyTaxScheme `xml:"PartyTaxScheme"`
+	PartyLegalEntity PartyLegalEntity `xml:"PartyLegalEntity"`
+}
+
+type PartyTaxScheme struct {
+	CompanyID string `xml:"CompanyID"`
+}
+
+type PartyLegalEntity struct {
+	RegistrationName string `xml:"RegistrationName"`
+	CompanyID        string `xml:"CompanyID"`
+}
+
+// Amount is a monetary amount with its currency.
+type Amount struct {
+	Value      string `xml:",chardata"`
+	CurrencyID string `xml:"currencyID,attr"`
+}
+
+// AllowanceCharge is a discount (ChargeIndicator false) or a charge.
+type AllowanceCharge struct {
+	ChargeIndicator string `xml:"ChargeIndicator"`
+	Amount          Amount `xml:"Amount"`
+}
+
+// MonetaryTotal holds the totals of the invoice. PayableAmount is what the buyer pays.
+type MonetaryTotal struct {
+	LineExtensionAmount   *Amount `xml:"LineExtensionAmount"`
+	PrepaidAmount         *Amount `xml:"PrepaidAmount"`
+	PayableRoundingAmount *Amount `xml:"PayableRoundingAmount"`
+	PayableAmount         *Amount `xml:"PayableAmount"`
+}
+
+// InvoiceLine is a line of the invoice. LineExtensionAmount is the net amount of the line, after
+// its own allowances and charges and before VAT.
+type InvoiceLine struct {
+	ID                  string `xml:"ID"`
+	LineExtensionAmount Amount `xml:"LineExtensionAmount"`
+	Item                Item   `xml:"Item"`
+}
+
+type Item struct {
+	Name                  string       `xml:"Name"`
+	ClassifiedTaxCategory *TaxCategory `xml:"ClassifiedTaxCategory"`
+}
+
+// TaxCategory is the VAT category of a line. Exempt and zero rated categories have no Percent.
+type TaxCategory struct {
+	ID      string `xml:"ID"`
+	Percent string `xml:"Percent"`
+}
+
+// Parse decodes and validates a UBL invoice.
+func Parse(r io.Reader) (*Invoice, error) {
+	var invoice Invoice
+	if err := xml.NewDecoder(r).Decode(&invoice); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidUBL, err)
+	}
+	if err := invoice.Validate(); err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+// Validate checks the elements the receipt is built from. Problems wrapping ErrUnsupported are
+// valid UBL that has no equivalent on a receipt, such as credit notes, allowances on the whole
+// invoice or prepaid amounts.
+func (i *Invoice) Validate() error {
+	if i.XMLName.Space == CreditNoteNamespace {
+		return fmt.Errorf("%w: %w: CreditNote documents, only Invoice", ErrInvalidUBL, ErrUnsupported)
+	}
+	if i.XMLName.Space != InvoiceNamespace || i.XMLName.Local != "Invoice" {
+		return fmt.Errorf("%w: the root element must be an Invoice of the %s namespace", ErrInvalidUBL, InvoiceNamespace)
+	}
+
+	var problems []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Errorf(format, args...))
+		}
+	}
+	unsupported := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Errorf("%w: "+format, append([]interface{}{ErrUnsupported}, args...)...))
+		}
+	}
+	documentCurrency := i.DocumentCurrencyCode
+	checkAmount := func(amount *Amount, name string) {
+		if amount == nil {
+			return
+		}
+		check(amountPattern.MatchString(strings.TrimSpace(amount.Value)), "%s %q is not a positive amount", name, amount.Value)
+		unsupported(amount.CurrencyID == "" || amount.CurrencyID == documentCurrency, "%s in %s while the DocumentCurrencyCode is %s", name, amount.CurrencyID, documentCurrency)
+	}
+
+	check(i.UBLVersionID == "" || i.UBLVersionID == "2.1", "UBLVersionID must be 2.1, got %q", i.UBLVersionID)
+	check(strings.TrimSpace(i.ID) != "", "the invoice has no ID")
+	_, err := time.Parse("2006-01-02", i.IssueDate)
+	check(err == nil, "IssueDate must be written as YYYY-MM-DD, got %q", i.IssueDate)
+	check(i.IssueTime == "" || issueTimePattern.MatchString(i.IssueTime), "IssueTime must be written as hh:mm:ss, got %q", i.IssueTime)
+	unsupported(i.InvoiceTypeCode == "" || i.InvoiceTypeCode == TypeCommercialInvoice, "InvoiceTypeCode %s, only %s commercial invoices", i.InvoiceTypeCode, TypeCommercialInvoice)
+	check(currencyPattern.MatchString(documentCurrency), "DocumentCurrencyCode must be an ISO 4217 code, got %q", documentCurrency)
+	unsupported(currency.MinorUnits(documentCurrency) <= 2, "DocumentCurrencyCode %s, whose amounts have more than two decimals", documentCurrency)
+
+	check(i.Retailer() != "", "the AccountingSupplierParty has no PartyName nor RegistrationName")
+
+	unsupported(len(i.AllowanceCharges) == 0, "AllowanceCharge on the whole invoice, only on its lines")
+	checkAmount(i.LegalMonetaryTotal.LineExtensionAmount, "LegalMonetaryTotal LineExtensionAmount")
+	checkAmount(i.LegalMonetaryTotal.PrepaidAmount, "PrepaidAmount")
+	unsupported(i.LegalMonetaryTotal.PrepaidAmount == nil || toCents(parseAmount(i.LegalMonetaryTotal.PrepaidAmount)) == 0, "PrepaidAmount")
+	if rounding := i.LegalMonetaryTotal.PayableRoundingAmount; rounding != nil {
+		// The rounding is the only amount that can be negative.
+		value := strings.TrimPrefix(strings.TrimSpace(rounding.Value), "-")
+		checkAmount(&Amount{Value: value, CurrencyID: rounding.CurrencyID}, "PayableRoundingAmount")
+	}
+	check(i.LegalMonetaryTotal.PayableAmount != nil, "the LegalMonetaryTotal has no PayableAmount")
+	checkAmount(i.LegalMonetaryTotal.PayableAmount, "PayableAmount")
+
+	check(len(i.InvoiceLines) > 0, "the invoice has no InvoiceLine")
+	var lines float64
+	for _, line := range i.InvoiceLines {
+		name := fmt.Sprintf("InvoiceLine %s", line.ID)
+		check(strings.TrimSpace(line.Item.Name) != "", "%s has no Item Name", name)
+		checkAmount(&line.LineExtensionAmount, name+" LineExtensionAmount")
+		if category := line.Item.ClassifiedTaxCategory; category != nil {
+			check(category.Percent == "" || amountPattern.MatchString(category.Percent), "the VAT Percent %q of %s is not a number", category.Percent, name)
+		}
+		lines += parseAmount(&line.LineExtensionAmount)
+	}
+	if total := i.LegalMonetaryTotal.LineExtensionAmount; total != nil && amountPattern.MatchString(strings.TrimSpace(total.Value)) {
+		check(toCents(lines) == toCents(parseAmount(total)), "LegalMonetaryTotal LineExtensionAmount %s is not the sum of the lines", total.Value)
+	}
+
+	if len(problems) == 0 {
+		// The lines are priced with their VAT, so they can only be off the PayableAmount by the
+		// rounding of every line.
+		prices, payable := i.linePrices()
+		var sum int64
+		for _, price := range prices {
+			sum += price
+		}
+		check(abs(payable-sum) <= int64(len(prices)), "the lines and their VAT add up to %s, not the PayableAmount %s", formatCents(sum), formatCents(payable))
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %w", ErrInvalidUBL, errors.Join(problems...))
+	}
+	return nil
+}
+
+// Retailer returns the trading name of the supplier, or its legal name when it has none.
+func (i *Invoice) Retailer() string {
+	for _, name := range i.AccountingSupplierParty.PartyNames {
+		if name = strings.TrimSpace(name); name != "" {
+			return name
+		}
+	}
+	return strings.TrimSpace(i.AccountingSupplierParty.PartyLegalEntity.RegistrationName)
+}
+
+// Receipt maps a validated invoice to a receipt. Every item is priced at the LineExtensionAmount
+// of its line plus the VAT of its category, and the last item absorbs the rounding of the VAT
+// and the PayableRoundingAmount, so that the items add up to the PayableAmount. Invoices without
+// an IssueTime are dated at midnight.
+func (i *Invoice) Receipt() *models.Receipt {
+	purchaseTime := "00:00"
+	if match := issueTimePattern.FindStringSubmatch(i.IssueTime); match != nil {
+		purchaseTime = match[1]
+	}
+	prices, payable := i.linePrices()
+	receipt := &models.Receipt{
+		Retailer:     i.Retailer(),
+		PurchaseDate: i.IssueDate,
+		PurchaseTime: purchaseTime,
+		Total:        formatAmount(payable, i.DocumentCurrencyCode),
+		Currency:     i.DocumentCurrencyCode,
+		Invoice: &models.InvoiceReference{
+			Format:   models.InvoiceFormatUBL,
+			Issuer:   i.issuer(),
+			Number:   strings.TrimSpace(i.ID),
+			UUID:     strings.TrimSpace(i.UUID),
+			Currency: i.DocumentCurrencyCode,
+		},
+	}
+
+	var sum int64
+	for _, price := range prices {
+		sum += price
+	}
+	prices[len(prices)-1] += payable - sum
+
+	for n, line := range i.InvoiceLines {
+		receipt.Items = append(receipt.Items, models.ReceiptItem{
+			ShortDescription: strings.TrimSpace(line.Item.Name),
+			Price:            formatAmount(prices[n], i.DocumentCurrencyCode),
+		})
+	}
+	return receipt
+}
+
+// linePrices returns the price of every line with its VAT and the PayableAmount without its
+// rounding, in cents.
+func (i *Invoice) linePrices() ([]int64, int64) {
+	prices := make([]int64, len(i.InvoiceLines))
+	for n, line := range i.InvoiceLines {
+		net := parseAmount(&line.LineExtensionAmount)
+		var percent float64
+		if category := line.Item.ClassifiedTaxCategory; category != nil {
+			percent, _ = strconv.ParseFloat(category.Percent, 64)
+		}
+		prices[n] = toCents(net * (1 + percent/100))
+	}
+	payable := toCents(parseAmount(i.LegalMonetaryTotal.PayableAmount))
+	if rounding := i.LegalMonetaryTotal.PayableRoundingAmount; rounding != nil {
+		prices[len(prices)-1] += toCents(parseAmount(rounding))
+	}
+	return prices, payable
+}
+
+// issuer prefers the VAT number of the supplier, which is the same on every network it uses.
+func (i *Invoice) issuer() string {
+	party := i.AccountingSupplierParty
+	for _, scheme := range party.PartyTaxSchemes {
+		if id := strings.TrimSpace(scheme.CompanyID); id != "" {
+			return id
+		}
+	}
+	for _, id := range []string{party.PartyLegalEntity.CompanyID, party.EndpointID} {
+		if id = strings.TrimSpace(id); id != "" {
+			return id
+		}
+	}
+	return i.Retailer()
+}
+
+// parseAmount returns 0 for the missing optional amounts; the required ones are validated first.
+func parseAmount(amount *Amount) float64 {
+	if amount == nil {
+		return 0
+	}
+	value, _ := strconv.ParseFloat(strings.TrimSpace(amount.Value), 64)
+	return value
+}
+
+func toCents(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+func formatCents(cents int64) string {
+	sign := ""
+	if cents < 0 {
+		sign, cents = "-", -cents
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100)
+}
+
+// formatAmount writes an amount computed in cents with the decimals of the currency, e.g.
+// without the cents for yen.
+func formatAmount(cents int64, code string) string {
+	return currency.FormatAmount(currency.ToMinor(float64(cents)/100, code), code)
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
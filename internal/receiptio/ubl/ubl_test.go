@@ -0,0 +1,99 @@
+package ubl
+
+import (
+	"errors"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"strings"
+	"testing"
+)
+
+func readExample(t *testing.T) string {
+	t.Helper()
+	content, err := os.ReadFile("../../../examples/ubl-invoice.xml")
+	require.NoError(t, err)
+	return string(content)
+}
+
+func TestParse(t *testing.T) {
+	t.Run("Example invoice", func(t *testing.T) {
+		invoice, err := Parse(strings.NewReader(readExample(t)))
+		require.NoError(t, err)
+
+		receipt := invoice.Receipt()
+
+		assert.Equal(t, "De Hoek Supermarkt", receipt.Retailer)
+		assert.Equal(t, "2023-11-07", receipt.PurchaseDate)
+		assert.Equal(t, "15:10", receipt.PurchaseTime)
+		assert.Equal(t, "23.81", receipt.Total)
+		assert.Equal(t, "EUR", receipt.Currency)
+		assert.Equal(t, []models.ReceiptItem{
+			{ShortDescription: "Koffiebonen 1 kg", Price: "13.52"},
+			{ShortDescription: "Stroopwafels", Price: "5.45"},
+			{ShortDescription: "Afwasmiddel", Price: "4.84"},
+		}, receipt.Items)
+		assert.Equal(t, &models.InvoiceReference{
+			Format:   models.InvoiceFormatUBL,
+			Issuer:   "NL123456789B01",
+			Number:   "INV-2023-0457",
+			Currency: "EUR",
+		}, receipt.Invoice)
+		valid, err := receipt.IsValid(0)
+		assert.NoError(t, err)
+		assert.True(t, valid)
+	})
+
+	t.Run("Payable rounding and no issue time", func(t *testing.T) {
+		document := strings.NewReplacer(
+			"<cbc:IssueTime>15:10:00</cbc:IssueTime>", "",
+			`<cbc:PayableAmount currencyID="EUR">23.81</cbc:PayableAmount>`,
+			`<cbc:PayableRoundingAmount currencyID="EUR">-0.01</cbc:PayableRoundingAmount>
+    <cbc:PayableAmount currencyID="EUR">23.80</cbc:PayableAmount>`,
+			"<cac:PartyName>\n        <cbc:Name>De Hoek Supermarkt</cbc:Name>\n      </cac:PartyName>", "",
+		).Replace(readExample(t))
+
+		invoice, err := Parse(strings.NewReader(document))
+		require.NoError(t, err)
+		receipt := invoice.Receipt()
+
+		assert.Equal(t, "De Hoek Supermarkt B.V.", receipt.Retailer)
+		assert.Equal(t, "00:00", receipt.PurchaseTime)
+		assert.Equal(t, "23.80", receipt.Total)
+		assert.Equal(t, "4.83", receipt.Items[2].Price)
+	})
+
+	t.Run("Invalid documents", func(t *testing.T) {
+		tests := []struct {
+			name        string
+			replacer    *strings.Replacer
+			problem     string
+			unsupported bool
+		}{
+			{"Credit note", strings.NewReplacer("<Invoice xmlns=\"urn:oasis:names:specification:ubl:schema:xsd:Invoice-2\"", "<CreditNote xmlns=\"urn:oasis:names:specification:ubl:schema:xsd:CreditNote-2\"", "</Invoice>", "</CreditNote>"), "CreditNote", true},
+			{"Other document", strings.NewReplacer("xsd:Invoice-2", "xsd:Order-2"), "root element", false},
+			{"Version", strings.NewReplacer("<cbc:UBLVersionID>2.1</cbc:UBLVersionID>", "<cbc:UBLVersionID>2.0</cbc:UBLVersionID>"), "UBLVersionID", false},
+			{"Issue date", strings.NewReplacer("2023-11-07", "07-11-2023"), "IssueDate", false},
+			{"Prepayment invoice", strings.NewReplacer("<cbc:InvoiceTypeCode>380", "<cbc:InvoiceTypeCode>386"), "InvoiceTypeCode 386", true},
+			{"Line in another currency", strings.NewReplacer(`<cbc:LineExtensionAmount currencyID="EUR">4.00`, `<cbc:LineExtensionAmount currencyID="USD">4.00`), "InvoiceLine 3 LineExtensionAmount in USD", true},
+			{"Currency with three decimals", strings.NewReplacer("EUR", "KWD"), "DocumentCurrencyCode KWD", true},
+			{"Invoice allowance", strings.NewReplacer("<cac:TaxTotal>", "<cac:AllowanceCharge><cbc:ChargeIndicator>false</cbc:ChargeIndicator><cbc:Amount currencyID=\"EUR\">1.00</cbc:Amount></cac:AllowanceCharge>\n  <cac:TaxTotal>"), "AllowanceCharge", true},
+			{"Prepaid", strings.NewReplacer("<cbc:PayableAmount", "<cbc:PrepaidAmount currencyID=\"EUR\">10.00</cbc:PrepaidAmount>\n    <cbc:PayableAmount"), "PrepaidAmount", true},
+			{"Negative line", strings.NewReplacer(`currencyID="EUR">5.00</cbc:LineExtensionAmount>`, `currencyID="EUR">-5.00</cbc:LineExtensionAmount>`), "InvoiceLine 2 LineExtensionAmount \"-5.00\"", false},
+			{"Line total", strings.NewReplacer(`<cbc:LineExtensionAmount currencyID="EUR">21.40`, `<cbc:LineExtensionAmount currencyID="EUR">22.40`), "not the sum of the lines", false},
+			{"Payable amount", strings.NewReplacer(`<cbc:PayableAmount currencyID="EUR">23.81`, `<cbc:PayableAmount currencyID="EUR">25.00`), "add up to 23.81, not the PayableAmount 25.00", false},
+			{"Item name", strings.NewReplacer("<cbc:Name>Stroopwafels</cbc:Name>", ""), "InvoiceLine 2 has no Item Name", false},
+			{"Not XML", strings.NewReplacer("<Invoice", "{"), "not a valid UBL", false},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				_, err := Parse(strings.NewReader(tt.replacer.Replace(readExample(t))))
+
+				assert.ErrorIs(t, err, ErrInvalidUBL)
+				assert.ErrorContains(t, err, tt.problem)
+				assert.Equal(t, tt.unsupported, errors.Is(err, ErrUnsupported))
+			})
+		}
+	})
+}
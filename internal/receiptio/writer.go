@@ -0,0 +1,233 @@
+// Package receiptio reads and writes receipts in the file formats used to move them in bulk.
+package receiptio
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
+	"github.com/google/uuid"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Format is the encoding of a receipts file.
+type Format string
+
+const (
+	// FormatNDJSON files hold one JSON receipt per line.
+	FormatNDJSON Format = "ndjson"
+	// FormatCSV files hold one receipt or one item per row, depending on the Layout.
+	FormatCSV Format = "csv"
+)
+
+// Layout is the way receipts are flattened into CSV rows.
+type Layout string
+
+const (
+	// LayoutReceipt writes a row per receipt, with the number of items instead of the items.
+	LayoutReceipt Layout = "receipt"
+	// LayoutItem writes a row per item, repeating the receipt columns on each of them.
+	LayoutItem Layout = "item"
+)
+
+var (
+	// ErrUnknownFormat is returned for a format other than ndjson and csv
+	ErrUnknownFormat = errors.New("the format must be ndjson or csv")
+	// ErrUnknownLayout is returned for a layout other than receipt and item
+	ErrUnknownLayout = errors.New("the layout must be receipt or item")
+)
+
+// Column names of the exported CSV files. They are also the default mapping of the importer,
+// so that an export with the item layout can be imported back.
+const (
+	ColumnID               = "id"
+	ColumnRetailer         = "retailer"
+	ColumnPurchaseDate     = "purchaseDate"
+	ColumnPurchaseTime     = "purchaseTime"
+	ColumnTotal            = "total"
+	ColumnStatus           = "status"
+	ColumnCreatedAt        = "createdAt"
+	ColumnPoints           = "points"
+	ColumnItemCount        = "itemCount"
+	ColumnShortDescription = "shortDescription"
+	ColumnPrice            = "price"
+)
+
+var (
+	receiptColumns = []string{ColumnID, ColumnRetailer, ColumnPurchaseDate, ColumnPurchaseTime, ColumnTotal, ColumnStatus, ColumnCreatedAt, ColumnPoints}
+	itemColumns    = []string{ColumnShortDescription, ColumnPrice}
+)
+
+// Record is an exported receipt. Points is nil for the receipts that are not scored.
+type Record struct {
+	Receipt *models.Receipt
+	Points  *int
+}
+
+type exportedReceipt struct {
+	ID       string `json:"id"`
+	Retailer string `json:"retailer"`
+	// RetailerID and RetailerName are only exported for the receipts of registered retailers.
+	RetailerID   *uuid.UUID           `json:"retailerId,omitempty"`
+	RetailerName string               `json:"retailerName,omitempty"`
+	PurchaseDate string               `json:"purchaseDate"`
+	PurchaseTime string               `json:"purchaseTime"`
+	Total        string               `json:"total"`
+	Items        []models.ReceiptItem `json:"items"`
+	// Type and OriginalID are only exported for returns.
+	Type       models.ReceiptType   `json:"type,omitempty"`
+	OriginalID *uuid.UUID           `json:"originalReceiptId,omitempty"`
+	Status     models.ReceiptStatus `json:"status"`
+	CreatedAt  string               `json:"createdAt"`
+	Points     *int                 `json:"points,omitempty"`
+}
+
+// Writer encodes records one at a time, so that exports can be streamed.
+type Writer interface {
+	Write(record Record) error
+	// Flush writes the buffered records to the underlying writer.
+	Flush() error
+	// ContentType is the media type of the encoded records.
+	ContentType() string
+}
+
+// NewWriter returns a writer for the format. The layout only applies to CSV.
+func NewWriter(w io.Writer, format Format, layout Layout) (Writer, error) {
+	switch format {
+	case FormatNDJSON:
+		return &ndjsonWriter{encoder: json.NewEncoder(w)}, nil
+	case FormatCSV:
+		if layout != LayoutReceipt && layout != LayoutItem {
+			return nil, ErrUnknownLayout
+		}
+		return &csvWriter{writer: csv.NewWriter(w), layout: layout}, nil
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
+
+type ndjsonWriter struct {
+	encoder *json.Encoder
+}
+
+func (w *ndjsonWriter) Write(record Record) error {
+	receipt := record.Receipt
+	return w.encoder.Encode(exportedReceipt{
+		ID:           receipt.ID.String(),
+		Retailer:     receipt.Retailer,
+		RetailerID:   receipt.RetailerID,
+		RetailerName: receipt.RetailerName,
+		PurchaseDate: receipt.PurchaseDate,
+		PurchaseTime: receipt.PurchaseTime,
+		Total:        receipt.Total,
+		Items:        receipt.Items,
+		Type:         receipt.Type,
+		OriginalID:   receipt.OriginalID,
+		Status:       receipt.Status,
+		CreatedAt:    formatCreatedAt(receipt.CreatedAt),
+		Points:       record.Points,
+	})
+}
+
+// Flush is a no-op: the encoder writes every record as soon as it is encoded.
+func (w *ndjsonWriter) Flush() error {
+	return nil
+}
+
+func (w *ndjsonWriter) ContentType() string {
+	return "application/x-ndjson"
+}
+
+type csvWriter struct {
+	writer        *csv.Writer
+	layout        Layout
+	headerWritten bool
+}
+
+func (w *csvWriter) Write(record Record) error {
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+
+	receipt := record.Receipt
+	points := ""
+	if record.Points != nil {
+		points = strconv.Itoa(*record.Points)
+	}
+	columns := []string{
+		receipt.ID.String(),
+		receipt.Retailer,
+		receipt.PurchaseDate,
+		receipt.PurchaseTime,
+		receipt.Total,
+		string(receipt.Status),
+		formatCreatedAt(receipt.CreatedAt),
+		points,
+	}
+
+	if w.layout == LayoutReceipt {
+		return w.writer.Write(append(columns, strconv.Itoa(len(receipt.Items))))
+	}
+	for _, item := range receipt.Items {
+		if err := w.writer.Write(append(columns[:len(columns):len(columns)], item.ShortDescription, item.Price)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Flush also writes the header when no record was written, so that empty exports still
+// describe their columns.
+func (w *csvWriter) Flush() error {
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+func (w *csvWriter) writeHeader() error {
+	if w.headerWritten {
+		return nil
+	}
+	header := append([]string{}, receiptColumns...)
+	if w.layout == LayoutItem {
+		header = append(header, itemColumns...)
+	} else {
+		header = append(header, ColumnItemCount)
+	}
+	w.headerWritten = true
+	return w.writer.Write(header)
+}
+
+func (w *csvWriter) ContentType() string {
+	return "text/csv; charset=utf-8"
+}
+
+func formatCreatedAt(createdAt time.Time) string {
+	if createdAt.IsZero() {
+		return ""
+	}
+	return createdAt.UTC().Format(time.RFC3339)
+}
+
+// ParseFormat returns the format with the given name.
+func ParseFormat(format string) (Format, error) {
+	switch Format(format) {
+	case FormatNDJSON, FormatCSV:
+		return Format(format), nil
+	}
+	return "", fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+}
+
+// ParseLayout returns the layout with the given name.
+func ParseLayout(layout string) (Layout, error) {
+	switch Layout(layout) {
+	case LayoutReceipt, LayoutItem:
+		return Layout(layout), nil
+	}
+	return "", fmt.Errorf("%w: %q", ErrUnknownLayout, layout)
+}
@@ -0,0 +1,258 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	receiptprocessor "github.com/CarlosMtz98/receipt-processor-challenge"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/auth"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/config"
+	fraudService "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/fraud/service"
+	mailHttp "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/mail/delivery/http"
+	mailService "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/mail/service"
+	receiptGraphQL "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/delivery/graphql"
+	receiptGrpc "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/delivery/grpc"
+	receiptHttp "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/delivery/http"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/repository"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/service"
+	retailerHttp "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/retailer/delivery/http"
+	retailerRepository "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/retailer/repository"
+	retailerService "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/retailer/service"
+	reviewHttp "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/review/delivery/http"
+	reviewRepository "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/review/repository"
+	reviewService "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/review/service"
+	signatureRepository "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/signature/repository"
+	signatureService "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/signature/service"
+	webhookHttp "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/webhook/delivery/http"
+	webhookRepository "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/webhook/repository"
+	webhookService "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/webhook/service"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/eventbus"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/middleware"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/ratelimit"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/stream"
+	"github.com/CarlosMtz98/receipt-processor-challenge/pkg/currency"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+	"log"
+	"time"
+)
+
+// App is the receipt processor wired from its configuration: the HTTP router, the gRPC server
+// and the background workers that relay the events, deliver the webhooks and read the maildir.
+// The server and receiptctl build it the same way, so that both serve the same API.
+type App struct {
+	Router     *gin.Engine
+	GRPCServer *grpc.Server
+	workers    []func(ctx context.Context)
+}
+
+// NewApp wires the repositories, services and handlers the configuration enables. The workers
+// only run once Start is called.
+func NewApp(cfg *config.Config) (*App, error) {
+	app := &App{}
+
+	webhookRepo := webhookRepository.InitWebhookRepository()
+	webhookClient := webhookService.NewWebhookClient(cfg.Webhook.Timeout, cfg.Webhook.AllowPrivateNetworks)
+	dispatcher := webhookService.NewDispatcher(webhookRepo, webhookClient, newDispatcherOptions(cfg.Webhook))
+	webhookSvc := webhookService.NewWebhookService(webhookRepo, dispatcher)
+
+	bus := eventbus.NewBus(eventbus.DefaultBusOptions())
+	bus.Subscribe("webhooks", webhookSvc.Publish)
+	broker := stream.NewBroker(cfg.StreamReplayBuffer, stream.DefaultClientBuffer)
+	bus.Subscribe("stream", broker.Publish)
+	app.workers = append(app.workers, dispatcher.Start, bus.Start)
+
+	var serviceOpts []service.Option
+	if cfg.TerminalRegistryFile != "" {
+		terminalKeyRepo, err := signatureRepository.LoadTerminalKeyRepository(cfg.TerminalRegistryFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the terminal registry: %w", err)
+		}
+		serviceOpts = append(serviceOpts, service.WithSignatureService(signatureService.NewSignatureService(terminalKeyRepo)))
+		log.Println("Receipt signature verification enabled")
+	}
+
+	if cfg.Fraud.Enabled {
+		serviceOpts = append(serviceOpts, service.WithFraudService(newFraudService(cfg.Fraud)))
+		log.Println("Fraud checks enabled")
+	}
+
+	if cfg.Rules.QuantityAware || cfg.Rules.PreTax || cfg.Rules.CanonicalRetailer {
+		serviceOpts = append(serviceOpts, service.WithRuleSet(service.RuleSet{
+			QuantityAware:     cfg.Rules.QuantityAware,
+			PreTax:            cfg.Rules.PreTax,
+			CanonicalRetailer: cfg.Rules.CanonicalRetailer,
+		}))
+		log.Printf("Scoring rules: quantity-aware items %t, pre-tax totals %t, registered retailer names %t",
+			cfg.Rules.QuantityAware, cfg.Rules.PreTax, cfg.Rules.CanonicalRetailer)
+	}
+	serviceOpts = append(serviceOpts, service.WithTotalTolerance(cfg.TotalTolerance))
+
+	rates := currency.NewRates(cfg.Currency.Base)
+	if cfg.Currency.ExchangeRatesFile != "" {
+		loaded, err := currency.LoadRates(cfg.Currency.ExchangeRatesFile, cfg.Currency.Base)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the exchange rates: %w", err)
+		}
+		rates = loaded
+		log.Printf("Receipts in other currencies than %s converted with %s", cfg.Currency.Base, cfg.Currency.ExchangeRatesFile)
+	}
+	serviceOpts = append(serviceOpts, service.WithExchangeRates(rates))
+
+	if len(cfg.RetailerTimeZones) > 0 {
+		serviceOpts = append(serviceOpts, service.WithRetailerTimeZones(cfg.RetailerTimeZones))
+		log.Printf("Time zones set for %d retailers", len(cfg.RetailerTimeZones))
+	}
+
+	retailerSvc := retailerService.NewRetailerService(retailerRepository.InitRetailerRepository())
+	serviceOpts = append(serviceOpts, service.WithRetailerRegistry(retailerSvc))
+
+	receiptRepo := repository.InitReceiptRepository()
+	relayOptions := eventbus.DefaultRelayOptions()
+	relayOptions.PollInterval = cfg.OutboxPollInterval
+	app.workers = append(app.workers, eventbus.NewRelay(receiptRepo, bus, relayOptions).Start)
+
+	receiptService := service.NewReceiptService(receiptRepo, serviceOpts...)
+	reviewSvc := reviewService.NewReviewService(receiptRepo, reviewRepository.InitReviewRepository(), receiptService)
+	mailSvc := mailService.NewMailService(receiptService, cfg.Mail.MinConfidence)
+	if cfg.Mail.Maildir != "" {
+		watcher, err := mailService.NewMaildirWatcher(mailSvc, cfg.Mail.Maildir, cfg.Mail.PollInterval)
+		if err != nil {
+			return nil, err
+		}
+		app.workers = append(app.workers, watcher.Start)
+		log.Printf("Watching the maildir %s for receipts", cfg.Mail.Maildir)
+	}
+	// The APIs share the limiter, so that the limits and quota of a client hold across them.
+	var rateLimiter *middleware.RateLimiter
+	var graphQLOpts []receiptGraphQL.Option
+	if cfg.RateLimit.Enabled() {
+		rateLimiter = middleware.NewRateLimiter(newRateLimitConfig(cfg.RateLimit))
+		graphQLOpts = append(graphQLOpts, receiptGraphQL.WithQuota(rateLimiter))
+	}
+	graphQLHandler, err := receiptGraphQL.NewGraphQLHandler(receiptService, receiptGraphQL.Limits{
+		MaxDepth:      cfg.GraphQL.MaxDepth,
+		MaxComplexity: cfg.GraphQL.MaxComplexity,
+	}, graphQLOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build the GraphQL schema: %w", err)
+	}
+
+	opts := []Option{
+		WithTrustedProxies(cfg.TrustedProxies),
+		WithReviewHandler(reviewHttp.NewReviewHandler(reviewSvc)),
+		WithWebhookHandler(webhookHttp.NewWebhookHandler(webhookSvc)),
+		WithRetailerHandler(retailerHttp.NewRetailerHandler(retailerSvc)),
+		WithStreamHandler(receiptHttp.NewStreamHandler(broker)),
+		WithExportHandler(receiptHttp.NewExportHandler(receiptService)),
+		WithInvoiceHandler(receiptHttp.NewInvoiceHandler(receiptService)),
+		WithParseHandler(receiptHttp.NewParseHandler(receiptService)),
+		WithMailHandler(mailHttp.NewMailHandler(mailSvc)),
+		WithGraphQLHandler(graphQLHandler),
+	}
+	// The gRPC interceptors run in the same order as the HTTP middleware.
+	var grpcInterceptors []grpc.UnaryServerInterceptor
+	if cfg.Auth.Enabled() {
+		verifier := newVerifier(cfg.Auth)
+		opts = append(opts, WithScopeGuard(middleware.NewJWTAuthenticator(verifier)))
+		grpcInterceptors = append(grpcInterceptors, middleware.GRPCAuthInterceptor(verifier, receiptGrpc.MethodScopes))
+		log.Println("Bearer token authentication enabled")
+	} else {
+		log.Println("The admin routes are not served without authentication")
+	}
+	if len(cfg.Auth.APIKeys) > 0 {
+		apiKeys := middleware.APIKeys(cfg.Auth.APIKeys)
+		opts = append(opts, WithAPIKeys(apiKeys))
+		grpcInterceptors = append(grpcInterceptors, apiKeys.GRPCInterceptor())
+		log.Printf("API keys issued to %d integrations", len(cfg.Auth.APIKeys))
+	}
+	if rateLimiter != nil {
+		opts = append(opts, WithRateLimiter(rateLimiter))
+		grpcInterceptors = append(grpcInterceptors, rateLimiter.GRPCInterceptor(receiptGrpc.MethodRoutes))
+		log.Println("Rate limiting enabled")
+	}
+
+	if cfg.OpenAPI.Enabled {
+		spec, err := loadOpenAPISpec(cfg.OpenAPI.SpecFile)
+		if err != nil {
+			return nil, err
+		}
+		validator, err := middleware.NewOpenAPIValidator(spec, cfg.OpenAPI.ValidateResponses)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build the OpenAPI validator: %w", err)
+		}
+		opts = append(opts, WithOpenAPIValidator(validator))
+		log.Printf("OpenAPI validation enabled (responses: %t)", cfg.OpenAPI.ValidateResponses)
+	}
+
+	app.GRPCServer = NewGRPCServer(receiptGrpc.NewReceiptServer(receiptService), cfg.GRPCReflection, grpc.ChainUnaryInterceptor(grpcInterceptors...))
+	app.Router = SetupRoutes(receiptHttp.NewReceiptHandler(receiptService), opts...)
+	return app, nil
+}
+
+// Start runs the background workers until the context is cancelled.
+func (a *App) Start(ctx context.Context) {
+	for _, worker := range a.workers {
+		go worker(ctx)
+	}
+}
+
+func newVerifier(cfg config.AuthConfig) *auth.Verifier {
+	var keys *auth.JWKSCache
+	if cfg.JWKSFile != "" {
+		keys = auth.NewFileJWKS(cfg.JWKSFile, cfg.JWKSCacheTTL)
+	} else {
+		keys = auth.NewRemoteJWKS(cfg.JWKSURL, nil, cfg.JWKSCacheTTL)
+	}
+
+	return auth.NewVerifier(keys, auth.VerifierOptions{
+		Issuer:    cfg.Issuer,
+		Audience:  cfg.Audience,
+		ClockSkew: cfg.ClockSkew,
+	})
+}
+
+func newRateLimitConfig(cfg config.RateLimitConfig) ratelimit.Config {
+	routes := make(map[string]ratelimit.Limit, len(cfg.Routes))
+	for route, limit := range cfg.Routes {
+		routes[route] = ratelimit.Limit{Rate: limit.RequestsPerSecond, Burst: limit.Burst}
+	}
+
+	return ratelimit.Config{
+		Default:     ratelimit.Limit{Rate: cfg.RequestsPerSecond, Burst: cfg.Burst},
+		Routes:      routes,
+		DailyQuota:  cfg.DailySubmissionQuota,
+		QuotaRoutes: []string{"POST /receipts/process", "POST /receipts/cfdi", "POST /receipts/email"},
+	}
+}
+
+func newFraudService(cfg config.FraudConfig) fraudService.FraudService {
+	options := fraudService.DefaultCheckOptions()
+	options.FutureTolerance = cfg.FutureTolerance
+	options.ZonedFutureTolerance = cfg.ZonedFutureTolerance
+	options.MaxReceiptAge = cfg.MaxReceiptAge
+	options.MaxTotal = cfg.MaxTotal
+	options.VelocityLimit = cfg.VelocityLimit
+	options.VelocityWindow = cfg.VelocityWindow
+	options.RepeatedItemsLimit = cfg.RepeatedItemsLimit
+
+	return fraudService.NewFraudService(cfg.HoldThreshold, fraudService.DefaultChecks(options, time.Now)...)
+}
+
+func newDispatcherOptions(cfg config.WebhookConfig) webhookService.DispatcherOptions {
+	options := webhookService.DefaultDispatcherOptions()
+	options.MaxAttempts = cfg.MaxAttempts
+	options.BaseBackoff = cfg.BaseBackoff
+	options.MaxBackoff = cfg.MaxBackoff
+	options.AllowPrivateNetworks = cfg.AllowPrivateNetworks
+	return options
+}
+
+// loadOpenAPISpec validates the traffic against the spec embedded in the binary unless a file
+// replaces it.
+func loadOpenAPISpec(specFile string) (*openapi3.T, error) {
+	if specFile == "" {
+		return middleware.ParseOpenAPISpec(receiptprocessor.OpenAPISpec)
+	}
+	return middleware.LoadOpenAPISpec(specFile)
+}
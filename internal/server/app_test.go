@@ -0,0 +1,49 @@
+package server
+
+import (
+	"bytes"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/config"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewApp(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Defaults", func(t *testing.T) {
+		cfg, err := config.Load()
+		require.NoError(t, err)
+		app, err := NewApp(cfg)
+		require.NoError(t, err)
+
+		body := `{"retailer":"Target","purchaseDate":"2022-01-01","purchaseTime":"13:01","total":"6.49","items":[{"shortDescription":"Mountain Dew 12PK","price":"6.49"}]}`
+		req := httptest.NewRequest(http.MethodPost, "/receipts/process", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		app.Router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		// The spec embedded in the binary validates the requests.
+		req = httptest.NewRequest(http.MethodPost, "/receipts/process", bytes.NewBufferString(`{"retailer":"Target"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w = httptest.NewRecorder()
+		app.Router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "/problems/invalid-request")
+
+		assert.Contains(t, app.GRPCServer.GetServiceInfo(), "receipt.v1.ReceiptService")
+	})
+
+	t.Run("Invalid exchange rates", func(t *testing.T) {
+		cfg, err := config.Load()
+		require.NoError(t, err)
+		cfg.Currency.ExchangeRatesFile = "missing.json"
+
+		_, err = NewApp(cfg)
+		assert.Error(t, err)
+	})
+}
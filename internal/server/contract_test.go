@@ -0,0 +1,420 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	receiptprocessor "github.com/CarlosMtz98/receipt-processor-challenge"
+	mailHttp "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/mail/delivery/http"
+	mailService "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/mail/service"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
+	receiptGraphQL "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/delivery/graphql"
+	receiptHttp "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/delivery/http"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/mock"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/repository"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/service"
+	retailerHttp "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/retailer/delivery/http"
+	retailerMock "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/retailer/mock"
+	reviewHttp "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/review/delivery/http"
+	reviewMock "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/review/mock"
+	webhookHttp "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/webhook/delivery/http"
+	webhookMock "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/webhook/mock"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/middleware"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/stream"
+	"github.com/CarlosMtz98/receipt-processor-challenge/pkg/currency"
+	"github.com/CarlosMtz98/receipt-processor-challenge/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// contractCase is a request against the real router and the documented response it must get.
+type contractCase struct {
+	name   string
+	method string
+	// operation is the documented path the request targets.
+	operation string
+	path      func() string
+	body      string
+	// contentType of the body, application/json when empty.
+	contentType string
+	status      int
+}
+
+// TestContract runs the router with request and response validation enabled, so every
+// response below is also checked against its schema in api.yml. It fails when a documented
+// path and status code is not exercised.
+func TestContract(t *testing.T) {
+	spec, err := middleware.ParseOpenAPISpec(receiptprocessor.OpenAPISpec)
+	require.NoError(t, err)
+	validator, err := middleware.NewOpenAPIValidator(spec, true)
+	require.NoError(t, err)
+
+	rates, err := currency.LoadRates("../../examples/exchange-rates.json", currency.DefaultCurrency)
+	require.NoError(t, err)
+	receiptRepo := repository.InitReceiptRepository()
+	receiptSvc := service.NewReceiptService(receiptRepo, service.WithExchangeRates(rates))
+	gin.SetMode(gin.TestMode)
+	r := SetupRoutes(receiptHttp.NewReceiptHandler(receiptSvc),
+		WithOpenAPIValidator(validator),
+		WithExportHandler(receiptHttp.NewExportHandler(receiptSvc)),
+		WithInvoiceHandler(receiptHttp.NewInvoiceHandler(receiptSvc)),
+		WithParseHandler(receiptHttp.NewParseHandler(receiptSvc)),
+		WithMailHandler(mailHttp.NewMailHandler(mailService.NewMailService(receiptSvc, 0.8))),
+	)
+
+	morningReceipt, err := os.ReadFile("../../examples/morning-receipt.json")
+	require.NoError(t, err)
+	ublInvoice, err := os.ReadFile("../../examples/ubl-invoice.xml")
+	require.NoError(t, err)
+	cfdiInvoice, err := os.ReadFile("../../examples/cfdi-invoice.xml")
+	require.NoError(t, err)
+	emailReceipt, err := os.ReadFile("../../examples/email-receipt.eml")
+	require.NoError(t, err)
+	// The UBL invoice, already submitted to /receipts/process, forwarded as an attachment.
+	emailInvoice := "From: Walgreens <invoices@walgreens.example>\r\nSubject: Your invoice\r\nMIME-Version: 1.0\r\n" +
+		"Content-Type: multipart/mixed; boundary=\"invoice\"\r\n\r\n--invoice\r\n" +
+		"Content-Type: application/xml\r\nContent-Disposition: attachment; filename=\"invoice.xml\"\r\n\r\n" +
+		string(ublInvoice) + "\r\n--invoice--\r\n"
+
+	var processedID string
+	held := &models.Receipt{ID: uuid.New(), Status: models.ReceiptStatusHeld, CreatedAt: time.Now()}
+	rejected := &models.Receipt{
+		ID:        uuid.New(),
+		Status:    models.ReceiptStatusRejected,
+		Review:    &models.ReviewDecision{Outcome: models.ReviewRejected, Reason: "duplicate submission"},
+		CreatedAt: time.Now(),
+	}
+	purchase := &models.Receipt{
+		ID:           uuid.New(),
+		Retailer:     "Walgreens",
+		PurchaseDate: "2022-01-02",
+		PurchaseTime: "08:13",
+		Items:        []models.ReceiptItem{{ShortDescription: "Pepsi", Price: "1.25"}, {ShortDescription: "Dasani", Price: "1.40"}},
+		Total:        "2.65",
+		Status:       models.ReceiptStatusProcessed,
+		CreatedAt:    time.Now(),
+		// The address httptest requests come from, so that the return is from the same client.
+		SubmittedBy: "ip:192.0.2.1",
+	}
+	for _, receipt := range []*models.Receipt{held, rejected, purchase} {
+		require.NoError(t, receiptRepo.Create(context.Background(), receipt))
+	}
+
+	pointsPath := func(id string) func() string {
+		return func() string { return "/receipts/" + id + "/points" }
+	}
+	cases := []contractCase{
+		{
+			name: "Process example receipt", method: http.MethodPost, operation: "/receipts/process",
+			path: func() string { return "/receipts/process" }, body: string(morningReceipt), status: http.StatusOK,
+		},
+		{
+			name: "Process receipt missing the retailer", method: http.MethodPost, operation: "/receipts/process",
+			path: func() string { return "/receipts/process" }, status: http.StatusBadRequest,
+			body: `{"purchaseDate":"2022-01-02","purchaseTime":"08:13","total":"1.25","items":[{"shortDescription":"Pepsi","price":"1.25"}]}`,
+		},
+		{
+			name: "Process receipt with a wrong total", method: http.MethodPost, operation: "/receipts/process",
+			path: func() string { return "/receipts/process" }, status: http.StatusBadRequest,
+			body: `{"retailer":"Walgreens","purchaseDate":"2022-01-02","purchaseTime":"08:13","total":"9.99","items":[{"shortDescription":"Pepsi","price":"1.25"}]}`,
+		},
+		{
+			name: "Process receipt with item quantities", method: http.MethodPost, operation: "/receipts/process",
+			path: func() string { return "/receipts/process" }, status: http.StatusOK,
+			body: `{"retailer":"Walgreens","purchaseDate":"2022-01-02","purchaseTime":"08:13","total":"3.75","items":[{"shortDescription":"Pepsi","price":"3.75","quantity":"3","unitPrice":"1.25","sku":"012000001291","category":"beverages"}]}`,
+		},
+		{
+			name: "Process receipt with an item price that is not the quantity times the unit price", method: http.MethodPost, operation: "/receipts/process",
+			path: func() string { return "/receipts/process" }, status: http.StatusBadRequest,
+			body: `{"retailer":"Walgreens","purchaseDate":"2022-01-02","purchaseTime":"08:13","total":"3.50","items":[{"shortDescription":"Pepsi","price":"3.50","quantity":"3","unitPrice":"1.25"}]}`,
+		},
+		{
+			name: "Process receipt with taxes, discounts and a tip", method: http.MethodPost, operation: "/receipts/process",
+			path: func() string { return "/receipts/process" }, status: http.StatusOK,
+			body: `{"retailer":"Walgreens","purchaseDate":"2022-01-02","purchaseTime":"08:13","total":"4.66","subtotal":"3.75","discounts":[{"description":"Coupon","amount":"0.50"}],"taxes":[{"description":"Sales tax","amount":"0.41"}],"tip":"1.00","items":[{"shortDescription":"Pepsi","price":"3.75"}]}`,
+		},
+		{
+			name: "Process receipt with a subtotal that is not the items total", method: http.MethodPost, operation: "/receipts/process",
+			path: func() string { return "/receipts/process" }, status: http.StatusBadRequest,
+			body: `{"retailer":"Walgreens","purchaseDate":"2022-01-02","purchaseTime":"08:13","total":"3.91","subtotal":"3.50","taxes":[{"description":"Sales tax","amount":"0.41"}],"items":[{"shortDescription":"Pepsi","price":"3.75"}]}`,
+		},
+		{
+			name: "Process receipt in yen", method: http.MethodPost, operation: "/receipts/process",
+			path: func() string { return "/receipts/process" }, status: http.StatusOK,
+			body: `{"retailer":"Lawson","purchaseDate":"2022-03-20","purchaseTime":"13:01","total":"150","currency":"JPY","items":[{"shortDescription":"Tea","price":"150"}]}`,
+		},
+		{
+			name: "Process receipt in a currency without an exchange rate", method: http.MethodPost, operation: "/receipts/process",
+			path: func() string { return "/receipts/process" }, status: http.StatusBadRequest,
+			body: `{"retailer":"Migros","purchaseDate":"2022-03-20","purchaseTime":"13:01","total":"1.50","currency":"CHF","items":[{"shortDescription":"Tea","price":"1.50"}]}`,
+		},
+		{
+			name: "Process receipt in dollars with the decimals of yen", method: http.MethodPost, operation: "/receipts/process",
+			path: func() string { return "/receipts/process" }, status: http.StatusBadRequest,
+			body: `{"retailer":"Walgreens","purchaseDate":"2022-01-02","purchaseTime":"08:13","total":"150","currency":"USD","items":[{"shortDescription":"Pepsi","price":"150"}]}`,
+		},
+		{
+			name: "Process receipt with the moment of the purchase", method: http.MethodPost, operation: "/receipts/process",
+			path: func() string { return "/receipts/process" }, status: http.StatusOK,
+			body: `{"retailer":"Walgreens","purchasedAt":"2022-01-02T20:30:00Z","timeZone":"America/Chicago","total":"1.25","items":[{"shortDescription":"Pepsi","price":"1.25"}]}`,
+		},
+		{
+			name: "Process receipt with a purchase time that does not match the moment of the purchase", method: http.MethodPost, operation: "/receipts/process",
+			path: func() string { return "/receipts/process" }, status: http.StatusBadRequest,
+			body: `{"retailer":"Walgreens","purchaseDate":"2022-01-02","purchaseTime":"20:30","purchasedAt":"2022-01-02T20:30:00Z","timeZone":"America/Chicago","total":"1.25","items":[{"shortDescription":"Pepsi","price":"1.25"}]}`,
+		},
+		{
+			name: "Points of the processed receipt", method: http.MethodGet, operation: "/receipts/{id}/points",
+			path: func() string { return "/receipts/" + processedID + "/points" }, status: http.StatusOK,
+		},
+		{
+			name: "Process return of an item of a receipt", method: http.MethodPost, operation: "/receipts/process",
+			path: func() string { return "/receipts/process" }, status: http.StatusOK,
+			body: `{"retailer":"Walgreens","purchaseDate":"2022-01-03","purchaseTime":"10:00","total":"1.40","type":"return","originalReceiptId":"` + purchase.ID.String() + `","items":[{"shortDescription":"Dasani","price":"1.40"}]}`,
+		},
+		{
+			name: "Points of the return", method: http.MethodGet, operation: "/receipts/{id}/points",
+			path: func() string { return "/receipts/" + processedID + "/points" }, status: http.StatusOK,
+		},
+		{
+			name: "Points of the receipt with a return", method: http.MethodGet, operation: "/receipts/{id}/points",
+			path: pointsPath(purchase.ID.String()), status: http.StatusOK,
+		},
+		{
+			name: "Process return of an item that was already returned", method: http.MethodPost, operation: "/receipts/process",
+			path: func() string { return "/receipts/process" }, status: http.StatusBadRequest,
+			body: `{"retailer":"Walgreens","purchaseDate":"2022-01-03","purchaseTime":"10:00","total":"1.40","type":"return","originalReceiptId":"` + purchase.ID.String() + `","items":[{"shortDescription":"Dasani","price":"1.40"}]}`,
+		},
+		{
+			name: "Points of a held receipt", method: http.MethodGet, operation: "/receipts/{id}/points",
+			path: pointsPath(held.ID.String()), status: http.StatusAccepted,
+		},
+		{
+			name: "Points of a rejected receipt", method: http.MethodGet, operation: "/receipts/{id}/points",
+			path: pointsPath(rejected.ID.String()), status: http.StatusConflict,
+		},
+		{
+			name: "Points of a malformed id", method: http.MethodGet, operation: "/receipts/{id}/points",
+			path: pointsPath("not-a-uuid"), status: http.StatusBadRequest,
+		},
+		{
+			name: "Points of an unknown receipt", method: http.MethodGet, operation: "/receipts/{id}/points",
+			path: pointsPath(uuid.NewString()), status: http.StatusNotFound,
+		},
+		{
+			name: "Process UBL invoice", method: http.MethodPost, operation: "/receipts/process",
+			path: func() string { return "/receipts/process" }, body: string(ublInvoice), contentType: "application/xml", status: http.StatusOK,
+		},
+		{
+			name: "Process the same UBL invoice", method: http.MethodPost, operation: "/receipts/process",
+			path: func() string { return "/receipts/process" }, body: string(ublInvoice), contentType: "application/xml", status: http.StatusConflict,
+		},
+		{
+			name: "Process CFDI invoice", method: http.MethodPost, operation: "/receipts/cfdi",
+			path: func() string { return "/receipts/cfdi" }, body: string(cfdiInvoice), contentType: "application/xml", status: http.StatusOK,
+		},
+		{
+			name: "Process the same CFDI invoice", method: http.MethodPost, operation: "/receipts/cfdi",
+			path: func() string { return "/receipts/cfdi" }, body: string(cfdiInvoice), contentType: "application/xml", status: http.StatusConflict,
+		},
+		{
+			name: "Process a UBL invoice as a CFDI invoice", method: http.MethodPost, operation: "/receipts/cfdi",
+			path: func() string { return "/receipts/cfdi" }, body: string(ublInvoice), contentType: "application/xml", status: http.StatusBadRequest,
+		},
+		{
+			name: "Parse receipt text", method: http.MethodPost, operation: "/receipts/parse",
+			path: func() string { return "/receipts/parse" }, contentType: "text/plain", status: http.StatusOK,
+			body: "WALGREENS\n01/02/2022 08:13\nPEPSI 1.25\nDASANI 1.40\nTOTAL 2.65\n",
+		},
+		{
+			name: "Parse receipt text that can't be read", method: http.MethodPost, operation: "/receipts/parse",
+			path: func() string { return "/receipts/parse" }, contentType: "text/plain", status: http.StatusOK,
+			body: "Thank you for shopping with us!\n",
+		},
+		{
+			name: "Parse receipt text that is too large", method: http.MethodPost, operation: "/receipts/parse",
+			path: func() string { return "/receipts/parse" }, contentType: "text/plain", status: http.StatusBadRequest,
+			body: strings.Repeat("PEPSI 1.25\n", 10000),
+		},
+		{
+			name: "Process email receipt", method: http.MethodPost, operation: "/receipts/email",
+			path: func() string { return "/receipts/email" }, body: string(emailReceipt), contentType: "message/rfc822", status: http.StatusOK,
+		},
+		{
+			name: "Process email without a receipt", method: http.MethodPost, operation: "/receipts/email",
+			path: func() string { return "/receipts/email" }, contentType: "message/rfc822", status: http.StatusBadRequest,
+			body: "From: jane.doe@example.com\r\nSubject: Hello\r\n\r\nSee you tomorrow!\r\n",
+		},
+		{
+			name: "Process email with an invoice that was already submitted", method: http.MethodPost, operation: "/receipts/email",
+			path: func() string { return "/receipts/email" }, body: emailInvoice, contentType: "message/rfc822", status: http.StatusConflict,
+		},
+		{
+			name: "Export receipts", method: http.MethodGet, operation: "/receipts/export",
+			path: func() string { return "/receipts/export" }, status: http.StatusOK,
+		},
+		{
+			name: "Export processed receipts as CSV", method: http.MethodGet, operation: "/receipts/export",
+			path: func() string { return "/receipts/export?format=csv&layout=item&status=processed" }, status: http.StatusOK,
+		},
+		{
+			name: "Export receipts in an unknown format", method: http.MethodGet, operation: "/receipts/export",
+			path: func() string { return "/receipts/export?format=xml" }, status: http.StatusBadRequest,
+		},
+	}
+
+	covered := make(map[string]bool)
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path(), strings.NewReader(tc.body))
+			if tc.contentType != "" {
+				req.Header.Set("Content-Type", tc.contentType)
+			} else if tc.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, tc.status, w.Code, w.Body.String())
+			if tc.status == http.StatusOK && tc.method == http.MethodPost {
+				var response struct{ ID string }
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				if response.ID != "" {
+					processedID = response.ID
+				}
+			}
+		})
+		covered[fmt.Sprintf("%s %s %d", tc.method, tc.operation, tc.status)] = true
+	}
+
+	var documented []string
+	for path, item := range spec.Paths.Map() {
+		for method, operation := range item.Operations() {
+			for status := range operation.Responses.Map() {
+				documented = append(documented, fmt.Sprintf("%s %s %s", method, path, status))
+			}
+		}
+	}
+	sort.Strings(documented)
+	for _, response := range documented {
+		assert.True(t, covered[response], "the contract tests don't exercise %s", response)
+	}
+}
+
+func TestContractViolations(t *testing.T) {
+	spec, err := middleware.ParseOpenAPISpec(receiptprocessor.OpenAPISpec)
+	require.NoError(t, err)
+	validator, err := middleware.NewOpenAPIValidator(spec, true)
+	require.NoError(t, err)
+
+	receiptHandler := receiptHttp.NewReceiptHandler(service.NewReceiptService(repository.InitReceiptRepository()))
+	gin.SetMode(gin.TestMode)
+	r := SetupRoutes(receiptHandler, WithOpenAPIValidator(validator))
+
+	body := `{"retailer":"Target","purchaseDate":"January 1st","purchaseTime":"13:01","total":"6.49","items":[]}`
+	req := httptest.NewRequest(http.MethodPost, "/receipts/process", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, utils.ProblemContentType, w.Header().Get("Content-Type"))
+
+	var problem struct {
+		Type       string
+		Status     int
+		Violations []struct{ In, Pointer, Message string }
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+	assert.Equal(t, middleware.ProblemInvalidRequest, problem.Type)
+	assert.Equal(t, http.StatusBadRequest, problem.Status)
+
+	pointers := make([]string, len(problem.Violations))
+	for i, violation := range problem.Violations {
+		assert.Equal(t, "body", violation.In)
+		pointers[i] = violation.Pointer
+	}
+	assert.ElementsMatch(t, []string{"/purchaseDate", "/items"}, pointers)
+}
+
+// undocumentedRoutes are served but left out of api.yml, so that their traffic isn't validated.
+var undocumentedRoutes = map[string]string{
+	"GET /health":                                   "liveness probe",
+	"GET /debug/vars":                               "process variables and counters of expvar",
+	"GET /receipts/stream":                          "Server-Sent Events, which OpenAPI 3.0 can't describe",
+	"POST /graphql":                                 "described by the GraphQL schema",
+	"GET /admin/reviews":                            "admin API, see Instructions.md",
+	"POST /admin/reviews/:id/approve":               "admin API, see Instructions.md",
+	"POST /admin/reviews/:id/reject":                "admin API, see Instructions.md",
+	"GET /admin/reviews/:id/decisions":              "admin API, see Instructions.md",
+	"POST /admin/webhooks":                          "admin API, see Instructions.md",
+	"GET /admin/webhooks":                           "admin API, see Instructions.md",
+	"DELETE /admin/webhooks/:id":                    "admin API, see Instructions.md",
+	"GET /admin/webhooks/dead-letters":              "admin API, see Instructions.md",
+	"POST /admin/webhooks/deliveries/:id/redeliver": "admin API, see Instructions.md",
+	"POST /admin/retailers":                         "admin API, see Instructions.md",
+	"GET /admin/retailers":                          "admin API, see Instructions.md",
+	"GET /admin/retailers/:id":                      "admin API, see Instructions.md",
+	"PUT /admin/retailers/:id":                      "admin API, see Instructions.md",
+	"DELETE /admin/retailers/:id":                   "admin API, see Instructions.md",
+}
+
+// TestRoutesDocumented fails when a route is neither documented in api.yml nor listed in
+// undocumentedRoutes, or when a listed route is no longer served.
+func TestRoutesDocumented(t *testing.T) {
+	spec, err := middleware.ParseOpenAPISpec(receiptprocessor.OpenAPISpec)
+	require.NoError(t, err)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	receiptSvc := mock.NewMockReceiptService(ctrl)
+	graphQLHandler, err := receiptGraphQL.NewGraphQLHandler(receiptSvc, receiptGraphQL.Limits{})
+	require.NoError(t, err)
+	gin.SetMode(gin.TestMode)
+	r := SetupRoutes(receiptHttp.NewReceiptHandler(receiptSvc),
+		WithScopeGuard(grantedScopes{}),
+		WithStreamHandler(receiptHttp.NewStreamHandler(stream.NewBroker(10, stream.DefaultClientBuffer))),
+		WithExportHandler(receiptHttp.NewExportHandler(receiptSvc)),
+		WithInvoiceHandler(receiptHttp.NewInvoiceHandler(receiptSvc)),
+		WithParseHandler(receiptHttp.NewParseHandler(receiptSvc)),
+		WithMailHandler(mailHttp.NewMailHandler(mailService.NewMailService(receiptSvc, 0.8))),
+		WithGraphQLHandler(graphQLHandler),
+		WithReviewHandler(reviewHttp.NewReviewHandler(reviewMock.NewMockReviewService(ctrl))),
+		WithWebhookHandler(webhookHttp.NewWebhookHandler(webhookMock.NewMockWebhookService(ctrl))),
+		WithRetailerHandler(retailerHttp.NewRetailerHandler(retailerMock.NewMockRetailerService(ctrl))),
+	)
+
+	served := make(map[string]bool)
+	for _, route := range r.Routes() {
+		name := route.Method + " " + route.Path
+		served[name] = true
+		if _, ok := undocumentedRoutes[name]; ok {
+			continue
+		}
+
+		// api.yml names the path parameters {id} where gin names them :id.
+		segments := strings.Split(route.Path, "/")
+		for i, segment := range segments {
+			if strings.HasPrefix(segment, ":") {
+				segments[i] = "{" + segment[1:] + "}"
+			}
+		}
+		item := spec.Paths.Find(strings.Join(segments, "/"))
+		assert.True(t, item != nil && item.GetOperation(route.Method) != nil,
+			"%s is neither documented in api.yml nor listed in undocumentedRoutes", name)
+	}
+	for name := range undocumentedRoutes {
+		assert.True(t, served[name], "%s is listed in undocumentedRoutes but not served", name)
+	}
+}
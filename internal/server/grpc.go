@@ -0,0 +1,19 @@
+package server
+
+import (
+	receiptv1 "github.com/CarlosMtz98/receipt-processor-challenge/pkg/pb/receipt/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+)
+
+// NewGRPCServer serves the receipt API over gRPC. With enableReflection, reflection is
+// registered so that tools like grpcurl can discover the API; it describes the API to anyone
+// who can reach the port, so it is meant for development.
+func NewGRPCServer(receiptServer receiptv1.ReceiptServiceServer, enableReflection bool, opts ...grpc.ServerOption) *grpc.Server {
+	server := grpc.NewServer(opts...)
+	receiptv1.RegisterReceiptServiceServer(server, receiptServer)
+	if enableReflection {
+		reflection.Register(server)
+	}
+	return server
+}
@@ -0,0 +1,22 @@
+package server
+
+import (
+	receiptv1 "github.com/CarlosMtz98/receipt-processor-challenge/pkg/pb/receipt/v1"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestNewGRPCServer(t *testing.T) {
+	hasReflection := func(enableReflection bool) bool {
+		for name := range NewGRPCServer(receiptv1.UnimplementedReceiptServiceServer{}, enableReflection).GetServiceInfo() {
+			if strings.HasPrefix(name, "grpc.reflection.") {
+				return true
+			}
+		}
+		return false
+	}
+
+	assert.True(t, hasReflection(true))
+	assert.False(t, hasReflection(false))
+}
@@ -1,24 +1,228 @@
 package server
 
 import (
+	"expvar"
+	mailHttp "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/mail/delivery/http"
+	receiptGraphQL "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/delivery/graphql"
 	receiptHttp "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/delivery/http"
+	retailerHttp "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/retailer/delivery/http"
+	reviewHttp "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/review/delivery/http"
+	webhookHttp "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/webhook/delivery/http"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/middleware"
 	"github.com/gin-gonic/gin"
+	"log"
 	"net/http"
 )
 
-func SetupRoutes(receiptHandler receiptHttp.ReceiptHandler) *gin.Engine {
+// ScopeDebugRead allows reading the process variables under /debug.
+const ScopeDebugRead = "debug:read"
+
+// Option customizes the router built by SetupRoutes.
+type Option func(*routerOptions)
+
+type routerOptions struct {
+	guard           middleware.ScopeGuard
+	trustedProxies  []string
+	apiKeys         middleware.APIKeys
+	rateLimiter     *middleware.RateLimiter
+	validator       *middleware.OpenAPIValidator
+	reviewHandler   reviewHttp.ReviewHandler
+	webhookHandler  webhookHttp.WebhookHandler
+	retailerHandler retailerHttp.RetailerHandler
+	streamHandler   receiptHttp.StreamHandler
+	exportHandler   receiptHttp.ExportHandler
+	invoiceHandler  receiptHttp.InvoiceHandler
+	parseHandler    receiptHttp.ParseHandler
+	mailHandler     mailHttp.MailHandler
+	graphQLHandler  receiptGraphQL.GraphQLHandler
+}
+
+// WithScopeGuard protects the API routes with the given authentication guard.
+func WithScopeGuard(guard middleware.ScopeGuard) Option {
+	return func(o *routerOptions) {
+		o.guard = guard
+	}
+}
+
+// WithTrustedProxies trusts the X-Forwarded-For header of the given proxies, addresses or CIDR
+// ranges, for the client IP. Without it the client IP is the address of the connection.
+func WithTrustedProxies(proxies []string) Option {
+	return func(o *routerOptions) {
+		o.trustedProxies = proxies
+	}
+}
+
+// WithAPIKeys identifies the clients sending one of the keys in X-API-Key by its name.
+func WithAPIKeys(apiKeys middleware.APIKeys) Option {
+	return func(o *routerOptions) {
+		o.apiKeys = apiKeys
+	}
+}
+
+// WithRateLimiter applies per-client rate limits and quotas to the API routes.
+func WithRateLimiter(rateLimiter *middleware.RateLimiter) Option {
+	return func(o *routerOptions) {
+		o.rateLimiter = rateLimiter
+	}
+}
+
+// WithOpenAPIValidator checks the documented API routes against the OpenAPI specification.
+func WithOpenAPIValidator(validator *middleware.OpenAPIValidator) Option {
+	return func(o *routerOptions) {
+		o.validator = validator
+	}
+}
+
+// WithReviewHandler exposes the review queue of held receipts under /admin/reviews, when the
+// routes are authenticated.
+func WithReviewHandler(reviewHandler reviewHttp.ReviewHandler) Option {
+	return func(o *routerOptions) {
+		o.reviewHandler = reviewHandler
+	}
+}
+
+// WithWebhookHandler exposes the webhook administration API under /admin/webhooks, when the
+// routes are authenticated.
+func WithWebhookHandler(webhookHandler webhookHttp.WebhookHandler) Option {
+	return func(o *routerOptions) {
+		o.webhookHandler = webhookHandler
+	}
+}
+
+// WithRetailerHandler exposes the retailer registry administration API under /admin/retailers,
+// when the routes are authenticated.
+func WithRetailerHandler(retailerHandler retailerHttp.RetailerHandler) Option {
+	return func(o *routerOptions) {
+		o.retailerHandler = retailerHandler
+	}
+}
+
+// WithStreamHandler exposes the Server-Sent Events feed under /receipts/stream.
+func WithStreamHandler(streamHandler receiptHttp.StreamHandler) Option {
+	return func(o *routerOptions) {
+		o.streamHandler = streamHandler
+	}
+}
+
+// WithExportHandler exposes the bulk export of receipts under /receipts/export.
+func WithExportHandler(exportHandler receiptHttp.ExportHandler) Option {
+	return func(o *routerOptions) {
+		o.exportHandler = exportHandler
+	}
+}
+
+// WithInvoiceHandler accepts electronic invoices under /receipts, e.g. /receipts/cfdi.
+func WithInvoiceHandler(invoiceHandler receiptHttp.InvoiceHandler) Option {
+	return func(o *routerOptions) {
+		o.invoiceHandler = invoiceHandler
+	}
+}
+
+// WithParseHandler exposes the receipt text parser under /receipts/parse.
+func WithParseHandler(parseHandler receiptHttp.ParseHandler) Option {
+	return func(o *routerOptions) {
+		o.parseHandler = parseHandler
+	}
+}
+
+// WithMailHandler accepts receipts forwarded by email under /receipts/email.
+func WithMailHandler(mailHandler mailHttp.MailHandler) Option {
+	return func(o *routerOptions) {
+		o.mailHandler = mailHandler
+	}
+}
+
+// WithGraphQLHandler exposes the GraphQL API under /graphql.
+func WithGraphQLHandler(graphQLHandler receiptGraphQL.GraphQLHandler) Option {
+	return func(o *routerOptions) {
+		o.graphQLHandler = graphQLHandler
+	}
+}
+
+func SetupRoutes(receiptHandler receiptHttp.ReceiptHandler, opts ...Option) *gin.Engine {
+	options := routerOptions{
+		guard: middleware.AllowAll{},
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
 	router := gin.New()
+	// Handlers hand the gin.Context to the services, which read the principal and the
+	// cancellation from the context of the request.
+	router.ContextWithFallback = true
+	// Gin trusts every proxy by default, which would let callers pick their client IP, and with
+	// it their rate limits and submitter, with X-Forwarded-For. Invalid entries trust none.
+	if err := router.SetTrustedProxies(options.trustedProxies); err != nil {
+		log.Printf("Trusting no proxy: %v", err)
+	}
 	router.Use(gin.Logger())
 	router.Use(gin.Recovery())
 
 	health := router.Group("/health")
-	receipt := router.Group("/receipts")
 
-	receiptHttp.MapReceiptRoutes(receipt, receiptHandler)
+	apiMiddleware := []gin.HandlerFunc{options.guard.Authenticate()}
+	if len(options.apiKeys) > 0 {
+		apiMiddleware = append(apiMiddleware, options.apiKeys.Handler())
+	}
+	if options.rateLimiter != nil {
+		apiMiddleware = append(apiMiddleware, options.rateLimiter.Handler())
+	}
+	if options.validator != nil {
+		apiMiddleware = append(apiMiddleware, options.validator.Handler())
+	}
+	receipt := router.Group("/receipts", apiMiddleware...)
+
+	receiptHttp.MapReceiptRoutes(receipt, receiptHandler, options.guard)
+	if options.streamHandler != nil {
+		receiptHttp.MapStreamRoutes(receipt, options.streamHandler, options.guard)
+	}
+	if options.exportHandler != nil {
+		receiptHttp.MapExportRoutes(receipt, options.exportHandler, options.guard)
+	}
+	if options.invoiceHandler != nil {
+		receiptHttp.MapInvoiceRoutes(receipt, options.invoiceHandler, options.guard)
+	}
+	if options.parseHandler != nil {
+		receiptHttp.MapParseRoutes(receipt, options.parseHandler, options.guard)
+	}
+	if options.mailHandler != nil {
+		mailHttp.MapMailRoutes(receipt, options.mailHandler, options.guard)
+	}
+
+	if options.graphQLHandler != nil {
+		graphQL := router.Group("/graphql", apiMiddleware...)
+		receiptGraphQL.MapGraphQLRoutes(graphQL, options.graphQLHandler)
+	}
+
+	// The admin routes are only served behind authentication: without it their scopes are not
+	// enforced and any submitter could, e.g., release its own held receipts or subscribe a
+	// webhook to every receipt.
+	_, authDisabled := options.guard.(middleware.AllowAll)
+
+	if options.reviewHandler != nil && !authDisabled {
+		reviews := router.Group("/admin/reviews", apiMiddleware...)
+		reviewHttp.MapReviewRoutes(reviews, options.reviewHandler, options.guard)
+	}
+
+	if options.webhookHandler != nil && !authDisabled {
+		webhooks := router.Group("/admin/webhooks", apiMiddleware...)
+		webhookHttp.MapWebhookRoutes(webhooks, options.webhookHandler, options.guard)
+	}
+
+	if options.retailerHandler != nil && !authDisabled {
+		retailers := router.Group("/admin/retailers", apiMiddleware...)
+		retailerHttp.MapRetailerRoutes(retailers, options.retailerHandler, options.guard)
+	}
 
 	health.GET("", func(c *gin.Context) {
 		c.JSON(http.StatusOK, map[string]string{"status": "OK"})
 	})
+	// The process variables and counters are not public: they need the debug scope and count
+	// against the rate limits like any API call.
+	debug := router.Group("/debug", apiMiddleware...)
+	debug.Use(options.guard.Require(ScopeDebugRead))
+	debug.GET("/vars", gin.WrapH(expvar.Handler()))
 
 	return router
 }
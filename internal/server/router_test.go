@@ -3,6 +3,16 @@ package server
 import (
 	receiptHttp "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/delivery/http"
 	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/receipt/mock"
+	retailerHttp "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/retailer/delivery/http"
+	retailerMock "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/retailer/mock"
+	reviewHttp "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/review/delivery/http"
+	reviewMock "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/review/mock"
+	webhookHttp "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/webhook/delivery/http"
+	webhookMock "github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/webhook/mock"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/middleware"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/ratelimit"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/stream"
+	"github.com/CarlosMtz98/receipt-processor-challenge/pkg/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
@@ -31,3 +41,189 @@ func TestHealthCheckEndpoint(t *testing.T) {
 	// Assert that the response status code is 200 OK
 	assert.Equal(t, http.StatusOK, w.Code)
 }
+
+func TestStreamEndpoint(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	receiptHandler := receiptHttp.NewReceiptHandler(mock.NewMockReceiptService(ctrl))
+	streamHandler := receiptHttp.NewStreamHandler(stream.NewBroker(10, stream.DefaultClientBuffer))
+	gin.SetMode(gin.TestMode)
+	r := SetupRoutes(receiptHandler, WithStreamHandler(streamHandler))
+
+	req := httptest.NewRequest("GET", "/receipts/stream", nil)
+	req.Header.Set(receiptHttp.LastEventIDHeader, "not-a-number")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+// grantedScopes is a ScopeGuard whose every caller holds the same scopes.
+type grantedScopes []string
+
+func (g grantedScopes) Authenticate() gin.HandlerFunc {
+	return func(c *gin.Context) { c.Next() }
+}
+
+func (g grantedScopes) Require(scopes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, scope := range scopes {
+			granted := false
+			for _, held := range g {
+				granted = granted || held == scope
+			}
+			if !granted {
+				utils.HandleForbidden(c, "missing "+scope)
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+func TestDebugVarsEndpoint(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	receiptHandler := receiptHttp.NewReceiptHandler(mock.NewMockReceiptService(ctrl))
+	gin.SetMode(gin.TestMode)
+
+	for _, tt := range []struct {
+		guard  middleware.ScopeGuard
+		status int
+	}{
+		{guard: grantedScopes{"receipts:read", "receipts:write"}, status: http.StatusForbidden},
+		{guard: grantedScopes{ScopeDebugRead}, status: http.StatusOK},
+	} {
+		req := httptest.NewRequest("GET", "/debug/vars", nil)
+		w := httptest.NewRecorder()
+		SetupRoutes(receiptHandler, WithScopeGuard(tt.guard)).ServeHTTP(w, req)
+
+		assert.Equal(t, tt.status, w.Code)
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	receiptHandler := receiptHttp.NewReceiptHandler(mock.NewMockReceiptService(ctrl))
+	gin.SetMode(gin.TestMode)
+
+	send := func(router http.Handler, forwardedFor string) int {
+		req := httptest.NewRequest("GET", "/debug/vars", nil)
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+	newRouter := func(opts ...Option) http.Handler {
+		rateLimiter := middleware.NewRateLimiter(ratelimit.Config{Default: ratelimit.Limit{Rate: 0.001, Burst: 1}})
+		opts = append(opts, WithScopeGuard(grantedScopes{ScopeDebugRead}), WithRateLimiter(rateLimiter))
+		return SetupRoutes(receiptHandler, opts...)
+	}
+
+	t.Run("Forwarded addresses share the bucket of the connection", func(t *testing.T) {
+		router := newRouter()
+		assert.Equal(t, http.StatusOK, send(router, "198.51.100.1"))
+		assert.Equal(t, http.StatusTooManyRequests, send(router, "198.51.100.2"))
+	})
+
+	t.Run("Trusted proxies forward the client IP", func(t *testing.T) {
+		router := newRouter(WithTrustedProxies([]string{"192.0.2.0/24"}))
+		assert.Equal(t, http.StatusOK, send(router, "198.51.100.1"))
+		assert.Equal(t, http.StatusOK, send(router, "198.51.100.2"))
+		assert.Equal(t, http.StatusTooManyRequests, send(router, "198.51.100.2"))
+	})
+}
+
+func TestReviewEndpoint(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	receiptHandler := receiptHttp.NewReceiptHandler(mock.NewMockReceiptService(ctrl))
+	reviewSvc := reviewMock.NewMockReviewService(ctrl)
+	reviewSvc.EXPECT().ListPending(gomock.Any()).Return(nil, nil).AnyTimes()
+	reviewHandler := reviewHttp.NewReviewHandler(reviewSvc)
+	gin.SetMode(gin.TestMode)
+
+	for _, tt := range []struct {
+		name   string
+		opts   []Option
+		status int
+	}{
+		{name: "Without authentication", status: http.StatusNotFound},
+		{name: "Without the scope", opts: []Option{WithScopeGuard(grantedScopes{"receipts:write"})}, status: http.StatusForbidden},
+		{name: "With the scope", opts: []Option{WithScopeGuard(grantedScopes{reviewHttp.ScopeReceiptsReview})}, status: http.StatusOK},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/admin/reviews", nil)
+			w := httptest.NewRecorder()
+			SetupRoutes(receiptHandler, append(tt.opts, WithReviewHandler(reviewHandler))...).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.status, w.Code)
+		})
+	}
+}
+
+func TestRetailerEndpoint(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	receiptHandler := receiptHttp.NewReceiptHandler(mock.NewMockReceiptService(ctrl))
+	retailerSvc := retailerMock.NewMockRetailerService(ctrl)
+	retailerSvc.EXPECT().ListRetailers(gomock.Any()).Return(nil, nil).AnyTimes()
+	retailerHandler := retailerHttp.NewRetailerHandler(retailerSvc)
+	gin.SetMode(gin.TestMode)
+
+	req := httptest.NewRequest("GET", "/admin/retailers", nil)
+	w := httptest.NewRecorder()
+	SetupRoutes(receiptHandler, WithRetailerHandler(retailerHandler)).ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	req = httptest.NewRequest("GET", "/admin/retailers", nil)
+	w = httptest.NewRecorder()
+	SetupRoutes(receiptHandler, WithScopeGuard(grantedScopes{retailerHttp.ScopeRetailersAdmin}), WithRetailerHandler(retailerHandler)).ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestWebhookEndpoint(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	receiptHandler := receiptHttp.NewReceiptHandler(mock.NewMockReceiptService(ctrl))
+	webhookSvc := webhookMock.NewMockWebhookService(ctrl)
+	webhookSvc.EXPECT().ListSubscriptions(gomock.Any()).Return(nil, nil).AnyTimes()
+	webhookHandler := webhookHttp.NewWebhookHandler(webhookSvc)
+	gin.SetMode(gin.TestMode)
+
+	for _, tt := range []struct {
+		name   string
+		opts   []Option
+		status int
+	}{
+		{name: "Without authentication", status: http.StatusNotFound},
+		{name: "With the scope", opts: []Option{WithScopeGuard(grantedScopes{webhookHttp.ScopeWebhooksAdmin})}, status: http.StatusOK},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/admin/webhooks", nil)
+			w := httptest.NewRecorder()
+			SetupRoutes(receiptHandler, append(tt.opts, WithWebhookHandler(webhookHandler))...).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.status, w.Code)
+		})
+	}
+}
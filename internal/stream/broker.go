@@ -0,0 +1,123 @@
+package stream
+
+import (
+	"context"
+	"expvar"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
+	"sync"
+)
+
+// DefaultClientBuffer is how many events may be queued for a client before it is disconnected.
+const DefaultClientBuffer = 64
+
+// droppedClients counts the subscribers disconnected for falling behind.
+var droppedClients = expvar.NewInt("stream_dropped_clients")
+
+// Filter selects the events a subscriber receives.
+type Filter func(event *models.ReceiptEvent) bool
+
+// Subscription receives the live events matching its filter. Events is closed when the
+// subscription is cancelled or when the subscriber falls behind; a client that was dropped
+// can resume from the last event it received.
+type Subscription struct {
+	Events <-chan *models.ReceiptEvent
+
+	events chan *models.ReceiptEvent
+	filter Filter
+}
+
+// Broker fans the receipt events out to the stream subscribers and keeps the latest events
+// in a bounded buffer so that reconnecting clients can replay what they missed. Publishing
+// never blocks on a subscriber.
+type Broker struct {
+	mu sync.Mutex
+	// replay is a ring buffer holding the latest events; next is the slot written next.
+	replay       []*models.ReceiptEvent
+	next         int
+	clientBuffer int
+	lastSequence int64
+	subscribers  map[*Subscription]struct{}
+}
+
+func NewBroker(replaySize, clientBuffer int) *Broker {
+	if clientBuffer < 1 {
+		clientBuffer = DefaultClientBuffer
+	}
+
+	return &Broker{
+		replay:       make([]*models.ReceiptEvent, replaySize),
+		clientBuffer: clientBuffer,
+		subscribers:  make(map[*Subscription]struct{}),
+	}
+}
+
+// Publish is an event bus handler. Events seen before, by sequence, are ignored.
+func (b *Broker) Publish(ctx context.Context, event *models.ReceiptEvent) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if event.Sequence <= b.lastSequence {
+		return nil
+	}
+	b.lastSequence = event.Sequence
+
+	if len(b.replay) > 0 {
+		b.replay[b.next] = event
+		b.next = (b.next + 1) % len(b.replay)
+	}
+
+	for subscription := range b.subscribers {
+		if !subscription.filter(event) {
+			continue
+		}
+
+		select {
+		case subscription.events <- event:
+		default:
+			b.remove(subscription)
+			droppedClients.Add(1)
+		}
+	}
+	return nil
+}
+
+// Subscribe registers a subscriber and returns the buffered events after lastSequence that
+// match the filter. Replay and registration happen atomically, so no event is missed or
+// repeated between the two.
+func (b *Broker) Subscribe(lastSequence int64, filter Filter) (*Subscription, []*models.ReceiptEvent) {
+	events := make(chan *models.ReceiptEvent, b.clientBuffer)
+	subscription := &Subscription{Events: events, events: events, filter: filter}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var replay []*models.ReceiptEvent
+	if lastSequence > 0 {
+		for i := range b.replay {
+			event := b.replay[(b.next+i)%len(b.replay)]
+			if event != nil && event.Sequence > lastSequence && filter(event) {
+				replay = append(replay, event)
+			}
+		}
+	}
+
+	b.subscribers[subscription] = struct{}{}
+	return subscription, replay
+}
+
+// Unsubscribe stops the subscription. It is safe to call more than once.
+func (b *Broker) Unsubscribe(subscription *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.remove(subscription)
+}
+
+// remove must be called with the lock held.
+func (b *Broker) remove(subscription *Subscription) {
+	if _, ok := b.subscribers[subscription]; !ok {
+		return
+	}
+	delete(b.subscribers, subscription)
+	close(subscription.events)
+}
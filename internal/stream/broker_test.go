@@ -0,0 +1,84 @@
+package stream
+
+import (
+	"context"
+	"github.com/CarlosMtz98/receipt-processor-challenge/internal/domain/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func newEvent(sequence int64, retailer string) *models.ReceiptEvent {
+	return &models.ReceiptEvent{ID: uuid.New(), Sequence: sequence, Type: models.ReceiptCreated, Retailer: retailer}
+}
+
+func all(*models.ReceiptEvent) bool { return true }
+
+func TestBroker(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Bounded replay", func(t *testing.T) {
+		broker := NewBroker(3, 1)
+		for sequence := int64(1); sequence <= 5; sequence++ {
+			assert.NoError(t, broker.Publish(ctx, newEvent(sequence, "Target")))
+		}
+
+		_, replay := broker.Subscribe(1, all)
+		assert.Len(t, replay, 3)
+		assert.Equal(t, int64(3), replay[0].Sequence)
+		assert.Equal(t, int64(5), replay[2].Sequence)
+
+		_, replay = broker.Subscribe(4, all)
+		assert.Len(t, replay, 1)
+
+		_, replay = broker.Subscribe(0, all)
+		assert.Empty(t, replay)
+	})
+
+	t.Run("Duplicates are ignored", func(t *testing.T) {
+		broker := NewBroker(3, 4)
+		subscription, _ := broker.Subscribe(0, all)
+
+		event := newEvent(1, "Target")
+		assert.NoError(t, broker.Publish(ctx, event))
+		assert.NoError(t, broker.Publish(ctx, event))
+		assert.Len(t, subscription.Events, 1)
+	})
+
+	t.Run("Filter", func(t *testing.T) {
+		broker := NewBroker(3, 4)
+		subscription, _ := broker.Subscribe(0, func(event *models.ReceiptEvent) bool {
+			return event.Retailer == "Walmart"
+		})
+
+		assert.NoError(t, broker.Publish(ctx, newEvent(1, "Target")))
+		assert.NoError(t, broker.Publish(ctx, newEvent(2, "Walmart")))
+		assert.Equal(t, int64(2), (<-subscription.Events).Sequence)
+	})
+
+	t.Run("Slow consumers are dropped without blocking", func(t *testing.T) {
+		broker := NewBroker(10, 2)
+		slow, _ := broker.Subscribe(0, all)
+		fast, _ := broker.Subscribe(0, all)
+
+		for sequence := int64(1); sequence <= 3; sequence++ {
+			assert.NoError(t, broker.Publish(ctx, newEvent(sequence, "Target")))
+			<-fast.Events
+		}
+
+		var received []int64
+		for event := range slow.Events {
+			received = append(received, event.Sequence)
+		}
+		assert.Equal(t, []int64{1, 2}, received)
+
+		// The dropped client resumes from the last event it received.
+		_, replay := broker.Subscribe(2, all)
+		assert.Equal(t, int64(3), replay[0].Sequence)
+
+		broker.Unsubscribe(slow)
+		broker.Unsubscribe(fast)
+		_, open := <-fast.Events
+		assert.False(t, open)
+	})
+}
@@ -0,0 +1,75 @@
+// Package currency knows the precision of the ISO 4217 currencies receipts are paid in, and
+// converts their amounts with exchange rates loaded from a local file.
+package currency
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// DefaultCurrency is the currency of the receipts that don't state one.
+const DefaultCurrency = "USD"
+
+// minorUnits lists the currencies whose amounts don't have two decimals, from the ISO 4217
+// list of codes.
+var minorUnits = map[string]int{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0, "PYG": 0,
+	"RWF": 0, "UGX": 0, "UYI": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+	"CLF": 4, "UYW": 4,
+}
+
+// MinorUnits returns the number of decimals of the amounts in the currency, e.g. 2 for USD,
+// 0 for JPY and 3 for KWD. An empty code is the default currency.
+func MinorUnits(code string) int {
+	if units, ok := minorUnits[code]; ok {
+		return units
+	}
+	return 2
+}
+
+// ToMinor rounds an amount to the minor unit of the currency, e.g. 12.345 USD to 1235 cents.
+func ToMinor(amount float64, code string) int64 {
+	return int64(math.Round(amount * math.Pow10(MinorUnits(code))))
+}
+
+// ToMajor returns an amount in minor units in the main unit of the currency, e.g. 1235 cents
+// to 12.35 USD.
+func ToMajor(amount int64, code string) float64 {
+	return float64(amount) / math.Pow10(MinorUnits(code))
+}
+
+// ParseAmount parses an amount such as "12.35" in minor units of the currency.
+func ParseAmount(amount string, code string) (int64, error) {
+	value, err := strconv.ParseFloat(amount, 64)
+	if err != nil {
+		return 0, err
+	}
+	return ToMinor(value, code), nil
+}
+
+// FormatAmount writes an amount in minor units with the decimals of the currency, e.g. 1235
+// cents as "12.35" and 1235 yen as "1235".
+func FormatAmount(amount int64, code string) string {
+	units := MinorUnits(code)
+	sign := ""
+	if amount < 0 {
+		sign, amount = "-", -amount
+	}
+	if units == 0 {
+		return fmt.Sprintf("%s%d", sign, amount)
+	}
+	scale := int64(math.Pow10(units))
+	return fmt.Sprintf("%s%d.%0*d", sign, amount/scale, units, amount%scale)
+}
+
+// Convert converts an amount written in the currency from to the currency to, where one unit of
+// from is worth rate units of to. The result is rounded to the minor unit of to.
+func Convert(amount string, from string, to string, rate float64) (string, error) {
+	value, err := ParseAmount(amount, from)
+	if err != nil {
+		return "", err
+	}
+	return FormatAmount(ToMinor(ToMajor(value, from)*rate, to), to), nil
+}
@@ -0,0 +1,49 @@
+package currency
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestMinorUnits(t *testing.T) {
+	assert.Equal(t, 2, MinorUnits("USD"))
+	assert.Equal(t, 2, MinorUnits(""))
+	assert.Equal(t, 0, MinorUnits("JPY"))
+	assert.Equal(t, 3, MinorUnits("KWD"))
+}
+
+func TestAmounts(t *testing.T) {
+	for _, tt := range []struct {
+		amount string
+		code   string
+		minor  int64
+	}{
+		{amount: "12.35", code: "USD", minor: 1235},
+		{amount: "1500", code: "JPY", minor: 1500},
+		{amount: "1.250", code: "KWD", minor: 1250},
+		{amount: "0.07", code: "EUR", minor: 7},
+	} {
+		minor, err := ParseAmount(tt.amount, tt.code)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.minor, minor, tt.amount)
+		assert.Equal(t, tt.amount, FormatAmount(minor, tt.code))
+	}
+
+	assert.Equal(t, "-0.05", FormatAmount(-5, "USD"))
+	_, err := ParseAmount("twelve", "USD")
+	assert.Error(t, err)
+}
+
+func TestConvert(t *testing.T) {
+	converted, err := Convert("1500", "JPY", "USD", 0.008383)
+	assert.NoError(t, err)
+	assert.Equal(t, "12.57", converted)
+
+	converted, err = Convert("12.57", "USD", "JPY", 119.29)
+	assert.NoError(t, err)
+	assert.Equal(t, "1499", converted)
+
+	converted, err = Convert("1.250", "KWD", "EUR", 2.9758)
+	assert.NoError(t, err)
+	assert.Equal(t, "3.72", converted)
+}
@@ -0,0 +1,107 @@
+package currency
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"time"
+)
+
+// MaxRateAge is how many days before a date its rates can be taken from, so that the rates
+// published on a Friday also cover the weekend and bank holidays.
+const MaxRateAge = 7
+
+var (
+	// ErrNoRate is returned when there is no exchange rate for a currency near a date
+	ErrNoRate = errors.New("no exchange rate")
+	// ErrInvalidRates is returned when the exchange-rate file cannot be read
+	ErrInvalidRates = errors.New("the exchange rates are not valid")
+
+	codePattern = regexp.MustCompile(`^[A-Z]{3}$`)
+)
+
+// Rate is the rate an amount was converted with: one unit of Currency is worth Rate units of
+// Base on Date.
+type Rate struct {
+	Currency string
+	Base     string
+	Rate     float64
+	Date     string
+}
+
+// Rates are the exchange rates of every currency to a base currency, by day.
+type Rates struct {
+	base string
+	days map[string]map[string]float64
+}
+
+// NewRates builds an empty rate table, which only knows the base currency.
+func NewRates(base string) *Rates {
+	return &Rates{base: base, days: map[string]map[string]float64{}}
+}
+
+// LoadRates reads a rate table from a JSON file keyed by date, giving for every currency the
+// value of one unit in the base currency:
+//
+//	{"2022-03-18": {"EUR": 1.1052, "JPY": 0.008383}}
+func LoadRates(path string, base string) (*Rates, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ParseRates(file, base)
+}
+
+// ParseRates reads a rate table in the format of LoadRates.
+func ParseRates(r io.Reader, base string) (*Rates, error) {
+	var days map[string]map[string]float64
+	if err := json.NewDecoder(r).Decode(&days); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRates, err)
+	}
+	for date, rates := range days {
+		if _, err := time.Parse(time.DateOnly, date); err != nil {
+			return nil, fmt.Errorf("%w: %q is not a YYYY-MM-DD date", ErrInvalidRates, date)
+		}
+		for code, rate := range rates {
+			if !codePattern.MatchString(code) {
+				return nil, fmt.Errorf("%w: %q is not an ISO 4217 code", ErrInvalidRates, code)
+			}
+			if rate <= 0 {
+				return nil, fmt.Errorf("%w: the rate of %s on %s must be positive", ErrInvalidRates, code, date)
+			}
+		}
+	}
+	rates := NewRates(base)
+	if days != nil {
+		rates.days = days
+	}
+	return rates, nil
+}
+
+// Base is the currency the amounts are converted to.
+func (r *Rates) Base() string {
+	return r.base
+}
+
+// Lookup returns the rate of the currency on the date, or on the closest earlier day with a
+// rate up to MaxRateAge days before. The base currency has a rate of 1.
+func (r *Rates) Lookup(code string, date string) (Rate, error) {
+	if code == r.base {
+		return Rate{Currency: code, Base: r.base, Rate: 1, Date: date}, nil
+	}
+	day, err := time.Parse(time.DateOnly, date)
+	if err != nil {
+		return Rate{}, err
+	}
+	for age := 0; age <= MaxRateAge; age++ {
+		published := day.AddDate(0, 0, -age).Format(time.DateOnly)
+		if rate, ok := r.days[published][code]; ok {
+			return Rate{Currency: code, Base: r.base, Rate: rate, Date: published}, nil
+		}
+	}
+	return Rate{}, fmt.Errorf("%w from %s to %s on %s", ErrNoRate, code, r.base, date)
+}
@@ -0,0 +1,59 @@
+package currency
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestLoadRates(t *testing.T) {
+	rates, err := LoadRates("../../examples/exchange-rates.json", "USD")
+	require.NoError(t, err)
+	assert.Equal(t, "USD", rates.Base())
+
+	rate, err := rates.Lookup("JPY", "2022-03-18")
+	assert.NoError(t, err)
+	assert.Equal(t, Rate{Currency: "JPY", Base: "USD", Rate: 0.008383, Date: "2022-03-18"}, rate)
+
+	_, err = LoadRates("missing.json", "USD")
+	assert.Error(t, err)
+}
+
+func TestParseRates(t *testing.T) {
+	for name, document := range map[string]string{
+		"Not JSON":        `{"2022-03-18": `,
+		"Date":            `{"18/03/2022": {"EUR": 1.1058}}`,
+		"Currency code":   `{"2022-03-18": {"euro": 1.1058}}`,
+		"Rate below zero": `{"2022-03-18": {"EUR": -1.1058}}`,
+	} {
+		_, err := ParseRates(strings.NewReader(document), "USD")
+		assert.ErrorIs(t, err, ErrInvalidRates, name)
+	}
+}
+
+func TestRates_Lookup(t *testing.T) {
+	rates, err := ParseRates(strings.NewReader(`{"2022-03-18": {"EUR": 1.1058}, "2022-03-21": {"EUR": 1.1032}}`), "USD")
+	require.NoError(t, err)
+
+	rate, err := rates.Lookup("EUR", "2022-03-21")
+	assert.NoError(t, err)
+	assert.Equal(t, 1.1032, rate.Rate)
+
+	// The rate published on Friday applies over the weekend.
+	rate, err = rates.Lookup("EUR", "2022-03-20")
+	assert.NoError(t, err)
+	assert.Equal(t, Rate{Currency: "EUR", Base: "USD", Rate: 1.1058, Date: "2022-03-18"}, rate)
+
+	rate, err = rates.Lookup("USD", "2022-03-20")
+	assert.NoError(t, err)
+	assert.Equal(t, 1.0, rate.Rate)
+
+	_, err = rates.Lookup("EUR", "2022-03-10")
+	assert.ErrorIs(t, err, ErrNoRate)
+	_, err = rates.Lookup("EUR", "2022-03-29")
+	assert.ErrorIs(t, err, ErrNoRate)
+	_, err = rates.Lookup("GBP", "2022-03-18")
+	assert.ErrorIs(t, err, ErrNoRate)
+	assert.EqualError(t, err, "no exchange rate from GBP to USD on 2022-03-18")
+}
@@ -0,0 +1,72 @@
+// Package timezone reads the time zones of the stores receipts are issued in, given as IANA
+// names or as fixed UTC offsets.
+package timezone
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	// The zone database is embedded so that the names resolve in images without one.
+	_ "time/tzdata"
+)
+
+var offsetPattern = regexp.MustCompile(`^([+-])(\d{2}):?(\d{2})$`)
+
+// Load returns the location of an IANA time zone name such as "America/Chicago", or of a UTC
+// offset such as "-05:00" or "+0530". "UTC" and "Z" are UTC.
+func Load(name string) (*time.Location, error) {
+	if name == "Z" {
+		return time.UTC, nil
+	}
+	if match := offsetPattern.FindStringSubmatch(name); match != nil {
+		hours, _ := strconv.Atoi(match[2])
+		minutes, _ := strconv.Atoi(match[3])
+		if hours > 14 || minutes > 59 {
+			return nil, fmt.Errorf("the UTC offset %q is out of range", name)
+		}
+		seconds := (hours*60 + minutes) * 60
+		if match[1] == "-" {
+			seconds = -seconds
+		}
+		return time.FixedZone(Offset(seconds), seconds), nil
+	}
+	// An empty name or "Local" would be the zone of the server.
+	if name == "" || name == "Local" {
+		return nil, fmt.Errorf("%q is not a time zone name", name)
+	}
+	return time.LoadLocation(name)
+}
+
+// Offset writes an offset in seconds east of UTC as "+hh:mm".
+func Offset(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign, seconds = "-", -seconds
+	}
+	return fmt.Sprintf("%s%02d:%02d", sign, seconds/3600, seconds%3600/60)
+}
+
+// ParseRetailerZones reads a comma separated list of "retailer=zone" entries, e.g.
+// "Target=America/Chicago,Lawson=Asia/Tokyo", keyed by the lowercase retailer name.
+func ParseRetailerZones(value string) (map[string]string, error) {
+	zones := make(map[string]string)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		retailer, zone, found := strings.Cut(entry, "=")
+		retailer, zone = strings.TrimSpace(retailer), strings.TrimSpace(zone)
+		if !found || retailer == "" {
+			return nil, fmt.Errorf("invalid retailer time zone %q", entry)
+		}
+		if _, err := Load(zone); err != nil {
+			return nil, fmt.Errorf("invalid time zone for %s: %v", retailer, err)
+		}
+		zones[strings.ToLower(retailer)] = zone
+	}
+	return zones, nil
+}
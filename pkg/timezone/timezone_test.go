@@ -0,0 +1,45 @@
+package timezone
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestLoad(t *testing.T) {
+	at := time.Date(2022, time.January, 1, 20, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		clock string
+	}{
+		{name: "America/Chicago", clock: "14:30"},
+		{name: "Asia/Tokyo", clock: "05:30"},
+		{name: "UTC", clock: "20:30"},
+		{name: "Z", clock: "20:30"},
+		{name: "-05:00", clock: "15:30"},
+		{name: "+0530", clock: "02:00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			location, err := Load(tt.name)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.clock, at.In(location).Format("15:04"))
+		})
+	}
+
+	for _, name := range []string{"", "Local", "Mars/Olympus", "+15:00", "-05:75", "5:00"} {
+		_, err := Load(name)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestParseRetailerZones(t *testing.T) {
+	zones, err := ParseRetailerZones(" Target=America/Chicago, Lawson = Asia/Tokyo,,")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"target": "America/Chicago", "lawson": "Asia/Tokyo"}, zones)
+
+	for _, value := range []string{"Target", "=UTC", "Target=Mars/Olympus"} {
+		_, err := ParseRetailerZones(value)
+		assert.Error(t, err, value)
+	}
+}
@@ -1,13 +1,41 @@
 package utils
 
 import (
+	"github.com/CarlosMtz98/receipt-processor-challenge/pkg/currency"
 	"github.com/go-playground/validator/v10"
+	"reflect"
 	"regexp"
 )
 
-// CurrencyValidator is a custom function to verify that a string matches the currency format
+// amountPatterns match the amounts of the currencies with 0 to 4 decimals
+var amountPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`^\d+$`),
+	regexp.MustCompile(`^\d+\.\d$`),
+	regexp.MustCompile(`^\d+\.\d{2}$`),
+	regexp.MustCompile(`^\d+\.\d{3}$`),
+	regexp.MustCompile(`^\d+\.\d{4}$`),
+}
+
+// CurrencyValidator is a custom function to verify that a string matches the currency format:
+// an amount with the decimals of the Currency field of the validated struct, e.g. none for JPY
+// and three for KWD, or two when there is no such field
 func CurrencyValidator(fl validator.FieldLevel) bool {
-	currencyPattern := `^\d+\.\d{2}$`
-	currencyRegex := regexp.MustCompile(currencyPattern)
-	return currencyRegex.MatchString(fl.Field().String())
+	return amountPatterns[currency.MinorUnits(amountCurrency(fl))].MatchString(fl.Field().String())
+}
+
+// amountCurrency returns the Currency field of the validated struct, which also applies to the
+// amounts of its nested structs such as the items of a receipt
+func amountCurrency(fl validator.FieldLevel) string {
+	top := fl.Top()
+	for top.Kind() == reflect.Ptr || top.Kind() == reflect.Interface {
+		top = top.Elem()
+	}
+	if top.Kind() != reflect.Struct {
+		return ""
+	}
+	field := top.FieldByName("Currency")
+	if !field.IsValid() || field.Kind() != reflect.String {
+		return ""
+	}
+	return field.String()
 }
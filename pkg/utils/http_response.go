@@ -1,16 +1,20 @@
 package utils
 
 import (
+	"encoding/json"
 	"github.com/CarlosMtz98/receipt-processor-challenge/internal/dto"
 	"github.com/gin-gonic/gin"
 	"net/http"
 )
 
+// ProblemContentType is the media type of RFC 7807 problem responses.
+const ProblemContentType = "application/problem+json"
+
 func HandleBadRequest(c *gin.Context, message string, err error) {
 	c.JSON(http.StatusBadRequest, dto.ResponseErrorModel{
 		Code:    http.StatusBadRequest,
 		Message: message,
-		Details: err.Error(),
+		Details: errorDetails(err),
 	})
 }
 
@@ -25,6 +29,63 @@ func HandleInternalError(c *gin.Context, message string, err error) {
 	c.JSON(http.StatusInternalServerError, dto.ResponseErrorModel{
 		Code:    http.StatusInternalServerError,
 		Message: message,
-		Details: err.Error(),
+		Details: errorDetails(err),
+	})
+}
+
+func HandleUnauthorized(c *gin.Context, message string, err error) {
+	c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+	c.AbortWithStatusJSON(http.StatusUnauthorized, dto.ResponseErrorModel{
+		Code:    http.StatusUnauthorized,
+		Message: message,
+		Details: errorDetails(err),
+	})
+}
+
+func HandleForbidden(c *gin.Context, message string) {
+	c.Header("WWW-Authenticate", `Bearer error="insufficient_scope"`)
+	c.AbortWithStatusJSON(http.StatusForbidden, dto.ResponseErrorModel{
+		Code:    http.StatusForbidden,
+		Message: message,
 	})
 }
+
+func errorDetails(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+func HandleTooManyRequests(c *gin.Context, message string) {
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, dto.ResponseErrorModel{
+		Code:    http.StatusTooManyRequests,
+		Message: message,
+	})
+}
+
+func HandleConflict(c *gin.Context, message string) {
+	c.JSON(http.StatusConflict, dto.ResponseErrorModel{
+		Code:    http.StatusConflict,
+		Message: message,
+	})
+}
+
+// HandleProblem aborts the request with an RFC 7807 problem.
+func HandleProblem(c *gin.Context, problem dto.ProblemDetails) {
+	c.Abort()
+	c.Render(problem.Status, problemRender{problem: problem})
+}
+
+type problemRender struct {
+	problem dto.ProblemDetails
+}
+
+func (r problemRender) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	return json.NewEncoder(w).Encode(r.problem)
+}
+
+func (r problemRender) WriteContentType(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", ProblemContentType)
+}
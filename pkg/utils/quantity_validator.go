@@ -0,0 +1,20 @@
+package utils
+
+import (
+	"github.com/go-playground/validator/v10"
+	"regexp"
+	"strconv"
+)
+
+var quantityRegex = regexp.MustCompile(`^\d+(\.\d{1,3})?$`)
+
+// QuantityValidator verifies that a string is a positive quantity with up to three decimals,
+// enough for weights in kilograms or pounds
+func QuantityValidator(fl validator.FieldLevel) bool {
+	value := fl.Field().String()
+	if !quantityRegex.MatchString(value) {
+		return false
+	}
+	quantity, err := strconv.ParseFloat(value, 64)
+	return err == nil && quantity > 0
+}
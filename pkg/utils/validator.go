@@ -13,6 +13,12 @@ func init() {
 	if err := validate.RegisterValidation("currency", CurrencyValidator); err != nil {
 		return
 	}
+	if err := validate.RegisterValidation("quantity", QuantityValidator); err != nil {
+		return
+	}
+	if err := validate.RegisterValidation("zone", ZoneValidator); err != nil {
+		return
+	}
 }
 
 func ValidateStruct(ctx context.Context, s interface{}) error {
@@ -0,0 +1,13 @@
+package utils
+
+import (
+	"github.com/CarlosMtz98/receipt-processor-challenge/pkg/timezone"
+	"github.com/go-playground/validator/v10"
+)
+
+// ZoneValidator verifies that a string is an IANA time zone name, such as America/Chicago, or
+// a UTC offset such as -05:00
+func ZoneValidator(fl validator.FieldLevel) bool {
+	_, err := timezone.Load(fl.Field().String())
+	return err == nil
+}